@@ -1,14 +1,31 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
+	"garage-api/internal/analytics"
 	"garage-api/internal/config"
 	"garage-api/internal/database"
+	"garage-api/internal/feed"
 	"garage-api/internal/handlers"
+	"garage-api/internal/importer"
+	"garage-api/internal/invoice"
 	"garage-api/internal/middleware"
 	"garage-api/internal/models"
+	"garage-api/internal/notify"
+	"garage-api/internal/payment"
+	"garage-api/internal/recommend"
+	"garage-api/internal/scheduler"
+	"garage-api/internal/tax"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -57,7 +74,184 @@ func main() {
 
 	// Initialize models
 	productModel := &models.ProductModel{DB: db}
-	productHandler := &handlers.ProductHandler{ProductModel: productModel}
+	translationModel := &models.TranslationModel{DB: db}
+	attributeModel := &models.AttributeModel{DB: db}
+	viewModel := &models.ViewModel{DB: db}
+	views := &analytics.Recorder{Store: viewModel, FlushInterval: cfg.ViewFlushInterval}
+	productHandler := &handlers.ProductHandler{
+		ProductModel:     productModel,
+		RelationModel:    &models.RelationModel{DB: db},
+		BundleModel:      &models.BundleModel{DB: db},
+		TranslationModel: translationModel,
+		AttributeModel:   attributeModel,
+		Locales:          cfg.Locales,
+		Views:            views,
+	}
+	translationHandler := &handlers.TranslationHandler{TranslationModel: translationModel, ProductModel: productModel, Locales: cfg.Locales}
+	taxModel := &models.TaxModel{DB: db}
+	taxCalculator := &tax.TableCalculator{Rates: taxModel, Mode: cfg.TaxMode, Rounding: cfg.TaxRounding}
+	taxAddress := tax.Address{Country: cfg.TaxCountry, Region: cfg.TaxRegion}.Normalize()
+	taxHandler := &handlers.TaxHandler{TaxModel: taxModel}
+	cartModel := &models.CartModel{DB: db}
+	authHandler := &handlers.AuthHandler{UserModel: &models.UserModel{DB: db}, CartModel: cartModel}
+	cartHandler := &handlers.CartHandler{CartModel: cartModel, Tax: taxCalculator, TaxAddress: taxAddress}
+	orderModel := &models.OrderModel{DB: db, Tax: taxCalculator}
+	addressModel := &models.AddressModel{DB: db}
+	addressHandler := &handlers.AddressHandler{AddressModel: addressModel}
+	orderHandler := &handlers.OrderHandler{OrderModel: orderModel, AddressModel: addressModel, TaxAddress: taxAddress}
+	invoiceHandler := &handlers.InvoiceHandler{
+		OrderModel:   orderModel,
+		InvoiceModel: &models.InvoiceModel{DB: db},
+		Seller: invoice.Seller{
+			Name:    cfg.SellerName,
+			Address: cfg.SellerAddress,
+			VATID:   cfg.SellerVATID,
+			Email:   cfg.SellerEmail,
+		},
+		Currency: cfg.Currency,
+	}
+	shippingHandler := &handlers.ShippingHandler{ShippingModel: &models.ShippingModel{DB: db}, CartModel: cartModel, AddressModel: addressModel, DefaultAddress: taxAddress}
+
+	// Payment providers are keyed by the name used in webhook URLs. Webhooks
+	// mark orders paid, so no provider is registered without a webhook secret.
+	if cfg.PaymentProvider == "" {
+		log.Fatal("❌ PAYMENT_PROVIDER is not set")
+	}
+	providers := map[string]payment.PaymentProvider{}
+	if cfg.StripeSecretKey != "" {
+		if cfg.StripeWebhookSecret == "" {
+			log.Fatal("❌ STRIPE_WEBHOOK_SECRET must be set along with STRIPE_SECRET_KEY")
+		}
+		providers["stripe"] = &payment.Stripe{
+			SecretKey:     cfg.StripeSecretKey,
+			WebhookSecret: cfg.StripeWebhookSecret,
+			BaseURL:       cfg.StripeAPIURL,
+			Client:        &http.Client{Timeout: 30 * time.Second},
+		}
+	}
+	if cfg.PaymentProvider == "fake" {
+		if cfg.FakePaymentWebhookSecret == "" {
+			log.Fatal("❌ FAKE_PAYMENT_WEBHOOK_SECRET must be set to use the fake payment provider")
+		}
+		log.Println("⚠️ Using the fake payment provider; no money will be collected")
+		providers["fake"] = &payment.Fake{WebhookSecret: cfg.FakePaymentWebhookSecret}
+	}
+	if _, ok := providers[cfg.PaymentProvider]; !ok {
+		log.Fatalf("❌ Payment provider %q is not configured", cfg.PaymentProvider)
+	}
+	paymentHandler := &handlers.PaymentHandler{
+		Providers:       providers,
+		DefaultProvider: cfg.PaymentProvider,
+		Currency:        cfg.Currency,
+		OrderModel:      orderModel,
+		PaymentModel:    &models.PaymentModel{DB: db},
+	}
+	returnHandler := &handlers.ReturnHandler{
+		ReturnModel:  &models.ReturnModel{DB: db},
+		Providers:    providers,
+		PaymentModel: paymentHandler.PaymentModel,
+	}
+	reviewHandler := &handlers.ReviewHandler{ReviewModel: &models.ReviewModel{DB: db}}
+	wishlistHandler := &handlers.WishlistHandler{WishlistModel: &models.WishlistModel{DB: db}}
+	viewHandler := &handlers.ViewHandler{ViewModel: viewModel}
+	recommendationModel := &models.RecommendationModel{DB: db}
+	recommendationHandler := &handlers.RecommendationHandler{RecommendationModel: recommendationModel, ProductModel: productModel}
+	priceModel := &models.PriceModel{DB: db}
+	publishingHandler := &handlers.PublishingHandler{PublishingModel: &models.PublishingModel{DB: db}, ProductModel: productModel}
+	priceHandler := &handlers.PriceHandler{PriceModel: priceModel, ProductModel: productModel}
+	categoryModel := &models.CategoryModel{DB: db}
+	categoryHandler := &handlers.CategoryHandler{CategoryModel: categoryModel}
+	attributeHandler := &handlers.AttributeHandler{AttributeModel: attributeModel, CategoryModel: categoryModel}
+	promotionHandler := &handlers.PromotionHandler{PromotionModel: &models.PromotionModel{DB: db}}
+	tagHandler := &handlers.TagHandler{TagModel: &models.TagModel{DB: db}, ProductModel: productModel}
+
+	// Background work runs until the server has shut down, so that views
+	// recorded by the last requests are still written and background imports
+	// end before the process does
+	background, stopBackground := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+
+	importHandler := &handlers.ImportHandler{
+		Importer: &importer.Importer{Products: productModel, Jobs: importer.NewJobStore(), Background: background, Workers: &workers},
+	}
+	feedHandler := &handlers.FeedHandler{
+		ProductModel: productModel,
+		Options: feed.Options{
+			Title:       cfg.StoreName,
+			Description: cfg.StoreName + " product catalog",
+			SiteURL:     cfg.SiteURL,
+			Currency:    cfg.Currency,
+		},
+		Cache: &feed.Cache{TTL: cfg.FeedCacheTTL},
+	}
+
+	// Back-in-stock notifications are queued by the database and sent in the
+	// background
+	var mailer notify.Mailer = &notify.LogMailer{}
+	if cfg.SMTPAddr != "" {
+		smtpMailer := &notify.SMTPMailer{Addr: cfg.SMTPAddr, From: cfg.MailFrom}
+		if cfg.SMTPUsername != "" {
+			host, _, _ := net.SplitHostPort(cfg.SMTPAddr)
+			smtpMailer.Auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, host)
+		}
+		mailer = smtpMailer
+	} else {
+		log.Println("⚠️ SMTP_ADDR is not set; emails will only be logged")
+	}
+	dispatcher := &notify.Dispatcher{
+		Queue:    models.NotificationModel{DB: db},
+		Notifier: &notify.EmailNotifier{Mailer: mailer, StoreName: cfg.StoreName, SiteURL: cfg.SiteURL},
+		Interval: cfg.NotifyInterval,
+	}
+
+	workers.Add(2)
+	go func() {
+		defer workers.Done()
+		dispatcher.Run(background)
+	}()
+
+	// Product views are recorded off the request path and written in batches
+	go func() {
+		defer workers.Done()
+		views.Run(background)
+	}()
+
+	// Scheduled price changes show as soon as they fall due; this job stores
+	// them and records the price revisions
+	jobs := scheduler.Start(background, scheduler.Job{
+		Name:     "price changes",
+		Interval: cfg.PriceSchedulerInterval,
+		Run: func(ctx context.Context) error {
+			applied, err := priceModel.ApplyDue(100)
+			if applied > 0 {
+				log.Printf("💲 Applied %d scheduled price changes", applied)
+			}
+			return err
+		},
+	}, scheduler.Job{
+		// Raw views past the retention are rolled up into daily counts
+		Name:     "view rollup",
+		Interval: cfg.ViewRollupInterval,
+		Run: func(ctx context.Context) error {
+			rolled, err := viewModel.Rollup(time.Now().Add(-cfg.ViewRetention))
+			if rolled > 0 {
+				log.Printf("📈 Rolled up views into %d daily counts", rolled)
+			}
+			return err
+		},
+	}, scheduler.Job{
+		// "Customers who bought this also bought" is recomputed from all
+		// orders and views at once
+		Name:     "recommendations",
+		Interval: cfg.RecommendationInterval,
+		Run: func(ctx context.Context) error {
+			computed, err := recommendationModel.Rebuild(recommend.Options{})
+			if err == nil {
+				log.Printf("🧲 Computed %d product recommendations", computed)
+			}
+			return err
+		},
+	})
 
 	// Initialize router
 	log.Println("🛠️ Setting up router...")
@@ -77,9 +271,14 @@ func main() {
 	log.Println("🔓 Setting up public routes...")
 	public := router.Group("/api/v1")
 	{
-		public.POST("/register", handlers.Register)
-		public.POST("/login", handlers.Login)
-		public.GET("/products", productHandler.GetAllProducts)
+		public.POST("/register", authHandler.Register)
+		public.POST("/login", authHandler.Login)
+		public.GET("/products/:id/reviews", reviewHandler.GetProductReviews)
+		public.GET("/categories", categoryHandler.GetAllCategories)
+		public.GET("/categories/:id/attributes", attributeHandler.GetCategoryAttributes)
+		public.GET("/tags", tagHandler.GetAllTags)
+		public.GET("/feeds/google.xml", feedHandler.GetGoogleFeed)
+		public.POST("/webhooks/payments/:provider", paymentHandler.HandleWebhook)
 		public.GET("/health", func(c *gin.Context) {
 			c.JSON(200, gin.H{
 				"status": "ok",
@@ -88,15 +287,138 @@ func main() {
 		})
 	}
 
+	// Cart routes, available to guests and signed-in users
+	log.Println("🛒 Setting up cart routes...")
+	cart := router.Group("/api/v1/cart")
+	cart.Use(middleware.OptionalJWTAuth())
+	{
+		cart.GET("", cartHandler.GetCart)
+		cart.POST("/items", cartHandler.AddCartItem)
+		cart.PUT("/items/:product_id", cartHandler.UpdateCartItem)
+		cart.DELETE("/items/:product_id", cartHandler.RemoveCartItem)
+		cart.POST("/coupon", cartHandler.ApplyCoupon)
+		cart.DELETE("/coupon", cartHandler.RemoveCoupon)
+	}
+	shippingQuote := router.Group("/api/v1/shipping")
+	shippingQuote.Use(middleware.OptionalJWTAuth())
+	shippingQuote.POST("/quote", shippingHandler.Quote)
+
+	// Catalog routes; editors and admins also see products that are not published
+	catalog := router.Group("/api/v1/products")
+	catalog.Use(middleware.OptionalJWTAuth())
+	{
+		catalog.GET("", productHandler.GetAllProducts)
+		catalog.GET("/compare", productHandler.CompareProducts)
+		catalog.GET("/trending", viewHandler.GetTrendingProducts)
+		catalog.GET("/:id", productHandler.GetProductByID)
+		catalog.GET("/:id/recommendations", recommendationHandler.GetRecommendations)
+		catalog.GET("/by-slug/:slug", productHandler.GetProductBySlug)
+	}
+
 	// Protected routes
 	log.Println("🔐 Setting up protected routes...")
 	protected := router.Group("/api/v1")
 	protected.Use(middleware.JWTAuth())
 	{
-		protected.POST("/products", productHandler.CreateProduct)
-		protected.GET("/products/:id", productHandler.GetProductByID)
-		protected.PUT("/products/:id", productHandler.UpdateProduct)
-		protected.DELETE("/products/:id", productHandler.DeleteProduct)
+		protected.POST("/products/:id/reviews", reviewHandler.CreateReview)
+		protected.POST("/reviews/:id/helpful", reviewHandler.VoteHelpful)
+		protected.GET("/me/reviews", reviewHandler.GetMyReviews)
+		protected.GET("/me/wishlist", wishlistHandler.GetMyWishlist)
+		protected.POST("/me/wishlist", wishlistHandler.AddToMyWishlist)
+		protected.DELETE("/me/wishlist/:product_id", wishlistHandler.RemoveFromMyWishlist)
+		protected.GET("/me/stock-subscriptions", wishlistHandler.GetMyStockSubscriptions)
+		protected.POST("/me/stock-subscriptions", wishlistHandler.SubscribeToStock)
+		protected.DELETE("/me/stock-subscriptions/:product_id", wishlistHandler.UnsubscribeFromStock)
+		protected.GET("/me/recently-viewed", viewHandler.GetRecentlyViewed)
+		protected.GET("/me/addresses", addressHandler.GetMyAddresses)
+		protected.POST("/me/addresses", addressHandler.CreateMyAddress)
+		protected.GET("/me/addresses/:id", addressHandler.GetMyAddress)
+		protected.PUT("/me/addresses/:id", addressHandler.UpdateMyAddress)
+		protected.DELETE("/me/addresses/:id", addressHandler.DeleteMyAddress)
+		protected.POST("/checkout", orderHandler.Checkout)
+		protected.GET("/me/orders", orderHandler.GetMyOrders)
+		protected.GET("/me/orders/:id", orderHandler.GetMyOrder)
+		protected.POST("/me/orders/:id/cancel", orderHandler.CancelMyOrder)
+		protected.POST("/me/orders/:id/payment", paymentHandler.CreatePayment)
+		protected.GET("/orders/:id/invoice.pdf", invoiceHandler.GetInvoicePDF)
+		protected.GET("/orders/:id/credit-note.pdf", invoiceHandler.GetCreditNotePDF)
+		protected.POST("/me/orders/:id/returns", returnHandler.CreateReturn)
+		protected.GET("/me/returns", returnHandler.GetMyReturns)
+		protected.GET("/me/returns/:id", returnHandler.GetMyReturn)
+	}
+
+	// Editor routes
+	log.Println("✏️ Setting up editor routes...")
+	editor := router.Group("/api/v1")
+	editor.Use(middleware.JWTAuth(), middleware.RequireRole(models.RoleEditor, models.RoleAdmin))
+	{
+		editor.POST("/products", productHandler.CreateProduct)
+		editor.POST("/products/bulk", productHandler.BulkProducts)
+		editor.GET("/products/export", productHandler.ExportProducts)
+		editor.POST("/products/import", importHandler.ImportProducts)
+		editor.GET("/products/import/:id", importHandler.GetImportJob)
+		editor.PUT("/products/:id", productHandler.UpdateProduct)
+		editor.DELETE("/products/:id", productHandler.DeleteProduct)
+		editor.PUT("/products/:id/tags", tagHandler.SetProductTags)
+		editor.PUT("/products/:id/status", publishingHandler.SetProductStatus)
+		editor.GET("/products/:id/status-history", publishingHandler.GetProductStatusHistory)
+		editor.GET("/products/:id/translations", translationHandler.GetProductTranslations)
+		editor.PUT("/products/:id/translations/:locale", translationHandler.SetProductTranslation)
+		editor.DELETE("/products/:id/translations/:locale", translationHandler.DeleteProductTranslation)
+		editor.GET("/translations/missing", translationHandler.GetMissingTranslations)
+		editor.POST("/categories", categoryHandler.CreateCategory)
+		editor.PUT("/categories/:id", categoryHandler.UpdateCategory)
+		editor.DELETE("/categories/:id", categoryHandler.DeleteCategory)
+		editor.PUT("/categories/:id/attributes/:code", attributeHandler.SetCategoryAttribute)
+		editor.DELETE("/categories/:id/attributes/:code", attributeHandler.DeleteCategoryAttribute)
+		editor.POST("/tags", tagHandler.CreateTag)
+		editor.DELETE("/tags/:id", tagHandler.DeleteTag)
+		editor.GET("/feeds/google/validation", feedHandler.ValidateGoogleFeed)
+	}
+
+	// Admin routes
+	log.Println("🛡️ Setting up admin routes...")
+	admin := router.Group("/api/v1")
+	admin.Use(middleware.JWTAuth(), middleware.RequireRole(models.RoleAdmin))
+	{
+		admin.PUT("/products/:id/relations", productHandler.SetProductRelations)
+		admin.PUT("/products/:id/bundle", productHandler.SetProductBundle)
+		admin.DELETE("/products/:id/bundle", productHandler.DeleteProductBundle)
+		admin.PUT("/products/:id/sale", priceHandler.SetSale)
+		admin.DELETE("/products/:id/sale", priceHandler.ClearSale)
+		admin.GET("/products/:id/price-changes", priceHandler.GetPriceChanges)
+		admin.POST("/products/:id/price-changes", priceHandler.SchedulePriceChange)
+		admin.DELETE("/products/:id/price-changes/:change_id", priceHandler.CancelPriceChange)
+		admin.GET("/products/:id/price-history", priceHandler.GetPriceHistory)
+		admin.GET("/orders", orderHandler.GetAllOrders)
+		admin.GET("/orders/:id", orderHandler.GetOrderByID)
+		admin.PUT("/orders/:id/status", orderHandler.UpdateOrderStatus)
+		admin.POST("/orders/:id/refund", paymentHandler.RefundOrder)
+		admin.GET("/returns", returnHandler.GetAllReturns)
+		admin.GET("/returns/:id", returnHandler.GetReturnByID)
+		admin.PUT("/returns/:id/status", returnHandler.UpdateReturnStatus)
+		admin.POST("/returns/:id/refund", returnHandler.RefundReturn)
+		admin.GET("/reviews", reviewHandler.GetAllReviews)
+		admin.PUT("/reviews/:id/status", reviewHandler.UpdateReviewStatus)
+		admin.DELETE("/reviews/:id", reviewHandler.DeleteReview)
+		admin.GET("/promotions", promotionHandler.GetAllPromotions)
+		admin.POST("/promotions", promotionHandler.CreatePromotion)
+		admin.GET("/promotions/:id", promotionHandler.GetPromotionByID)
+		admin.PUT("/promotions/:id", promotionHandler.UpdatePromotion)
+		admin.DELETE("/promotions/:id", promotionHandler.DeletePromotion)
+		admin.GET("/tax/classes", taxHandler.GetTaxClasses)
+		admin.POST("/tax/classes", taxHandler.CreateTaxClass)
+		admin.GET("/tax/rates", taxHandler.GetTaxRates)
+		admin.POST("/tax/rates", taxHandler.CreateTaxRate)
+		admin.DELETE("/tax/rates/:id", taxHandler.DeleteTaxRate)
+		admin.GET("/shipping/zones", shippingHandler.GetZones)
+		admin.POST("/shipping/zones", shippingHandler.CreateZone)
+		admin.PUT("/shipping/zones/:id", shippingHandler.UpdateZone)
+		admin.DELETE("/shipping/zones/:id", shippingHandler.DeleteZone)
+		admin.GET("/shipping/methods", shippingHandler.GetMethods)
+		admin.POST("/shipping/methods", shippingHandler.CreateMethod)
+		admin.PUT("/shipping/methods/:id", shippingHandler.UpdateMethod)
+		admin.DELETE("/shipping/methods/:id", shippingHandler.DeleteMethod)
 	}
 
 	// Start server
@@ -106,16 +428,142 @@ func main() {
 	log.Println("  🔓 Public:")
 	log.Println("    POST /api/v1/register")
 	log.Println("    POST /api/v1/login")
-	log.Println("    GET  /api/v1/products")
+	log.Println("    GET  /api/v1/products/:id/reviews")
+	log.Println("    GET  /api/v1/categories")
+	log.Println("    GET  /api/v1/categories/:id/attributes")
+	log.Println("    GET  /api/v1/tags")
+	log.Println("    GET  /api/v1/feeds/google.xml")
+	log.Println("    POST /api/v1/webhooks/payments/:provider")
+	log.Println("  🛒 Cart (guest or signed in):")
+	log.Println("    GET    /api/v1/cart")
+	log.Println("    POST   /api/v1/cart/items")
+	log.Println("    PUT    /api/v1/cart/items/:product_id")
+	log.Println("    DELETE /api/v1/cart/items/:product_id")
+	log.Println("    POST   /api/v1/cart/coupon")
+	log.Println("    DELETE /api/v1/cart/coupon")
+	log.Println("    POST   /api/v1/shipping/quote")
+	log.Println("  📦 Catalog (only published products for customers):")
+	log.Println("    GET    /api/v1/products")
+	log.Println("    GET    /api/v1/products/compare")
+	log.Println("    GET    /api/v1/products/trending")
+	log.Println("    GET    /api/v1/products/:id")
+	log.Println("    GET    /api/v1/products/:id/recommendations")
+	log.Println("    GET    /api/v1/products/by-slug/:slug")
 	log.Println("  🔐 Protected:")
+	log.Println("    POST   /api/v1/products/:id/reviews")
+	log.Println("    POST   /api/v1/reviews/:id/helpful")
+	log.Println("    GET    /api/v1/me/reviews")
+	log.Println("    GET    /api/v1/me/wishlist")
+	log.Println("    POST   /api/v1/me/wishlist")
+	log.Println("    DELETE /api/v1/me/wishlist/:product_id")
+	log.Println("    GET    /api/v1/me/stock-subscriptions")
+	log.Println("    POST   /api/v1/me/stock-subscriptions")
+	log.Println("    DELETE /api/v1/me/stock-subscriptions/:product_id")
+	log.Println("    GET    /api/v1/me/recently-viewed")
+	log.Println("    POST   /api/v1/categories")
+	log.Println("    PUT    /api/v1/categories/:id")
+	log.Println("    DELETE /api/v1/categories/:id")
+	log.Println("    POST   /api/v1/tags")
+	log.Println("    DELETE /api/v1/tags/:id")
+	log.Println("    GET    /api/v1/feeds/google/validation")
+	log.Println("    GET    /api/v1/me/addresses")
+	log.Println("    POST   /api/v1/me/addresses")
+	log.Println("    GET    /api/v1/me/addresses/:id")
+	log.Println("    PUT    /api/v1/me/addresses/:id")
+	log.Println("    DELETE /api/v1/me/addresses/:id")
+	log.Println("    POST   /api/v1/checkout")
+	log.Println("    GET    /api/v1/me/orders")
+	log.Println("    GET    /api/v1/me/orders/:id")
+	log.Println("    POST   /api/v1/me/orders/:id/cancel")
+	log.Println("    POST   /api/v1/me/orders/:id/payment")
+	log.Println("    GET    /api/v1/orders/:id/invoice.pdf")
+	log.Println("    GET    /api/v1/orders/:id/credit-note.pdf")
+	log.Println("    POST   /api/v1/me/orders/:id/returns")
+	log.Println("    GET    /api/v1/me/returns")
+	log.Println("    GET    /api/v1/me/returns/:id")
+	log.Println("  ✏️ Editor:")
 	log.Println("    POST   /api/v1/products")
-	log.Println("    GET    /api/v1/products/:id")
+	log.Println("    POST   /api/v1/products/bulk")
+	log.Println("    GET    /api/v1/products/export")
+	log.Println("    POST   /api/v1/products/import")
+	log.Println("    GET    /api/v1/products/import/:id")
 	log.Println("    PUT    /api/v1/products/:id")
 	log.Println("    DELETE /api/v1/products/:id")
+	log.Println("    PUT    /api/v1/products/:id/tags")
+	log.Println("    PUT    /api/v1/products/:id/status")
+	log.Println("    GET    /api/v1/products/:id/status-history")
+	log.Println("    GET    /api/v1/products/:id/translations")
+	log.Println("    PUT    /api/v1/products/:id/translations/:locale")
+	log.Println("    DELETE /api/v1/products/:id/translations/:locale")
+	log.Println("    GET    /api/v1/translations/missing")
+	log.Println("    PUT    /api/v1/categories/:id/attributes/:code")
+	log.Println("    DELETE /api/v1/categories/:id/attributes/:code")
+	log.Println("  🛡️ Admin:")
+	log.Println("    PUT    /api/v1/products/:id/relations")
+	log.Println("    PUT    /api/v1/products/:id/bundle")
+	log.Println("    DELETE /api/v1/products/:id/bundle")
+	log.Println("    PUT    /api/v1/products/:id/sale")
+	log.Println("    DELETE /api/v1/products/:id/sale")
+	log.Println("    GET    /api/v1/products/:id/price-changes")
+	log.Println("    POST   /api/v1/products/:id/price-changes")
+	log.Println("    DELETE /api/v1/products/:id/price-changes/:change_id")
+	log.Println("    GET    /api/v1/products/:id/price-history")
+	log.Println("    GET    /api/v1/orders")
+	log.Println("    GET    /api/v1/orders/:id")
+	log.Println("    PUT    /api/v1/orders/:id/status")
+	log.Println("    POST   /api/v1/orders/:id/refund")
+	log.Println("    GET    /api/v1/returns")
+	log.Println("    GET    /api/v1/returns/:id")
+	log.Println("    PUT    /api/v1/returns/:id/status")
+	log.Println("    POST   /api/v1/returns/:id/refund")
+	log.Println("    GET    /api/v1/reviews")
+	log.Println("    PUT    /api/v1/reviews/:id/status")
+	log.Println("    DELETE /api/v1/reviews/:id")
+	log.Println("    GET    /api/v1/promotions")
+	log.Println("    POST   /api/v1/promotions")
+	log.Println("    GET    /api/v1/promotions/:id")
+	log.Println("    PUT    /api/v1/promotions/:id")
+	log.Println("    DELETE /api/v1/promotions/:id")
+	log.Println("    GET    /api/v1/tax/classes")
+	log.Println("    POST   /api/v1/tax/classes")
+	log.Println("    GET    /api/v1/tax/rates")
+	log.Println("    POST   /api/v1/tax/rates")
+	log.Println("    DELETE /api/v1/tax/rates/:id")
+	log.Println("    GET    /api/v1/shipping/zones")
+	log.Println("    POST   /api/v1/shipping/zones")
+	log.Println("    PUT    /api/v1/shipping/zones/:id")
+	log.Println("    DELETE /api/v1/shipping/zones/:id")
+	log.Println("    GET    /api/v1/shipping/methods")
+	log.Println("    POST   /api/v1/shipping/methods")
+	log.Println("    PUT    /api/v1/shipping/methods/:id")
+	log.Println("    DELETE /api/v1/shipping/methods/:id")
 	log.Println("  📚 Documentation:")
 	log.Println("    GET /swagger/*any")
 
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("❌ Failed to start server: %v", err)
+	// On SIGINT or SIGTERM, finish the requests in flight, then stop the
+	// background work; queued views are written and running imports rolled
+	// back before it returns
+	quit, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: ":" + port, Handler: router}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("❌ Failed to start server: %v", err)
+		}
+	}()
+
+	<-quit.Done()
+	log.Println("🛑 Shutting down...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("⚠️ Server shutdown: %v", err)
 	}
+
+	stopBackground()
+	workers.Wait()
+	<-jobs
+	log.Println("👋 Stopped")
 } 
\ No newline at end of file
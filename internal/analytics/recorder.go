@@ -0,0 +1,129 @@
+// Package analytics records catalog activity, such as product page views,
+// off the request path. Events are buffered in memory and written in
+// batches, so recording never waits on the database.
+package analytics
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// View is a product page seen by a customer or guest
+type View struct {
+	ProductID int
+	// UserID is 0 for guests
+	UserID   int
+	ViewedAt time.Time
+}
+
+// Store persists recorded views
+type Store interface {
+	InsertViews(views []View) error
+}
+
+// Recorder buffers views and writes them to its Store in batches. Views
+// arriving while the buffer is full are dropped rather than slowing down the
+// request that recorded them.
+type Recorder struct {
+	Store Store
+	// BufferSize caps the views waiting to be written; defaults to 1024
+	BufferSize int
+	// BatchSize caps the views written at once; defaults to 100
+	BatchSize int
+	// FlushInterval is the longest a view waits for its batch to fill;
+	// defaults to 5 seconds
+	FlushInterval time.Duration
+
+	once    sync.Once
+	views   chan View
+	dropped atomic.Int64
+}
+
+func (r *Recorder) buffer() chan View {
+	r.once.Do(func() {
+		size := r.BufferSize
+		if size <= 0 {
+			size = 1024
+		}
+		r.views = make(chan View, size)
+	})
+	return r.views
+}
+
+func (r *Recorder) batchSize() int {
+	if r.BatchSize <= 0 {
+		return 100
+	}
+	return r.BatchSize
+}
+
+// Record queues a view without blocking. It reports false when the buffer
+// is full and the view was dropped.
+func (r *Recorder) Record(v View) bool {
+	if v.ViewedAt.IsZero() {
+		v.ViewedAt = time.Now()
+	}
+	select {
+	case r.buffer() <- v:
+		return true
+	default:
+		r.dropped.Add(1)
+		return false
+	}
+}
+
+// Dropped returns how many views were dropped because the buffer was full
+func (r *Recorder) Dropped() int64 {
+	return r.dropped.Load()
+}
+
+// Run writes queued views until ctx is cancelled, then writes the views
+// still queued. A batch that fails to be written is logged and discarded.
+func (r *Recorder) Run(ctx context.Context) {
+	interval := r.FlushInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	views := r.buffer()
+	batch := make([]View, 0, r.batchSize())
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := r.Store.InsertViews(batch); err != nil {
+			log.Printf("⚠️ Writing %d product views failed: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case v := <-views:
+			batch = append(batch, v)
+			if len(batch) >= r.batchSize() {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-ctx.Done():
+			for {
+				select {
+				case v := <-views:
+					batch = append(batch, v)
+					if len(batch) >= r.batchSize() {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
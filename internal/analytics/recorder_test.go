@@ -0,0 +1,113 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryStore struct {
+	mu       sync.Mutex
+	batches  [][]View
+	fail     bool
+	attempts int
+}
+
+func (s *memoryStore) InsertViews(views []View) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	if s.fail {
+		return errors.New("database down")
+	}
+	s.batches = append(s.batches, append([]View(nil), views...))
+	return nil
+}
+
+func (s *memoryStore) sizes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var sizes []int
+	for _, b := range s.batches {
+		sizes = append(sizes, len(b))
+	}
+	return sizes
+}
+
+func TestRecorder_Record(t *testing.T) {
+	// Test case 1: A full buffer drops views instead of blocking
+	t.Run("buffer full", func(t *testing.T) {
+		r := &Recorder{Store: &memoryStore{}, BufferSize: 2}
+
+		assert.True(t, r.Record(View{ProductID: 1}))
+		assert.True(t, r.Record(View{ProductID: 2, UserID: 3}))
+		assert.False(t, r.Record(View{ProductID: 3}))
+		assert.Equal(t, int64(1), r.Dropped())
+	})
+}
+
+func TestRecorder_Run(t *testing.T) {
+	// Test case 1: Views are written in full batches, and the rest once ctx is cancelled
+	t.Run("batches", func(t *testing.T) {
+		store := &memoryStore{}
+		r := &Recorder{Store: store, BatchSize: 2, FlushInterval: time.Hour}
+		for id := 1; id <= 5; id++ {
+			r.Record(View{ProductID: id})
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			r.Run(ctx)
+			close(done)
+		}()
+
+		assert.Eventually(t, func() bool { return len(store.sizes()) >= 2 }, time.Second, time.Millisecond)
+		cancel()
+		<-done
+
+		assert.Equal(t, []int{2, 2, 1}, store.sizes())
+		assert.Equal(t, 5, store.batches[2][0].ProductID)
+		assert.False(t, store.batches[0][0].ViewedAt.IsZero())
+	})
+
+	// Test case 2: A partial batch is written after FlushInterval
+	t.Run("flush interval", func(t *testing.T) {
+		store := &memoryStore{}
+		r := &Recorder{Store: store, FlushInterval: 5 * time.Millisecond}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go r.Run(ctx)
+
+		r.Record(View{ProductID: 7, UserID: 2})
+		assert.Eventually(t, func() bool { return len(store.sizes()) == 1 }, time.Second, time.Millisecond)
+	})
+
+	// Test case 3: A failed write does not stop the recorder
+	t.Run("store fails", func(t *testing.T) {
+		store := &memoryStore{fail: true}
+		r := &Recorder{Store: store, BatchSize: 1, FlushInterval: time.Hour}
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			r.Run(ctx)
+			close(done)
+		}()
+
+		r.Record(View{ProductID: 1})
+		r.Record(View{ProductID: 2})
+		assert.Eventually(t, func() bool {
+			store.mu.Lock()
+			defer store.mu.Unlock()
+			return store.attempts == 2
+		}, time.Second, time.Millisecond)
+		cancel()
+		<-done
+
+		assert.Empty(t, store.sizes())
+	})
+}
@@ -4,6 +4,11 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"garage-api/internal/i18n"
+	"garage-api/internal/tax"
 )
 
 type Config struct {
@@ -13,6 +18,61 @@ type Config struct {
 	DBPassword string
 	DBName     string
 	DBSSLMode  string
+
+	// Storefront settings used when publishing the catalog
+	StoreName    string
+	SiteURL      string
+	Currency     string
+	FeedCacheTTL time.Duration
+
+	// Payments. PaymentProvider has no default, so a deployment never takes
+	// payments through a provider it did not configure. Stripe is enabled
+	// when a secret key is set; the fake provider, meant for development,
+	// only when it is the chosen provider and has a webhook secret.
+	PaymentProvider          string
+	StripeSecretKey          string
+	StripeWebhookSecret      string
+	StripeAPIURL             string
+	FakePaymentWebhookSecret string
+
+	// Tax. TaxCountry and TaxRegion are used when a cart or checkout names
+	// no address.
+	TaxMode     tax.Mode
+	TaxRounding tax.Rounding
+	TaxCountry  string
+	TaxRegion   string
+
+	// Seller details printed on invoices. SellerAddress lines are separated
+	// by ";" or newlines in SELLER_ADDRESS.
+	SellerName    string
+	SellerAddress []string
+	SellerVATID   string
+	SellerEmail   string
+
+	// Mail. Without an SMTP relay, mail is only logged.
+	SMTPAddr       string
+	SMTPUsername   string
+	SMTPPassword   string
+	MailFrom       string
+	NotifyInterval time.Duration
+
+	// PriceSchedulerInterval is how often due price changes are stored
+	PriceSchedulerInterval time.Duration
+
+	// Product views are written in batches every ViewFlushInterval. Raw
+	// views older than ViewRetention are rolled up into daily counts every
+	// ViewRollupInterval.
+	ViewFlushInterval  time.Duration
+	ViewRetention      time.Duration
+	ViewRollupInterval time.Duration
+
+	// RecommendationInterval is how often product recommendations are
+	// recomputed from orders and views
+	RecommendationInterval time.Duration
+
+	// Locales product content can be served in. Products' own fields are
+	// written in the default locale.
+	Locales i18n.Locales
 }
 
 func LoadConfig() (*Config, error) {
@@ -21,6 +81,56 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("invalid DB_PORT value: %v", err)
 	}
 
+	feedCacheTTL, err := time.ParseDuration(getEnv("FEED_CACHE_TTL", "15m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid FEED_CACHE_TTL value: %v", err)
+	}
+
+	taxMode, err := tax.ParseMode(getEnv("TAX_MODE", "exclusive"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TAX_MODE value: %v", err)
+	}
+
+	taxRounding, err := tax.ParseRounding(getEnv("TAX_ROUNDING", "line"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TAX_ROUNDING value: %v", err)
+	}
+
+	notifyInterval, err := time.ParseDuration(getEnv("NOTIFY_INTERVAL", "30s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid NOTIFY_INTERVAL value: %v", err)
+	}
+
+	priceSchedulerInterval, err := time.ParseDuration(getEnv("PRICE_SCHEDULER_INTERVAL", "1m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PRICE_SCHEDULER_INTERVAL value: %v", err)
+	}
+
+	viewFlushInterval, err := time.ParseDuration(getEnv("VIEW_FLUSH_INTERVAL", "5s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid VIEW_FLUSH_INTERVAL value: %v", err)
+	}
+
+	viewRetention, err := time.ParseDuration(getEnv("VIEW_RETENTION", "720h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid VIEW_RETENTION value: %v", err)
+	}
+
+	viewRollupInterval, err := time.ParseDuration(getEnv("VIEW_ROLLUP_INTERVAL", "1h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid VIEW_ROLLUP_INTERVAL value: %v", err)
+	}
+
+	recommendationInterval, err := time.ParseDuration(getEnv("RECOMMENDATION_INTERVAL", "24h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RECOMMENDATION_INTERVAL value: %v", err)
+	}
+
+	locales, err := i18n.ParseLocales(getEnv("DEFAULT_LOCALE", "en"), getEnv("LOCALES", "en,pt"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DEFAULT_LOCALE or LOCALES value: %v", err)
+	}
+
 	return &Config{
 		DBHost:     getEnv("DB_HOST", "pihole.local"),
 		DBPort:     port,
@@ -28,6 +138,43 @@ func LoadConfig() (*Config, error) {
 		DBPassword: getEnv("DB_PASSWORD", "casaos"),
 		DBName:     getEnv("DB_NAME", "garage-web"),
 		DBSSLMode:  getEnv("DB_SSL_MODE", "disable"),
+
+		StoreName:    getEnv("STORE_NAME", "Garage"),
+		SiteURL:      getEnv("SITE_URL", "http://192.168.1.2:8080"),
+		Currency:     getEnv("CURRENCY", "USD"),
+		FeedCacheTTL: feedCacheTTL,
+
+		PaymentProvider:          os.Getenv("PAYMENT_PROVIDER"),
+		StripeSecretKey:          os.Getenv("STRIPE_SECRET_KEY"),
+		StripeWebhookSecret:      os.Getenv("STRIPE_WEBHOOK_SECRET"),
+		StripeAPIURL:             getEnv("STRIPE_API_URL", "https://api.stripe.com"),
+		FakePaymentWebhookSecret: os.Getenv("FAKE_PAYMENT_WEBHOOK_SECRET"),
+
+		TaxMode:     taxMode,
+		TaxRounding: taxRounding,
+		TaxCountry:  getEnv("TAX_COUNTRY", "US"),
+		TaxRegion:   os.Getenv("TAX_REGION"),
+
+		SellerName:    getEnv("SELLER_NAME", getEnv("STORE_NAME", "Garage")),
+		SellerAddress: splitLines(os.Getenv("SELLER_ADDRESS")),
+		SellerVATID:   os.Getenv("SELLER_VAT_ID"),
+		SellerEmail:   os.Getenv("SELLER_EMAIL"),
+
+		SMTPAddr:       os.Getenv("SMTP_ADDR"),
+		SMTPUsername:   os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:   os.Getenv("SMTP_PASSWORD"),
+		MailFrom:       getEnv("MAIL_FROM", getEnv("SELLER_EMAIL", "noreply@localhost")),
+		NotifyInterval: notifyInterval,
+
+		PriceSchedulerInterval: priceSchedulerInterval,
+
+		ViewFlushInterval:  viewFlushInterval,
+		ViewRetention:      viewRetention,
+		ViewRollupInterval: viewRollupInterval,
+
+		RecommendationInterval: recommendationInterval,
+
+		Locales: locales,
 	}, nil
 }
 
@@ -36,6 +183,17 @@ func (c *Config) GetDSN() string {
 		c.DBHost, c.DBPort, c.DBUser, c.DBPassword, c.DBName, c.DBSSLMode)
 }
 
+// splitLines splits a multi-line setting on ";" or newlines, dropping blank lines
+func splitLines(v string) []string {
+	var lines []string
+	for _, line := range strings.FieldsFunc(v, func(r rune) bool { return r == ';' || r == '\n' }) {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
@@ -0,0 +1,56 @@
+package feed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Entry is a generated feed together with its validation report
+type Entry struct {
+	Body        []byte
+	ETag        string
+	Issues      []Issue
+	Total       int
+	GeneratedAt time.Time
+}
+
+// Cache keeps the last generated feed for a fixed time so that crawlers
+// polling the feed do not hit the database on every request
+type Cache struct {
+	TTL time.Duration
+
+	mu    sync.Mutex
+	entry *Entry
+}
+
+// Get returns the cached entry, calling build to regenerate it when the
+// cache is empty or expired. Concurrent callers wait for a single build.
+func (c *Cache) Get(build func() (*Entry, error)) (*Entry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entry != nil && time.Since(c.entry.GeneratedAt) < c.TTL {
+		return c.entry, nil
+	}
+
+	entry, err := build()
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(entry.Body)
+	entry.ETag = `"` + hex.EncodeToString(sum[:8]) + `"`
+	entry.GeneratedAt = time.Now()
+	c.entry = entry
+	return entry, nil
+}
+
+// Invalidate drops the cached entry so the next Get rebuilds it
+func (c *Cache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entry = nil
+}
@@ -0,0 +1,153 @@
+// Package feed builds product feeds for shopping and advertising platforms.
+package feed
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"garage-api/internal/models"
+)
+
+// Google Merchant attribute limits
+const (
+	maxTitleLength       = 150
+	maxDescriptionLength = 5000
+)
+
+// Options describe the store publishing the feed
+type Options struct {
+	Title       string
+	Description string
+	SiteURL     string
+	Currency    string
+}
+
+// Issue reports a product left out of the feed and why
+type Issue struct {
+	ProductID int      `json:"product_id" example:"4"`
+	Name      string   `json:"name" example:"4K Monitor"`
+	Missing   []string `json:"missing" example:"image_link,description"`
+}
+
+type googleRSS struct {
+	XMLName xml.Name      `xml:"rss"`
+	Version string        `xml:"version,attr"`
+	G       string        `xml:"xmlns:g,attr"`
+	Channel googleChannel `xml:"channel"`
+}
+
+type googleChannel struct {
+	Title       string       `xml:"title"`
+	Link        string       `xml:"link"`
+	Description string       `xml:"description"`
+	Items       []googleItem `xml:"item"`
+}
+
+type googleItem struct {
+	ID               string `xml:"g:id"`
+	Title            string `xml:"g:title"`
+	Description      string `xml:"g:description"`
+	Link             string `xml:"g:link"`
+	ImageLink        string `xml:"g:image_link"`
+	Availability     string `xml:"g:availability"`
+	Price            string `xml:"g:price"`
+	SalePrice        string `xml:"g:sale_price,omitempty"`
+	Condition        string `xml:"g:condition"`
+	MPN              string `xml:"g:mpn,omitempty"`
+	IdentifierExists string `xml:"g:identifier_exists,omitempty"`
+}
+
+// ValidateGoogle returns the mandatory Google Merchant attributes product is missing
+func ValidateGoogle(product models.Product, opts Options) []string {
+	var missing []string
+	if strings.TrimSpace(product.Name) == "" {
+		missing = append(missing, "title")
+	}
+	if strings.TrimSpace(product.Description) == "" {
+		missing = append(missing, "description")
+	}
+	if opts.SiteURL == "" {
+		missing = append(missing, "link")
+	}
+	if strings.TrimSpace(product.ImagePath) == "" {
+		missing = append(missing, "image_link")
+	}
+	if product.Price <= 0 {
+		missing = append(missing, "price")
+	}
+	if opts.Currency == "" {
+		missing = append(missing, "price_currency")
+	}
+	return missing
+}
+
+// BuildGoogle renders products as a Google Merchant RSS 2.0 feed. Products
+// missing mandatory attributes are left out and reported as issues.
+func BuildGoogle(products []models.Product, opts Options) ([]byte, []Issue, error) {
+	siteURL := strings.TrimRight(opts.SiteURL, "/")
+
+	rss := googleRSS{
+		Version: "2.0",
+		G:       "http://base.google.com/ns/1.0",
+		Channel: googleChannel{
+			Title:       opts.Title,
+			Link:        siteURL,
+			Description: opts.Description,
+			Items:       make([]googleItem, 0, len(products)),
+		},
+	}
+
+	issues := []Issue{}
+	for _, p := range products {
+		if missing := ValidateGoogle(p, opts); len(missing) > 0 {
+			issues = append(issues, Issue{ProductID: p.ID, Name: p.Name, Missing: missing})
+			continue
+		}
+
+		item := googleItem{
+			ID:           strconv.Itoa(p.ID),
+			Title:        truncate(p.Name, maxTitleLength),
+			Description:  truncate(p.Description, maxDescriptionLength),
+			Link:         fmt.Sprintf("%s/products/%d", siteURL, p.ID),
+			ImageLink:    absoluteURL(siteURL, p.ImagePath),
+			Availability: "out_of_stock",
+			Price:        fmt.Sprintf("%.2f %s", p.Price, opts.Currency),
+			Condition:    "new",
+		}
+		if p.Stock > 0 {
+			item.Availability = "in_stock"
+		}
+		if p.EffectivePrice > 0 && p.EffectivePrice < p.Price {
+			item.SalePrice = fmt.Sprintf("%.2f %s", p.EffectivePrice, opts.Currency)
+		}
+		if p.SKU != "" {
+			item.MPN = p.SKU
+		} else {
+			item.IdentifierExists = "no"
+		}
+		rss.Channel.Items = append(rss.Channel.Items, item)
+	}
+
+	body, err := xml.MarshalIndent(rss, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	return append([]byte(xml.Header), body...), issues, nil
+}
+
+func absoluteURL(siteURL, path string) string {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	return siteURL + "/" + strings.TrimLeft(path, "/")
+}
+
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
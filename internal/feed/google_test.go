@@ -0,0 +1,62 @@
+package feed
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"garage-api/internal/models"
+)
+
+func TestBuildGoogle(t *testing.T) {
+	opts := Options{Title: "Garage", SiteURL: "https://shop.example.com/", Currency: "USD"}
+	products := []models.Product{
+		{ID: 1, Name: "Gaming Laptop", Description: "RTX 3080 & more", Price: 1999.99, ImagePath: "/images/gaming-laptop.jpg", SKU: "LAP-001", Stock: 3, EffectivePrice: 1799.99},
+		{ID: 2, Name: "Webcam", Description: "1080p webcam", Price: 89.99, ImagePath: "https://cdn.example.com/webcam.jpg"},
+		{ID: 3, Name: "Capture Card", Price: 159.99},
+	}
+
+	body, issues, err := BuildGoogle(products, opts)
+	assert.NoError(t, err)
+
+	feed := string(body)
+	assert.True(t, strings.HasPrefix(feed, `<?xml version="1.0" encoding="UTF-8"?>`))
+	assert.Contains(t, feed, `<rss version="2.0" xmlns:g="http://base.google.com/ns/1.0">`)
+	assert.Contains(t, feed, `<g:description>RTX 3080 &amp; more</g:description>`)
+	assert.Contains(t, feed, `<g:link>https://shop.example.com/products/1</g:link>`)
+	assert.Contains(t, feed, `<g:image_link>https://shop.example.com/images/gaming-laptop.jpg</g:image_link>`)
+	assert.Contains(t, feed, `<g:image_link>https://cdn.example.com/webcam.jpg</g:image_link>`)
+	assert.Contains(t, feed, `<g:price>1999.99 USD</g:price>`)
+	assert.Contains(t, feed, `<g:sale_price>1799.99 USD</g:sale_price>`)
+	assert.Equal(t, 1, strings.Count(feed, "<g:sale_price>"))
+	assert.Contains(t, feed, `<g:availability>in_stock</g:availability>`)
+	assert.Contains(t, feed, `<g:availability>out_of_stock</g:availability>`)
+	assert.Contains(t, feed, `<g:mpn>LAP-001</g:mpn>`)
+	assert.Contains(t, feed, `<g:identifier_exists>no</g:identifier_exists>`)
+	assert.NotContains(t, feed, "Capture Card")
+
+	assert.Equal(t, []Issue{{ProductID: 3, Name: "Capture Card", Missing: []string{"description", "image_link"}}}, issues)
+}
+
+func TestCache_Get(t *testing.T) {
+	cache := &Cache{TTL: time.Hour}
+	builds := 0
+	build := func() (*Entry, error) {
+		builds++
+		return &Entry{Body: []byte("<rss/>")}, nil
+	}
+
+	first, err := cache.Get(build)
+	assert.NoError(t, err)
+	second, err := cache.Get(build)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, builds)
+	assert.Equal(t, first.ETag, second.ETag)
+	assert.NotEmpty(t, first.ETag)
+
+	cache.Invalidate()
+	_, err = cache.Get(build)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, builds)
+}
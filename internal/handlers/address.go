@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"garage-api/internal/models"
+	"garage-api/internal/postal"
+)
+
+type AddressHandler struct {
+	AddressModel models.AddressModelInterface
+}
+
+// AddressRequest represents the request body for creating or updating an address
+type AddressRequest struct {
+	Name            string `json:"name" binding:"required,max=255" example:"Jane Doe"`
+	Company         string `json:"company" binding:"max=255" example:"Garage GmbH"`
+	Line1           string `json:"line1" binding:"required,max=255" example:"Torstraße 1"`
+	Line2           string `json:"line2" binding:"max=255" example:"3rd floor"`
+	City            string `json:"city" binding:"required,max=255" example:"Berlin"`
+	Region          string `json:"region" binding:"max=10" example:""`
+	PostalCode      string `json:"postal_code" binding:"max=16" example:"10119"`
+	Country         string `json:"country" binding:"required,len=2" example:"DE"`
+	Phone           string `json:"phone" binding:"max=32" example:"+49 30 1234567"`
+	DefaultShipping bool   `json:"default_shipping" example:"true"`
+	DefaultBilling  bool   `json:"default_billing" example:"true"`
+}
+
+// address validates the request and converts it into an address of the
+// signed-in user
+func (r AddressRequest) address(userID int) (*models.Address, string) {
+	country := strings.ToUpper(r.Country)
+	postalCode, err := postal.Normalize(country, r.PostalCode)
+	if err != nil {
+		return nil, err.Error()
+	}
+
+	return &models.Address{
+		UserID:          userID,
+		Name:            strings.TrimSpace(r.Name),
+		Company:         strings.TrimSpace(r.Company),
+		Line1:           strings.TrimSpace(r.Line1),
+		Line2:           strings.TrimSpace(r.Line2),
+		City:            strings.TrimSpace(r.City),
+		Region:          strings.ToUpper(strings.TrimSpace(r.Region)),
+		PostalCode:      postalCode,
+		Country:         country,
+		Phone:           strings.TrimSpace(r.Phone),
+		DefaultShipping: r.DefaultShipping,
+		DefaultBilling:  r.DefaultBilling,
+	}, ""
+}
+
+// @Summary List my addresses
+// @Description List the signed-in user's addresses, defaults first
+// @Tags addresses
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.Address
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /me/addresses [get]
+func (h *AddressHandler) GetMyAddresses(c *gin.Context) {
+	addresses, err := h.AddressModel.List(c.GetInt("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, addresses)
+}
+
+// @Summary Get one of my addresses
+// @Description Get an address of the signed-in user
+// @Tags addresses
+// @Accept json
+// @Produce json
+// @Param id path int true "Address ID"
+// @Success 200 {object} models.Address
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /me/addresses/{id} [get]
+func (h *AddressHandler) GetMyAddress(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address ID"})
+		return
+	}
+
+	address, err := h.AddressModel.Get(c.GetInt("userID"), id)
+	if err != nil {
+		if err.Error() == "address not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, address)
+}
+
+// @Summary Add an address
+// @Description Add an address to the signed-in user's address book. Postal codes are checked against the country's format and normalized. The first address becomes the default shipping and billing address; setting a default flag takes it from the previous default.
+// @Tags addresses
+// @Accept json
+// @Produce json
+// @Param address body AddressRequest true "Address details"
+// @Success 201 {object} models.Address
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /me/addresses [post]
+func (h *AddressHandler) CreateMyAddress(c *gin.Context) {
+	var req AddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	address, msg := req.address(c.GetInt("userID"))
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := h.AddressModel.Create(address); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, address)
+}
+
+// @Summary Update one of my addresses
+// @Description Replace an address of the signed-in user. Orders already placed keep the address they were placed with.
+// @Tags addresses
+// @Accept json
+// @Produce json
+// @Param id path int true "Address ID"
+// @Param address body AddressRequest true "Address details"
+// @Success 200 {object} models.Address
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /me/addresses/{id} [put]
+func (h *AddressHandler) UpdateMyAddress(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address ID"})
+		return
+	}
+
+	var req AddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	address, msg := req.address(c.GetInt("userID"))
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	address.ID = id
+
+	if err := h.AddressModel.Update(address); err != nil {
+		if err.Error() == "address not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, address)
+}
+
+// @Summary Delete one of my addresses
+// @Description Delete an address of the signed-in user. Orders already placed keep their copy.
+// @Tags addresses
+// @Accept json
+// @Produce json
+// @Param id path int true "Address ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /me/addresses/{id} [delete]
+func (h *AddressHandler) DeleteMyAddress(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address ID"})
+		return
+	}
+
+	if err := h.AddressModel.Delete(c.GetInt("userID"), id); err != nil {
+		if err.Error() == "address not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"garage-api/internal/models"
+)
+
+type AttributeHandler struct {
+	AttributeModel models.AttributeModelInterface
+	CategoryModel  models.CategoryModelInterface
+}
+
+// AttributeRequest represents the request body for defining a category attribute
+type AttributeRequest struct {
+	Name string `json:"name" binding:"required,max=255" example:"Refresh rate"`
+	// Type is string, number, enum, boolean or unit
+	Type string `json:"type" binding:"required" example:"unit"`
+	// Unit is required for unit attributes, Options for enum ones
+	Unit     string   `json:"unit" binding:"max=16" example:"Hz"`
+	Options  []string `json:"options" example:"linear,tactile,clicky"`
+	Required bool     `json:"required" example:"false"`
+	Position int      `json:"position" example:"1"`
+}
+
+// definition validates the request and returns the attribute it defines
+func (r AttributeRequest) definition(categoryID int, code string) (*models.AttributeDefinition, string) {
+	if !models.ValidAttributeCode(code) {
+		return nil, "Attribute codes are lower-case letters, digits and underscores, starting with a letter"
+	}
+	def := &models.AttributeDefinition{
+		CategoryID: categoryID,
+		Code:       code,
+		Name:       r.Name,
+		Type:       models.AttributeType(r.Type),
+		Required:   r.Required,
+		Position:   r.Position,
+	}
+	if !def.Type.Valid() {
+		return nil, "Type must be string, number, enum, boolean or unit"
+	}
+
+	switch def.Type {
+	case models.AttributeUnit:
+		if r.Unit == "" {
+			return nil, "Unit attributes need a unit"
+		}
+		def.Unit = r.Unit
+	case models.AttributeEnum:
+		seen := make(map[string]bool)
+		for _, option := range r.Options {
+			if option == "" || len(option) > models.MaxAttributeLength || seen[option] {
+				return nil, "Options must be distinct, non-empty values"
+			}
+			seen[option] = true
+			def.Options = append(def.Options, option)
+		}
+		if len(def.Options) == 0 {
+			return nil, "Enum attributes need options"
+		}
+	}
+	if def.Type != models.AttributeUnit && r.Unit != "" {
+		return nil, "Only unit attributes have a unit"
+	}
+	if def.Type != models.AttributeEnum && len(r.Options) > 0 {
+		return nil, "Only enum attributes have options"
+	}
+
+	return def, ""
+}
+
+// @Summary List a category's attributes
+// @Description List the attributes the products of a category carry, in display order
+// @Tags categories
+// @Produce json
+// @Param id path int true "Category ID"
+// @Success 200 {array} models.AttributeDefinition
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /categories/{id}/attributes [get]
+func (h *AttributeHandler) GetCategoryAttributes(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	if _, err := h.CategoryModel.Get(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	defs, err := h.AttributeModel.List(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, defs)
+}
+
+// @Summary Define a category attribute
+// @Description Create or replace an attribute of a category's products, identified by its code (editors and admins). Products already carrying the attribute are checked against the new definition when next saved.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param id path int true "Category ID"
+// @Param code path string true "Attribute code, e.g. refresh_rate"
+// @Param attribute body AttributeRequest true "Attribute definition"
+// @Success 200 {object} models.AttributeDefinition
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /categories/{id}/attributes/{code} [put]
+func (h *AttributeHandler) SetCategoryAttribute(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	var req AttributeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	def, msg := req.definition(id, c.Param("code"))
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := h.AttributeModel.Set(def); err != nil {
+		if err.Error() == "category not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, def)
+}
+
+// @Summary Delete a category attribute
+// @Description Remove an attribute from a category (editors and admins), along with the values its products had for it
+// @Tags categories
+// @Produce json
+// @Param id path int true "Category ID"
+// @Param code path string true "Attribute code"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /categories/{id}/attributes/{code} [delete]
+func (h *AttributeHandler) DeleteCategoryAttribute(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	if err := h.AttributeModel.Delete(id, c.Param("code")); err != nil {
+		if err.Error() == "attribute not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attribute not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -1,13 +1,20 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"garage-api/internal/models"
 )
 
+type AuthHandler struct {
+	UserModel models.UserModelInterface
+	CartModel models.CartModelInterface
+}
+
 // LoginRequest represents the login credentials
 type LoginRequest struct {
 	Username string `json:"username" binding:"required" example:"john_doe"`
@@ -27,42 +34,70 @@ type LoginResponse struct {
 // @Param credentials body LoginRequest true "User credentials"
 // @Success 201 {object} map[string]string
 // @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
 // @Router /register [post]
-func Register(c *gin.Context) {
+func (h *AuthHandler) Register(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// TODO: Implement user registration logic
+	if err := h.UserModel.Create(req.Username, req.Password); err != nil {
+		if err.Error() == "username already exists" {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusCreated, gin.H{"message": "User registered successfully"})
 }
 
 // @Summary Login user
-// @Description Authenticate user and return JWT token
+// @Description Authenticate user and return JWT token. When the request carries the X-Cart-Token header of a guest cart, its items are merged into the user's cart.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param credentials body LoginRequest true "User credentials"
+// @Param X-Cart-Token header string false "Guest cart token"
 // @Success 200 {object} LoginResponse
 // @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /login [post]
-func Login(c *gin.Context) {
+func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// TODO: Implement user authentication logic
-	// For now, we'll just create a token for any user
+	user, err := h.UserModel.Authenticate(req.Username, req.Password)
+	if err != nil {
+		if err.Error() == "invalid credentials" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// A failed merge must not prevent the user from signing in; the guest
+	// cart stays available under its token
+	if cartToken := c.GetHeader(cartTokenHeader); cartToken != "" {
+		if err := h.CartModel.Merge(cartToken, user.ID); err != nil {
+			log.Printf("⚠️ Failed to merge guest cart for user %d: %v", user.ID, err)
+		}
+	}
 
 	// Create the token
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"username": req.Username,
-		"userID":   1, // This should be the actual user ID
+		"username": user.Username,
+		"userID":   user.ID,
+		"role":     user.Role,
 		"exp":      time.Now().Add(time.Hour * 24).Unix(),
 	})
 
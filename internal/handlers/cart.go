@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"garage-api/internal/models"
+	"garage-api/internal/promotion"
+	"garage-api/internal/tax"
+)
+
+// cartTokenHeader carries the token identifying a guest cart
+const cartTokenHeader = "X-Cart-Token"
+
+type CartHandler struct {
+	CartModel models.CartModelInterface
+	Tax       tax.TaxCalculator
+	// TaxAddress is used for tax estimates when the request names no country
+	TaxAddress tax.Address
+}
+
+// AddCartItemRequest represents the request body for adding a product to the cart
+type AddCartItemRequest struct {
+	ProductID int `json:"product_id" binding:"required" example:"1"`
+	Quantity  int `json:"quantity" binding:"required,min=1,max=1000" example:"1"`
+}
+
+// UpdateCartItemRequest represents the request body for changing an item's quantity
+type UpdateCartItemRequest struct {
+	Quantity int `json:"quantity" binding:"min=0,max=1000" example:"2"`
+}
+
+// ApplyCouponRequest represents the request body for applying a coupon to the cart
+type ApplyCouponRequest struct {
+	Code string `json:"code" binding:"required,max=64" example:"SUMMER10"`
+}
+
+// findCart returns the cart of the signed-in user or, for guests, the cart
+// named by the X-Cart-Token header. With create set, a missing cart is
+// created; otherwise nil is returned.
+func findCart(c *gin.Context, cartModel models.CartModelInterface, create bool) (*models.Cart, error) {
+	if userID := c.GetInt("userID"); userID != 0 {
+		cart, err := cartModel.FindByUser(userID)
+		if err != nil && err.Error() == "cart not found" {
+			if !create {
+				return nil, nil
+			}
+			return cartModel.CreateForUser(userID)
+		}
+		return cart, err
+	}
+
+	if token := c.GetHeader(cartTokenHeader); token != "" {
+		cart, err := cartModel.FindByToken(token)
+		if err == nil || err.Error() != "cart not found" {
+			return cart, err
+		}
+	}
+
+	if !create {
+		return nil, nil
+	}
+	return cartModel.CreateGuest()
+}
+
+// taxAddress returns the address named by the country and region query
+// parameters, or the default tax address
+func (h *CartHandler) taxAddress(c *gin.Context) tax.Address {
+	if country := c.Query("country"); country != "" {
+		return tax.Address{Country: country, Region: c.Query("region")}.Normalize()
+	}
+	return h.TaxAddress
+}
+
+// respondWithCart writes the full cart and, when prices changed since the
+// shopper last saw them, records the new prices as seen
+func (h *CartHandler) respondWithCart(c *gin.Context, cartID int, status int) {
+	cart, err := h.CartModel.Get(cartID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := cart.ApplyTax(c.Request.Context(), h.Tax, h.taxAddress(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if cart.Token != "" {
+		c.Header(cartTokenHeader, cart.Token)
+	}
+	c.JSON(status, cart)
+
+	if cart.PriceChanged {
+		if err := h.CartModel.RefreshPrices(cart.ID); err != nil {
+			log.Printf("⚠️ Failed to refresh prices of cart %d: %v", cart.ID, err)
+		}
+	}
+}
+
+// @Summary Get the cart
+// @Description Get the signed-in user's cart, or a guest cart identified by the X-Cart-Token header. Totals use current product prices; items whose price changed since the last visit are flagged once. Tax is estimated for the given country and region, or the store's default.
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param X-Cart-Token header string false "Guest cart token"
+// @Param country query string false "ISO country code for the tax estimate"
+// @Param region query string false "Region code for the tax estimate"
+// @Success 200 {object} models.Cart
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /cart [get]
+func (h *CartHandler) GetCart(c *gin.Context) {
+	cart, err := findCart(c, h.CartModel, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if cart == nil {
+		c.JSON(http.StatusOK, models.Cart{Items: []models.CartItem{}, Discounts: []promotion.Applied{}, Taxes: []tax.RateTotal{}})
+		return
+	}
+
+	h.respondWithCart(c, cart.ID, http.StatusOK)
+}
+
+// @Summary Add a product to the cart
+// @Description Add a product to the cart, creating the cart if needed. Guests receive the token of their new cart in the X-Cart-Token response header and must send it back on later requests.
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param X-Cart-Token header string false "Guest cart token"
+// @Param item body AddCartItemRequest true "Product and quantity"
+// @Success 200 {object} models.Cart
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /cart/items [post]
+func (h *CartHandler) AddCartItem(c *gin.Context) {
+	var req AddCartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cart, err := findCart(c, h.CartModel, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.CartModel.AddItem(cart.ID, req.ProductID, req.Quantity); err != nil {
+		if err.Error() == "product not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.respondWithCart(c, cart.ID, http.StatusOK)
+}
+
+// @Summary Change the quantity of a cart item
+// @Description Set the quantity of a product already in the cart; zero removes it
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param X-Cart-Token header string false "Guest cart token"
+// @Param product_id path int true "Product ID"
+// @Param item body UpdateCartItemRequest true "New quantity"
+// @Success 200 {object} models.Cart
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /cart/items/{product_id} [put]
+func (h *CartHandler) UpdateCartItem(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("product_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req UpdateCartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cart, err := findCart(c, h.CartModel, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if cart == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cart not found"})
+		return
+	}
+
+	if err := h.CartModel.SetItemQuantity(cart.ID, productID, req.Quantity); err != nil {
+		if err.Error() == "item not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in cart"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.respondWithCart(c, cart.ID, http.StatusOK)
+}
+
+// @Summary Remove a product from the cart
+// @Description Remove a product from the cart
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param X-Cart-Token header string false "Guest cart token"
+// @Param product_id path int true "Product ID"
+// @Success 200 {object} models.Cart
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /cart/items/{product_id} [delete]
+func (h *CartHandler) RemoveCartItem(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("product_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	cart, err := findCart(c, h.CartModel, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if cart == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cart not found"})
+		return
+	}
+
+	if err := h.CartModel.RemoveItem(cart.ID, productID); err != nil {
+		if err.Error() == "item not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in cart"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.respondWithCart(c, cart.ID, http.StatusOK)
+}
+
+// @Summary Apply a coupon to the cart
+// @Description Apply a coupon code to the cart, replacing any previous one. The code is rejected with 422 when it does not exist or does not apply to the cart as it is now.
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param X-Cart-Token header string false "Guest cart token"
+// @Param coupon body ApplyCouponRequest true "Coupon code"
+// @Success 200 {object} models.Cart
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /cart/coupon [post]
+func (h *CartHandler) ApplyCoupon(c *gin.Context) {
+	var req ApplyCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cart, err := findCart(c, h.CartModel, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if cart == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cart not found"})
+		return
+	}
+
+	if err := h.CartModel.SetCoupon(cart.ID, req.Code); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := h.CartModel.Get(cart.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(updated.Items) == 0 {
+		h.clearCoupon(cart.ID)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cart is empty"})
+		return
+	}
+	if updated.CouponError != "" {
+		h.clearCoupon(cart.ID)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": updated.CouponError})
+		return
+	}
+
+	h.respondWithCart(c, cart.ID, http.StatusOK)
+}
+
+// @Summary Remove the coupon from the cart
+// @Description Remove the coupon code from the cart. Automatic promotions still apply.
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param X-Cart-Token header string false "Guest cart token"
+// @Success 200 {object} models.Cart
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /cart/coupon [delete]
+func (h *CartHandler) RemoveCoupon(c *gin.Context) {
+	cart, err := findCart(c, h.CartModel, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if cart == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cart not found"})
+		return
+	}
+
+	if err := h.CartModel.SetCoupon(cart.ID, ""); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.respondWithCart(c, cart.ID, http.StatusOK)
+}
+
+// clearCoupon drops a coupon that was rejected so it is not reported again
+func (h *CartHandler) clearCoupon(cartID int) {
+	if err := h.CartModel.SetCoupon(cartID, ""); err != nil {
+		log.Printf("⚠️ Failed to clear coupon of cart %d: %v", cartID, err)
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"garage-api/internal/feed"
+	"garage-api/internal/models"
+)
+
+type FeedHandler struct {
+	ProductModel models.ProductModelInterface
+	Options      feed.Options
+	Cache        *feed.Cache
+}
+
+// FeedValidationResponse reports products left out of the Google Merchant feed
+type FeedValidationResponse struct {
+	GeneratedAt time.Time    `json:"generated_at"`
+	Total       int          `json:"total" example:"10"`
+	Valid       int          `json:"valid" example:"9"`
+	Issues      []feed.Issue `json:"issues"`
+}
+
+func (h *FeedHandler) googleFeed() (*feed.Entry, error) {
+	return h.Cache.Get(func() (*feed.Entry, error) {
+		products, err := h.ProductModel.List(models.ProductFilter{PublishedOnly: true})
+		if err != nil {
+			return nil, err
+		}
+
+		body, issues, err := feed.BuildGoogle(products, h.Options)
+		if err != nil {
+			return nil, err
+		}
+		return &feed.Entry{Body: body, Issues: issues, Total: len(products)}, nil
+	})
+}
+
+// @Summary Google Merchant product feed
+// @Description RSS 2.0 product feed in Google Merchant format. The feed is cached; products missing mandatory attributes are left out (see /feeds/google/validation).
+// @Tags feeds
+// @Produce xml
+// @Success 200 {string} string "RSS 2.0 feed"
+// @Success 304 "Not Modified"
+// @Failure 500 {object} map[string]string
+// @Router /feeds/google.xml [get]
+func (h *FeedHandler) GetGoogleFeed(c *gin.Context) {
+	entry, err := h.googleFeed()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("ETag", entry.ETag)
+	c.Header("Last-Modified", entry.GeneratedAt.UTC().Format(http.TimeFormat))
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.Cache.TTL.Seconds())))
+	if c.GetHeader("If-None-Match") == entry.ETag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", entry.Body)
+}
+
+// @Summary Validate the Google Merchant feed
+// @Description List products left out of the Google Merchant feed because they miss mandatory attributes. Editors and admins can validate the cached feed; only admins can regenerate it with refresh.
+// @Tags feeds
+// @Produce json
+// @Param refresh query bool false "Regenerate the feed instead of using the cached one (admins only)"
+// @Success 200 {object} FeedValidationResponse
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /feeds/google/validation [get]
+func (h *FeedHandler) ValidateGoogleFeed(c *gin.Context) {
+	if refresh, _ := strconv.ParseBool(c.Query("refresh")); refresh {
+		// Regenerating reads the whole catalog, so only admins may skip the cache
+		if c.GetString("role") != models.RoleAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can refresh the feed"})
+			return
+		}
+		h.Cache.Invalidate()
+	}
+
+	entry, err := h.googleFeed()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, FeedValidationResponse{
+		GeneratedAt: entry.GeneratedAt,
+		Total:       entry.Total,
+		Valid:       entry.Total - len(entry.Issues),
+		Issues:      entry.Issues,
+	})
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"garage-api/internal/invoice"
+	"garage-api/internal/models"
+)
+
+type InvoiceHandler struct {
+	OrderModel   models.OrderModelInterface
+	InvoiceModel models.InvoiceModelInterface
+	Seller       invoice.Seller
+	Currency     string
+}
+
+// @Summary Download an order's invoice
+// @Description Download the PDF invoice of a paid order. Invoices are numbered without gaps per year and issued when the order is paid. Only the order's owner and admins may download it.
+// @Tags orders
+// @Produce application/pdf
+// @Param id path int true "Order ID"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /orders/{id}/invoice.pdf [get]
+func (h *InvoiceHandler) GetInvoicePDF(c *gin.Context) {
+	h.servePDF(c, models.InvoiceKindInvoice)
+}
+
+// @Summary Download an order's credit note
+// @Description Download the PDF credit note issued when an order was refunded. Only the order's owner and admins may download it.
+// @Tags orders
+// @Produce application/pdf
+// @Param id path int true "Order ID"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /orders/{id}/credit-note.pdf [get]
+func (h *InvoiceHandler) GetCreditNotePDF(c *gin.Context) {
+	h.servePDF(c, models.InvoiceKindCreditNote)
+}
+
+func (h *InvoiceHandler) servePDF(c *gin.Context, kind models.InvoiceKind) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	// Other users' orders are reported as not found
+	order, err := h.OrderModel.Get(id)
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+	if order.UserID != c.GetInt("userID") && c.GetString("role") != models.RoleAdmin {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	issued, err := h.InvoiceModel.Get(order.ID, kind)
+	if err != nil {
+		if err.Error() == "invoice not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No " + invoiceLabel(kind) + " has been issued for this order"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := invoice.Render(&buf, h.document(order, issued)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s.pdf"`, issued.Number))
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+func invoiceLabel(kind models.InvoiceKind) string {
+	if kind == models.InvoiceKindCreditNote {
+		return "credit note"
+	}
+	return "invoice"
+}
+
+// document lays out an order as the issued invoice or credit note
+func (h *InvoiceHandler) document(order *models.Order, issued *models.Invoice) invoice.Document {
+	d := invoice.Document{
+		CreditNote:       issued.Kind == models.InvoiceKindCreditNote,
+		Number:           issued.Number,
+		Credits:          issued.Credits,
+		IssuedAt:         issued.IssuedAt,
+		OrderID:          order.ID,
+		Currency:         h.Currency,
+		Seller:           h.Seller,
+		Subtotal:         order.Subtotal,
+		Discount:         order.Discount,
+		Shipping:         order.ShippingCost,
+		Tax:              order.Tax,
+		Total:            order.Total,
+		PricesIncludeTax: order.PricesIncludeTax,
+	}
+
+	// The billing address falls back to the shipping address, as at checkout
+	if order.BillingAddress != nil {
+		d.BillTo = order.BillingAddress.Lines()
+	} else if order.ShippingAddress != nil {
+		d.BillTo = order.ShippingAddress.Lines()
+	}
+	if order.ShippingAddress != nil {
+		d.ShipTo = order.ShippingAddress.Lines()
+	}
+
+	for _, item := range order.Items {
+		d.Lines = append(d.Lines, invoice.Line{
+			Description: item.Name,
+			SKU:         item.SKU,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Discount:    item.Discount,
+			TaxRate:     item.TaxRate,
+			Tax:         item.Tax,
+			Amount:      models.RoundMoney(item.LineTotal - item.Discount),
+		})
+	}
+	return d
+}
@@ -0,0 +1,432 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"garage-api/internal/models"
+	"garage-api/internal/promotion"
+	"garage-api/internal/tax"
+)
+
+const (
+	defaultOrderLimit = 50
+	maxOrderLimit     = 200
+)
+
+type OrderHandler struct {
+	OrderModel   models.OrderModelInterface
+	AddressModel models.AddressModelInterface
+	// TaxAddress is used at checkout when the user has no shipping address
+	// and the request names no country
+	TaxAddress tax.Address
+}
+
+// CheckoutRequest represents the optional request body of a checkout. The
+// addresses default to the user's default shipping and billing addresses;
+// the billing address falls back to the shipping address. Country and region
+// can stand in for a shipping address.
+type CheckoutRequest struct {
+	ShippingAddressID int    `json:"shipping_address_id" binding:"min=0" example:"1"`
+	BillingAddressID  int    `json:"billing_address_id" binding:"min=0" example:"0"`
+	Country           string `json:"country" binding:"omitempty,len=2" example:""`
+	Region            string `json:"region" binding:"max=10" example:""`
+	// ShippingMethodID is one of the options from POST /shipping/quote
+	ShippingMethodID int `json:"shipping_method_id" binding:"min=0" example:"1"`
+}
+
+// UpdateOrderStatusRequest represents the request body for changing an order's status
+type UpdateOrderStatusRequest struct {
+	Status models.OrderStatus `json:"status" binding:"required" example:"shipped"`
+}
+
+// StockErrorResponse lists the cart lines that could not be reserved
+type StockErrorResponse struct {
+	Error     string                 `json:"error" example:"Insufficient stock"`
+	Shortages []models.StockShortage `json:"shortages"`
+}
+
+// UnavailableErrorResponse lists the cart lines whose products can no longer be bought
+type UnavailableErrorResponse struct {
+	Error    string                      `json:"error" example:"Some products are no longer available"`
+	Products []models.UnavailableProduct `json:"products"`
+}
+
+// parseTime accepts either an RFC 3339 timestamp or a plain date
+func parseTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
+
+func parseOrderFilter(c *gin.Context) (models.OrderFilter, error) {
+	filter := models.OrderFilter{Limit: defaultOrderLimit}
+
+	if v := c.Query("status"); v != "" {
+		filter.Status = models.OrderStatus(v)
+		if !filter.Status.Valid() {
+			return filter, errors.New("Invalid status")
+		}
+	}
+	if v := c.Query("user_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			return filter, errors.New("Invalid user_id")
+		}
+		filter.UserID = id
+	}
+	if v := c.Query("from"); v != "" {
+		t, err := parseTime(v)
+		if err != nil {
+			return filter, errors.New("Invalid from")
+		}
+		filter.From = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := parseTime(v)
+		if err != nil {
+			return filter, errors.New("Invalid to")
+		}
+		filter.To = t
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxOrderLimit {
+			return filter, errors.New("Invalid limit")
+		}
+		filter.Limit = limit
+	}
+	if v := c.Query("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return filter, errors.New("Invalid offset")
+		}
+		filter.Offset = offset
+	}
+
+	return filter, nil
+}
+
+// respondOrderError maps order model errors to responses
+func respondOrderError(c *gin.Context, err error) {
+	var stockErr *models.StockError
+	var unavailableErr *models.UnavailableError
+	var transitionErr *models.TransitionError
+	var couponErr *promotion.CouponError
+	switch {
+	case errors.As(err, &stockErr):
+		c.JSON(http.StatusConflict, StockErrorResponse{Error: "Insufficient stock", Shortages: stockErr.Shortages})
+	case errors.As(err, &unavailableErr):
+		c.JSON(http.StatusConflict, UnavailableErrorResponse{Error: "Some products are no longer available", Products: unavailableErr.Products})
+	case errors.As(err, &transitionErr):
+		c.JSON(http.StatusConflict, gin.H{"error": transitionErr.Error()})
+	case errors.As(err, &couponErr):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": couponErr.Error()})
+	case err.Error() == "cart is empty":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cart is empty"})
+	case err.Error() == "shipping method is required":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Shipping method is required"})
+	case err.Error() == "shipping method not available":
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Shipping method is not available for this address"})
+	case err.Error() == "address not found":
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Address not found"})
+	case err.Error() == "order not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// ownOrder loads the order named in the path and checks it belongs to the
+// signed-in user. Other users' orders are reported as not found.
+func ownOrder(c *gin.Context, orderModel models.OrderModelInterface) (*models.Order, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return nil, false
+	}
+
+	order, err := orderModel.Get(id)
+	if err != nil {
+		respondOrderError(c, err)
+		return nil, false
+	}
+	if order.UserID != c.GetInt("userID") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return nil, false
+	}
+
+	return order, true
+}
+
+// @Summary Check out the cart
+// @Description Turn the signed-in user's cart into a pending order. Prices, discounts and tax are captured at checkout, the cart's coupon is redeemed and stock is reserved; the cart is emptied. The shipping and billing addresses are copied into the order; they default to the user's default addresses. Tax and shipping are computed for the shipping address, or the given country and region, or the store's default. A shipping method from POST /shipping/quote is required when any is available for the address. Products archived or taken back to draft since they were added fail the checkout with a 409 listing them in `products`.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param checkout body CheckoutRequest false "Addresses and shipping method"
+// @Success 201 {object} models.Order
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} StockErrorResponse
+// @Failure 422 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /checkout [post]
+func (h *OrderHandler) Checkout(c *gin.Context) {
+	var req CheckoutRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	userID := c.GetInt("userID")
+	opts := models.CheckoutOptions{Address: h.TaxAddress, ShippingMethodID: req.ShippingMethodID}
+	if req.Country != "" {
+		opts.Address = tax.Address{Country: req.Country, Region: req.Region}
+	}
+
+	shippingAddress, billingAddress, err := h.checkoutAddresses(userID, req)
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+	if shippingAddress != nil {
+		opts.ShippingAddress = shippingAddress.Snapshot()
+	}
+	if billingAddress != nil {
+		opts.BillingAddress = billingAddress.Snapshot()
+	}
+
+	order, err := h.OrderModel.Checkout(userID, opts)
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, order)
+}
+
+// checkoutAddresses picks the addresses of a checkout from the user's
+// address book. A country in the request replaces the default shipping
+// address, not one named by ID.
+func (h *OrderHandler) checkoutAddresses(userID int, req CheckoutRequest) (shipping, billing *models.Address, err error) {
+	if req.ShippingAddressID == 0 || req.BillingAddressID == 0 {
+		shipping, billing, err = h.AddressModel.GetDefaults(userID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if req.Country != "" {
+			shipping = nil
+		}
+	}
+
+	if req.ShippingAddressID != 0 {
+		if shipping, err = h.AddressModel.Get(userID, req.ShippingAddressID); err != nil {
+			return nil, nil, err
+		}
+	}
+	if req.BillingAddressID != 0 {
+		if billing, err = h.AddressModel.Get(userID, req.BillingAddressID); err != nil {
+			return nil, nil, err
+		}
+	}
+	if billing == nil {
+		billing = shipping
+	}
+	return shipping, billing, nil
+}
+
+// @Summary List my orders
+// @Description List the signed-in user's orders, newest first
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param status query string false "Filter by status" Enums(pending, paid, shipped, delivered, cancelled, refunded)
+// @Param limit query int false "Maximum number of orders (default 50, max 200)"
+// @Param offset query int false "Number of orders to skip"
+// @Success 200 {array} models.Order
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /me/orders [get]
+func (h *OrderHandler) GetMyOrders(c *gin.Context) {
+	filter, err := parseOrderFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.UserID = c.GetInt("userID")
+
+	orders, err := h.OrderModel.List(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, orders)
+}
+
+// @Summary Get one of my orders
+// @Description Get an order of the signed-in user
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {object} models.Order
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /me/orders/{id} [get]
+func (h *OrderHandler) GetMyOrder(c *gin.Context) {
+	order, ok := ownOrder(c, h.OrderModel)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// @Summary Cancel one of my orders
+// @Description Cancel an order of the signed-in user that has not been paid yet, releasing its stock
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {object} models.Order
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /me/orders/{id}/cancel [post]
+func (h *OrderHandler) CancelMyOrder(c *gin.Context) {
+	order, ok := ownOrder(c, h.OrderModel)
+	if !ok {
+		return
+	}
+
+	// Once paid, cancelling involves a refund and is left to admins
+	if order.Status != models.OrderPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only pending orders can be cancelled"})
+		return
+	}
+
+	order, err := h.OrderModel.UpdateStatus(order.ID, models.OrderCancelled)
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// @Summary List all orders
+// @Description List orders of all users, newest first (admin only)
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param status query string false "Filter by status" Enums(pending, paid, shipped, delivered, cancelled, refunded)
+// @Param user_id query int false "Filter by user"
+// @Param from query string false "Created at or after (RFC 3339 or YYYY-MM-DD)"
+// @Param to query string false "Created before (RFC 3339 or YYYY-MM-DD)"
+// @Param limit query int false "Maximum number of orders (default 50, max 200)"
+// @Param offset query int false "Number of orders to skip"
+// @Success 200 {array} models.Order
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /orders [get]
+func (h *OrderHandler) GetAllOrders(c *gin.Context) {
+	filter, err := parseOrderFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	orders, err := h.OrderModel.List(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, orders)
+}
+
+// @Summary Get an order
+// @Description Get any order by ID (admin only)
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {object} models.Order
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /orders/{id} [get]
+func (h *OrderHandler) GetOrderByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	order, err := h.OrderModel.Get(id)
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// @Summary Change an order's status
+// @Description Move an order through its lifecycle (admin only). Allowed transitions: pending → paid|cancelled, paid → shipped|cancelled|refunded, shipped → delivered|refunded, delivered → refunded. Cancelling releases reserved stock.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Param status body UpdateOrderStatusRequest true "New status"
+// @Success 200 {object} models.Order
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /orders/{id}/status [put]
+func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	var req UpdateOrderStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !req.Status.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return
+	}
+
+	order, err := h.OrderModel.UpdateStatus(id, req.Status)
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
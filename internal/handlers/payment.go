@@ -0,0 +1,357 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"garage-api/internal/models"
+	"garage-api/internal/payment"
+)
+
+// maxWebhookBytes bounds the size of webhook payloads read into memory
+const maxWebhookBytes = 1 << 20
+
+type PaymentHandler struct {
+	Providers map[string]payment.PaymentProvider
+	// DefaultProvider is used when the customer does not pick one
+	DefaultProvider string
+	Currency        string
+	OrderModel      models.OrderModelInterface
+	PaymentModel    models.TxPaymentModelInterface
+}
+
+// PaymentIntentResponse gives the client what it needs to complete a payment with the provider
+type PaymentIntentResponse struct {
+	OrderID  int            `json:"order_id" example:"1"`
+	Provider string         `json:"provider" example:"stripe"`
+	Intent   payment.Intent `json:"intent"`
+}
+
+// WebhookResponse reports what happened to a webhook event
+type WebhookResponse struct {
+	Status string `json:"status" example:"processed"`
+}
+
+// @Summary Start paying an order
+// @Description Create a payment intent for one of the signed-in user's pending orders. The client completes the payment with the provider using the returned client secret; the order becomes paid once the provider confirms it through the webhook. Calling this again for the same order and provider returns the same intent.
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Param provider query string false "Payment provider (defaults to the configured one)"
+// @Success 201 {object} PaymentIntentResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Security Bearer
+// @Router /me/orders/{id}/payment [post]
+func (h *PaymentHandler) CreatePayment(c *gin.Context) {
+	order, ok := ownOrder(c, h.OrderModel)
+	if !ok {
+		return
+	}
+
+	if order.Status != models.OrderPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only pending orders can be paid"})
+		return
+	}
+
+	name := c.DefaultQuery("provider", h.DefaultProvider)
+	provider, ok := h.Providers[name]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown payment provider"})
+		return
+	}
+
+	intent, err := provider.CreateIntent(c.Request.Context(), payment.IntentRequest{
+		OrderID:        order.ID,
+		Amount:         payment.ToMinorUnits(order.Total),
+		Currency:       h.Currency,
+		IdempotencyKey: fmt.Sprintf("order-%d", order.ID),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = h.PaymentModel.Create(&models.Payment{
+		OrderID:  order.ID,
+		Provider: provider.Name(),
+		IntentID: intent.ID,
+		Status:   string(intent.Status),
+		Amount:   order.Total,
+		Currency: h.Currency,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, PaymentIntentResponse{OrderID: order.ID, Provider: provider.Name(), Intent: *intent})
+}
+
+// @Summary Payment provider webhook
+// @Description Receive payment events from a provider. The signature is verified and each event is applied once, even when delivered concurrently: authorized payments are captured, captured payments mark the order paid, and refunds mark it refunded. Money captured for an order cancelled in the meantime is refunded right away.
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Param provider path string true "Payment provider" Enums(stripe, fake)
+// @Success 200 {object} WebhookResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Router /webhooks/payments/{provider} [post]
+func (h *PaymentHandler) HandleWebhook(c *gin.Context) {
+	provider, ok := h.Providers[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown payment provider"})
+		return
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBytes))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read payload"})
+		return
+	}
+
+	event, err := provider.VerifyWebhook(payload, c.Request.Header)
+	if err != nil {
+		if errors.Is(err, payment.ErrInvalidSignature) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid signature"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The event is claimed in the transaction that applies it, so concurrent
+	// deliveries of the same event wait for each other and apply it once
+	tx, err := h.PaymentModel.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	payments := h.PaymentModel.WithTx(tx)
+	claimed, err := payments.ClaimEvent(provider.Name(), event.ID, string(event.Type))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !claimed {
+		c.JSON(http.StatusOK, WebhookResponse{Status: "duplicate"})
+		return
+	}
+
+	status, code, err := h.applyEvent(c, payments, provider, event)
+	if err != nil {
+		// Rolling back releases the claim, so the provider's retry applies
+		// the event later
+		c.JSON(code, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, WebhookResponse{Status: status})
+}
+
+// applyEvent updates the payment and its order for a verified event through
+// payments, which runs in the transaction that claimed the event. On failure
+// it returns the status code to answer with.
+func (h *PaymentHandler) applyEvent(c *gin.Context, payments models.PaymentModelInterface, provider payment.PaymentProvider, event *payment.Event) (string, int, error) {
+	if event.Type == payment.EventIgnored {
+		return "ignored", 0, nil
+	}
+
+	p, err := payments.GetByIntent(provider.Name(), event.IntentID)
+	if err != nil {
+		if err.Error() == "payment not found" {
+			return "ignored", 0, nil
+		}
+		return "", http.StatusInternalServerError, err
+	}
+
+	switch event.Type {
+	case payment.EventPaymentAuthorized:
+		if err := payments.SetStatus(p.ID, string(payment.IntentAuthorized)); err != nil {
+			return "", http.StatusInternalServerError, err
+		}
+
+		order, err := h.OrderModel.Get(p.OrderID)
+		if err != nil {
+			return "", http.StatusInternalServerError, err
+		}
+		// A cancelled order is not charged; the authorization simply lapses
+		if order.Status != models.OrderPending {
+			return "processed", 0, nil
+		}
+
+		intent, err := provider.Capture(c.Request.Context(), p.IntentID, 0)
+		if err != nil {
+			return "", http.StatusBadGateway, err
+		}
+		if intent.Status != payment.IntentSucceeded {
+			return "processed", 0, nil
+		}
+		if err := payments.SetStatus(p.ID, string(payment.IntentSucceeded)); err != nil {
+			return "", http.StatusInternalServerError, err
+		}
+		return h.markPaid(c, payments, provider, p)
+
+	case payment.EventPaymentSucceeded:
+		if err := payments.SetStatus(p.ID, string(payment.IntentSucceeded)); err != nil {
+			return "", http.StatusInternalServerError, err
+		}
+		return h.markPaid(c, payments, provider, p)
+
+	case payment.EventPaymentFailed:
+		// The order stays pending so the customer can try again
+		if err := payments.SetStatus(p.ID, string(payment.IntentFailed)); err != nil {
+			return "", http.StatusInternalServerError, err
+		}
+		return "processed", 0, nil
+
+	case payment.EventRefunded:
+		if err := payments.SetStatus(p.ID, "refunded"); err != nil {
+			return "", http.StatusInternalServerError, err
+		}
+		if err := moveOrder(payments, p.OrderID, models.OrderRefunded); err != nil {
+			return "", http.StatusInternalServerError, err
+		}
+		return "processed", 0, nil
+	}
+
+	return "ignored", 0, nil
+}
+
+// markPaid moves the order of a captured payment to paid. Money captured for
+// an order that was cancelled in the meantime is refunded, as the customer
+// will not get the order.
+func (h *PaymentHandler) markPaid(c *gin.Context, payments models.PaymentModelInterface, provider payment.PaymentProvider, p *models.Payment) (string, int, error) {
+	err := payments.MoveOrder(p.OrderID, models.OrderPaid)
+
+	var transitionErr *models.TransitionError
+	if errors.As(err, &transitionErr) && transitionErr.From == models.OrderCancelled {
+		if _, err := provider.Refund(c.Request.Context(), p.IntentID, 0); err != nil {
+			return "", http.StatusBadGateway, err
+		}
+		if err := payments.SetStatus(p.ID, "refunded"); err != nil {
+			return "", http.StatusInternalServerError, err
+		}
+		log.Printf("💸 Refunded payment %d captured for cancelled order %d", p.ID, p.OrderID)
+		return "refunded", 0, nil
+	}
+
+	if err := ignoreTransition(p.OrderID, models.OrderPaid, err); err != nil {
+		return "", http.StatusInternalServerError, err
+	}
+	return "processed", 0, nil
+}
+
+// moveOrder applies a status change requested by the provider
+func moveOrder(payments models.PaymentModelInterface, orderID int, status models.OrderStatus) error {
+	return ignoreTransition(orderID, status, payments.MoveOrder(orderID, status))
+}
+
+// ignoreTransition leaves orders already in the target state alone, so
+// events arriving by more than one path are harmless; other rejected
+// transitions are logged for follow-up.
+func ignoreTransition(orderID int, status models.OrderStatus, err error) error {
+	var transitionErr *models.TransitionError
+	if errors.As(err, &transitionErr) {
+		if transitionErr.From != status {
+			log.Printf("⚠️ Payment event for order %d ignored: %v", orderID, err)
+		}
+		return nil
+	}
+	return err
+}
+
+// @Summary Refund an order
+// @Description Refund the captured payment of an order through its provider and mark the order refunded (admin only)
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {object} models.Order
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Security Bearer
+// @Router /orders/{id}/refund [post]
+func (h *PaymentHandler) RefundOrder(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	order, err := h.OrderModel.Get(id)
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+	if !order.Status.CanTransitionTo(models.OrderRefunded) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("cannot refund an order that is %s", order.Status)})
+		return
+	}
+
+	p, ok := refundPayment(c, h.Providers, h.PaymentModel, order.ID, 0)
+	if !ok {
+		return
+	}
+
+	if err := h.PaymentModel.SetStatus(p.ID, "refunded"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, err = h.OrderModel.UpdateStatus(order.ID, models.OrderRefunded)
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// refundPayment refunds amount, in cents, of the order's captured payment
+// through its provider; 0 refunds whatever is left. On failure it writes the
+// response and returns false.
+func refundPayment(c *gin.Context, providers map[string]payment.PaymentProvider, payments models.PaymentModelInterface, orderID int, amount int64) (*models.Payment, bool) {
+	p, err := payments.GetLatestForOrder(orderID, string(payment.IntentSucceeded))
+	if err != nil {
+		if err.Error() == "payment not found" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Order has no captured payment"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	provider, ok := providers[p.Provider]
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Payment provider " + p.Provider + " is not configured"})
+		return nil, false
+	}
+
+	if _, err := provider.Refund(c.Request.Context(), p.IntentID, amount); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return p, true
+}
@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"garage-api/internal/models"
+)
+
+const (
+	defaultPriceHistoryLimit = 50
+	maxPriceHistoryLimit     = 500
+)
+
+type PriceHandler struct {
+	PriceModel   models.PriceModelInterface
+	ProductModel models.ProductModelInterface
+}
+
+// SaleRequest represents the request body for putting a product on sale
+type SaleRequest struct {
+	SalePrice float64 `json:"sale_price" binding:"required,gt=0" example:"1499.99"`
+	// StartsAt and EndsAt are optional; without them the sale starts now
+	// and runs until it is removed
+	StartsAt *time.Time `json:"starts_at" example:"2024-11-29T00:00:00Z"`
+	EndsAt   *time.Time `json:"ends_at" example:"2024-12-02T23:59:59Z"`
+}
+
+// sale validates the request and converts it into a sale
+func (r SaleRequest) sale() (*models.Sale, string) {
+	if r.StartsAt != nil && r.EndsAt != nil && !r.EndsAt.After(*r.StartsAt) {
+		return nil, "ends_at must be after starts_at"
+	}
+	if r.EndsAt != nil && !r.EndsAt.After(time.Now()) {
+		return nil, "ends_at must be in the future"
+	}
+	return &models.Sale{Price: r.SalePrice, StartsAt: r.StartsAt, EndsAt: r.EndsAt}, ""
+}
+
+// PriceChangeRequest represents the request body for scheduling a price change
+type PriceChangeRequest struct {
+	Price       float64   `json:"price" binding:"required,gt=0" example:"1799.99"`
+	EffectiveAt time.Time `json:"effective_at" binding:"required" example:"2024-11-29T00:00:00Z"`
+	Note        string    `json:"note" binding:"max=255" example:"Black Friday"`
+}
+
+// respondPriceError maps price model errors to responses
+func respondPriceError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "product not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+	case "price change not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pending price change not found"})
+	case "product is a bundle":
+		c.JSON(http.StatusConflict, gin.H{"error": "Bundles are priced from their components"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// @Summary Put a product on sale
+// @Description Set a product's sale price, optionally for a time window (admin only). While the sale runs, the product's effective_price is the sale price unless the regular price is lower.
+// @Tags prices
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param sale body SaleRequest true "Sale"
+// @Success 200 {object} models.Product
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /products/{id}/sale [put]
+func (h *PriceHandler) SetSale(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req SaleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sale, msg := req.sale()
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := h.PriceModel.SetSale(id, *sale); err != nil {
+		respondPriceError(c, err)
+		return
+	}
+
+	product, err := h.ProductModel.Get(id)
+	if err != nil {
+		respondPriceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+// @Summary End a product's sale
+// @Description Remove a product's sale price (admin only)
+// @Tags prices
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /products/{id}/sale [delete]
+func (h *PriceHandler) ClearSale(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	if err := h.PriceModel.ClearSale(id); err != nil {
+		respondPriceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary List a product's scheduled price changes
+// @Description List a product's planned, applied and cancelled price changes, latest first (admin only)
+// @Tags prices
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {array} models.PriceChange
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /products/{id}/price-changes [get]
+func (h *PriceHandler) GetPriceChanges(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	changes, err := h.PriceModel.ListChanges(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+
+// @Summary Schedule a price change
+// @Description Plan a product's regular price to change at a future time (admin only). The new price shows from that time on; a background job then stores it and records a price revision.
+// @Tags prices
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param change body PriceChangeRequest true "Price change"
+// @Success 201 {object} models.PriceChange
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /products/{id}/price-changes [post]
+func (h *PriceHandler) SchedulePriceChange(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req PriceChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !req.EffectiveAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "effective_at must be in the future"})
+		return
+	}
+
+	change := &models.PriceChange{
+		ProductID:   id,
+		Price:       req.Price,
+		EffectiveAt: req.EffectiveAt,
+		Note:        strings.TrimSpace(req.Note),
+		CreatedBy:   c.GetInt("userID"),
+	}
+	if err := h.PriceModel.ScheduleChange(change); err != nil {
+		respondPriceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, change)
+}
+
+// @Summary Cancel a scheduled price change
+// @Description Cancel a price change that has not been applied yet (admin only)
+// @Tags prices
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param change_id path int true "Price change ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /products/{id}/price-changes/{change_id} [delete]
+func (h *PriceHandler) CancelPriceChange(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+	changeID, err := strconv.Atoi(c.Param("change_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid price change ID"})
+		return
+	}
+
+	if err := h.PriceModel.CancelChange(id, changeID); err != nil {
+		respondPriceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Get a product's price history
+// @Description List the changes of a product's regular price, latest first (admin only)
+// @Tags prices
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param limit query int false "Maximum number of revisions (default 50, max 500)"
+// @Success 200 {array} models.PriceRevision
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /products/{id}/price-history [get]
+func (h *PriceHandler) GetPriceHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	limit := defaultPriceHistoryLimit
+	if v := c.Query("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxPriceHistoryLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+	}
+
+	revisions, err := h.PriceModel.History(id, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
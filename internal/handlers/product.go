@@ -1,15 +1,28 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"garage-api/internal/analytics"
+	"garage-api/internal/i18n"
 	"garage-api/internal/models"
 )
 
 type ProductHandler struct {
-	ProductModel models.ProductModelInterface
+	ProductModel     models.TxProductModelInterface
+	RelationModel    models.RelationModelInterface
+	BundleModel      models.BundleModelInterface
+	TranslationModel models.TranslationModelInterface
+	AttributeModel   models.AttributeModelInterface
+	Locales          i18n.Locales
+	// Views records product page views; editors' and admins' views are
+	// not recorded
+	Views *analytics.Recorder
 }
 
 // CreateProductRequest represents the request body for creating a product
@@ -17,42 +30,183 @@ type CreateProductRequest struct {
 	Name        string  `json:"name" binding:"required" example:"Hammer"`
 	Description string  `json:"description" binding:"required" example:"A sturdy hammer for construction"`
 	Price       float64 `json:"price" binding:"required" example:"29.99"`
+	SKU         string  `json:"sku" example:"HAM-001"`
+	// Slug defaults to one derived from the name
+	Slug        string  `json:"slug" binding:"max=100" example:"hammer"`
+	Stock       int     `json:"stock" binding:"min=0" example:"25"`
+	CategoryID  *int    `json:"category_id" example:"2"`
+	TaxClass    string  `json:"tax_class" binding:"max=32" example:"standard"`
+	Weight      float64 `json:"weight" binding:"min=0" example:"0.8"`
+	Length      float64 `json:"length" binding:"min=0" example:"30"`
+	Width       float64 `json:"width" binding:"min=0" example:"20"`
+	Height      float64 `json:"height" binding:"min=0" example:"5"`
+	// Attributes must match the attribute definitions of the category
+	Attributes map[string]interface{} `json:"attributes" swaggertype:"object"`
 }
 
 // UpdateProductRequest represents the request body for updating a product
 type UpdateProductRequest struct {
-	Name        string  `json:"name" example:"Updated Hammer"`
-	Description string  `json:"description" example:"An updated hammer description"`
-	Price       float64 `json:"price" example:"39.99"`
+	Name        string   `json:"name" example:"Updated Hammer"`
+	Description string   `json:"description" example:"An updated hammer description"`
+	Price       float64  `json:"price" example:"39.99"`
+	SKU         string   `json:"sku" example:"HAM-001"`
+	Slug        string   `json:"slug" binding:"max=100" example:"updated-hammer"`
+	Stock       *int     `json:"stock" binding:"omitempty,min=0" example:"30"`
+	CategoryID  *int     `json:"category_id" example:"2"`
+	TaxClass    string   `json:"tax_class" binding:"max=32" example:"standard"`
+	Weight      *float64 `json:"weight" binding:"omitempty,min=0" example:"0.8"`
+	Length      *float64 `json:"length" binding:"omitempty,min=0" example:"30"`
+	Width       *float64 `json:"width" binding:"omitempty,min=0" example:"20"`
+	Height      *float64 `json:"height" binding:"omitempty,min=0" example:"5"`
+	// Attributes, when given, replace all of the product's attributes
+	Attributes map[string]interface{} `json:"attributes" swaggertype:"object"`
+}
+
+// AttributeErrorResponse lists the attribute values a product was rejected for
+type AttributeErrorResponse struct {
+	Error    string                    `json:"error" example:"Invalid attributes"`
+	Problems []models.AttributeProblem `json:"problems"`
+}
+
+// parseProductFilter reads the product list filters from the query string
+func parseProductFilter(c *gin.Context) (models.ProductFilter, error) {
+	filter := models.ProductFilter{Search: c.Query("q")}
+
+	if v := c.Query("min_price"); v != "" {
+		price, err := strconv.ParseFloat(v, 64)
+		if err != nil || price < 0 {
+			return filter, errors.New("Invalid min_price")
+		}
+		filter.MinPrice = price
+	}
+	if v := c.Query("max_price"); v != "" {
+		price, err := strconv.ParseFloat(v, 64)
+		if err != nil || price < 0 {
+			return filter, errors.New("Invalid max_price")
+		}
+		filter.MaxPrice = price
+	}
+	if v := c.Query("category"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			return filter, errors.New("Invalid category")
+		}
+		filter.CategoryID = id
+	}
+	if v := c.Query("tags"); v != "" {
+		for _, tag := range strings.Split(v, ",") {
+			if tag = models.NormalizeTagName(tag); tag != "" {
+				filter.Tags = append(filter.Tags, tag)
+			}
+		}
+	}
+	if v := c.Query("status"); v != "" {
+		filter.Status = models.ProductStatus(v)
+		if !filter.Status.Valid() {
+			return filter, errors.New("Invalid status")
+		}
+	}
+	for key, values := range c.Request.URL.Query() {
+		code, ok := strings.CutPrefix(key, "attr.")
+		if !ok {
+			continue
+		}
+		if !models.ValidAttributeCode(code) {
+			return filter, errors.New("Invalid attribute " + code)
+		}
+		for _, v := range values {
+			for _, value := range strings.Split(v, ",") {
+				if value = strings.TrimSpace(value); value != "" {
+					if filter.Attributes == nil {
+						filter.Attributes = make(map[string][]string)
+					}
+					filter.Attributes[code] = append(filter.Attributes[code], value)
+				}
+			}
+		}
+	}
+
+	return filter, nil
+}
+
+// localize serves products in the best locale the caller accepts
+func (h *ProductHandler) localize(c *gin.Context, products []models.Product) error {
+	c.Header("Vary", "Accept-Language")
+	return h.TranslationModel.Localize(products, h.Locales.Negotiate(c.GetHeader("Accept-Language")))
+}
+
+// ProductListResponse is returned by the product list when facets are requested
+type ProductListResponse struct {
+	Products []models.Product      `json:"products"`
+	Facets   *models.ProductFacets `json:"facets"`
 }
 
 // @Summary Get all products
-// @Description Get a list of all products
+// @Description Get a list of all products, optionally filtered. With facets=true the response is an object holding the products and their counts per tag, category and price bucket. Only published products are listed, unless the caller is an editor or admin. Names and descriptions are translated according to Accept-Language, falling back to the default locale.
 // @Tags products
 // @Accept json
 // @Produce json
+// @Param Accept-Language header string false "Preferred locales, e.g. pt-BR,pt;q=0.9"
+// @Param q query string false "Search in name and description"
+// @Param min_price query number false "Minimum price"
+// @Param max_price query number false "Maximum price"
+// @Param category query int false "Category ID"
+// @Param tags query string false "Comma-separated tags; products must carry all of them"
+// @Param status query string false "Status: draft, in_review, published or archived (editors and admins)"
+// @Param attr.code query string false "Attribute value, with the attribute's code in place of code, e.g. attr.refresh_rate=144; comma-separated values match any of them"
+// @Param facets query bool false "Include facet counts"
 // @Success 200 {array} models.Product
+// @Success 200 {object} ProductListResponse
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /products [get]
 func (h *ProductHandler) GetAllProducts(c *gin.Context) {
-	products, err := h.ProductModel.GetAll()
+	filter, err := parseProductFilter(c)
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !canEditCatalog(c) {
+		filter.PublishedOnly = true
+	}
+
+	products, err := h.ProductModel.List(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.localize(c, products); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	if withFacets, _ := strconv.ParseBool(c.Query("facets")); withFacets {
+		facets, err := h.ProductModel.Facets(filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if products == nil {
+			products = []models.Product{}
+		}
+		c.JSON(http.StatusOK, ProductListResponse{Products: products, Facets: facets})
+		return
+	}
+
 	c.JSON(http.StatusOK, products)
 }
 
 // @Summary Get a product by ID
-// @Description Get a product's details by its ID
+// @Description Get a product's details by its ID, with its related products and, for bundles, its components. Products that are not published are only shown to editors and admins. Names and descriptions are translated according to Accept-Language, falling back to the default locale. Views by customers and guests count towards trending and recently viewed products.
 // @Tags products
 // @Accept json
 // @Produce json
 // @Param id path int true "Product ID"
+// @Param Accept-Language header string false "Preferred locales, e.g. pt-BR,pt;q=0.9"
 // @Success 200 {object} models.Product
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
 // @Router /products/{id} [get]
 func (h *ProductHandler) GetProductByID(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -67,17 +221,110 @@ func (h *ProductHandler) GetProductByID(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, product)
+	h.respondProductPage(c, product)
+}
+
+// @Summary Get a product by slug
+// @Description Get a product's details by its slug, like GET /products/{id}. A slug the product was known by before a rename answers with a 301 redirect to its current slug.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param slug path string true "Product slug"
+// @Param Accept-Language header string false "Preferred locales, e.g. pt-BR,pt;q=0.9"
+// @Success 200 {object} models.Product
+// @Success 301 "Moved Permanently"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /products/by-slug/{slug} [get]
+func (h *ProductHandler) GetProductBySlug(c *gin.Context) {
+	product, err := h.ProductModel.GetBySlug(c.Param("slug"))
+	if err == nil {
+		h.respondProductPage(c, product)
+		return
+	}
+	if err.Error() != "product not found" {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, err = h.ProductModel.GetByOldSlug(c.Param("slug"))
+	if err != nil || (!canEditCatalog(c) && !product.IsPublished(time.Now())) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	location := strings.TrimSuffix(c.Request.URL.Path, c.Param("slug")) + product.Slug
+	if c.Request.URL.RawQuery != "" {
+		location += "?" + c.Request.URL.RawQuery
+	}
+	c.Redirect(http.StatusMovedPermanently, location)
+}
+
+// respondProductPage answers with a product's details, with its related
+// products, its bundle and its content in the caller's locale. Products
+// that are not published are only shown to editors and admins.
+func (h *ProductHandler) respondProductPage(c *gin.Context, product *models.Product) {
+	staff := canEditCatalog(c)
+	if !staff && !product.IsPublished(time.Now()) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	var err error
+	if product.Relations, err = h.RelationModel.List(product.ID, !staff); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	bundle, err := h.BundleModel.Get(product.ID)
+	if err != nil && err.Error() != "bundle not found" {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	product.Bundle = bundle
+
+	localized := []models.Product{*product}
+	if err := h.localize(c, localized); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Language", localized[0].Locale)
+
+	if h.Views != nil && !staff {
+		h.Views.Record(analytics.View{ProductID: product.ID, UserID: c.GetInt("userID")})
+	}
+
+	c.JSON(http.StatusOK, localized[0])
+}
+
+// respondProductWriteError maps errors of product creates and updates to responses
+func respondProductWriteError(c *gin.Context, err error) {
+	var attrErr *models.AttributeError
+	if errors.As(err, &attrErr) {
+		c.JSON(http.StatusBadRequest, AttributeErrorResponse{Error: "Invalid attributes", Problems: attrErr.Problems})
+		return
+	}
+	switch err.Error() {
+	case "tax class not found":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown tax class"})
+	case "slug taken":
+		c.JSON(http.StatusConflict, gin.H{"error": "Slug is already used by another product"})
+	case "product not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
 }
 
 // @Summary Create a new product
-// @Description Create a new product with the provided details
+// @Description Create a new product with the provided details (editors and admins). New products are drafts until published. Without a slug, one is derived from the name, with a numeric suffix when it is taken. Attributes are checked against the definitions of the product's category.
 // @Tags products
 // @Accept json
 // @Produce json
 // @Param product body CreateProductRequest true "Product details"
 // @Success 201 {object} models.Product
-// @Failure 400 {object} map[string]string
+// @Failure 400 {object} AttributeErrorResponse
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Security Bearer
 // @Router /products [post]
@@ -92,10 +339,20 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 		Name:        req.Name,
 		Description: req.Description,
 		Price:       req.Price,
+		SKU:         req.SKU,
+		Slug:        req.Slug,
+		Stock:       req.Stock,
+		CategoryID:  req.CategoryID,
+		TaxClass:    req.TaxClass,
+		Weight:      req.Weight,
+		Length:      req.Length,
+		Width:       req.Width,
+		Height:      req.Height,
+		Attributes:  req.Attributes,
 	}
 
 	if err := h.ProductModel.Create(product); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondProductWriteError(c, err)
 		return
 	}
 
@@ -103,15 +360,17 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 }
 
 // @Summary Update a product
-// @Description Update an existing product's details
+// @Description Update an existing product's details. Renaming a product whose slug follows its name derives a new slug; the old slug then redirects to the new one. Attributes, when given, replace the product's attributes and are checked against the definitions of its category. Editors can only update drafts and products in review; published and archived products are changed by admins.
 // @Tags products
 // @Accept json
 // @Produce json
 // @Param id path int true "Product ID"
 // @Param product body UpdateProductRequest true "Product details to update"
 // @Success 200 {object} models.Product
-// @Failure 400 {object} map[string]string
+// @Failure 400 {object} AttributeErrorResponse
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Security Bearer
 // @Router /products/{id} [put]
@@ -128,9 +387,21 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 		return
 	}
 
-	product, err := h.ProductModel.Get(id)
+	tx, err := h.ProductModel.Begin()
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	model := h.ProductModel.WithTx(tx)
+	product, err := model.Lock(id)
+	if err != nil {
+		respondProductWriteError(c, err)
+		return
+	}
+	if c.GetString("role") != models.RoleAdmin && !product.Status.Unreleased() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can change published or archived products"})
 		return
 	}
 
@@ -140,11 +411,54 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 	if req.Description != "" {
 		product.Description = req.Description
 	}
+	if req.SKU != "" {
+		product.SKU = req.SKU
+	}
+	if req.Slug != "" {
+		product.Slug = req.Slug
+	}
+	if req.CategoryID != nil {
+		product.CategoryID = req.CategoryID
+	}
+	if req.TaxClass != "" {
+		product.TaxClass = req.TaxClass
+	}
+	if req.Weight != nil {
+		product.Weight = *req.Weight
+	}
+	if req.Length != nil {
+		product.Length = *req.Length
+	}
+	if req.Width != nil {
+		product.Width = *req.Width
+	}
+	if req.Height != nil {
+		product.Height = *req.Height
+	}
+	if req.Attributes != nil {
+		product.Attributes = req.Attributes
+	}
+
+	if err := model.Update(product); err != nil {
+		respondProductWriteError(c, err)
+		return
+	}
+	if req.Stock != nil {
+		if err := model.SetStock(id, *req.Stock); err != nil {
+			respondProductWriteError(c, err)
+			return
+		}
+		product.Stock = *req.Stock
+	}
 	if req.Price != 0 {
+		if err := model.SetPrice(id, req.Price); err != nil {
+			respondProductWriteError(c, err)
+			return
+		}
 		product.Price = req.Price
 	}
 
-	if err := h.ProductModel.Update(product); err != nil {
+	if err := tx.Commit(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -153,13 +467,16 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 }
 
 // @Summary Delete a product
-// @Description Delete a product by its ID
+// @Description Delete a product by its ID. Components of a bundle cannot be deleted. Editors can only delete drafts and products in review; published and archived products are deleted by admins.
 // @Tags products
 // @Accept json
 // @Produce json
 // @Param id path int true "Product ID"
 // @Success 204 "No Content"
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Security Bearer
 // @Router /products/{id} [delete]
@@ -170,11 +487,38 @@ func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 		return
 	}
 
-	if err := h.ProductModel.Delete(id); err != nil {
+	tx, err := h.ProductModel.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	model := h.ProductModel.WithTx(tx)
+	product, err := model.Lock(id)
+	if err != nil {
+		respondProductWriteError(c, err)
+		return
+	}
+	if c.GetString("role") != models.RoleAdmin && !product.Status.Unreleased() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can delete published or archived products"})
+		return
+	}
+
+	if err := model.Delete(id); err != nil {
 		if err.Error() == "product not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
+		if err.Error() == "product in bundle" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Product is a component of a bundle"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
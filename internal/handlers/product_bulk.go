@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"garage-api/internal/models"
+)
+
+const (
+	BulkModeAtomic  = "atomic"
+	BulkModePartial = "partial"
+)
+
+// BulkProductOperation represents a single create, update or delete in a bulk request
+type BulkProductOperation struct {
+	Op          string  `json:"op" binding:"required,oneof=create update delete" example:"create"`
+	ID          int     `json:"id,omitempty" example:"1"`
+	Name        string  `json:"name,omitempty" example:"Hammer"`
+	Description string  `json:"description,omitempty" example:"A sturdy hammer for construction"`
+	Price       float64 `json:"price,omitempty" example:"29.99"`
+	SKU         string  `json:"sku,omitempty" example:"HAM-001"`
+	Slug        string  `json:"slug,omitempty" example:"hammer"`
+	Stock       *int    `json:"stock,omitempty" example:"25"`
+}
+
+// BulkProductRequest represents the request body for bulk product operations
+type BulkProductRequest struct {
+	Mode       string                 `json:"mode" binding:"omitempty,oneof=atomic partial" example:"atomic"`
+	Operations []BulkProductOperation `json:"operations" binding:"required,min=1,max=1000,dive"`
+}
+
+// BulkProductResult represents the outcome of a single bulk operation
+type BulkProductResult struct {
+	Index   int             `json:"index" example:"0"`
+	Op      string          `json:"op" example:"create"`
+	Status  string          `json:"status" example:"created"`
+	Error   string          `json:"error,omitempty"`
+	Product *models.Product `json:"product,omitempty"`
+}
+
+// BulkProductResponse represents the response of a bulk product request
+type BulkProductResponse struct {
+	Mode      string              `json:"mode" example:"atomic"`
+	Succeeded int                 `json:"succeeded" example:"2"`
+	Failed    int                 `json:"failed" example:"0"`
+	Results   []BulkProductResult `json:"results"`
+}
+
+// @Summary Bulk create, update and delete products
+// @Description Apply many product operations in one request. In atomic mode (default) all operations run in a single transaction and nothing is written if any of them fails. In partial mode each operation is applied in a transaction of its own and failures are reported per item. Editors can only update and delete drafts and products in review.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param operations body BulkProductRequest true "Bulk operations"
+// @Success 200 {object} BulkProductResponse
+// @Success 207 {object} BulkProductResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 422 {object} BulkProductResponse
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /products/bulk [post]
+func (h *ProductHandler) BulkProducts(c *gin.Context) {
+	var req BulkProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = BulkModeAtomic
+	}
+
+	admin := c.GetString("role") == models.RoleAdmin
+
+	if req.Mode == BulkModePartial {
+		resp := BulkProductResponse{Mode: req.Mode, Results: make([]BulkProductResult, 0, len(req.Operations))}
+		for i, op := range req.Operations {
+			result := applyOwnTransaction(h.ProductModel, i, op, admin)
+			if result.Error != "" {
+				resp.Failed++
+			} else {
+				resp.Succeeded++
+			}
+			resp.Results = append(resp.Results, result)
+		}
+
+		status := http.StatusOK
+		if resp.Failed > 0 {
+			status = http.StatusMultiStatus
+		}
+		c.JSON(status, resp)
+		return
+	}
+
+	tx, err := h.ProductModel.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	txModel := h.ProductModel.WithTx(tx)
+	resp := BulkProductResponse{Mode: req.Mode, Results: make([]BulkProductResult, 0, len(req.Operations))}
+	for i, op := range req.Operations {
+		result := applyBulkOperation(txModel, i, op, admin)
+		resp.Results = append(resp.Results, result)
+		if result.Error == "" {
+			continue
+		}
+
+		// Nothing was written: mark earlier operations as rolled back and
+		// report the remaining ones as skipped
+		for j := range resp.Results[:i] {
+			resp.Results[j].Status = "rolled_back"
+			resp.Results[j].Product = nil
+		}
+		for j := i + 1; j < len(req.Operations); j++ {
+			resp.Results = append(resp.Results, BulkProductResult{Index: j, Op: req.Operations[j].Op, Status: "skipped"})
+		}
+		resp.Failed = 1
+		c.JSON(http.StatusUnprocessableEntity, resp)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp.Succeeded = len(resp.Results)
+	c.JSON(http.StatusOK, resp)
+}
+
+// applyOwnTransaction applies a partial-mode operation in a transaction of
+// its own, so an operation failing halfway leaves nothing of it written
+func applyOwnTransaction(model models.TxProductModelInterface, index int, op BulkProductOperation, admin bool) BulkProductResult {
+	tx, err := model.Begin()
+	if err != nil {
+		return BulkProductResult{Index: index, Op: op.Op, Status: "failed", Error: err.Error()}
+	}
+	defer tx.Rollback()
+
+	result := applyBulkOperation(model.WithTx(tx), index, op, admin)
+	if result.Error != "" {
+		return result
+	}
+	if err := tx.Commit(); err != nil {
+		return BulkProductResult{Index: index, Op: op.Op, Status: "failed", Error: err.Error()}
+	}
+	return result
+}
+
+func applyBulkOperation(model models.ProductModelInterface, index int, op BulkProductOperation, admin bool) BulkProductResult {
+	result := BulkProductResult{Index: index, Op: op.Op}
+
+	product, err := runBulkOperation(model, op, admin)
+	if err != nil {
+		result.Status = "failed"
+		result.Error = err.Error()
+		return result
+	}
+
+	switch op.Op {
+	case "create":
+		result.Status = "created"
+	case "update":
+		result.Status = "updated"
+	case "delete":
+		result.Status = "deleted"
+	}
+	result.Product = product
+	return result
+}
+
+// runBulkOperation applies one operation. Unless admin is set, only drafts
+// and products in review are updated or deleted.
+func runBulkOperation(model models.ProductModelInterface, op BulkProductOperation, admin bool) (*models.Product, error) {
+	switch op.Op {
+	case "create":
+		if op.Name == "" || op.Description == "" || op.Price == 0 {
+			return nil, errors.New("name, description and price are required")
+		}
+		product := &models.Product{
+			Name:        op.Name,
+			Description: op.Description,
+			Price:       op.Price,
+			SKU:         op.SKU,
+			Slug:        op.Slug,
+		}
+		if op.Stock != nil {
+			product.Stock = *op.Stock
+		}
+		if err := model.Create(product); err != nil {
+			return nil, err
+		}
+		return product, nil
+
+	case "update":
+		if op.ID == 0 {
+			return nil, errors.New("id is required")
+		}
+		if op.Stock != nil && *op.Stock < 0 {
+			return nil, errors.New("stock must not be negative")
+		}
+		product, err := model.Lock(op.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !admin && !product.Status.Unreleased() {
+			return nil, errors.New("only admins can change published or archived products")
+		}
+		if op.Name != "" {
+			product.Name = op.Name
+		}
+		if op.Description != "" {
+			product.Description = op.Description
+		}
+		if op.SKU != "" {
+			product.SKU = op.SKU
+		}
+		if op.Slug != "" {
+			product.Slug = op.Slug
+		}
+		if err := model.Update(product); err != nil {
+			return nil, err
+		}
+		if op.Stock != nil {
+			if err := model.SetStock(op.ID, *op.Stock); err != nil {
+				return nil, err
+			}
+			product.Stock = *op.Stock
+		}
+		if op.Price != 0 {
+			if err := model.SetPrice(op.ID, op.Price); err != nil {
+				return nil, err
+			}
+			product.Price = op.Price
+		}
+		return product, nil
+
+	case "delete":
+		if op.ID == 0 {
+			return nil, errors.New("id is required")
+		}
+		product, err := model.Lock(op.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !admin && !product.Status.Unreleased() {
+			return nil, errors.New("only admins can delete published or archived products")
+		}
+		if err := model.Delete(op.ID); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	return nil, errors.New("unknown operation")
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"garage-api/internal/models"
+)
+
+// CompareNotFoundResponse names the requested products that do not exist,
+// or that the caller cannot see
+type CompareNotFoundResponse struct {
+	Error      string `json:"error" example:"Products not found"`
+	MissingIDs []int  `json:"missing_ids" example:"4"`
+}
+
+// parseCompareIDs reads the comma-separated product IDs to compare, in
+// order and without repeats
+func parseCompareIDs(v string) ([]int, string) {
+	var ids []int
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(v, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil || id <= 0 {
+			return nil, "Invalid product ID " + part
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) < 2 {
+		return nil, "Give at least 2 product IDs to compare"
+	}
+	if len(ids) > models.MaxComparedProducts {
+		return nil, fmt.Sprintf("At most %d products can be compared", models.MaxComparedProducts)
+	}
+	return ids, ""
+}
+
+// @Summary Compare products
+// @Description Lay products side by side: one column per product, in the order given, and one row per field or attribute of their categories, with a value per product (null where it has none) and whether the values differ. Only published products can be compared, unless the caller is an editor or admin. Names are translated according to Accept-Language.
+// @Tags products
+// @Produce json
+// @Param ids query string true "Comma-separated product IDs, e.g. 1,4,5"
+// @Param differences_only query bool false "Only return the rows whose values differ"
+// @Param Accept-Language header string false "Preferred locales, e.g. pt-BR,pt;q=0.9"
+// @Success 200 {object} models.Comparison
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} CompareNotFoundResponse
+// @Failure 500 {object} map[string]string
+// @Router /products/compare [get]
+func (h *ProductHandler) CompareProducts(c *gin.Context) {
+	ids, msg := parseCompareIDs(c.Query("ids"))
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	found, err := h.ProductModel.List(models.ProductFilter{IDs: ids, PublishedOnly: !canEditCatalog(c)})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	byID := make(map[int]models.Product, len(found))
+	for _, p := range found {
+		byID[p.ID] = p
+	}
+
+	products := make([]models.Product, 0, len(ids))
+	var missing []int
+	for _, id := range ids {
+		p, ok := byID[id]
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+		products = append(products, p)
+	}
+	if len(missing) > 0 {
+		c.JSON(http.StatusNotFound, CompareNotFoundResponse{Error: "Products not found", MissingIDs: missing})
+		return
+	}
+
+	if err := h.localize(c, products); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var defs []models.AttributeDefinition
+	listed := make(map[int]bool)
+	for _, p := range products {
+		if p.CategoryID == nil || listed[*p.CategoryID] {
+			continue
+		}
+		listed[*p.CategoryID] = true
+		categoryDefs, err := h.AttributeModel.List(*p.CategoryID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defs = append(defs, categoryDefs...)
+	}
+
+	comparison := models.CompareProducts(products, defs)
+	if differencesOnly, _ := strconv.ParseBool(c.Query("differences_only")); differencesOnly {
+		rows := comparison.Rows[:0]
+		for _, row := range comparison.Rows {
+			if row.Differs {
+				rows = append(rows, row)
+			}
+		}
+		comparison.Rows = rows
+	}
+
+	c.JSON(http.StatusOK, comparison)
+}
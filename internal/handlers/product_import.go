@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"garage-api/internal/importer"
+)
+
+const (
+	// maxImportFileSize is the largest spreadsheet accepted by the import endpoint
+	maxImportFileSize = 20 << 20
+	// maxImportFormOverhead allows for the multipart framing and the mapping
+	// field on top of the file
+	maxImportFormOverhead = 1 << 20
+	// asyncImportRows is the row count above which imports run in the background
+	asyncImportRows = 500
+)
+
+type ImportHandler struct {
+	Importer *importer.Importer
+}
+
+// @Summary Import products from a spreadsheet
+// @Description Upsert products from a CSV or XLSX file. Rows are matched to existing products by SKU, or by name when the row has no SKU. The first row must be a header; columns are recognised by name (name, description, price, sku, image_path, html_content) or through an explicit mapping. With dry_run=true nothing is written and the response shows what would be created, updated or skipped. Files with more than 500 rows, or requests with async=true, are processed in the background and return a job to poll.
+// @Tags products
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV or XLSX file"
+// @Param mapping formData string false "JSON object mapping spreadsheet headers to product fields, e.g. {\"Unit Cost\":\"price\"}"
+// @Param dry_run query bool false "Validate and report without writing"
+// @Param async query bool false "Process the file in the background"
+// @Success 200 {object} importer.Result
+// @Success 202 {object} importer.Job
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /products/import [post]
+func (h *ImportHandler) ImportProducts(c *gin.Context) {
+	// The multipart body is read in full before the file can be looked at,
+	// so its size is capped while it is read
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportFileSize+maxImportFormOverhead)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file is too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	if fileHeader.Size > maxImportFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is too large"})
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+	async, _ := strconv.ParseBool(c.Query("async"))
+	opts := importer.Options{DryRun: dryRun}
+	if mapping := c.PostForm("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &opts.Mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "mapping must be a JSON object of header to field"})
+			return
+		}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	records, err := importer.ReadRecords(fileHeader.Filename, file, fileHeader.Size)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := importer.CheckHeader(records, opts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if async || len(records)-1 > asyncImportRows {
+		job, err := h.Importer.RunAsync(fileHeader.Filename, records, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, job)
+		return
+	}
+
+	result, err := h.Importer.Run(c.Request.Context(), records, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// @Summary Get import job status
+// @Description Get the status and, once finished, the result of a background import
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path string true "Import job ID"
+// @Success 200 {object} importer.Job
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security Bearer
+// @Router /products/import/{id} [get]
+func (h *ImportHandler) GetImportJob(c *gin.Context) {
+	job, ok := h.Importer.Jobs.Get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import job not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"garage-api/internal/models"
+	"garage-api/internal/promotion"
+)
+
+type PromotionHandler struct {
+	PromotionModel models.PromotionModelInterface
+}
+
+// PromotionRequest represents the request body for creating or updating a
+// promotion. Leave code empty for a promotion that applies automatically.
+type PromotionRequest struct {
+	Name         string         `json:"name" binding:"required,max=255" example:"Buy 2 mouse pads, get 10% off"`
+	Code         string         `json:"code" binding:"max=64" example:""`
+	Type         promotion.Type `json:"type" binding:"required" example:"percentage"`
+	Value        float64        `json:"value" binding:"min=0" example:"10"`
+	StartsAt     *time.Time     `json:"starts_at"`
+	EndsAt       *time.Time     `json:"ends_at"`
+	MinSubtotal  float64        `json:"min_subtotal" binding:"min=0" example:"0"`
+	MinQuantity  int            `json:"min_quantity" binding:"min=0" example:"2"`
+	ProductIDs   []int          `json:"product_ids" binding:"max=1000" example:"4"`
+	CategoryIDs  []int          `json:"category_ids" binding:"max=1000"`
+	UsageLimit   int            `json:"usage_limit" binding:"min=0" example:"0"`
+	PerUserLimit int            `json:"per_user_limit" binding:"min=0" example:"0"`
+	Priority     int            `json:"priority" example:"0"`
+	Active       *bool          `json:"active" example:"true"`
+}
+
+// promotion validates the request and converts it into a model
+func (r *PromotionRequest) promotion() (*models.Promotion, string) {
+	if !r.Type.Valid() {
+		return nil, "Invalid promotion type"
+	}
+	if r.Type == promotion.Percentage && (r.Value <= 0 || r.Value > 100) {
+		return nil, "Percentage must be between 0 and 100"
+	}
+	if r.Type == promotion.FixedAmount && r.Value <= 0 {
+		return nil, "Amount must be greater than 0"
+	}
+	if r.StartsAt != nil && r.EndsAt != nil && !r.EndsAt.After(*r.StartsAt) {
+		return nil, "ends_at must be after starts_at"
+	}
+
+	p := &models.Promotion{
+		Name:         r.Name,
+		Code:         r.Code,
+		Type:         r.Type,
+		Value:        r.Value,
+		StartsAt:     r.StartsAt,
+		EndsAt:       r.EndsAt,
+		MinSubtotal:  r.MinSubtotal,
+		MinQuantity:  r.MinQuantity,
+		ProductIDs:   r.ProductIDs,
+		CategoryIDs:  r.CategoryIDs,
+		UsageLimit:   r.UsageLimit,
+		PerUserLimit: r.PerUserLimit,
+		Priority:     r.Priority,
+		Active:       r.Active == nil || *r.Active,
+	}
+	if p.ProductIDs == nil {
+		p.ProductIDs = []int{}
+	}
+	if p.CategoryIDs == nil {
+		p.CategoryIDs = []int{}
+	}
+	return p, ""
+}
+
+// @Summary Get all promotions
+// @Description Get all coupons and automatic promotions with how often each has been redeemed
+// @Tags promotions
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.Promotion
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /promotions [get]
+func (h *PromotionHandler) GetAllPromotions(c *gin.Context) {
+	promotions, err := h.PromotionModel.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, promotions)
+}
+
+// @Summary Get a promotion by ID
+// @Description Get a single coupon or automatic promotion
+// @Tags promotions
+// @Accept json
+// @Produce json
+// @Param id path int true "Promotion ID"
+// @Success 200 {object} models.Promotion
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /promotions/{id} [get]
+func (h *PromotionHandler) GetPromotionByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid promotion ID"})
+		return
+	}
+
+	p, err := h.PromotionModel.Get(id)
+	if err != nil {
+		if err.Error() == "promotion not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Promotion not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+// @Summary Create a promotion
+// @Description Create a coupon, when a code is given, or a promotion that applies automatically to every matching cart. Codes are case-insensitive.
+// @Tags promotions
+// @Accept json
+// @Produce json
+// @Param promotion body PromotionRequest true "Promotion details"
+// @Success 201 {object} models.Promotion
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /promotions [post]
+func (h *PromotionHandler) CreatePromotion(c *gin.Context) {
+	var req PromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p, msg := req.promotion()
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := h.PromotionModel.Create(p); err != nil {
+		if err.Error() == "code already exists" {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, p)
+}
+
+// @Summary Update a promotion
+// @Description Replace the settings of a promotion. Past redemptions are kept and still count towards usage limits.
+// @Tags promotions
+// @Accept json
+// @Produce json
+// @Param id path int true "Promotion ID"
+// @Param promotion body PromotionRequest true "Promotion details"
+// @Success 200 {object} models.Promotion
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /promotions/{id} [put]
+func (h *PromotionHandler) UpdatePromotion(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid promotion ID"})
+		return
+	}
+
+	var req PromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p, msg := req.promotion()
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	p.ID = id
+
+	if err := h.PromotionModel.Update(p); err != nil {
+		switch err.Error() {
+		case "promotion not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Promotion not found"})
+		case "code already exists":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	updated, err := h.PromotionModel.Get(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// @Summary Delete a promotion
+// @Description Delete a promotion together with its redemption history. Set active to false instead to keep the history.
+// @Tags promotions
+// @Accept json
+// @Produce json
+// @Param id path int true "Promotion ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /promotions/{id} [delete]
+func (h *PromotionHandler) DeletePromotion(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid promotion ID"})
+		return
+	}
+
+	if err := h.PromotionModel.Delete(id); err != nil {
+		if err.Error() == "promotion not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Promotion not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
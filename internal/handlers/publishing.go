@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"garage-api/internal/models"
+)
+
+type PublishingHandler struct {
+	PublishingModel models.PublishingModelInterface
+	ProductModel    models.ProductModelInterface
+}
+
+// ProductStatusRequest represents the request body for moving a product
+// through the publishing workflow
+type ProductStatusRequest struct {
+	Status string `json:"status" binding:"required" example:"published"`
+	// PublishAt schedules a publication; the product goes live at that time
+	PublishAt *time.Time `json:"publish_at" example:"2024-03-01T09:00:00Z"`
+	Note      string     `json:"note" binding:"max=255" example:"Approved for the spring catalog"`
+}
+
+// change validates the request and converts it into a status change
+func (r ProductStatusRequest) change(productID, userID int) (*models.ProductStatusChange, string) {
+	status := models.ProductStatus(r.Status)
+	if !status.Valid() {
+		return nil, "status must be draft, in_review, published or archived"
+	}
+	if r.PublishAt != nil && status != models.ProductPublished {
+		return nil, "publish_at can only be set when publishing"
+	}
+	return &models.ProductStatusChange{
+		ProductID: productID,
+		ToStatus:  status,
+		PublishAt: r.PublishAt,
+		Note:      r.Note,
+		ChangedBy: userID,
+	}, ""
+}
+
+// canEditCatalog reports whether the signed-in user, if any, manages the
+// catalog and may see products that are not published
+func canEditCatalog(c *gin.Context) bool {
+	role := c.GetString("role")
+	return role == models.RoleEditor || role == models.RoleAdmin
+}
+
+// @Summary Change a product's status
+// @Description Move a product through the publishing workflow: draft, in_review, published, archived. Editors can submit drafts for review and send them back to draft; only admins can publish, schedule a publication with publish_at, unpublish or archive a published product.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param status body ProductStatusRequest true "New status"
+// @Success 200 {object} models.Product
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /products/{id}/status [put]
+func (h *PublishingHandler) SetProductStatus(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req ProductStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	change, msg := req.change(id, c.GetInt("userID"))
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := h.PublishingModel.SetStatus(change, c.GetString("role") == models.RoleAdmin); err != nil {
+		var transitionErr *models.ProductTransitionError
+		switch {
+		case errors.As(err, &transitionErr):
+			c.JSON(http.StatusConflict, gin.H{"error": transitionErr.Error()})
+		case err.Error() == "publisher required":
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can publish or unpublish products"})
+		case err.Error() == "product not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	product, err := h.ProductModel.Get(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+// @Summary Get a product's status history
+// @Description List a product's moves through the publishing workflow, latest first
+// @Tags products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {array} models.ProductStatusChange
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /products/{id}/status-history [get]
+func (h *PublishingHandler) GetProductStatusHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	if _, err := h.ProductModel.Get(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	history, err := h.PublishingModel.History(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"garage-api/internal/models"
+)
+
+type RecommendationHandler struct {
+	RecommendationModel models.RecommendationModelInterface
+	ProductModel        models.ProductModelInterface
+}
+
+// @Summary Get product recommendations
+// @Description List the products customers who bought or viewed this product also bought or viewed, strongest first. Recommendations are recomputed periodically; products with too few are topped up with the bestsellers of their category, marked with source "bestseller". Products that are not published are only shown to editors and admins.
+// @Tags products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param limit query int false "Number of products, up to 50" default(10)
+// @Success 200 {array} models.RecommendedProduct
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /products/{id}/recommendations [get]
+func (h *RecommendationHandler) GetRecommendations(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+	limit, ok := viewLimit(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	product, err := h.ProductModel.Get(id)
+	if err != nil || (!canEditCatalog(c) && !product.IsPublished(time.Now())) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	products, err := h.RecommendationModel.List(id, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// New or rarely bought products have few similar ones; the category's
+	// bestsellers, or the catalog's without a category, fill in
+	if len(products) < limit {
+		exclude := []int{id}
+		for _, p := range products {
+			exclude = append(exclude, p.ProductID)
+		}
+		categoryID := 0
+		if product.CategoryID != nil {
+			categoryID = *product.CategoryID
+		}
+		bestsellers, err := h.RecommendationModel.Bestsellers(categoryID, exclude, limit-len(products))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		products = append(products, bestsellers...)
+	}
+
+	c.JSON(http.StatusOK, products)
+}
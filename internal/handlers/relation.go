@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"garage-api/internal/models"
+)
+
+const maxBundleItems = 20
+
+// ProductRelationsRequest represents the request body for setting a
+// product's relations
+type ProductRelationsRequest struct {
+	Relations []models.ProductRelation `json:"relations" binding:"max=100"`
+}
+
+// BundleRequest represents the request body for making a product a bundle
+type BundleRequest struct {
+	DiscountPercent float64             `json:"discount_percent" binding:"min=0,lt=100" example:"10"`
+	Items           []BundleItemRequest `json:"items" binding:"required,min=1"`
+}
+
+// BundleItemRequest is a component of a bundle request
+type BundleItemRequest struct {
+	ProductID int `json:"product_id" binding:"required,min=1" example:"5"`
+	Quantity  int `json:"quantity" binding:"required,min=1,max=100" example:"1"`
+}
+
+// items validates the request and converts it into the bundle's components
+func (r BundleRequest) items(bundleID int) ([]models.BundleItem, string) {
+	if len(r.Items) > maxBundleItems {
+		return nil, fmt.Sprintf("A bundle has at most %d components", maxBundleItems)
+	}
+
+	seen := make(map[int]bool)
+	items := make([]models.BundleItem, 0, len(r.Items))
+	for _, item := range r.Items {
+		if item.ProductID == bundleID {
+			return nil, "A bundle cannot contain itself"
+		}
+		if seen[item.ProductID] {
+			return nil, fmt.Sprintf("Product %d is listed twice", item.ProductID)
+		}
+		seen[item.ProductID] = true
+		items = append(items, models.BundleItem{ProductID: item.ProductID, Quantity: item.Quantity})
+	}
+	return items, ""
+}
+
+// respondRelationError maps relation and bundle model errors to responses
+func respondRelationError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "product not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+	case "bundle not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product is not a bundle"})
+	case "related product not found":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Related product not found"})
+	case "component not found":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Component product not found"})
+	case "component is a bundle":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bundles cannot contain bundles"})
+	case "product is a bundle component":
+		c.JSON(http.StatusConflict, gin.H{"error": "Product is a component of a bundle"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// @Summary Set a product's relations
+// @Description Replace the products suggested on a product's page (admin only). Types are related, accessory, upsell and replacement; products are shown in the order given within each type.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param relations body ProductRelationsRequest true "Relations"
+// @Success 200 {array} models.RelatedProduct
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /products/{id}/relations [put]
+func (h *ProductHandler) SetProductRelations(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req ProductRelationsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, r := range req.Relations {
+		if !r.Type.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid relation type, expected related, accessory, upsell or replacement"})
+			return
+		}
+		if r.ProductID == id {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A product cannot be related to itself"})
+			return
+		}
+	}
+
+	related, err := h.RelationModel.Set(id, req.Relations)
+	if err != nil {
+		respondRelationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, related)
+}
+
+// @Summary Make a product a bundle
+// @Description Make a product a bundle of other products, or replace its components (admin only). The bundle's price becomes the components' total less the discount and its stock the number of complete sets in stock; both follow the components from then on. Selling a bundle takes its components out of stock.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param bundle body BundleRequest true "Bundle"
+// @Success 200 {object} models.Bundle
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /products/{id}/bundle [put]
+func (h *ProductHandler) SetProductBundle(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req BundleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	items, msg := req.items(id)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	bundle, err := h.BundleModel.Set(id, req.DiscountPercent, items)
+	if err != nil {
+		respondRelationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, bundle)
+}
+
+// @Summary Unbundle a product
+// @Description Turn a bundle back into a plain product (admin only). It keeps its last price and stock.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /products/{id}/bundle [delete]
+func (h *ProductHandler) DeleteProductBundle(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	if err := h.BundleModel.Delete(id); err != nil {
+		respondRelationError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,366 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"garage-api/internal/models"
+	"garage-api/internal/payment"
+)
+
+type ReturnHandler struct {
+	ReturnModel  models.ReturnModelInterface
+	Providers    map[string]payment.PaymentProvider
+	PaymentModel models.PaymentModelInterface
+}
+
+// ReturnItemRequest names an order line, or part of one, to send back
+type ReturnItemRequest struct {
+	OrderItemID int                 `json:"order_item_id" binding:"required,min=1" example:"3"`
+	Quantity    int                 `json:"quantity" binding:"required,min=1" example:"1"`
+	Reason      models.ReturnReason `json:"reason" binding:"required" example:"defective"`
+	Comment     string              `json:"comment" binding:"max=1000" example:"Left ear cup is silent"`
+}
+
+// CreateReturnRequest represents the request body for requesting a return
+type CreateReturnRequest struct {
+	Items []ReturnItemRequest `json:"items" binding:"required,min=1,dive"`
+	Note  string              `json:"note" binding:"max=2000" example:"Bought the wrong size"`
+}
+
+// UpdateReturnStatusRequest represents the request body for changing a return's status
+type UpdateReturnStatusRequest struct {
+	Status models.ReturnStatus `json:"status" binding:"required" example:"approved"`
+	// Note replaces the admin note when given, e.g. why a return was rejected
+	Note string `json:"note" binding:"max=2000" example:"Send it to our warehouse in Berlin"`
+}
+
+// returnFor validates the request and converts it into a return of the
+// signed-in user for an order
+func (r CreateReturnRequest) returnFor(orderID, userID int) (*models.Return, string) {
+	ret := &models.Return{OrderID: orderID, UserID: userID, Note: strings.TrimSpace(r.Note)}
+	seen := map[int]bool{}
+	for _, item := range r.Items {
+		if !item.Reason.Valid() {
+			return nil, "Invalid reason, expected damaged, defective, wrong_item, not_as_described, no_longer_needed or other"
+		}
+		if seen[item.OrderItemID] {
+			return nil, fmt.Sprintf("Order item %d is listed more than once", item.OrderItemID)
+		}
+		seen[item.OrderItemID] = true
+		ret.Items = append(ret.Items, models.ReturnItem{
+			OrderItemID: item.OrderItemID,
+			Quantity:    item.Quantity,
+			Reason:      item.Reason,
+			Comment:     strings.TrimSpace(item.Comment),
+		})
+	}
+	return ret, ""
+}
+
+func parseReturnFilter(c *gin.Context) (models.ReturnFilter, error) {
+	filter := models.ReturnFilter{Limit: defaultOrderLimit}
+
+	if v := c.Query("status"); v != "" {
+		filter.Status = models.ReturnStatus(v)
+		if !filter.Status.Valid() {
+			return filter, errors.New("Invalid status")
+		}
+	}
+	if v := c.Query("user_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			return filter, errors.New("Invalid user_id")
+		}
+		filter.UserID = id
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxOrderLimit {
+			return filter, errors.New("Invalid limit")
+		}
+		filter.Limit = limit
+	}
+	if v := c.Query("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return filter, errors.New("Invalid offset")
+		}
+		filter.Offset = offset
+	}
+
+	return filter, nil
+}
+
+// respondReturnError maps return model errors to responses
+func respondReturnError(c *gin.Context, err error) {
+	var quantityErr *models.ReturnQuantityError
+	var transitionErr *models.ReturnTransitionError
+	switch {
+	case errors.As(err, &quantityErr):
+		c.JSON(http.StatusConflict, gin.H{"error": quantityErr.Error()})
+	case errors.As(err, &transitionErr):
+		c.JSON(http.StatusConflict, gin.H{"error": transitionErr.Error()})
+	case err.Error() == "order not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+	case err.Error() == "order not delivered":
+		c.JSON(http.StatusConflict, gin.H{"error": "Only delivered orders can be returned"})
+	case err.Error() == "order item not found":
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Order item not found in this order"})
+	case err.Error() == "return not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Return not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// getReturn loads the return named in the path
+func (h *ReturnHandler) getReturn(c *gin.Context) (*models.Return, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid return ID"})
+		return nil, false
+	}
+
+	ret, err := h.ReturnModel.Get(id)
+	if err != nil {
+		respondReturnError(c, err)
+		return nil, false
+	}
+	return ret, true
+}
+
+// @Summary Request a return
+// @Description Ask to send back items of one of the signed-in user's delivered orders. Each line needs a reason: damaged, defective, wrong_item, not_as_described, no_longer_needed or other. Items already in an open or completed return cannot be returned again. The refund amount is the lines' share of what was paid; the return that sends back the last items of an order refunds the rest of the order, shipping included.
+// @Tags returns
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Param return body CreateReturnRequest true "Items to return"
+// @Success 201 {object} models.Return
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /me/orders/{id}/returns [post]
+func (h *ReturnHandler) CreateReturn(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	var req CreateReturnRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ret, msg := req.returnFor(orderID, c.GetInt("userID"))
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := h.ReturnModel.Create(ret); err != nil {
+		respondReturnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, ret)
+}
+
+// @Summary List my returns
+// @Description List the signed-in user's returns, newest first
+// @Tags returns
+// @Accept json
+// @Produce json
+// @Param status query string false "Filter by status" Enums(requested, approved, rejected, received, refunded)
+// @Param limit query int false "Maximum number of returns (default 50, max 200)"
+// @Param offset query int false "Number of returns to skip"
+// @Success 200 {array} models.Return
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /me/returns [get]
+func (h *ReturnHandler) GetMyReturns(c *gin.Context) {
+	filter, err := parseReturnFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.UserID = c.GetInt("userID")
+
+	returns, err := h.ReturnModel.List(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, returns)
+}
+
+// @Summary Get one of my returns
+// @Description Get a return of the signed-in user
+// @Tags returns
+// @Accept json
+// @Produce json
+// @Param id path int true "Return ID"
+// @Success 200 {object} models.Return
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /me/returns/{id} [get]
+func (h *ReturnHandler) GetMyReturn(c *gin.Context) {
+	ret, ok := h.getReturn(c)
+	if !ok {
+		return
+	}
+	// Other users' returns are reported as not found
+	if ret.UserID != c.GetInt("userID") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Return not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ret)
+}
+
+// @Summary List all returns
+// @Description List returns of all users, newest first (admin only)
+// @Tags returns
+// @Accept json
+// @Produce json
+// @Param status query string false "Filter by status" Enums(requested, approved, rejected, received, refunded)
+// @Param user_id query int false "Filter by user"
+// @Param limit query int false "Maximum number of returns (default 50, max 200)"
+// @Param offset query int false "Number of returns to skip"
+// @Success 200 {array} models.Return
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /returns [get]
+func (h *ReturnHandler) GetAllReturns(c *gin.Context) {
+	filter, err := parseReturnFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	returns, err := h.ReturnModel.List(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, returns)
+}
+
+// @Summary Get a return
+// @Description Get any return (admin only)
+// @Tags returns
+// @Accept json
+// @Produce json
+// @Param id path int true "Return ID"
+// @Success 200 {object} models.Return
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /returns/{id} [get]
+func (h *ReturnHandler) GetReturnByID(c *gin.Context) {
+	ret, ok := h.getReturn(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, ret)
+}
+
+// @Summary Change a return's status
+// @Description Move a return through its lifecycle (admin only). Allowed transitions: requested → approved|rejected, approved → received|rejected, received → refunded. Receiving a return puts its items back in stock. Setting refunded records a refund made outside the shop; POST /returns/{id}/refund refunds through the payment provider instead. Refunding the return that completes an order's returns refunds the order.
+// @Tags returns
+// @Accept json
+// @Produce json
+// @Param id path int true "Return ID"
+// @Param status body UpdateReturnStatusRequest true "New status"
+// @Success 200 {object} models.Return
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /returns/{id}/status [put]
+func (h *ReturnHandler) UpdateReturnStatus(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid return ID"})
+		return
+	}
+
+	var req UpdateReturnStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !req.Status.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return
+	}
+
+	ret, err := h.ReturnModel.UpdateStatus(id, req.Status, strings.TrimSpace(req.Note))
+	if err != nil {
+		respondReturnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ret)
+}
+
+// @Summary Refund a return
+// @Description Refund a received return's amount from the order's captured payment through its provider and mark the return refunded (admin only)
+// @Tags returns
+// @Accept json
+// @Produce json
+// @Param id path int true "Return ID"
+// @Success 200 {object} models.Return
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Security Bearer
+// @Router /returns/{id}/refund [post]
+func (h *ReturnHandler) RefundReturn(c *gin.Context) {
+	ret, ok := h.getReturn(c)
+	if !ok {
+		return
+	}
+	if !ret.Status.CanTransitionTo(models.ReturnRefunded) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("cannot refund a return that is %s", ret.Status)})
+		return
+	}
+
+	// An amount of 0 would refund the whole payment
+	if amount := payment.ToMinorUnits(ret.RefundAmount); amount > 0 {
+		if _, ok := refundPayment(c, h.Providers, h.PaymentModel, ret.OrderID, amount); !ok {
+			return
+		}
+	}
+
+	ret, err := h.ReturnModel.UpdateStatus(ret.ID, models.ReturnRefunded, "")
+	if err != nil {
+		respondReturnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ret)
+}
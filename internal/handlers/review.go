@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"garage-api/internal/models"
+)
+
+const (
+	defaultReviewLimit = 20
+	maxReviewLimit     = 100
+)
+
+type ReviewHandler struct {
+	ReviewModel models.ReviewModelInterface
+}
+
+// CreateReviewRequest represents the request body for reviewing a product
+type CreateReviewRequest struct {
+	Rating int    `json:"rating" binding:"required,min=1,max=5" example:"4"`
+	Title  string `json:"title" binding:"required,max=255" example:"Comfortable for long sessions"`
+	Body   string `json:"body" binding:"max=5000" example:"Great sound, the microphone could be better."`
+}
+
+// UpdateReviewStatusRequest represents the request body for moderating a review
+type UpdateReviewStatusRequest struct {
+	Status models.ReviewStatus `json:"status" binding:"required" example:"approved"`
+	// Note replaces the moderation note when given, e.g. why a review was rejected
+	Note string `json:"note" binding:"max=2000" example:""`
+}
+
+// HelpfulVoteResponse reports a review's helpful count after a vote
+type HelpfulVoteResponse struct {
+	ReviewID     int `json:"review_id" example:"1"`
+	HelpfulCount int `json:"helpful_count" example:"6"`
+}
+
+func parseReviewFilter(c *gin.Context) (models.ReviewFilter, error) {
+	filter := models.ReviewFilter{Limit: defaultReviewLimit, Sort: c.DefaultQuery("sort", "newest")}
+
+	if !models.ValidReviewSort(filter.Sort) {
+		return filter, errors.New("Invalid sort, expected newest, oldest, helpful, highest or lowest")
+	}
+	if v := c.Query("status"); v != "" {
+		filter.Status = models.ReviewStatus(v)
+		if !filter.Status.Valid() {
+			return filter, errors.New("Invalid status")
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxReviewLimit {
+			return filter, errors.New("Invalid limit")
+		}
+		filter.Limit = limit
+	}
+	if v := c.Query("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return filter, errors.New("Invalid offset")
+		}
+		filter.Offset = offset
+	}
+
+	return filter, nil
+}
+
+// respondReviewError maps review model errors to responses
+func respondReviewError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "review not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+	case "product not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+	case "product not purchased":
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only customers who bought this product can review it"})
+	case "product already reviewed":
+		c.JSON(http.StatusConflict, gin.H{"error": "You have already reviewed this product"})
+	case "already voted":
+		c.JSON(http.StatusConflict, gin.H{"error": "You have already voted for this review"})
+	case "cannot vote on own review":
+		c.JSON(http.StatusConflict, gin.H{"error": "You cannot vote for your own review"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// @Summary List a product's reviews
+// @Description List the approved reviews of a product. The product's average rating and review count are part of the product itself.
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param sort query string false "Sort order" Enums(newest, oldest, helpful, highest, lowest) default(newest)
+// @Param limit query int false "Maximum number of reviews (default 20, max 100)"
+// @Param offset query int false "Number of reviews to skip"
+// @Success 200 {array} models.Review
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /products/{id}/reviews [get]
+func (h *ReviewHandler) GetProductReviews(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	filter, err := parseReviewFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.ProductID = id
+	filter.Status = models.ReviewApproved
+
+	reviews, err := h.ReviewModel.List(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, reviews)
+}
+
+// @Summary Review a product
+// @Description Rate a product from 1 to 5 stars. Only customers with a paid, shipped or delivered order of the product can review it, once. Reviews are shown after an admin approves them.
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param review body CreateReviewRequest true "Review"
+// @Success 201 {object} models.Review
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /products/{id}/reviews [post]
+func (h *ReviewHandler) CreateReview(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req CreateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required"})
+		return
+	}
+
+	review := &models.Review{
+		ProductID: id,
+		UserID:    c.GetInt("userID"),
+		Username:  c.GetString("username"),
+		Rating:    req.Rating,
+		Title:     title,
+		Body:      strings.TrimSpace(req.Body),
+	}
+	if err := h.ReviewModel.Create(review); err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, review)
+}
+
+// @Summary List my reviews
+// @Description List the signed-in user's reviews, whatever their moderation status
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param status query string false "Filter by status" Enums(pending, approved, rejected)
+// @Param limit query int false "Maximum number of reviews (default 20, max 100)"
+// @Param offset query int false "Number of reviews to skip"
+// @Success 200 {array} models.Review
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /me/reviews [get]
+func (h *ReviewHandler) GetMyReviews(c *gin.Context) {
+	filter, err := parseReviewFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.UserID = c.GetInt("userID")
+
+	reviews, err := h.ReviewModel.List(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, reviews)
+}
+
+// @Summary Vote a review helpful
+// @Description Mark an approved review as helpful. Each user votes once per review and not on their own reviews.
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param id path int true "Review ID"
+// @Success 200 {object} HelpfulVoteResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /reviews/{id}/helpful [post]
+func (h *ReviewHandler) VoteHelpful(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	count, err := h.ReviewModel.Vote(id, c.GetInt("userID"))
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, HelpfulVoteResponse{ReviewID: id, HelpfulCount: count})
+}
+
+// @Summary List reviews for moderation
+// @Description List reviews of all products (admin only). Defaults to the moderation queue: pending reviews, oldest first.
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param status query string false "Filter by status" Enums(pending, approved, rejected) default(pending)
+// @Param sort query string false "Sort order" Enums(newest, oldest, helpful, highest, lowest) default(oldest)
+// @Param limit query int false "Maximum number of reviews (default 20, max 100)"
+// @Param offset query int false "Number of reviews to skip"
+// @Success 200 {array} models.Review
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /reviews [get]
+func (h *ReviewHandler) GetAllReviews(c *gin.Context) {
+	filter, err := parseReviewFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if c.Query("status") == "" {
+		filter.Status = models.ReviewPending
+	}
+	if c.Query("sort") == "" {
+		filter.Sort = "oldest"
+	}
+
+	reviews, err := h.ReviewModel.List(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, reviews)
+}
+
+// @Summary Moderate a review
+// @Description Approve or reject a review (admin only). Only approved reviews are shown and count towards the product's average rating, which is updated right away.
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param id path int true "Review ID"
+// @Param status body UpdateReviewStatusRequest true "New status"
+// @Success 200 {object} models.Review
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /reviews/{id}/status [put]
+func (h *ReviewHandler) UpdateReviewStatus(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	var req UpdateReviewStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !req.Status.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return
+	}
+
+	review, err := h.ReviewModel.UpdateStatus(id, req.Status, strings.TrimSpace(req.Note))
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// @Summary Delete a review
+// @Description Delete a review and its votes (admin only)
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param id path int true "Review ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /reviews/{id} [delete]
+func (h *ReviewHandler) DeleteReview(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	if err := h.ReviewModel.Delete(id); err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
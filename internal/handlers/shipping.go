@@ -0,0 +1,422 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"garage-api/internal/models"
+	"garage-api/internal/shipping"
+	"garage-api/internal/tax"
+)
+
+// zoneCountryPattern matches the entries of a shipping zone: a country code,
+// a country-region code or "*"
+var zoneCountryPattern = regexp.MustCompile(`^([A-Z]{2}(-[A-Z0-9]{1,3})?|\*)$`)
+
+type ShippingHandler struct {
+	ShippingModel models.ShippingModelInterface
+	CartModel     models.CartModelInterface
+	AddressModel  models.AddressModelInterface
+	// DefaultAddress is quoted when the request names no country and the
+	// user has no default shipping address
+	DefaultAddress tax.Address
+}
+
+// ShippingQuoteRequest represents the optional request body of a shipping quote
+type ShippingQuoteRequest struct {
+	Country string `json:"country" binding:"omitempty,len=2" example:"DE"`
+	Region  string `json:"region" binding:"max=10" example:""`
+}
+
+// ShippingQuoteResponse lists the shipping options for the cart
+type ShippingQuoteResponse struct {
+	Country string `json:"country" example:"DE"`
+	Region  string `json:"region,omitempty" example:""`
+	// Weight is the billable weight of the cart in kilograms
+	Weight  float64           `json:"weight" example:"2.8"`
+	Options []shipping.Option `json:"options"`
+}
+
+// ShippingZoneRequest represents the request body for creating or updating a shipping zone
+type ShippingZoneRequest struct {
+	Name      string   `json:"name" binding:"required,max=255" example:"Europe"`
+	Countries []string `json:"countries" binding:"required,min=1" example:"DE,FR,NL"`
+}
+
+// ShippingMethodRequest represents the request body for creating or updating a shipping method
+type ShippingMethodRequest struct {
+	ZoneID   int               `json:"zone_id" binding:"required" example:"1"`
+	Name     string            `json:"name" binding:"required,max=255" example:"Standard"`
+	Type     shipping.RateType `json:"type" binding:"required" example:"weight"`
+	Cost     float64           `json:"cost" binding:"min=0" example:"0"`
+	Tiers    []shipping.Tier   `json:"tiers"`
+	FreeOver float64           `json:"free_over" binding:"min=0" example:"100"`
+	Active   *bool             `json:"active" example:"true"`
+}
+
+// zone validates the request and converts it into a zone
+func (r ShippingZoneRequest) zone() (*shipping.Zone, string) {
+	zone := &shipping.Zone{Name: r.Name}
+	for _, country := range r.Countries {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if !zoneCountryPattern.MatchString(country) {
+			return nil, "Invalid country " + strconv.Quote(country) + ", expected a code like DE, US-CA or *"
+		}
+		zone.Countries = append(zone.Countries, country)
+	}
+	return zone, ""
+}
+
+// method validates the request and converts it into a method
+func (r ShippingMethodRequest) method() (*shipping.Method, string) {
+	if !r.Type.Valid() {
+		return nil, "Invalid type, expected flat, weight or price"
+	}
+	if r.Type != shipping.Flat && len(r.Tiers) == 0 {
+		return nil, "Weight and price methods need at least one tier"
+	}
+	for _, tier := range r.Tiers {
+		if tier.UpTo < 0 || tier.Cost < 0 {
+			return nil, "Tier limits and costs cannot be negative"
+		}
+	}
+
+	method := &shipping.Method{ZoneID: r.ZoneID, Name: r.Name, Type: r.Type, Cost: r.Cost, Tiers: r.Tiers, FreeOver: r.FreeOver, Active: true}
+	if method.Tiers == nil {
+		method.Tiers = []shipping.Tier{}
+	}
+	if r.Active != nil {
+		method.Active = *r.Active
+	}
+	return method, ""
+}
+
+// @Summary Quote shipping for the cart
+// @Description List the shipping methods available for the cart shipped to the given country and region, or the signed-in user's default shipping address, or the store's default, cheapest first. Costs use the cart's billable weight (actual or volumetric, whichever is higher) and its discounted subtotal; a free shipping promotion makes every option free. Pass the chosen method_id to checkout.
+// @Tags shipping
+// @Accept json
+// @Produce json
+// @Param X-Cart-Token header string false "Guest cart token"
+// @Param address body ShippingQuoteRequest false "Destination"
+// @Success 200 {object} ShippingQuoteResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /shipping/quote [post]
+func (h *ShippingHandler) Quote(c *gin.Context) {
+	var req ShippingQuoteRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	addr := h.DefaultAddress
+	if req.Country != "" {
+		addr = tax.Address{Country: req.Country, Region: req.Region}.Normalize()
+	} else if userID := c.GetInt("userID"); userID != 0 {
+		shippingAddress, _, err := h.AddressModel.GetDefaults(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if shippingAddress != nil {
+			addr = tax.Address{Country: shippingAddress.Country, Region: shippingAddress.Region}
+		}
+	}
+
+	cart, err := findCart(c, h.CartModel, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if cart != nil {
+		cart, err = h.CartModel.Get(cart.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if cart == nil || len(cart.Items) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cart is empty"})
+		return
+	}
+
+	options, err := h.ShippingModel.Quote(cart, addr.Country, addr.Region)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ShippingQuoteResponse{
+		Country: addr.Country,
+		Region:  addr.Region,
+		Weight:  cart.Parcel().Weight,
+		Options: options,
+	})
+}
+
+// @Summary Get all shipping zones
+// @Description Get the shipping zones. An address matches the zone naming its region, then the one naming its country, then the "*" zone.
+// @Tags shipping
+// @Accept json
+// @Produce json
+// @Success 200 {array} shipping.Zone
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /shipping/zones [get]
+func (h *ShippingHandler) GetZones(c *gin.Context) {
+	zones, err := h.ShippingModel.GetZones()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, zones)
+}
+
+// @Summary Create a shipping zone
+// @Description Create a shipping zone covering countries (DE), regions (US-CA) or everywhere else (*)
+// @Tags shipping
+// @Accept json
+// @Produce json
+// @Param zone body ShippingZoneRequest true "Zone details"
+// @Success 201 {object} shipping.Zone
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /shipping/zones [post]
+func (h *ShippingHandler) CreateZone(c *gin.Context) {
+	var req ShippingZoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	zone, msg := req.zone()
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := h.ShippingModel.CreateZone(zone); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, zone)
+}
+
+// @Summary Update a shipping zone
+// @Description Replace a shipping zone's name and countries
+// @Tags shipping
+// @Accept json
+// @Produce json
+// @Param id path int true "Zone ID"
+// @Param zone body ShippingZoneRequest true "Zone details"
+// @Success 200 {object} shipping.Zone
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /shipping/zones/{id} [put]
+func (h *ShippingHandler) UpdateZone(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid zone ID"})
+		return
+	}
+
+	var req ShippingZoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	zone, msg := req.zone()
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	zone.ID = id
+
+	if err := h.ShippingModel.UpdateZone(zone); err != nil {
+		if err.Error() == "shipping zone not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Shipping zone not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, zone)
+}
+
+// @Summary Delete a shipping zone
+// @Description Delete a shipping zone and its methods. Orders keep the shipping they were charged.
+// @Tags shipping
+// @Accept json
+// @Produce json
+// @Param id path int true "Zone ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /shipping/zones/{id} [delete]
+func (h *ShippingHandler) DeleteZone(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid zone ID"})
+		return
+	}
+
+	if err := h.ShippingModel.DeleteZone(id); err != nil {
+		if err.Error() == "shipping zone not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Shipping zone not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Get all shipping methods
+// @Description Get the shipping methods of every zone, including inactive ones
+// @Tags shipping
+// @Accept json
+// @Produce json
+// @Success 200 {array} shipping.Method
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /shipping/methods [get]
+func (h *ShippingHandler) GetMethods(c *gin.Context) {
+	methods, err := h.ShippingModel.GetMethods()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, methods)
+}
+
+// @Summary Create a shipping method
+// @Description Create a shipping method for a zone. Flat methods charge cost; weight and price methods look the billable weight (kg) or the discounted subtotal up in their tiers, where up_to 0 means no upper limit. free_over makes the method free from that subtotal on.
+// @Tags shipping
+// @Accept json
+// @Produce json
+// @Param method body ShippingMethodRequest true "Method details"
+// @Success 201 {object} shipping.Method
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /shipping/methods [post]
+func (h *ShippingHandler) CreateMethod(c *gin.Context) {
+	var req ShippingMethodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	method, msg := req.method()
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := h.ShippingModel.CreateMethod(method); err != nil {
+		if err.Error() == "shipping zone not found" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown shipping zone"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, method)
+}
+
+// @Summary Update a shipping method
+// @Description Replace a shipping method's zone, rates and status
+// @Tags shipping
+// @Accept json
+// @Produce json
+// @Param id path int true "Method ID"
+// @Param method body ShippingMethodRequest true "Method details"
+// @Success 200 {object} shipping.Method
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /shipping/methods/{id} [put]
+func (h *ShippingHandler) UpdateMethod(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid method ID"})
+		return
+	}
+
+	var req ShippingMethodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	method, msg := req.method()
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	method.ID = id
+
+	if err := h.ShippingModel.UpdateMethod(method); err != nil {
+		switch err.Error() {
+		case "shipping zone not found":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown shipping zone"})
+		case "shipping method not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Shipping method not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, method)
+}
+
+// @Summary Delete a shipping method
+// @Description Delete a shipping method. Orders keep its name and the cost they were charged.
+// @Tags shipping
+// @Accept json
+// @Produce json
+// @Param id path int true "Method ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /shipping/methods/{id} [delete]
+func (h *ShippingHandler) DeleteMethod(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid method ID"})
+		return
+	}
+
+	if err := h.ShippingModel.DeleteMethod(id); err != nil {
+		if err.Error() == "shipping method not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Shipping method not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
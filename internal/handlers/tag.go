@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"garage-api/internal/models"
+)
+
+type TagHandler struct {
+	TagModel     models.TagModelInterface
+	ProductModel models.ProductModelInterface
+}
+
+// TagRequest represents the request body for creating a tag
+type TagRequest struct {
+	Name string `json:"name" binding:"required,max=64" example:"wireless"`
+}
+
+// ProductTagsRequest represents the request body for assigning tags to a product
+type ProductTagsRequest struct {
+	Tags []string `json:"tags" binding:"required,max=50,dive,max=64" example:"rgb,wireless"`
+}
+
+// @Summary Get all tags
+// @Description Get a list of all tags with the number of products carrying each
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.Tag
+// @Failure 500 {object} map[string]string
+// @Router /tags [get]
+func (h *TagHandler) GetAllTags(c *gin.Context) {
+	tags, err := h.TagModel.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+// @Summary Create a tag
+// @Description Create a new tag. Names are stored lowercase.
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param tag body TagRequest true "Tag details"
+// @Success 201 {object} models.Tag
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /tags [post]
+func (h *TagHandler) CreateTag(c *gin.Context) {
+	var req TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if models.NormalizeTagName(req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tag name is required"})
+		return
+	}
+
+	tag := &models.Tag{Name: req.Name}
+	if err := h.TagModel.Create(tag); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, tag)
+}
+
+// @Summary Delete a tag
+// @Description Delete a tag and remove it from all products
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param id path int true "Tag ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /tags/{id} [delete]
+func (h *TagHandler) DeleteTag(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag ID"})
+		return
+	}
+
+	if err := h.TagModel.Delete(id); err != nil {
+		if err.Error() == "tag not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Set the tags of a product
+// @Description Replace the tags assigned to a product. Tags that do not exist yet are created.
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param tags body ProductTagsRequest true "Tag names"
+// @Success 200 {object} models.Product
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /products/{id}/tags [put]
+func (h *TagHandler) SetProductTags(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req ProductTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, err := h.ProductModel.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	tags, err := h.TagModel.SetProductTags(product.ID, req.Tags)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	product.Tags = tags
+	c.JSON(http.StatusOK, product)
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"garage-api/internal/models"
+	"garage-api/internal/tax"
+)
+
+type TaxHandler struct {
+	TaxModel models.TaxModelInterface
+}
+
+// TaxClassRequest represents the request body for creating a tax class
+type TaxClassRequest struct {
+	Code string `json:"code" binding:"required,max=32" example:"books"`
+	Name string `json:"name" binding:"required,max=255" example:"Books"`
+}
+
+// TaxRateRequest represents the request body for creating a tax rate. Dates
+// are calendar days (YYYY-MM-DD); effective_to is exclusive.
+type TaxRateRequest struct {
+	Country       string  `json:"country" binding:"required,len=2" example:"DE"`
+	Region        string  `json:"region" binding:"max=10" example:""`
+	TaxClass      string  `json:"tax_class" binding:"required,max=32" example:"standard"`
+	Name          string  `json:"name" binding:"required,max=64" example:"VAT"`
+	Rate          float64 `json:"rate" binding:"min=0,max=100" example:"19"`
+	EffectiveFrom string  `json:"effective_from" binding:"required" example:"2024-01-01"`
+	EffectiveTo   string  `json:"effective_to" example:""`
+}
+
+// @Summary Get all tax classes
+// @Description Get the tax classes products can be assigned to
+// @Tags tax
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.TaxClass
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /tax/classes [get]
+func (h *TaxHandler) GetTaxClasses(c *gin.Context) {
+	classes, err := h.TaxModel.GetClasses()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, classes)
+}
+
+// @Summary Create a tax class
+// @Description Create a tax class. Codes are stored lowercase.
+// @Tags tax
+// @Accept json
+// @Produce json
+// @Param class body TaxClassRequest true "Tax class details"
+// @Success 201 {object} models.TaxClass
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /tax/classes [post]
+func (h *TaxHandler) CreateTaxClass(c *gin.Context) {
+	var req TaxClassRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	class := &models.TaxClass{Code: req.Code, Name: req.Name}
+	if err := h.TaxModel.CreateClass(class); err != nil {
+		if err.Error() == "tax class already exists" {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, class)
+}
+
+// @Summary Get tax rates
+// @Description Get all tax rates, including past and scheduled ones
+// @Tags tax
+// @Accept json
+// @Produce json
+// @Param country query string false "Only rates of this ISO country code"
+// @Success 200 {array} tax.Rate
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /tax/rates [get]
+func (h *TaxHandler) GetTaxRates(c *gin.Context) {
+	rates, err := h.TaxModel.GetRates(c.Query("country"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// @Summary Create a tax rate
+// @Description Add a tax rate for a tax class in a country, or a region of it. To change a rate, end the current one with effective_to and add the new one from that day.
+// @Tags tax
+// @Accept json
+// @Produce json
+// @Param rate body TaxRateRequest true "Tax rate details"
+// @Success 201 {object} tax.Rate
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /tax/rates [post]
+func (h *TaxHandler) CreateTaxRate(c *gin.Context) {
+	var req TaxRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	from, err := time.Parse("2006-01-02", req.EffectiveFrom)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid effective_from, expected YYYY-MM-DD"})
+		return
+	}
+	rate := &tax.Rate{Country: req.Country, Region: req.Region, Class: req.TaxClass, Name: req.Name, Rate: req.Rate, EffectiveFrom: from}
+	if req.EffectiveTo != "" {
+		to, err := time.Parse("2006-01-02", req.EffectiveTo)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid effective_to, expected YYYY-MM-DD"})
+			return
+		}
+		if !to.After(from) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "effective_to must be after effective_from"})
+			return
+		}
+		rate.EffectiveTo = &to
+	}
+
+	if err := h.TaxModel.CreateRate(rate); err != nil {
+		if err.Error() == "tax class not found" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown tax class"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rate)
+}
+
+// @Summary Delete a tax rate
+// @Description Delete a tax rate. Orders keep the tax they were charged.
+// @Tags tax
+// @Accept json
+// @Produce json
+// @Param id path int true "Tax rate ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /tax/rates/{id} [delete]
+func (h *TaxHandler) DeleteTaxRate(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tax rate ID"})
+		return
+	}
+
+	if err := h.TaxModel.DeleteRate(id); err != nil {
+		if err.Error() == "tax rate not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Tax rate not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"garage-api/internal/i18n"
+	"garage-api/internal/models"
+)
+
+type TranslationHandler struct {
+	TranslationModel models.TranslationModelInterface
+	ProductModel     models.ProductModelInterface
+	Locales          i18n.Locales
+}
+
+// TranslationRequest represents the request body for translating a product
+type TranslationRequest struct {
+	Name        string `json:"name" binding:"required,max=255" example:"Martelo"`
+	Description string `json:"description" example:"Um martelo robusto para construção"`
+	HTMLContent string `json:"html_content" example:"<p>Detalhes do produto em HTML</p>"`
+}
+
+// MissingTranslationsResponse lists the products not fully translated into a locale
+type MissingTranslationsResponse struct {
+	Locale   string                      `json:"locale" example:"pt"`
+	Count    int                         `json:"count" example:"1"`
+	Products []models.MissingTranslation `json:"products"`
+}
+
+// translationLocale reads a locale that translations can be written in: a
+// supported locale other than the default, which lives on the product itself
+func (h *TranslationHandler) translationLocale(c *gin.Context, value string) (string, bool) {
+	locale := i18n.Normalize(value)
+	if locale == "" || !h.Locales.Supports(locale) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported locale", "supported": h.Locales.Supported})
+		return "", false
+	}
+	if locale == h.Locales.Default {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Content in the default locale is edited on the product itself"})
+		return "", false
+	}
+	return locale, true
+}
+
+// @Summary List a product's translations
+// @Description List a product's content in the locales other than the default one
+// @Tags translations
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {array} models.ProductTranslation
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /products/{id}/translations [get]
+func (h *TranslationHandler) GetProductTranslations(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	if _, err := h.ProductModel.Get(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	translations, err := h.TranslationModel.List(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, translations)
+}
+
+// @Summary Translate a product
+// @Description Create or replace a product's name and descriptions in a supported locale. Descriptions left empty fall back to the default locale.
+// @Tags translations
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param locale path string true "Locale, e.g. pt or pt-BR"
+// @Param translation body TranslationRequest true "Translated content"
+// @Success 200 {object} models.ProductTranslation
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /products/{id}/translations/{locale} [put]
+func (h *TranslationHandler) SetProductTranslation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+	locale, ok := h.translationLocale(c, c.Param("locale"))
+	if !ok {
+		return
+	}
+
+	var req TranslationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	translation := &models.ProductTranslation{
+		ProductID:   id,
+		Locale:      locale,
+		Name:        req.Name,
+		Description: req.Description,
+		HTMLContent: req.HTMLContent,
+	}
+	if err := h.TranslationModel.Set(translation); err != nil {
+		if err.Error() == "product not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, translation)
+}
+
+// @Summary Delete a product's translation
+// @Description Remove a product's content in a locale; the product falls back to the next accepted locale
+// @Tags translations
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param locale path string true "Locale, e.g. pt or pt-BR"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /products/{id}/translations/{locale} [delete]
+func (h *TranslationHandler) DeleteProductTranslation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+	locale, ok := h.translationLocale(c, c.Param("locale"))
+	if !ok {
+		return
+	}
+
+	if err := h.TranslationModel.Delete(id, locale); err != nil {
+		if err.Error() == "translation not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Translation not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Report missing translations
+// @Description List the products, archived ones aside, that have no translation into a locale or leave its name or descriptions untranslated
+// @Tags translations
+// @Produce json
+// @Param locale query string true "Locale, e.g. pt"
+// @Success 200 {object} MissingTranslationsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /translations/missing [get]
+func (h *TranslationHandler) GetMissingTranslations(c *gin.Context) {
+	locale, ok := h.translationLocale(c, c.Query("locale"))
+	if !ok {
+		return
+	}
+
+	missing, err := h.TranslationModel.Missing(locale)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, MissingTranslationsResponse{Locale: locale, Count: len(missing), Products: missing})
+}
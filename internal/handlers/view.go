@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"garage-api/internal/models"
+)
+
+const (
+	defaultViewLimit = 10
+	maxViewLimit     = 50
+	// maxTrendingWindow bounds how far back trending views are counted
+	maxTrendingWindow = 90 * 24 * time.Hour
+)
+
+type ViewHandler struct {
+	ViewModel models.ViewModelInterface
+}
+
+// parseWindow reads a trending window: a Go duration such as "24h" or
+// "90m", or a number of days such as "7d"
+func parseWindow(v string) (time.Duration, bool) {
+	var window time.Duration
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, false
+		}
+		window = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if window, err = time.ParseDuration(v); err != nil {
+			return 0, false
+		}
+	}
+	return window, window > 0 && window <= maxTrendingWindow
+}
+
+// viewLimit reads the number of products to list
+func viewLimit(c *gin.Context) (int, bool) {
+	v := c.Query("limit")
+	if v == "" {
+		return defaultViewLimit, true
+	}
+	limit, err := strconv.Atoi(v)
+	return limit, err == nil && limit > 0 && limit <= maxViewLimit
+}
+
+// @Summary Get trending products
+// @Description List the published products with the most page views over a window, most viewed first
+// @Tags products
+// @Produce json
+// @Param window query string false "Window, e.g. 24h or 7d, up to 90d" default(24h)
+// @Param limit query int false "Number of products, up to 50" default(10)
+// @Success 200 {array} models.TrendingProduct
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /products/trending [get]
+func (h *ViewHandler) GetTrendingProducts(c *gin.Context) {
+	window, ok := parseWindow(c.DefaultQuery("window", "24h"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid window, expected e.g. 24h or 7d, up to 90d"})
+		return
+	}
+	limit, ok := viewLimit(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	products, err := h.ViewModel.Trending(time.Now().Add(-window), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, products)
+}
+
+// @Summary Get recently viewed products
+// @Description List the published products the current user viewed, latest first
+// @Tags products
+// @Produce json
+// @Param limit query int false "Number of products, up to 50" default(10)
+// @Success 200 {array} models.ViewedProduct
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /me/recently-viewed [get]
+func (h *ViewHandler) GetRecentlyViewed(c *gin.Context) {
+	limit, ok := viewLimit(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	products, err := h.ViewModel.RecentlyViewed(c.GetInt("userID"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, products)
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"garage-api/internal/models"
+)
+
+type WishlistHandler struct {
+	WishlistModel models.WishlistModelInterface
+}
+
+// AddWishlistItemRequest represents the request body for saving a product
+type AddWishlistItemRequest struct {
+	ProductID int `json:"product_id" binding:"required,min=1" example:"5"`
+}
+
+// StockSubscriptionRequest represents the request body for asking to be told
+// when a product is back in stock
+type StockSubscriptionRequest struct {
+	ProductID int    `json:"product_id" binding:"required,min=1" example:"5"`
+	Email     string `json:"email" binding:"required,email,max=255" example:"jane@example.com"`
+}
+
+// respondWishlistError maps wishlist model errors to responses
+func respondWishlistError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "product not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+	case "wishlist item not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product is not on your wishlist"})
+	case "subscription not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+	case "product in stock":
+		c.JSON(http.StatusConflict, gin.H{"error": "Product is in stock"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// @Summary List my wishlist
+// @Description List the products the signed-in user saved, most recently added first
+// @Tags wishlist
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.WishlistItem
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /me/wishlist [get]
+func (h *WishlistHandler) GetMyWishlist(c *gin.Context) {
+	items, err := h.WishlistModel.List(c.GetInt("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// @Summary Add to my wishlist
+// @Description Save a product to the signed-in user's wishlist. Saving a product twice keeps one entry.
+// @Tags wishlist
+// @Accept json
+// @Produce json
+// @Param item body AddWishlistItemRequest true "Product"
+// @Success 201 {object} models.WishlistItem
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /me/wishlist [post]
+func (h *WishlistHandler) AddToMyWishlist(c *gin.Context) {
+	var req AddWishlistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := h.WishlistModel.Add(c.GetInt("userID"), req.ProductID)
+	if err != nil {
+		respondWishlistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, item)
+}
+
+// @Summary Remove from my wishlist
+// @Description Remove a product from the signed-in user's wishlist
+// @Tags wishlist
+// @Accept json
+// @Produce json
+// @Param product_id path int true "Product ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /me/wishlist/{product_id} [delete]
+func (h *WishlistHandler) RemoveFromMyWishlist(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("product_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	if err := h.WishlistModel.Remove(c.GetInt("userID"), productID); err != nil {
+		respondWishlistError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary List my stock subscriptions
+// @Description List the out-of-stock products the signed-in user asked to be told about
+// @Tags wishlist
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.StockSubscription
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /me/stock-subscriptions [get]
+func (h *WishlistHandler) GetMyStockSubscriptions(c *gin.Context) {
+	subs, err := h.WishlistModel.ListSubscriptions(c.GetInt("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, subs)
+}
+
+// @Summary Subscribe to back-in-stock news
+// @Description Ask to be emailed once when an out-of-stock product is back. Subscribing again changes the email address. The subscription ends when the email is queued.
+// @Tags wishlist
+// @Accept json
+// @Produce json
+// @Param subscription body StockSubscriptionRequest true "Subscription"
+// @Success 201 {object} models.StockSubscription
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /me/stock-subscriptions [post]
+func (h *WishlistHandler) SubscribeToStock(c *gin.Context) {
+	var req StockSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub, err := h.WishlistModel.Subscribe(c.GetInt("userID"), req.ProductID, strings.TrimSpace(req.Email))
+	if err != nil {
+		respondWishlistError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, sub)
+}
+
+// @Summary Unsubscribe from back-in-stock news
+// @Description Cancel the signed-in user's back-in-stock subscription for a product
+// @Tags wishlist
+// @Accept json
+// @Produce json
+// @Param product_id path int true "Product ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security Bearer
+// @Router /me/stock-subscriptions/{product_id} [delete]
+func (h *WishlistHandler) UnsubscribeFromStock(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("product_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	if err := h.WishlistModel.Unsubscribe(c.GetInt("userID"), productID); err != nil {
+		respondWishlistError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,147 @@
+// Package i18n negotiates the locale product content is served in
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Normalize returns a BCP 47 language tag in its usual casing, e.g. "pt-BR"
+// for "pt_br", or "" when tag is not a well-formed language tag
+func Normalize(tag string) string {
+	parts := strings.Split(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"), "-")
+	if !isLetters(parts[0], 2, 3) {
+		return ""
+	}
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		p := parts[i]
+		switch {
+		case isLetters(p, 4, 4):
+			parts[i] = strings.ToUpper(p[:1]) + strings.ToLower(p[1:])
+		case isLetters(p, 2, 2):
+			parts[i] = strings.ToUpper(p)
+		case len(p) >= 1 && len(p) <= 8 && isAlnum(p):
+			parts[i] = strings.ToLower(p)
+		default:
+			return ""
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+func isLetters(s string, min, max int) bool {
+	if len(s) < min || len(s) > max {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlnum(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Locales are the locales the store's content is written in. Products'
+// own fields are in Default; the other locales come from translations.
+type Locales struct {
+	Default   string
+	Supported []string
+}
+
+// ParseLocales validates the default locale and a comma-separated list of
+// supported ones. The default locale is always supported.
+func ParseLocales(defaultLocale, supported string) (Locales, error) {
+	l := Locales{Default: Normalize(defaultLocale)}
+	if l.Default == "" {
+		return l, fmt.Errorf("invalid locale %q", defaultLocale)
+	}
+	l.Supported = []string{l.Default}
+	for _, tag := range strings.Split(supported, ",") {
+		if strings.TrimSpace(tag) == "" {
+			continue
+		}
+		locale := Normalize(tag)
+		if locale == "" {
+			return l, fmt.Errorf("invalid locale %q", tag)
+		}
+		if !l.Supports(locale) {
+			l.Supported = append(l.Supported, locale)
+		}
+	}
+	return l, nil
+}
+
+// Supports reports whether locale is one of the supported locales
+func (l Locales) Supports(locale string) bool {
+	for _, s := range l.Supported {
+		if strings.EqualFold(s, locale) {
+			return true
+		}
+	}
+	return false
+}
+
+// Negotiate returns the supported locales to try for an Accept-Language
+// header, most preferred first. Each language range falls back to its less
+// specific forms, so "pt-BR" also tries "pt". The chain always ends with the
+// default locale, which needs no translation.
+func (l Locales) Negotiate(acceptLanguage string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var ranges []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(part, ";")
+		w := weighted{tag: Normalize(fields[0]), q: 1}
+		for _, param := range fields[1:] {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				q, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					q = 0
+				}
+				w.q = q
+			}
+		}
+		if w.tag != "" && w.q > 0 {
+			ranges = append(ranges, w)
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	var chain []string
+	seen := make(map[string]bool)
+	for _, r := range ranges {
+		for tag := r.tag; tag != ""; tag = parent(tag) {
+			if seen[tag] || !l.Supports(tag) {
+				continue
+			}
+			if tag == l.Default {
+				return append(chain, tag)
+			}
+			seen[tag] = true
+			chain = append(chain, tag)
+		}
+	}
+	return append(chain, l.Default)
+}
+
+// parent strips the last subtag of a language tag, e.g. "pt" for "pt-BR"
+func parent(tag string) string {
+	if i := strings.LastIndex(tag, "-"); i > 0 {
+		return tag[:i]
+	}
+	return ""
+}
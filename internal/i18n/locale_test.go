@@ -0,0 +1,58 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		tag, want string
+	}{
+		{"en", "en"},
+		{"PT_br", "pt-BR"},
+		{" zh-hant-tw ", "zh-Hant-TW"},
+		{"es-419", "es-419"},
+		{"*", ""},
+		{"e", ""},
+		{"en-", ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Normalize(tt.tag), tt.tag)
+	}
+}
+
+func TestParseLocales(t *testing.T) {
+	// Test case 1: The default locale is always supported
+	locales, err := ParseLocales("EN", "pt, pt_BR,en")
+	assert.NoError(t, err)
+	assert.Equal(t, "en", locales.Default)
+	assert.Equal(t, []string{"en", "pt", "pt-BR"}, locales.Supported)
+
+	// Test case 2: Malformed locales are rejected
+	_, err = ParseLocales("en", "pt,português")
+	assert.EqualError(t, err, `invalid locale "português"`)
+}
+
+func TestLocales_Negotiate(t *testing.T) {
+	locales := Locales{Default: "en", Supported: []string{"en", "pt", "pt-BR", "es"}}
+
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"", []string{"en"}},
+		{"pt-BR,pt;q=0.9,en;q=0.8", []string{"pt-BR", "pt", "en"}},
+		{"pt-PT", []string{"pt", "en"}},
+		{"es;q=0.5, pt-BR", []string{"pt-BR", "pt", "es", "en"}},
+		{"en-GB,pt;q=0.7", []string{"en"}},
+		{"fr, *;q=0.1", []string{"en"}},
+		{"pt;q=0, es", []string{"es", "en"}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, locales.Negotiate(tt.header), tt.header)
+	}
+}
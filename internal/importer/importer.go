@@ -0,0 +1,407 @@
+// Package importer loads product catalogs from CSV and XLSX spreadsheets.
+package importer
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"garage-api/internal/models"
+	"garage-api/internal/spreadsheet"
+)
+
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionSkip   = "skip"
+	ActionError  = "error"
+)
+
+// Product fields a spreadsheet column can be mapped to
+const (
+	FieldName        = "name"
+	FieldDescription = "description"
+	FieldPrice       = "price"
+	FieldSKU         = "sku"
+	FieldImagePath   = "image_path"
+	FieldHTMLContent = "html_content"
+)
+
+// headerAliases maps normalized header names to product fields
+var headerAliases = map[string]string{
+	"name":         FieldName,
+	"title":        FieldName,
+	"product":      FieldName,
+	"product name": FieldName,
+	"description":  FieldDescription,
+	"price":        FieldPrice,
+	"unit price":   FieldPrice,
+	"sku":          FieldSKU,
+	"code":         FieldSKU,
+	"product code": FieldSKU,
+	"image":        FieldImagePath,
+	"image path":   FieldImagePath,
+	"image_path":   FieldImagePath,
+	"html":         FieldHTMLContent,
+	"html content": FieldHTMLContent,
+	"html_content": FieldHTMLContent,
+}
+
+// Record is a spreadsheet row together with its 1-based line number
+type Record struct {
+	Line   int
+	Values []string
+}
+
+// Options control how an import is run
+type Options struct {
+	DryRun bool
+	// Mapping maps spreadsheet headers to product fields and takes
+	// precedence over the built-in header aliases
+	Mapping map[string]string
+}
+
+// RowError describes why a row could not be imported
+type RowError struct {
+	Line    int    `json:"line" example:"3"`
+	Field   string `json:"field,omitempty" example:"price"`
+	Message string `json:"message" example:"price must be a positive number"`
+}
+
+// RowOutcome describes what happened (or, in a dry run, would happen) to a row
+type RowOutcome struct {
+	Line      int    `json:"line" example:"2"`
+	Action    string `json:"action" example:"create"`
+	ProductID int    `json:"product_id,omitempty" example:"1"`
+	SKU       string `json:"sku,omitempty" example:"HAM-001"`
+	Name      string `json:"name,omitempty" example:"Hammer"`
+}
+
+// Result summarizes an import
+type Result struct {
+	DryRun  bool         `json:"dry_run" example:"false"`
+	Total   int          `json:"total" example:"10"`
+	Created int          `json:"created" example:"6"`
+	Updated int          `json:"updated" example:"2"`
+	Skipped int          `json:"skipped" example:"1"`
+	Failed  int          `json:"failed" example:"1"`
+	Rows    []RowOutcome `json:"rows"`
+	Errors  []RowError   `json:"errors"`
+}
+
+// Importer upserts spreadsheet rows into the product catalog
+type Importer struct {
+	Products models.TxProductModelInterface
+	Jobs     *JobStore
+	// Background is the context background imports run in; when it is
+	// cancelled they stop and write nothing. Defaults to context.Background().
+	Background context.Context
+	// Workers, when set, tracks background imports so the caller can wait
+	// for them on shutdown
+	Workers *sync.WaitGroup
+}
+
+// ReadRecords reads all rows of a CSV or XLSX file, chosen by the file extension
+func ReadRecords(filename string, r io.ReaderAt, size int64) ([]Record, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return readCSV(io.NewSectionReader(r, 0, size))
+	case ".xlsx":
+		rows, err := spreadsheet.ReadXLSX(r, size)
+		if err != nil {
+			return nil, err
+		}
+		records := make([]Record, 0, len(rows))
+		for i, values := range rows {
+			records = append(records, Record{Line: i + 1, Values: values})
+		}
+		return records, nil
+	}
+	return nil, errors.New("unsupported file type, expected .csv or .xlsx")
+}
+
+func readCSV(r io.Reader) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var records []Record
+	for {
+		values, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv file: %v", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(records) == 0 && len(values) > 0 {
+			values[0] = strings.TrimPrefix(values[0], "\ufeff")
+		}
+		records = append(records, Record{Line: line, Values: values})
+	}
+	return records, nil
+}
+
+// mapHeader returns the column index of each product field found in header
+func mapHeader(header []string, overrides map[string]string) (map[string]int, error) {
+	normalized := make(map[string]string, len(overrides))
+	for k, v := range overrides {
+		normalized[normalizeHeader(k)] = v
+	}
+
+	columns := make(map[string]int)
+	for i, h := range header {
+		key := normalizeHeader(h)
+		field, ok := normalized[key]
+		if !ok {
+			field, ok = headerAliases[key]
+		}
+		if !ok {
+			continue
+		}
+		if !isField(field) {
+			return nil, fmt.Errorf("column %q is mapped to unknown field %q", h, field)
+		}
+		if _, dup := columns[field]; dup {
+			return nil, fmt.Errorf("more than one column is mapped to %q", field)
+		}
+		columns[field] = i
+	}
+
+	if _, ok := columns[FieldName]; !ok {
+		if _, ok := columns[FieldSKU]; !ok {
+			return nil, errors.New("header must contain a name or sku column")
+		}
+	}
+	return columns, nil
+}
+
+func normalizeHeader(h string) string {
+	return strings.ToLower(strings.Join(strings.Fields(h), " "))
+}
+
+func isField(field string) bool {
+	switch field {
+	case FieldName, FieldDescription, FieldPrice, FieldSKU, FieldImagePath, FieldHTMLContent:
+		return true
+	}
+	return false
+}
+
+// row holds the values of one spreadsheet row, keyed by product field.
+// Fields whose column is missing or whose cell is blank are absent.
+type row map[string]string
+
+func parseRow(columns map[string]int, values []string) row {
+	r := make(row)
+	for field, col := range columns {
+		if col < len(values) {
+			if v := strings.TrimSpace(values[col]); v != "" {
+				r[field] = v
+			}
+		}
+	}
+	return r
+}
+
+// validate checks the row and returns its price, if any
+func (r row) validate(line int) (float64, []RowError) {
+	var errs []RowError
+	var price float64
+
+	if v, ok := r[FieldPrice]; ok {
+		p, err := strconv.ParseFloat(strings.TrimPrefix(v, "$"), 64)
+		if err != nil || p <= 0 {
+			errs = append(errs, RowError{Line: line, Field: FieldPrice, Message: "price must be a positive number"})
+		} else {
+			price = p
+		}
+	}
+	if len(r[FieldName]) > 255 {
+		errs = append(errs, RowError{Line: line, Field: FieldName, Message: "name must be at most 255 characters"})
+	}
+	if len(r[FieldSKU]) > 64 {
+		errs = append(errs, RowError{Line: line, Field: FieldSKU, Message: "sku must be at most 64 characters"})
+	}
+	if len(r[FieldImagePath]) > 255 {
+		errs = append(errs, RowError{Line: line, Field: FieldImagePath, Message: "image path must be at most 255 characters"})
+	}
+	return price, errs
+}
+
+// apply copies the row's values onto product and reports whether anything changed
+func (r row) apply(product *models.Product, price float64) bool {
+	changed := false
+	set := func(dst *string, field string) {
+		if v, ok := r[field]; ok && *dst != v {
+			*dst = v
+			changed = true
+		}
+	}
+	set(&product.Name, FieldName)
+	set(&product.Description, FieldDescription)
+	set(&product.SKU, FieldSKU)
+	set(&product.ImagePath, FieldImagePath)
+	set(&product.HTMLContent, FieldHTMLContent)
+	if price != 0 && product.Price != price {
+		product.Price = price
+		changed = true
+	}
+	return changed
+}
+
+// CheckHeader reports whether the first record is a usable header row
+func CheckHeader(records []Record, opts Options) error {
+	if len(records) == 0 {
+		return errors.New("file is empty")
+	}
+	_, err := mapHeader(records[0].Values, opts.Mapping)
+	return err
+}
+
+// Run imports records, the first of which must be the header row. Rows are
+// matched to existing products by SKU when they have one, and by name
+// otherwise. Rows failing validation are reported and skipped; all writes
+// happen in a single transaction. With opts.DryRun nothing is written, and
+// nothing is either when ctx is cancelled before the import finishes.
+func (i *Importer) Run(ctx context.Context, records []Record, opts Options) (*Result, error) {
+	if err := CheckHeader(records, opts); err != nil {
+		return nil, err
+	}
+	columns, _ := mapHeader(records[0].Values, opts.Mapping)
+
+	result := &Result{DryRun: opts.DryRun, Rows: []RowOutcome{}, Errors: []RowError{}}
+
+	var products models.ProductModelInterface = i.Products
+	var commit func() error
+	if !opts.DryRun {
+		tx, err := i.Products.Begin()
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback()
+		products = i.Products.WithTx(tx)
+		commit = tx.Commit
+	}
+
+	seen := make(map[string]int)
+	for _, rec := range records[1:] {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		r := parseRow(columns, rec.Values)
+		if len(r) == 0 {
+			continue
+		}
+		result.Total++
+
+		outcome, errs, err := importRow(products, rec.Line, r, seen, opts.DryRun)
+		if err != nil {
+			return nil, err
+		}
+		if len(errs) > 0 {
+			result.Failed++
+			result.Errors = append(result.Errors, errs...)
+		}
+		switch outcome.Action {
+		case ActionCreate:
+			result.Created++
+		case ActionUpdate:
+			result.Updated++
+		case ActionSkip:
+			result.Skipped++
+		}
+		result.Rows = append(result.Rows, outcome)
+	}
+
+	if commit != nil {
+		if err := commit(); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// importRow matches, validates and (unless dryRun) writes a single row. Row
+// level problems are returned as RowErrors; a non-nil error means the
+// database failed and the import must be aborted.
+func importRow(products models.ProductModelInterface, line int, r row, seen map[string]int, dryRun bool) (RowOutcome, []RowError, error) {
+	outcome := RowOutcome{Line: line, Action: ActionError, SKU: r[FieldSKU], Name: r[FieldName]}
+
+	price, errs := r.validate(line)
+
+	key := "name:" + strings.ToLower(r[FieldName])
+	if r[FieldSKU] != "" {
+		key = "sku:" + r[FieldSKU]
+	}
+	if first, ok := seen[key]; ok {
+		errs = append(errs, RowError{Line: line, Message: fmt.Sprintf("duplicate of line %d", first)})
+	} else {
+		seen[key] = line
+	}
+	if len(errs) > 0 {
+		return outcome, errs, nil
+	}
+
+	var existing *models.Product
+	var err error
+	if sku := r[FieldSKU]; sku != "" {
+		existing, err = products.GetBySKU(sku)
+	} else {
+		existing, err = products.GetByName(r[FieldName])
+	}
+	if err != nil && err.Error() != "product not found" {
+		return outcome, nil, err
+	}
+
+	if existing == nil {
+		if r[FieldName] == "" {
+			errs = append(errs, RowError{Line: line, Field: FieldName, Message: "name is required for new products"})
+		}
+		if price == 0 {
+			errs = append(errs, RowError{Line: line, Field: FieldPrice, Message: "price is required for new products"})
+		}
+		if len(errs) > 0 {
+			return outcome, errs, nil
+		}
+
+		product := &models.Product{}
+		r.apply(product, price)
+		if !dryRun {
+			if err := products.Create(product); err != nil {
+				return outcome, nil, err
+			}
+		}
+		outcome.Action = ActionCreate
+		outcome.ProductID = product.ID
+		return outcome, nil, nil
+	}
+
+	outcome.ProductID = existing.ID
+	outcome.Name = existing.Name
+	repriced := price != 0 && existing.Price != price
+	if !r.apply(existing, 0) && !repriced {
+		outcome.Action = ActionSkip
+		return outcome, nil, nil
+	}
+	if !dryRun {
+		if err := products.Update(existing); err != nil {
+			return outcome, nil, err
+		}
+		if repriced {
+			if err := products.SetPrice(existing.ID, price); err != nil {
+				return outcome, nil, err
+			}
+		}
+	}
+	outcome.Action = ActionUpdate
+	outcome.Name = existing.Name
+	return outcome, nil, nil
+}
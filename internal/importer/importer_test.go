@@ -0,0 +1,129 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"garage-api/internal/models"
+)
+
+var productRowColumns = []string{"id", "name", "description", "price", "image_path", "html_content", "sku", "stock", "category_id", "tags", "tax_class", "weight", "length", "width", "height", "rating_average", "rating_count", "effective_price", "sale_price", "sale_starts_at", "sale_ends_at", "status", "publish_at", "slug", "attributes"}
+
+func TestReadRecords_CSV(t *testing.T) {
+	data := "\ufeffName,Price,SKU\n\"Hammer,\nheavy\",29.99,HAM-001\nScrewdriver,19.99,\n"
+
+	records, err := ReadRecords("catalog.csv", strings.NewReader(data), int64(len(data)))
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+	assert.Equal(t, "Name", records[0].Values[0])
+	assert.Equal(t, 2, records[1].Line)
+	assert.Equal(t, 4, records[2].Line)
+}
+
+func TestReadRecords_UnsupportedType(t *testing.T) {
+	_, err := ReadRecords("catalog.txt", strings.NewReader(""), 0)
+	assert.Error(t, err)
+}
+
+func TestImporter_Run(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	imp := &Importer{Products: models.ProductModel{DB: db}, Jobs: NewJobStore()}
+
+	records := []Record{
+		{Line: 1, Values: []string{"Product Name", "Unit Cost", "SKU"}},
+		{Line: 2, Values: []string{"Hammer", "29.99", "HAM-001"}},
+		{Line: 3, Values: []string{"Screwdriver", "24.99", "SCR-001"}},
+		{Line: 4, Values: []string{"Wrench", "abc", "WRE-001"}},
+		{Line: 5, Values: []string{"Pliers", "9.99", ""}},
+		{Line: 6, Values: []string{"Hammer again", "31.00", "HAM-001"}},
+		{Line: 7, Values: []string{"", "", ""}},
+	}
+	opts := Options{Mapping: map[string]string{"unit cost": FieldPrice}}
+
+	// Test case 1: Dry run reports the plan without writing
+	t.Run("dry run", func(t *testing.T) {
+		opts := opts
+		opts.DryRun = true
+
+		mock.ExpectQuery("FROM products WHERE sku = \\$1").
+			WithArgs("HAM-001").
+			WillReturnRows(sqlmock.NewRows(productRowColumns).
+				AddRow(1, "Hammer", "A sturdy hammer", 29.99, "", "", "HAM-001", 0, nil, "{}", "standard", 0.0, 0.0, 0.0, 0.0, 0.0, 0, 29.99, nil, nil, nil, "published", nil, "hammer", "{}"))
+		mock.ExpectQuery("FROM products WHERE sku = \\$1").
+			WithArgs("SCR-001").
+			WillReturnRows(sqlmock.NewRows(productRowColumns).
+				AddRow(2, "Screwdriver", "A useful tool", 19.99, "", "", "SCR-001", 0, nil, "{}", "standard", 0.0, 0.0, 0.0, 0.0, 0.0, 0, 19.99, nil, nil, nil, "published", nil, "screwdriver", "{}"))
+		mock.ExpectQuery("FROM products WHERE LOWER\\(name\\) = LOWER\\(\\$1\\)").
+			WithArgs("Pliers").
+			WillReturnError(sql.ErrNoRows)
+
+		result, err := imp.Run(context.Background(), records, opts)
+		assert.NoError(t, err)
+		assert.True(t, result.DryRun)
+		assert.Equal(t, 5, result.Total)
+		assert.Equal(t, 1, result.Created)
+		assert.Equal(t, 1, result.Updated)
+		assert.Equal(t, 1, result.Skipped)
+		assert.Equal(t, 2, result.Failed)
+
+		assert.Equal(t, ActionSkip, result.Rows[0].Action)
+		assert.Equal(t, ActionUpdate, result.Rows[1].Action)
+		assert.Equal(t, 2, result.Rows[1].ProductID)
+		assert.Equal(t, ActionError, result.Rows[2].Action)
+		assert.Equal(t, ActionCreate, result.Rows[3].Action)
+
+		assert.Equal(t, RowError{Line: 4, Field: FieldPrice, Message: "price must be a positive number"}, result.Errors[0])
+		assert.Equal(t, RowError{Line: 6, Message: "duplicate of line 2"}, result.Errors[1])
+	})
+
+	// Test case 2: Writes happen in a single transaction
+	t.Run("import", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("FROM products WHERE sku = \\$1").
+			WithArgs("HAM-001").
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery("SELECT slug FROM products").
+			WithArgs("hammer", "hammer-%", 0).
+			WillReturnRows(sqlmock.NewRows([]string{"slug"}))
+		mock.ExpectQuery("INSERT INTO products").
+			WithArgs("Hammer", "", 29.99, "", "", "HAM-001", 0, nil, "standard", 0.0, 0.0, 0.0, 0.0, "hammer", "{}").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+		mock.ExpectCommit()
+
+		result, err := imp.Run(context.Background(), records[:2], opts)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, result.Created)
+		assert.Equal(t, 11, result.Rows[0].ProductID)
+	})
+
+	// Test case 3: A cancelled import stops and writes nothing
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		_, err := imp.Run(ctx, records[:2], opts)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	// Test case 4: Header without a name or sku column
+	t.Run("invalid header", func(t *testing.T) {
+		_, err := imp.Run(context.Background(), []Record{{Line: 1, Values: []string{"Price"}}}, Options{})
+		assert.Error(t, err)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,137 @@
+package importer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+// jobRetention is how long finished jobs are kept before being discarded
+const jobRetention = 24 * time.Hour
+
+// Job tracks an import running in the background
+type Job struct {
+	ID         string     `json:"id" example:"9f86d081884c7d65"`
+	Filename   string     `json:"filename" example:"catalog.xlsx"`
+	Status     string     `json:"status" example:"running"`
+	DryRun     bool       `json:"dry_run" example:"false"`
+	Rows       int        `json:"rows" example:"2500"`
+	Result     *Result    `json:"result,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// JobStore keeps import jobs in memory
+type JobStore struct {
+	mu   sync.RWMutex
+	jobs map[string]*Job
+}
+
+func NewJobStore() *JobStore {
+	return &JobStore{jobs: make(map[string]*Job)}
+}
+
+// Get returns a snapshot of the job with the given ID
+func (s *JobStore) Get(id string) (Job, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+func (s *JobStore) add(job *Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, j := range s.jobs {
+		if j.FinishedAt != nil && time.Since(*j.FinishedAt) > jobRetention {
+			delete(s.jobs, id)
+		}
+	}
+	s.jobs[job.ID] = job
+}
+
+func (s *JobStore) update(id string, fn func(job *Job)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+// RunAsync starts the import in the background and returns the queued job.
+// The import runs in i.Background and is tracked by i.Workers.
+func (i *Importer) RunAsync(filename string, records []Record, opts Options) (Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return Job{}, err
+	}
+
+	job := &Job{
+		ID:        id,
+		Filename:  filename,
+		Status:    JobQueued,
+		DryRun:    opts.DryRun,
+		Rows:      len(records) - 1,
+		CreatedAt: time.Now(),
+	}
+	i.Jobs.add(job)
+	snapshot := *job
+
+	ctx := i.Background
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if i.Workers != nil {
+		i.Workers.Add(1)
+	}
+	go func() {
+		if i.Workers != nil {
+			defer i.Workers.Done()
+		}
+		i.Jobs.update(id, func(job *Job) { job.Status = JobRunning })
+
+		result, err := i.Run(ctx, records, opts)
+
+		i.Jobs.update(id, func(job *Job) {
+			now := time.Now()
+			job.FinishedAt = &now
+			if err != nil {
+				job.Status = JobFailed
+				job.Error = err.Error()
+				if errors.Is(err, context.Canceled) {
+					job.Error = "import interrupted by shutdown; nothing was written"
+				}
+				return
+			}
+			job.Status = JobCompleted
+			job.Result = result
+		})
+	}()
+
+	return snapshot, nil
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,300 @@
+// Package invoice lays out invoices and credit notes as PDF. Like the
+// other engines it does no I/O of its own: callers pass in everything the
+// document shows.
+package invoice
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"garage-api/internal/pdf"
+)
+
+// Seller is the business issuing the invoice
+type Seller struct {
+	Name    string
+	Address []string
+	VATID   string
+	Email   string
+}
+
+// Line is an invoiced order line. Amount is the line total after discount.
+type Line struct {
+	Description string
+	SKU         string
+	Quantity    int
+	UnitPrice   float64
+	Discount    float64
+	TaxRate     float64
+	Tax         float64
+	Amount      float64
+}
+
+// Document is an invoice, or with CreditNote set, a credit note reversing
+// the invoice numbered Credits. Amounts are given as on the invoice; a
+// credit note prints them negated.
+type Document struct {
+	CreditNote bool
+	Number     string
+	Credits    string
+	IssuedAt   time.Time
+	OrderID    int
+	Currency   string
+	Seller     Seller
+	BillTo     []string
+	ShipTo     []string
+	Lines      []Line
+	Subtotal   float64
+	Discount   float64
+	Shipping   float64
+	Tax        float64
+	Total      float64
+	// PricesIncludeTax means Tax is contained in the amounts rather than
+	// added to them
+	PricesIncludeTax bool
+}
+
+// TaxLine totals the tax charged at one rate
+type TaxLine struct {
+	Rate float64
+	Tax  float64
+}
+
+// Taxes groups the tax of the document's lines by rate, highest first.
+// Untaxed lines are left out.
+func (d Document) Taxes() []TaxLine {
+	byRate := map[float64]float64{}
+	for _, line := range d.Lines {
+		if line.Tax != 0 {
+			byRate[line.TaxRate] += line.Tax
+		}
+	}
+
+	taxes := make([]TaxLine, 0, len(byRate))
+	for rate, tax := range byRate {
+		taxes = append(taxes, TaxLine{Rate: rate, Tax: math.Round(tax*100) / 100})
+	}
+	sort.Slice(taxes, func(i, j int) bool { return taxes[i].Rate > taxes[j].Rate })
+	return taxes
+}
+
+// Page layout, in points
+const (
+	left       = 50.0
+	right      = pdf.A4Width - 50
+	top        = pdf.A4Height - 50
+	bottom     = 70.0
+	lineHeight = 14.0
+
+	colQuantity = 330.0
+	colPrice    = 395.0
+	colDiscount = 455.0
+	colTax      = 495.0
+)
+
+type renderer struct {
+	doc  *pdf.Document
+	d    Document
+	y    float64
+	page int
+}
+
+// Render writes the document as a PDF to w
+func Render(w io.Writer, d Document) error {
+	title := "Invoice"
+	if d.CreditNote {
+		title = "Credit note"
+	}
+
+	r := &renderer{doc: &pdf.Document{Title: title + " " + d.Number}, d: d}
+	r.newPage()
+	r.header(title)
+	r.addresses()
+	r.tableHeader()
+	for _, line := range d.Lines {
+		r.line(line)
+	}
+	r.totals()
+
+	_, err := r.doc.WriteTo(w)
+	return err
+}
+
+// amount formats an amount, negated on credit notes
+func (r *renderer) amount(v float64) string {
+	if r.d.CreditNote {
+		v = -v
+	}
+	return Money(v)
+}
+
+// Money formats an amount with two decimals and thousands separators
+func Money(v float64) string {
+	s := strconv.FormatFloat(math.Abs(v), 'f', 2, 64)
+	whole, cents := s[:len(s)-3], s[len(s)-3:]
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+	if math.Round(v*100) < 0 {
+		return "-" + whole + cents
+	}
+	return whole + cents
+}
+
+// rate formats a tax rate in percent
+func rate(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64) + "%"
+}
+
+func (r *renderer) newPage() {
+	r.doc.AddPage()
+	r.page++
+	r.y = top
+	if r.page > 1 {
+		r.doc.Text(left, r.y, pdf.Helvetica, 9, r.d.Seller.Name+" - "+r.d.Number)
+		r.doc.TextRight(right, r.y, pdf.Helvetica, 9, fmt.Sprintf("Page %d", r.page))
+		r.y -= 2 * lineHeight
+	}
+}
+
+// next moves down a line, starting a new page when this one is full
+func (r *renderer) next(repeatHeader bool) {
+	r.y -= lineHeight
+	if r.y < bottom {
+		r.newPage()
+		if repeatHeader {
+			r.tableHeader()
+		}
+	}
+}
+
+func (r *renderer) header(title string) {
+	seller := r.d.Seller
+	r.doc.Text(left, r.y, pdf.HelveticaBold, 16, seller.Name)
+	r.doc.TextRight(right, r.y, pdf.HelveticaBold, 18, title)
+
+	details := []string{"No. " + r.d.Number, "Date: " + r.d.IssuedAt.Format("2006-01-02"), fmt.Sprintf("Order: #%d", r.d.OrderID)}
+	if r.d.CreditNote && r.d.Credits != "" {
+		details = append(details, "Credits invoice: "+r.d.Credits)
+	}
+
+	sellerLines := append([]string{}, seller.Address...)
+	if seller.VATID != "" {
+		sellerLines = append(sellerLines, "VAT ID: "+seller.VATID)
+	}
+	if seller.Email != "" {
+		sellerLines = append(sellerLines, seller.Email)
+	}
+
+	y := r.y - 1.5*lineHeight
+	for i := 0; i < len(sellerLines) || i < len(details); i++ {
+		if i < len(sellerLines) {
+			r.doc.Text(left, y, pdf.Helvetica, 9, sellerLines[i])
+		}
+		if i < len(details) {
+			r.doc.TextRight(right, y, pdf.Helvetica, 10, details[i])
+		}
+		y -= lineHeight - 2
+	}
+	r.y = y - lineHeight
+}
+
+func (r *renderer) addresses() {
+	if len(r.d.BillTo) == 0 && len(r.d.ShipTo) == 0 {
+		return
+	}
+
+	r.doc.Text(left, r.y, pdf.HelveticaBold, 10, "Bill to")
+	if len(r.d.ShipTo) > 0 {
+		r.doc.Text(300, r.y, pdf.HelveticaBold, 10, "Ship to")
+	}
+	for i := 0; i < len(r.d.BillTo) || i < len(r.d.ShipTo); i++ {
+		r.y -= lineHeight - 2
+		if i < len(r.d.BillTo) {
+			r.doc.Text(left, r.y, pdf.Helvetica, 10, r.d.BillTo[i])
+		}
+		if i < len(r.d.ShipTo) {
+			r.doc.Text(300, r.y, pdf.Helvetica, 10, r.d.ShipTo[i])
+		}
+	}
+	r.y -= 2 * lineHeight
+}
+
+func (r *renderer) tableHeader() {
+	r.doc.Text(left, r.y, pdf.HelveticaBold, 9, "Description")
+	r.doc.TextRight(colQuantity, r.y, pdf.HelveticaBold, 9, "Qty")
+	r.doc.TextRight(colPrice, r.y, pdf.HelveticaBold, 9, "Unit price")
+	r.doc.TextRight(colDiscount, r.y, pdf.HelveticaBold, 9, "Discount")
+	r.doc.TextRight(colTax, r.y, pdf.HelveticaBold, 9, "Tax")
+	r.doc.TextRight(right, r.y, pdf.HelveticaBold, 9, "Amount")
+	r.doc.Line(left, r.y-4, right, r.y-4)
+	r.y -= 4
+	r.next(false)
+}
+
+func (r *renderer) line(line Line) {
+	description := line.Description
+	if line.SKU != "" {
+		description += " (" + line.SKU + ")"
+	}
+	r.doc.Text(left, r.y, pdf.Helvetica, 9, fit(description, colQuantity-left-40, 9))
+	r.doc.TextRight(colQuantity, r.y, pdf.Helvetica, 9, strconv.Itoa(line.Quantity))
+	r.doc.TextRight(colPrice, r.y, pdf.Helvetica, 9, Money(line.UnitPrice))
+	if line.Discount != 0 {
+		r.doc.TextRight(colDiscount, r.y, pdf.Helvetica, 9, Money(-line.Discount))
+	}
+	r.doc.TextRight(colTax, r.y, pdf.Helvetica, 9, rate(line.TaxRate))
+	r.doc.TextRight(right, r.y, pdf.Helvetica, 9, r.amount(line.Amount))
+	r.next(true)
+}
+
+func (r *renderer) totals() {
+	r.doc.Line(colPrice-60, r.y+lineHeight-4, right, r.y+lineHeight-4)
+	row := func(font pdf.Font, label string, v float64) {
+		r.doc.TextRight(colTax, r.y, font, 10, label)
+		r.doc.TextRight(right, r.y, font, 10, r.amount(v))
+		r.next(false)
+	}
+
+	row(pdf.Helvetica, "Subtotal", r.d.Subtotal)
+	if r.d.Discount != 0 {
+		row(pdf.Helvetica, "Discount", -r.d.Discount)
+	}
+	if r.d.Shipping != 0 {
+		row(pdf.Helvetica, "Shipping", r.d.Shipping)
+	}
+	label := "Tax"
+	if r.d.PricesIncludeTax {
+		label = "Included tax"
+	}
+	for _, tax := range r.d.Taxes() {
+		row(pdf.Helvetica, label+" "+rate(tax.Rate), tax.Tax)
+	}
+	row(pdf.HelveticaBold, "Total "+r.d.Currency, r.d.Total)
+
+	if r.d.CreditNote {
+		r.next(false)
+		note := "This credit note refunds the amounts above."
+		if r.d.Credits != "" {
+			note = "This credit note refunds the amounts above, charged on invoice " + r.d.Credits + "."
+		}
+		r.doc.Text(left, r.y, pdf.Helvetica, 9, note)
+	}
+}
+
+// fit shortens s with an ellipsis until it is at most width points wide
+func fit(s string, width, size float64) string {
+	if pdf.Width(pdf.Helvetica, size, s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && pdf.Width(pdf.Helvetica, size, string(runes)+"…") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return strings.TrimSpace(string(runes)) + "…"
+}
@@ -0,0 +1,97 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var sample = Document{
+	Number:   "INV-2024-000042",
+	IssuedAt: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+	OrderID:  11,
+	Currency: "EUR",
+	Seller:   Seller{Name: "Garage GmbH", Address: []string{"Torstraße 1", "10119 Berlin"}, VATID: "DE123456789"},
+	BillTo:   []string{"Jane Doe", "Hauptstraße 5", "80331 Munich", "DE"},
+	Lines: []Line{
+		{Description: "Gaming Laptop", SKU: "LAP-001", Quantity: 1, UnitPrice: 1899.99, Discount: 9.69, TaxRate: 19, Tax: 359.16, Amount: 1890.3},
+		{Description: "Mouse", Quantity: 3, UnitPrice: 19.99, Discount: 0.31, TaxRate: 19, Tax: 11.34, Amount: 59.66},
+		{Description: "Gift card", Quantity: 1, UnitPrice: 25, Amount: 25},
+	},
+	Subtotal: 1984.96,
+	Discount: 10,
+	Shipping: 6.9,
+	Tax:      370.5,
+	Total:    2352.36,
+}
+
+func TestMoney(t *testing.T) {
+	assert.Equal(t, "0.00", Money(0))
+	assert.Equal(t, "999.50", Money(999.5))
+	assert.Equal(t, "1,234,567.89", Money(1234567.89))
+	assert.Equal(t, "-1,899.99", Money(-1899.99))
+	assert.Equal(t, "0.00", Money(-0.001))
+}
+
+func TestDocument_Taxes(t *testing.T) {
+	d := sample
+	d.Lines = append(d.Lines, Line{Description: "Book", Quantity: 1, UnitPrice: 10.7, TaxRate: 7, Tax: 0.7, Amount: 10.7})
+	assert.Equal(t, []TaxLine{{Rate: 19, Tax: 370.5}, {Rate: 7, Tax: 0.7}}, d.Taxes())
+}
+
+func TestRender(t *testing.T) {
+	// Test case 1: An invoice shows its number, lines, taxes and totals
+	t.Run("invoice", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, Render(&buf, sample))
+		out := buf.String()
+
+		assert.True(t, strings.HasPrefix(out, "%PDF-"))
+		assert.Contains(t, out, "(Invoice) Tj")
+		assert.Contains(t, out, "(No. INV-2024-000042) Tj")
+		assert.Contains(t, out, "(Torstra\xdfe 1) Tj")
+		assert.Contains(t, out, "(Gaming Laptop \\(LAP-001\\)) Tj")
+		assert.Contains(t, out, "(1,890.30) Tj")
+		assert.Contains(t, out, "(Tax 19%) Tj")
+		assert.Contains(t, out, "(2,352.36) Tj")
+		assert.Contains(t, out, "/Count 1")
+	})
+
+	// Test case 2: A credit note negates the amounts and names the invoice
+	t.Run("credit note", func(t *testing.T) {
+		d := sample
+		d.CreditNote = true
+		d.Number = "CN-2024-000003"
+		d.Credits = "INV-2024-000042"
+
+		var buf bytes.Buffer
+		assert.NoError(t, Render(&buf, d))
+		out := buf.String()
+
+		assert.Contains(t, out, "(Credit note) Tj")
+		assert.Contains(t, out, "(Credits invoice: INV-2024-000042) Tj")
+		assert.Contains(t, out, "(-1,890.30) Tj")
+		assert.Contains(t, out, "(-2,352.36) Tj")
+	})
+
+	// Test case 3: Long invoices continue on further pages
+	t.Run("pagination", func(t *testing.T) {
+		d := sample
+		d.Lines = nil
+		for i := 1; i <= 80; i++ {
+			d.Lines = append(d.Lines, Line{Description: fmt.Sprintf("Part %d", i), Quantity: 1, UnitPrice: 1, Amount: 1})
+		}
+
+		var buf bytes.Buffer
+		assert.NoError(t, Render(&buf, d))
+		out := buf.String()
+
+		assert.Contains(t, out, "/Count 2")
+		assert.Contains(t, out, "(Page 2) Tj")
+		assert.Contains(t, out, "(Part 80) Tj")
+	})
+}
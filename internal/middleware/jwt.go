@@ -14,6 +14,8 @@ var jwtKey = []byte("your-secret-key") // In production, use an environment vari
 
 type Claims struct {
 	Username string `json:"username"`
+	UserID   int    `json:"userID"`
+	Role     string `json:"role"`
 	jwt.RegisteredClaims
 }
 
@@ -30,6 +32,38 @@ func GenerateToken(username string) (string, error) {
 	return token.SignedString(jwtKey)
 }
 
+// parseToken validates the bearer token in an Authorization header value.
+// The returned message is suitable for the client when err is not nil.
+func parseToken(authHeader string) (*Claims, string, error) {
+	bearerToken := strings.Split(authHeader, " ")
+	if len(bearerToken) != 2 || bearerToken[0] != "Bearer" {
+		return nil, "Invalid authorization header format", fmt.Errorf("invalid authorization header format")
+	}
+
+	tokenStr := bearerToken[1]
+	claims := &Claims{}
+
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return jwtKey, nil
+	})
+
+	if err != nil {
+		if err == jwt.ErrSignatureInvalid {
+			return nil, "Invalid token signature", err
+		}
+		return nil, "Invalid token", err
+	}
+
+	if !token.Valid {
+		return nil, "Invalid token", fmt.Errorf("invalid token")
+	}
+
+	return claims, "", nil
+}
+
 func JWTAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
@@ -38,38 +72,56 @@ func JWTAuth() gin.HandlerFunc {
 			return
 		}
 
-		bearerToken := strings.Split(authHeader, " ")
-		if len(bearerToken) != 2 || bearerToken[0] != "Bearer" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+		claims, message, err := parseToken(authHeader)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": message})
 			return
 		}
 
-		tokenStr := bearerToken[1]
-		claims := &Claims{}
-
-		token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return jwtKey, nil
-		})
+		// Add claims to context
+		c.Set("username", claims.Username)
+		c.Set("userID", claims.UserID)
+		c.Set("role", claims.Role)
+		c.Next()
+	}
+}
 
-		if err != nil {
-			if err == jwt.ErrSignatureInvalid {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token signature"})
-				return
-			}
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+// OptionalJWTAuth authenticates the request when it carries an Authorization
+// header and lets anonymous requests through, so the same route can serve
+// both guests and signed-in users
+func OptionalJWTAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.Next()
 			return
 		}
 
-		if !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		claims, message, err := parseToken(authHeader)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": message})
 			return
 		}
 
-		// Add claims to context
 		c.Set("username", claims.Username)
+		c.Set("userID", claims.UserID)
+		c.Set("role", claims.Role)
 		c.Next()
 	}
-} 
\ No newline at end of file
+}
+
+// RequireRole rejects requests whose token does not carry one of the given
+// roles. It must run after JWTAuth.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		for _, r := range roles {
+			if role == r {
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	}
+}
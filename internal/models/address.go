@@ -0,0 +1,260 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Address is an entry of a user's address book. A user has at most one
+// default shipping and one default billing address; their first address
+// becomes both.
+type Address struct {
+	ID              int       `json:"id" example:"1"`
+	UserID          int       `json:"user_id" example:"1"`
+	Name            string    `json:"name" example:"Jane Doe"`
+	Company         string    `json:"company,omitempty" example:"Garage GmbH"`
+	Line1           string    `json:"line1" example:"Torstraße 1"`
+	Line2           string    `json:"line2,omitempty" example:"3rd floor"`
+	City            string    `json:"city" example:"Berlin"`
+	Region          string    `json:"region,omitempty" example:""`
+	PostalCode      string    `json:"postal_code,omitempty" example:"10119"`
+	Country         string    `json:"country" example:"DE"`
+	Phone           string    `json:"phone,omitempty" example:"+49 30 1234567"`
+	DefaultShipping bool      `json:"default_shipping" example:"true"`
+	DefaultBilling  bool      `json:"default_billing" example:"true"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// OrderAddress is the copy of an address kept with an order
+type OrderAddress struct {
+	Name       string `json:"name" example:"Jane Doe"`
+	Company    string `json:"company,omitempty" example:"Garage GmbH"`
+	Line1      string `json:"line1" example:"Torstraße 1"`
+	Line2      string `json:"line2,omitempty" example:"3rd floor"`
+	City       string `json:"city" example:"Berlin"`
+	Region     string `json:"region,omitempty" example:""`
+	PostalCode string `json:"postal_code,omitempty" example:"10119"`
+	Country    string `json:"country" example:"DE"`
+	Phone      string `json:"phone,omitempty" example:"+49 30 1234567"`
+}
+
+// Snapshot copies the address for an order
+func (a Address) Snapshot() *OrderAddress {
+	return &OrderAddress{
+		Name:       a.Name,
+		Company:    a.Company,
+		Line1:      a.Line1,
+		Line2:      a.Line2,
+		City:       a.City,
+		Region:     a.Region,
+		PostalCode: a.PostalCode,
+		Country:    a.Country,
+		Phone:      a.Phone,
+	}
+}
+
+// Lines formats the address for printing, one line per entry
+func (a OrderAddress) Lines() []string {
+	city := strings.TrimSpace(strings.Join([]string{a.PostalCode, a.City, a.Region}, " "))
+	var lines []string
+	for _, line := range []string{a.Name, a.Company, a.Line1, a.Line2, city, a.Country} {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// marshalOrderAddress encodes an order address for a JSONB column; nil
+// stays NULL
+func marshalOrderAddress(a *OrderAddress) (interface{}, error) {
+	if a == nil {
+		return nil, nil
+	}
+	return json.Marshal(a)
+}
+
+// unmarshalOrderAddress decodes an order address read from a JSONB column
+func unmarshalOrderAddress(data []byte) (*OrderAddress, error) {
+	if data == nil {
+		return nil, nil
+	}
+	var a OrderAddress
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// AddressModelInterface defines the methods that an address model must implement
+type AddressModelInterface interface {
+	List(userID int) ([]Address, error)
+	Get(userID, id int) (*Address, error)
+	GetDefaults(userID int) (shipping, billing *Address, err error)
+	Create(address *Address) error
+	Update(address *Address) error
+	Delete(userID, id int) error
+}
+
+type AddressModel struct {
+	DB *sql.DB
+}
+
+const addressColumns = `id, user_id, name, COALESCE(company, ''), line1, COALESCE(line2, ''), city, COALESCE(region, ''),
+	COALESCE(postal_code, ''), country, COALESCE(phone, ''), is_default_shipping, is_default_billing, created_at, updated_at`
+
+func scanAddress(row rowScanner, a *Address) error {
+	return row.Scan(&a.ID, &a.UserID, &a.Name, &a.Company, &a.Line1, &a.Line2, &a.City, &a.Region,
+		&a.PostalCode, &a.Country, &a.Phone, &a.DefaultShipping, &a.DefaultBilling, &a.CreatedAt, &a.UpdatedAt)
+}
+
+func (m AddressModel) query(stmt string, args ...interface{}) ([]Address, error) {
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addresses := []Address{}
+	for rows.Next() {
+		var a Address
+		if err := scanAddress(rows, &a); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, a)
+	}
+	return addresses, rows.Err()
+}
+
+// List returns a user's addresses, defaults first
+func (m AddressModel) List(userID int) ([]Address, error) {
+	stmt := `SELECT ` + addressColumns + ` FROM addresses WHERE user_id = $1
+		ORDER BY is_default_shipping DESC, is_default_billing DESC, id`
+	return m.query(stmt, userID)
+}
+
+// Get returns one of a user's addresses. Other users' addresses are not found.
+func (m AddressModel) Get(userID, id int) (*Address, error) {
+	var a Address
+	stmt := `SELECT ` + addressColumns + ` FROM addresses WHERE id = $1 AND user_id = $2`
+	if err := scanAddress(m.DB.QueryRow(stmt, id, userID), &a); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("address not found")
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+// GetDefaults returns a user's default shipping and billing addresses, nil
+// when they have none
+func (m AddressModel) GetDefaults(userID int) (shipping, billing *Address, err error) {
+	stmt := `SELECT ` + addressColumns + ` FROM addresses WHERE user_id = $1 AND (is_default_shipping OR is_default_billing)`
+	addresses, err := m.query(stmt, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := range addresses {
+		if addresses[i].DefaultShipping {
+			shipping = &addresses[i]
+		}
+		if addresses[i].DefaultBilling {
+			billing = &addresses[i]
+		}
+	}
+	return shipping, billing, nil
+}
+
+// clearDefaults takes the default flags the address claims away from the
+// user's other addresses
+func clearDefaults(tx *sql.Tx, a *Address) error {
+	if a.DefaultShipping {
+		stmt := `UPDATE addresses SET is_default_shipping = FALSE WHERE user_id = $1 AND id <> $2 AND is_default_shipping`
+		if _, err := tx.Exec(stmt, a.UserID, a.ID); err != nil {
+			return err
+		}
+	}
+	if a.DefaultBilling {
+		stmt := `UPDATE addresses SET is_default_billing = FALSE WHERE user_id = $1 AND id <> $2 AND is_default_billing`
+		if _, err := tx.Exec(stmt, a.UserID, a.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create adds an address to a user's address book. It becomes the default
+// shipping or billing address when asked to, or when the user has none.
+func (m AddressModel) Create(a *Address) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := clearDefaults(tx, a); err != nil {
+		return err
+	}
+
+	stmt := `
+		INSERT INTO addresses (user_id, name, company, line1, line2, city, region, postal_code, country, phone,
+			is_default_shipping, is_default_billing)
+		VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, ''), $6, NULLIF($7, ''), NULLIF($8, ''), $9, NULLIF($10, ''),
+			$11 OR NOT EXISTS (SELECT 1 FROM addresses WHERE user_id = $1 AND is_default_shipping),
+			$12 OR NOT EXISTS (SELECT 1 FROM addresses WHERE user_id = $1 AND is_default_billing))
+		RETURNING id, is_default_shipping, is_default_billing, created_at, updated_at`
+
+	err = tx.QueryRow(stmt, a.UserID, a.Name, a.Company, a.Line1, a.Line2, a.City, a.Region, a.PostalCode, a.Country, a.Phone,
+		a.DefaultShipping, a.DefaultBilling).Scan(&a.ID, &a.DefaultShipping, &a.DefaultBilling, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Update replaces one of a user's addresses. Orders keep the copy they were
+// placed with.
+func (m AddressModel) Update(a *Address) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := clearDefaults(tx, a); err != nil {
+		return err
+	}
+
+	stmt := `
+		UPDATE addresses SET name = $3, company = NULLIF($4, ''), line1 = $5, line2 = NULLIF($6, ''), city = $7,
+			region = NULLIF($8, ''), postal_code = NULLIF($9, ''), country = $10, phone = NULLIF($11, ''),
+			is_default_shipping = $12, is_default_billing = $13, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING created_at, updated_at`
+
+	err = tx.QueryRow(stmt, a.ID, a.UserID, a.Name, a.Company, a.Line1, a.Line2, a.City, a.Region, a.PostalCode, a.Country, a.Phone,
+		a.DefaultShipping, a.DefaultBilling).Scan(&a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("address not found")
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes one of a user's addresses
+func (m AddressModel) Delete(userID, id int) error {
+	result, err := m.DB.Exec(`DELETE FROM addresses WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	return requireOne(result, "address not found")
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var addressRowColumns = []string{"id", "user_id", "name", "company", "line1", "line2", "city", "region", "postal_code", "country", "phone",
+	"is_default_shipping", "is_default_billing", "created_at", "updated_at"}
+
+func TestAddressModel_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := AddressModel{DB: db}
+	now := time.Now()
+
+	// Test case 1: A user's first address becomes their default for both
+	t.Run("first address becomes default", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO addresses .* RETURNING id, is_default_shipping, is_default_billing, created_at, updated_at").
+			WithArgs(7, "Jane Doe", "", "Torstraße 1", "", "Berlin", "", "10119", "DE", "", false, false).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "is_default_shipping", "is_default_billing", "created_at", "updated_at"}).
+				AddRow(1, true, true, now, now))
+		mock.ExpectCommit()
+
+		address := &Address{UserID: 7, Name: "Jane Doe", Line1: "Torstraße 1", City: "Berlin", PostalCode: "10119", Country: "DE"}
+		err := model.Create(address)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, address.ID)
+		assert.True(t, address.DefaultShipping)
+		assert.True(t, address.DefaultBilling)
+	})
+
+	// Test case 2: A new default shipping address replaces the old one
+	t.Run("new default shipping address", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE addresses SET is_default_shipping = FALSE WHERE user_id = \\$1 AND id <> \\$2 AND is_default_shipping").
+			WithArgs(7, 0).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO addresses").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "is_default_shipping", "is_default_billing", "created_at", "updated_at"}).
+				AddRow(2, true, false, now, now))
+		mock.ExpectCommit()
+
+		address := &Address{UserID: 7, Name: "Jane Doe", Line1: "Hauptstraße 5", City: "Munich", PostalCode: "80331", Country: "DE", DefaultShipping: true}
+		err := model.Create(address)
+		assert.NoError(t, err)
+		assert.True(t, address.DefaultShipping)
+		assert.False(t, address.DefaultBilling)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAddressModel_Update(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := AddressModel{DB: db}
+
+	// Test case 1: Another user's address is not found
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE addresses SET is_default_billing = FALSE").
+		WithArgs(8, 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("UPDATE addresses SET name = \\$3, .* WHERE id = \\$1 AND user_id = \\$2").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	err = model.Update(&Address{ID: 1, UserID: 8, Name: "Mallory", Line1: "Elsewhere 1", City: "Berlin", Country: "DE", DefaultBilling: true})
+	assert.Equal(t, "address not found", err.Error())
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAddressModel_GetDefaults(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := AddressModel{DB: db}
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT .* FROM addresses WHERE user_id = \\$1 AND \\(is_default_shipping OR is_default_billing\\)").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows(addressRowColumns).
+			AddRow(1, 7, "Jane Doe", "", "Torstraße 1", "", "Berlin", "", "10119", "DE", "", true, false, now, now).
+			AddRow(3, 7, "Jane Doe", "Garage GmbH", "Invalidenstraße 9", "", "Berlin", "", "10115", "DE", "", false, true, now, now))
+
+	shipping, billing, err := model.GetDefaults(7)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, shipping.ID)
+	assert.Equal(t, 3, billing.ID)
+	assert.Equal(t, &OrderAddress{Name: "Jane Doe", Company: "Garage GmbH", Line1: "Invalidenstraße 9", City: "Berlin", PostalCode: "10115", Country: "DE"}, billing.Snapshot())
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,283 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// AttributeType is the kind of value an attribute definition accepts
+type AttributeType string
+
+const (
+	AttributeString  AttributeType = "string"
+	AttributeNumber  AttributeType = "number"
+	AttributeEnum    AttributeType = "enum"
+	AttributeBoolean AttributeType = "boolean"
+	// AttributeUnit is a number measured in the definition's unit, e.g. a
+	// refresh rate in Hz
+	AttributeUnit AttributeType = "unit"
+)
+
+// Valid reports whether t is a known attribute type
+func (t AttributeType) Valid() bool {
+	switch t {
+	case AttributeString, AttributeNumber, AttributeEnum, AttributeBoolean, AttributeUnit:
+		return true
+	}
+	return false
+}
+
+var attributeCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// ValidAttributeCode reports whether code can name an attribute: lower-case
+// letters, digits and underscores, starting with a letter
+func ValidAttributeCode(code string) bool {
+	return attributeCodePattern.MatchString(code)
+}
+
+// MaxAttributeLength is the longest string attribute value accepted
+const MaxAttributeLength = 255
+
+// AttributeDefinition describes an attribute the products of a category
+// carry, e.g. the "resolution" of monitors. Code is the key of the value
+// in Product.Attributes.
+type AttributeDefinition struct {
+	ID         int           `json:"id" example:"1"`
+	CategoryID int           `json:"category_id" example:"2"`
+	Code       string        `json:"code" example:"refresh_rate"`
+	Name       string        `json:"name" example:"Refresh rate"`
+	Type       AttributeType `json:"type" example:"unit"`
+	// Unit is only set on unit attributes, Options only on enum ones
+	Unit     string   `json:"unit,omitempty" example:"Hz"`
+	Options  []string `json:"options,omitempty" example:"linear,tactile,clicky"`
+	Required bool     `json:"required" example:"false"`
+	Position int      `json:"position" example:"0"`
+}
+
+// AttributeProblem is an attribute value rejected by its definition
+type AttributeProblem struct {
+	Code    string `json:"code" example:"refresh_rate"`
+	Problem string `json:"problem" example:"must be a number"`
+}
+
+// AttributeError is returned when a product's attributes do not match the
+// definitions of its category
+type AttributeError struct {
+	Problems []AttributeProblem
+}
+
+func (e *AttributeError) Error() string {
+	return "invalid attributes"
+}
+
+// ValidateAttributes checks values against the definitions of a category
+// and returns them normalized: numbers as float64 and null values dropped.
+// Every problem found is reported in a single *AttributeError.
+func ValidateAttributes(defs []AttributeDefinition, values map[string]interface{}) (map[string]interface{}, error) {
+	byCode := make(map[string]AttributeDefinition, len(defs))
+	for _, d := range defs {
+		byCode[d.Code] = d
+	}
+
+	normalized := make(map[string]interface{}, len(values))
+	var problems []AttributeProblem
+	for code, value := range values {
+		if value == nil {
+			continue
+		}
+		d, ok := byCode[code]
+		if !ok {
+			problems = append(problems, AttributeProblem{Code: code, Problem: "not defined for the product's category"})
+			continue
+		}
+		v, problem := d.normalize(value)
+		if problem != "" {
+			problems = append(problems, AttributeProblem{Code: code, Problem: problem})
+			continue
+		}
+		normalized[code] = v
+	}
+	for _, d := range defs {
+		if _, ok := normalized[d.Code]; !ok && d.Required && values[d.Code] == nil {
+			problems = append(problems, AttributeProblem{Code: d.Code, Problem: "is required"})
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Slice(problems, func(i, j int) bool { return problems[i].Code < problems[j].Code })
+		return nil, &AttributeError{Problems: problems}
+	}
+	return normalized, nil
+}
+
+// normalize returns value as stored for d, or why it does not fit
+func (d AttributeDefinition) normalize(value interface{}) (interface{}, string) {
+	switch d.Type {
+	case AttributeString:
+		s, ok := value.(string)
+		if !ok || strings.TrimSpace(s) == "" {
+			return nil, "must be a non-empty string"
+		}
+		if len(s) > MaxAttributeLength {
+			return nil, fmt.Sprintf("must be at most %d characters", MaxAttributeLength)
+		}
+		return strings.TrimSpace(s), ""
+	case AttributeNumber, AttributeUnit:
+		var n float64
+		switch v := value.(type) {
+		case float64:
+			n = v
+		case int:
+			n = float64(v)
+		default:
+			if d.Type == AttributeUnit {
+				return nil, "must be a number of " + d.Unit
+			}
+			return nil, "must be a number"
+		}
+		if math.IsNaN(n) || math.IsInf(n, 0) {
+			return nil, "must be a finite number"
+		}
+		return n, ""
+	case AttributeEnum:
+		s, ok := value.(string)
+		if ok {
+			for _, option := range d.Options {
+				if s == option {
+					return s, ""
+				}
+			}
+		}
+		return nil, "must be one of " + strings.Join(d.Options, ", ")
+	case AttributeBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, "must be true or false"
+		}
+		return b, ""
+	}
+	return nil, "has an unknown type"
+}
+
+// attributeFilterValues returns the JSON values a filter value given as
+// text may be stored as: the text itself, and the number or boolean it
+// spells, if any
+func attributeFilterValues(s string) []interface{} {
+	values := []interface{}{s}
+	if n, err := strconv.ParseFloat(s, 64); err == nil && !math.IsNaN(n) && !math.IsInf(n, 0) {
+		values = append(values, n)
+	}
+	if b, err := strconv.ParseBool(s); err == nil && (s == "true" || s == "false") {
+		values = append(values, b)
+	}
+	return values
+}
+
+// encodeAttributes returns the JSON stored in products.attributes
+func encodeAttributes(values map[string]interface{}) (string, error) {
+	if len(values) == 0 {
+		return "{}", nil
+	}
+	b, err := json.Marshal(values)
+	return string(b), err
+}
+
+// AttributeModelInterface defines the methods that an attribute model must implement
+type AttributeModelInterface interface {
+	List(categoryID int) ([]AttributeDefinition, error)
+	Set(def *AttributeDefinition) error
+	Delete(categoryID int, code string) error
+}
+
+type AttributeModel struct {
+	DB *sql.DB
+}
+
+// listAttributes returns the attribute definitions of a category, in
+// display order
+func listAttributes(db DBTX, categoryID int) ([]AttributeDefinition, error) {
+	stmt := `
+		SELECT id, category_id, code, name, type, unit, options, required, position
+		FROM attribute_definitions
+		WHERE category_id = $1
+		ORDER BY position, code`
+	rows, err := db.Query(stmt, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	defs := []AttributeDefinition{}
+	for rows.Next() {
+		var d AttributeDefinition
+		if err := rows.Scan(&d.ID, &d.CategoryID, &d.Code, &d.Name, &d.Type, &d.Unit, pq.Array(&d.Options), &d.Required, &d.Position); err != nil {
+			return nil, err
+		}
+		defs = append(defs, d)
+	}
+	return defs, rows.Err()
+}
+
+// List returns the attribute definitions of a category, in display order
+func (m AttributeModel) List(categoryID int) ([]AttributeDefinition, error) {
+	return listAttributes(m.DB, categoryID)
+}
+
+// Set creates or replaces the definition of an attribute of a category,
+// identified by its code. Values already stored on products are checked
+// against the new definition the next time those products are saved.
+func (m AttributeModel) Set(def *AttributeDefinition) error {
+	stmt := `
+		INSERT INTO attribute_definitions (category_id, code, name, type, unit, options, required, position)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (category_id, code) DO UPDATE
+		SET name = EXCLUDED.name, type = EXCLUDED.type, unit = EXCLUDED.unit, options = EXCLUDED.options,
+			required = EXCLUDED.required, position = EXCLUDED.position
+		RETURNING id`
+	options := def.Options
+	if options == nil {
+		options = []string{}
+	}
+	err := m.DB.QueryRow(stmt, def.CategoryID, def.Code, def.Name, string(def.Type), def.Unit, pq.Array(options), def.Required, def.Position).
+		Scan(&def.ID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return errors.New("category not found")
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes an attribute definition, along with the values the
+// products of the category had for it
+func (m AttributeModel) Delete(categoryID int, code string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM attribute_definitions WHERE category_id = $1 AND code = $2`, categoryID, code)
+	if err != nil {
+		return err
+	}
+	if err := requireOne(result, "attribute not found"); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE products SET attributes = attributes - $2::text WHERE category_id = $1 AND attributes ? $2`, categoryID, code); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var attributeRowColumns = []string{"id", "category_id", "code", "name", "type", "unit", "options", "required", "position"}
+
+func TestValidateAttributes(t *testing.T) {
+	defs := []AttributeDefinition{
+		{Code: "resolution", Type: AttributeString, Required: true},
+		{Code: "refresh_rate", Type: AttributeUnit, Unit: "Hz"},
+		{Code: "panel", Type: AttributeEnum, Options: []string{"ips", "va", "tn"}},
+		{Code: "curved", Type: AttributeBoolean},
+		{Code: "inputs", Type: AttributeNumber},
+	}
+
+	// Test case 1: Valid values are normalized
+	t.Run("valid", func(t *testing.T) {
+		values, err := ValidateAttributes(defs, map[string]interface{}{
+			"resolution":   " 2560x1440 ",
+			"refresh_rate": 144,
+			"panel":        "ips",
+			"curved":       false,
+			"inputs":       nil,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"resolution": "2560x1440", "refresh_rate": 144.0, "panel": "ips", "curved": false}, values)
+	})
+
+	// Test case 2: Every problem is reported, ordered by code
+	t.Run("invalid", func(t *testing.T) {
+		_, err := ValidateAttributes(defs, map[string]interface{}{
+			"refresh_rate": "144 Hz",
+			"panel":        "oled",
+			"curved":       "yes",
+			"weight":       2.5,
+		})
+		var attrErr *AttributeError
+		assert.ErrorAs(t, err, &attrErr)
+		assert.Equal(t, []AttributeProblem{
+			{Code: "curved", Problem: "must be true or false"},
+			{Code: "panel", Problem: "must be one of ips, va, tn"},
+			{Code: "refresh_rate", Problem: "must be a number of Hz"},
+			{Code: "resolution", Problem: "is required"},
+			{Code: "weight", Problem: "not defined for the product's category"},
+		}, attrErr.Problems)
+	})
+}
+
+func TestAttributeModel_List(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := AttributeModel{DB: db}
+
+	mock.ExpectQuery("SELECT id, category_id, code, name, type, unit, options, required, position\\s+FROM attribute_definitions\\s+WHERE category_id = \\$1\\s+ORDER BY position, code").
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows(attributeRowColumns).
+			AddRow(1, 4, "switch_type", "Switch type", "enum", "", "{linear,tactile,clicky}", true, 0))
+
+	defs, err := model.List(4)
+	assert.NoError(t, err)
+	assert.Len(t, defs, 1)
+	assert.Equal(t, AttributeEnum, defs[0].Type)
+	assert.Equal(t, []string{"linear", "tactile", "clicky"}, defs[0].Options)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAttributeModel_Set(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := AttributeModel{DB: db}
+
+	// Test case 1: Created or replaced by code
+	t.Run("upsert", func(t *testing.T) {
+		def := &AttributeDefinition{CategoryID: 3, Code: "refresh_rate", Name: "Refresh rate", Type: AttributeUnit, Unit: "Hz", Position: 1}
+
+		mock.ExpectQuery("INSERT INTO attribute_definitions .+ON CONFLICT \\(category_id, code\\) DO UPDATE").
+			WithArgs(3, "refresh_rate", "Refresh rate", "unit", "Hz", pq.Array([]string{}), false, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+
+		err := model.Set(def)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, def.ID)
+	})
+
+	// Test case 2: Unknown category
+	t.Run("category not found", func(t *testing.T) {
+		def := &AttributeDefinition{CategoryID: 99, Code: "curved", Name: "Curved", Type: AttributeBoolean}
+
+		mock.ExpectQuery("INSERT INTO attribute_definitions").
+			WillReturnError(&pq.Error{Code: "23503"})
+
+		err := model.Set(def)
+		assert.EqualError(t, err, "category not found")
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAttributeModel_Delete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := AttributeModel{DB: db}
+
+	// Test case 1: The products of the category lose their values
+	t.Run("successful deletion", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM attribute_definitions WHERE category_id = \\$1 AND code = \\$2").
+			WithArgs(3, "curved").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE products SET attributes = attributes - \\$2::text WHERE category_id = \\$1 AND attributes \\? \\$2").
+			WithArgs(3, "curved").
+			WillReturnResult(sqlmock.NewResult(0, 7))
+		mock.ExpectCommit()
+
+		err := model.Delete(3, "curved")
+		assert.NoError(t, err)
+	})
+
+	// Test case 2: Unknown attribute
+	t.Run("attribute not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM attribute_definitions").
+			WithArgs(3, "weight").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := model.Delete(3, "weight")
+		assert.EqualError(t, err, "attribute not found")
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,162 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// Bundle is a product sold as a set of other products. The database derives
+// its price, the components' total less the discount, and its stock, the
+// number of complete sets in stock. Selling or restocking a bundle moves
+// its components' stock.
+type Bundle struct {
+	ProductID int `json:"product_id" example:"9"`
+	// DiscountPercent is taken off the components' total
+	DiscountPercent float64      `json:"discount_percent" example:"10"`
+	Price           float64      `json:"price" example:"2051.97"`
+	Stock           int          `json:"stock" example:"3"`
+	Items           []BundleItem `json:"items"`
+}
+
+// BundleItem is a component of a bundle
+type BundleItem struct {
+	ProductID int     `json:"product_id" example:"5"`
+	Name      string  `json:"name" example:"Gaming Headset"`
+	Quantity  int     `json:"quantity" example:"1"`
+	UnitPrice float64 `json:"unit_price" example:"149.99"`
+	Stock     int     `json:"stock" example:"12"`
+}
+
+// BundleModelInterface defines the methods that a bundle model must implement
+type BundleModelInterface interface {
+	Get(productID int) (*Bundle, error)
+	Set(productID int, discountPercent float64, items []BundleItem) (*Bundle, error)
+	Delete(productID int) error
+}
+
+type BundleModel struct {
+	DB *sql.DB
+}
+
+// Get returns a product's bundle
+func (m BundleModel) Get(productID int) (*Bundle, error) {
+	bundle := Bundle{ProductID: productID}
+	stmt := `
+		SELECT b.discount_percent, p.price, p.stock
+		FROM bundles b
+		JOIN products p ON p.id = b.product_id
+		WHERE b.product_id = $1`
+	if err := m.DB.QueryRow(stmt, productID).Scan(&bundle.DiscountPercent, &bundle.Price, &bundle.Stock); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("bundle not found")
+		}
+		return nil, err
+	}
+
+	stmt = `
+		SELECT p.id, p.name, bi.quantity, p.price, p.stock
+		FROM bundle_items bi
+		JOIN products p ON p.id = bi.component_id
+		WHERE bi.bundle_id = $1
+		ORDER BY p.id`
+	rows, err := m.DB.Query(stmt, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bundle.Items = []BundleItem{}
+	for rows.Next() {
+		var item BundleItem
+		if err := rows.Scan(&item.ProductID, &item.Name, &item.Quantity, &item.UnitPrice, &item.Stock); err != nil {
+			return nil, err
+		}
+		bundle.Items = append(bundle.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &bundle, nil
+}
+
+// Set turns a product into a bundle of items, or replaces its components.
+// Bundles cannot contain bundles. The product's price and stock are derived
+// from the components right away.
+func (m BundleModel) Set(productID int, discountPercent float64, items []BundleItem) (*Bundle, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var isComponent bool
+	stmt := `SELECT EXISTS (SELECT 1 FROM bundle_items WHERE component_id = p.id) FROM products p WHERE p.id = $1 FOR UPDATE`
+	if err := tx.QueryRow(stmt, productID).Scan(&isComponent); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("product not found")
+		}
+		return nil, err
+	}
+	if isComponent {
+		return nil, errors.New("product is a bundle component")
+	}
+
+	ids := make([]int64, len(items))
+	quantities := make([]int64, len(items))
+	for i, item := range items {
+		ids[i] = int64(item.ProductID)
+		quantities[i] = int64(item.Quantity)
+	}
+
+	var nested bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM bundles WHERE product_id = ANY($1))`, pq.Array(ids)).Scan(&nested); err != nil {
+		return nil, err
+	}
+	if nested {
+		return nil, errors.New("component is a bundle")
+	}
+
+	stmt = `
+		INSERT INTO bundles (product_id, discount_percent) VALUES ($1, $2)
+		ON CONFLICT (product_id) DO UPDATE SET discount_percent = EXCLUDED.discount_percent`
+	if _, err := tx.Exec(stmt, productID, discountPercent); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM bundle_items WHERE bundle_id = $1`, productID); err != nil {
+		return nil, err
+	}
+
+	stmt = `
+		INSERT INTO bundle_items (bundle_id, component_id, quantity)
+		SELECT $1, c.id, c.quantity FROM UNNEST($2::int[], $3::int[]) AS c(id, quantity)`
+	if _, err := tx.Exec(stmt, productID, pq.Array(ids), pq.Array(quantities)); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return nil, errors.New("component not found")
+		}
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`SELECT refresh_bundle($1)`, productID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return m.Get(productID)
+}
+
+// Delete turns a bundle back into a plain product, which keeps its last
+// price and stock
+func (m BundleModel) Delete(productID int) error {
+	result, err := m.DB.Exec(`DELETE FROM bundles WHERE product_id = $1`, productID)
+	if err != nil {
+		return err
+	}
+	return requireOne(result, "bundle not found")
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBundleModel_Set(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := BundleModel{DB: db}
+
+	// Test case 1: The bundle's price and stock are derived from its components
+	t.Run("set bundle", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM bundle_items WHERE component_id = p.id\\) FROM products p WHERE p.id = \\$1 FOR UPDATE").
+			WithArgs(9).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM bundles WHERE product_id = ANY\\(\\$1\\)\\)").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec("INSERT INTO bundles \\(product_id, discount_percent\\) VALUES \\(\\$1, \\$2\\)").
+			WithArgs(9, 10.0).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM bundle_items WHERE bundle_id = \\$1").
+			WithArgs(9).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO bundle_items \\(bundle_id, component_id, quantity\\)").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("SELECT refresh_bundle\\(\\$1\\)").
+			WithArgs(9).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT b.discount_percent, p.price, p.stock\\s+FROM bundles b").
+			WithArgs(9).
+			WillReturnRows(sqlmock.NewRows([]string{"discount_percent", "price", "stock"}).AddRow(10.0, 161.97, 4))
+		mock.ExpectQuery("SELECT p.id, p.name, bi.quantity, p.price, p.stock\\s+FROM bundle_items bi").
+			WithArgs(9).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "quantity", "price", "stock"}).
+				AddRow(5, "Gaming Headset", 1, 149.99, 4).
+				AddRow(7, "RGB Mouse Pad", 1, 29.99, 40))
+
+		bundle, err := model.Set(9, 10, []BundleItem{{ProductID: 5, Quantity: 1}, {ProductID: 7, Quantity: 1}})
+		assert.NoError(t, err)
+		assert.Equal(t, 161.97, bundle.Price)
+		assert.Equal(t, 4, bundle.Stock)
+		assert.Len(t, bundle.Items, 2)
+	})
+
+	// Test case 2: Bundles cannot contain bundles
+	t.Run("nested bundle", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM bundle_items WHERE component_id = p.id\\)").
+			WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM bundles").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		bundle, err := model.Set(10, 0, []BundleItem{{ProductID: 9, Quantity: 1}})
+		assert.Nil(t, bundle)
+		assert.Equal(t, "component is a bundle", err.Error())
+	})
+
+	// Test case 3: Product not found
+	t.Run("product not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM bundle_items").
+			WithArgs(999).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}))
+		mock.ExpectRollback()
+
+		bundle, err := model.Set(999, 0, []BundleItem{{ProductID: 5, Quantity: 1}})
+		assert.Nil(t, bundle)
+		assert.Equal(t, "product not found", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,449 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"math"
+	"time"
+
+	"garage-api/internal/promotion"
+	"garage-api/internal/shipping"
+	"garage-api/internal/tax"
+)
+
+// CartItem is a product line in a cart. UnitPrice is the product's current
+// price; AddedPrice is the price the shopper last saw, so a difference means
+// the price changed since their last visit.
+type CartItem struct {
+	ProductID    int     `json:"product_id" example:"1"`
+	Name         string  `json:"name" example:"Gaming Laptop"`
+	ImagePath    string  `json:"image_path,omitempty" example:"/images/gaming-laptop.jpg"`
+	Quantity     int     `json:"quantity" example:"1"`
+	UnitPrice    float64 `json:"unit_price" example:"1899.99"`
+	AddedPrice   float64 `json:"added_price" example:"1999.99"`
+	LineTotal    float64 `json:"line_total" example:"1899.99"`
+	PriceChanged bool    `json:"price_changed" example:"true"`
+	InStock      bool    `json:"in_stock" example:"true"`
+	// Discount is this line's share of the cart's promotions
+	Discount   float64 `json:"discount" example:"0"`
+	Tax        float64 `json:"tax" example:"303.33"`
+	CategoryID int     `json:"-"`
+	TaxClass   string  `json:"-"`
+	// Weight is the billable weight of one unit in kilograms
+	Weight float64 `json:"-"`
+}
+
+// Cart is a shopping cart owned by a user or, for guests, identified by an
+// opaque token. Discounts lists the promotions and coupon applied to it;
+// CouponError explains why the cart's coupon currently does not apply.
+// Tax is an estimate for the address passed to ApplyTax.
+type Cart struct {
+	ID           int                 `json:"id" example:"1"`
+	UserID       *int                `json:"user_id,omitempty" example:"1"`
+	Token        string              `json:"token,omitempty" example:"3f2a9c0d8e7b6a5f4e3d2c1b0a9f8e7d"`
+	Items        []CartItem          `json:"items"`
+	ItemCount    int                 `json:"item_count" example:"1"`
+	Subtotal     float64             `json:"subtotal" example:"1899.99"`
+	CouponCode   string              `json:"coupon_code,omitempty" example:"SUMMER10"`
+	CouponError  string              `json:"coupon_error,omitempty" example:"coupon SUMMER10 has expired"`
+	Discounts    []promotion.Applied `json:"discounts"`
+	Discount     float64             `json:"discount" example:"189.99"`
+	FreeShipping bool                `json:"free_shipping" example:"false"`
+	Tax          float64             `json:"tax" example:"273.03"`
+	TaxIncluded  bool                `json:"tax_included" example:"true"`
+	Taxes        []tax.RateTotal     `json:"taxes"`
+	Total        float64             `json:"total" example:"1710"`
+	PriceChanged bool                `json:"price_changed" example:"true"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+}
+
+// CartModelInterface defines the methods that a cart model must implement
+type CartModelInterface interface {
+	FindByUser(userID int) (*Cart, error)
+	FindByToken(token string) (*Cart, error)
+	CreateForUser(userID int) (*Cart, error)
+	CreateGuest() (*Cart, error)
+	Get(id int) (*Cart, error)
+	AddItem(cartID, productID, quantity int) error
+	SetItemQuantity(cartID, productID, quantity int) error
+	SetCoupon(cartID int, code string) error
+	RemoveItem(cartID, productID int) error
+	RefreshPrices(cartID int) error
+	Merge(token string, userID int) error
+}
+
+type CartModel struct {
+	DB *sql.DB
+}
+
+// RoundMoney rounds an amount to whole cents
+func RoundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func newCartToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+const cartColumns = `id, user_id, COALESCE(token, ''), COALESCE(coupon_code, ''), updated_at`
+
+func scanCart(row rowScanner) (*Cart, error) {
+	var cart Cart
+	var userID sql.NullInt64
+	err := row.Scan(&cart.ID, &userID, &cart.Token, &cart.CouponCode, &cart.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("cart not found")
+		}
+		return nil, err
+	}
+
+	if userID.Valid {
+		id := int(userID.Int64)
+		cart.UserID = &id
+	}
+	cart.Items = []CartItem{}
+	cart.Discounts = []promotion.Applied{}
+	return &cart, nil
+}
+
+// FindByUser returns the cart of a user, without its items
+func (m CartModel) FindByUser(userID int) (*Cart, error) {
+	stmt := `SELECT ` + cartColumns + ` FROM carts WHERE user_id = $1`
+
+	return scanCart(m.DB.QueryRow(stmt, userID))
+}
+
+// FindByToken returns the guest cart with the given token, without its items
+func (m CartModel) FindByToken(token string) (*Cart, error) {
+	stmt := `SELECT ` + cartColumns + ` FROM carts WHERE token = $1 AND user_id IS NULL`
+
+	return scanCart(m.DB.QueryRow(stmt, token))
+}
+
+// CreateForUser returns the cart of a user, creating it if needed
+func (m CartModel) CreateForUser(userID int) (*Cart, error) {
+	stmt := `
+		INSERT INTO carts (user_id) VALUES ($1)
+		ON CONFLICT (user_id) DO UPDATE SET updated_at = NOW()
+		RETURNING ` + cartColumns
+
+	return scanCart(m.DB.QueryRow(stmt, userID))
+}
+
+// CreateGuest creates an anonymous cart identified by a new random token
+func (m CartModel) CreateGuest() (*Cart, error) {
+	token, err := newCartToken()
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := `INSERT INTO carts (token) VALUES ($1) RETURNING ` + cartColumns
+
+	return scanCart(m.DB.QueryRow(stmt, token))
+}
+
+// Get returns a cart with its items priced at the products' current prices
+// and the promotions that apply to it
+func (m CartModel) Get(id int) (*Cart, error) {
+	stmt := `SELECT ` + cartColumns + ` FROM carts WHERE id = $1`
+
+	cart, err := scanCart(m.DB.QueryRow(stmt, id))
+	if err != nil {
+		return nil, err
+	}
+
+	stmt = `
+		SELECT ci.product_id, p.name, COALESCE(p.image_path, ''), ci.quantity, effective_price(p), ci.unit_price, p.stock, COALESCE(p.category_id, 0), p.tax_class,
+			p.weight, p.length * p.width * p.height
+		FROM cart_items ci
+		JOIN products p ON p.id = ci.product_id
+		WHERE ci.cart_id = $1
+		ORDER BY ci.id`
+
+	rows, err := m.DB.Query(stmt, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item CartItem
+		var stock int
+		var weight, volume float64
+		err := rows.Scan(&item.ProductID, &item.Name, &item.ImagePath, &item.Quantity, &item.UnitPrice, &item.AddedPrice, &stock, &item.CategoryID, &item.TaxClass, &weight, &volume)
+		if err != nil {
+			return nil, err
+		}
+		item.InStock = stock >= item.Quantity
+		item.Weight = shipping.BillableWeight(weight, volume)
+		cart.Items = append(cart.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	cart.computeTotals()
+	if err := cart.applyPromotions(m.DB, time.Now()); err != nil {
+		return nil, err
+	}
+	return cart, nil
+}
+
+func (c *Cart) computeTotals() {
+	c.ItemCount = 0
+	c.Subtotal = 0
+	c.PriceChanged = false
+	for i := range c.Items {
+		item := &c.Items[i]
+		item.LineTotal = RoundMoney(item.UnitPrice * float64(item.Quantity))
+		item.PriceChanged = RoundMoney(item.UnitPrice) != RoundMoney(item.AddedPrice)
+		c.ItemCount += item.Quantity
+		c.Subtotal += item.LineTotal
+		if item.PriceChanged {
+			c.PriceChanged = true
+		}
+	}
+	c.Subtotal = RoundMoney(c.Subtotal)
+	c.Total = c.Subtotal
+}
+
+// promotionLines converts cart items into lines for the promotion engine
+func (c *Cart) promotionLines() []promotion.Line {
+	lines := make([]promotion.Line, len(c.Items))
+	for i, item := range c.Items {
+		lines[i] = promotion.Line{ProductID: item.ProductID, CategoryID: item.CategoryID, Quantity: item.Quantity, UnitPrice: item.UnitPrice}
+	}
+	return lines
+}
+
+// applyPromotions computes the discounts of a priced cart. A coupon that no
+// longer applies is reported in CouponError and left out of the totals.
+func (c *Cart) applyPromotions(db DBTX, now time.Time) error {
+	if len(c.Items) == 0 {
+		return nil
+	}
+
+	userID := 0
+	if c.UserID != nil {
+		userID = *c.UserID
+	}
+
+	result, err := evaluatePromotions(db, c.promotionLines(), c.CouponCode, userID, now, false)
+	var couponErr *promotion.CouponError
+	if errors.As(err, &couponErr) {
+		c.CouponError = couponErr.Error()
+		result, err = evaluatePromotions(db, c.promotionLines(), "", userID, now, false)
+	}
+	if err != nil {
+		return err
+	}
+
+	for i := range c.Items {
+		c.Items[i].Discount = result.LineDiscounts[i]
+	}
+	c.Discounts = result.Applied
+	c.Discount = result.Discount
+	c.FreeShipping = result.FreeShipping
+	c.Total = result.Total
+	return nil
+}
+
+// Parcel describes the cart's items for shipping quotes
+func (c *Cart) Parcel() shipping.Parcel {
+	var weight float64
+	for _, item := range c.Items {
+		weight += item.Weight * float64(item.Quantity)
+	}
+	return shipping.Parcel{Weight: weight, Subtotal: RoundMoney(c.Subtotal - c.Discount)}
+}
+
+// ApplyTax estimates the tax of a cart shipped to addr. Tax is added to the
+// total unless prices already include it. A nil calc leaves the cart untaxed.
+func (c *Cart) ApplyTax(ctx context.Context, calc tax.TaxCalculator, addr tax.Address) error {
+	c.Taxes = []tax.RateTotal{}
+	if len(c.Items) == 0 || calc == nil {
+		return nil
+	}
+
+	lines := make([]tax.Line, len(c.Items))
+	for i, item := range c.Items {
+		lines[i] = tax.Line{Class: item.TaxClass, Amount: RoundMoney(item.LineTotal - item.Discount)}
+	}
+
+	result, err := calc.Calculate(ctx, tax.Request{Address: addr, Lines: lines, At: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	for i := range c.Items {
+		c.Items[i].Tax = result.Lines[i].Tax
+	}
+	c.Tax = result.Tax
+	c.TaxIncluded = result.Inclusive
+	c.Taxes = result.Rates
+	if !result.Inclusive {
+		c.Total = RoundMoney(c.Total + result.Tax)
+	}
+	return nil
+}
+
+func (m CartModel) touch(cartID int) error {
+	_, err := m.DB.Exec(`UPDATE carts SET updated_at = NOW() WHERE id = $1`, cartID)
+	return err
+}
+
+// AddItem adds quantity units of a product to a cart, on top of any already
+// there. Products that are not published cannot be added.
+func (m CartModel) AddItem(cartID, productID, quantity int) error {
+	stmt := `
+		INSERT INTO cart_items (cart_id, product_id, quantity, unit_price)
+		SELECT $1, id, $3, effective_price(products) FROM products WHERE id = $2 AND is_published(products)
+		ON CONFLICT (cart_id, product_id) DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity`
+
+	result, err := m.DB.Exec(stmt, cartID, productID, quantity)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("product not found")
+	}
+
+	return m.touch(cartID)
+}
+
+// SetItemQuantity changes the quantity of a product already in a cart;
+// a quantity of zero removes it
+func (m CartModel) SetItemQuantity(cartID, productID, quantity int) error {
+	if quantity <= 0 {
+		return m.RemoveItem(cartID, productID)
+	}
+
+	stmt := `UPDATE cart_items SET quantity = $3 WHERE cart_id = $1 AND product_id = $2`
+
+	result, err := m.DB.Exec(stmt, cartID, productID, quantity)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("item not found")
+	}
+
+	return m.touch(cartID)
+}
+
+func (m CartModel) RemoveItem(cartID, productID int) error {
+	stmt := `DELETE FROM cart_items WHERE cart_id = $1 AND product_id = $2`
+
+	result, err := m.DB.Exec(stmt, cartID, productID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("item not found")
+	}
+
+	return m.touch(cartID)
+}
+
+// SetCoupon attaches a coupon code to a cart; an empty code removes it
+func (m CartModel) SetCoupon(cartID int, code string) error {
+	stmt := `UPDATE carts SET coupon_code = NULLIF($2, ''), updated_at = NOW() WHERE id = $1`
+
+	result, err := m.DB.Exec(stmt, cartID, NormalizeCouponCode(code))
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("cart not found")
+	}
+
+	return nil
+}
+
+// RefreshPrices records the current product prices as the prices the shopper
+// has seen, so the same change is only reported once
+func (m CartModel) RefreshPrices(cartID int) error {
+	stmt := `
+		UPDATE cart_items ci SET unit_price = effective_price(p)
+		FROM products p
+		WHERE p.id = ci.product_id AND ci.cart_id = $1 AND ci.unit_price <> effective_price(p)`
+
+	_, err := m.DB.Exec(stmt, cartID)
+	return err
+}
+
+// Merge moves the items of the guest cart with the given token into the
+// user's cart, adding up quantities of products present in both, and deletes
+// the guest cart. The guest's coupon is kept unless the user's cart has one.
+// Unknown tokens are ignored.
+func (m CartModel) Merge(token string, userID int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var guestID int
+	stmt := `SELECT id FROM carts WHERE token = $1 AND user_id IS NULL FOR UPDATE`
+	if err := tx.QueryRow(stmt, token).Scan(&guestID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	var userCartID int
+	stmt = `
+		INSERT INTO carts (user_id, coupon_code) SELECT $1, coupon_code FROM carts WHERE id = $2
+		ON CONFLICT (user_id) DO UPDATE SET updated_at = NOW(), coupon_code = COALESCE(carts.coupon_code, EXCLUDED.coupon_code)
+		RETURNING id`
+	if err := tx.QueryRow(stmt, userID, guestID).Scan(&userCartID); err != nil {
+		return err
+	}
+
+	stmt = `
+		INSERT INTO cart_items (cart_id, product_id, quantity, unit_price)
+		SELECT $1, product_id, quantity, unit_price FROM cart_items WHERE cart_id = $2
+		ON CONFLICT (cart_id, product_id) DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity`
+	if _, err := tx.Exec(stmt, userCartID, guestID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM carts WHERE id = $1`, guestID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
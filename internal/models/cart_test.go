@@ -0,0 +1,280 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"garage-api/internal/tax"
+)
+
+var cartRowColumns = []string{"id", "user_id", "token", "coupon_code", "updated_at"}
+var cartItemRowColumns = []string{"product_id", "name", "image_path", "quantity", "price", "unit_price", "stock", "category_id", "tax_class", "weight", "volume"}
+
+const cartSelect = "SELECT id, user_id, COALESCE\\(token, ''\\), COALESCE\\(coupon_code, ''\\), updated_at FROM carts"
+
+func TestCartModel_Get(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := CartModel{DB: db}
+	now := time.Now()
+
+	// Test case 1: Totals use current prices and flag changed ones
+	t.Run("totals and price changes", func(t *testing.T) {
+		mock.ExpectQuery(cartSelect + " WHERE id = \\$1").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(cartRowColumns).AddRow(1, 7, "", "", now))
+		mock.ExpectQuery("SELECT ci.product_id, p.name, .* FROM cart_items ci JOIN products p ON p.id = ci.product_id WHERE ci.cart_id = \\$1").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(cartItemRowColumns).
+				AddRow(1, "Gaming Laptop", "", 1, 1899.99, 1999.99, 5, 2, "standard", 0.0, 0.0).
+				AddRow(2, "Mouse", "", 3, 19.99, 19.99, 2, 3, "standard", 0.0, 0.0))
+		mock.ExpectQuery("SELECT .* FROM promotions WHERE active AND code IS NULL").
+			WillReturnRows(sqlmock.NewRows(promotionRowColumns))
+		mock.ExpectQuery("SELECT promotion_id, COUNT\\(\\*\\) FROM promotion_redemptions WHERE user_id = \\$1").
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"promotion_id", "count"}))
+
+		cart, err := model.Get(1)
+		assert.NoError(t, err)
+		assert.Equal(t, 7, *cart.UserID)
+		assert.Len(t, cart.Items, 2)
+		assert.True(t, cart.Items[0].PriceChanged)
+		assert.False(t, cart.Items[1].PriceChanged)
+		assert.False(t, cart.Items[1].InStock)
+		assert.Equal(t, 59.97, cart.Items[1].LineTotal)
+		assert.Equal(t, 4, cart.ItemCount)
+		assert.Equal(t, 1959.96, cart.Subtotal)
+		assert.Equal(t, 1959.96, cart.Total)
+		assert.Empty(t, cart.Discounts)
+		assert.True(t, cart.PriceChanged)
+	})
+
+	// Test case 2: Automatic promotion and coupon make up the discount breakdown
+	t.Run("promotions and coupon", func(t *testing.T) {
+		mock.ExpectQuery(cartSelect + " WHERE id = \\$1").
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows(cartRowColumns).AddRow(2, 7, "", "TEN", now))
+		mock.ExpectQuery("SELECT ci.product_id, p.name, .* FROM cart_items ci").
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows(cartItemRowColumns).
+				AddRow(1, "Mouse Pad", "", 2, 15.0, 15.0, 10, 3, "standard", 0.0, 0.0).
+				AddRow(2, "Keyboard", "", 1, 70.0, 70.0, 10, 3, "standard", 0.0, 0.0))
+		mock.ExpectQuery("SELECT .* FROM promotions WHERE active AND code IS NULL").
+			WillReturnRows(sqlmock.NewRows(promotionRowColumns).
+				AddRow(promotionRow(20, "Buy 2 mouse pads, get 10% off", "", "percentage", 10, 2, "{1}", 0)...))
+		mock.ExpectQuery("SELECT .* FROM promotions WHERE active AND code = \\$1").
+			WithArgs("TEN").
+			WillReturnRows(sqlmock.NewRows(promotionRowColumns).
+				AddRow(promotionRow(1, "Ten off", "TEN", "fixed_amount", 10, 0, "{}", 1)...))
+		mock.ExpectQuery("SELECT promotion_id, COUNT\\(\\*\\) FROM promotion_redemptions WHERE user_id = \\$1").
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"promotion_id", "count"}))
+
+		cart, err := model.Get(2)
+		assert.NoError(t, err)
+		assert.Equal(t, 100.0, cart.Subtotal)
+		assert.Len(t, cart.Discounts, 2)
+		assert.Equal(t, 3.0, cart.Discounts[0].Amount)
+		assert.Equal(t, "TEN", cart.Discounts[1].Code)
+		assert.Equal(t, 13.0, cart.Discount)
+		assert.Equal(t, 87.0, cart.Total)
+		assert.Empty(t, cart.CouponError)
+	})
+
+	// Test case 3: A coupon that no longer applies is reported and ignored
+	t.Run("invalid coupon", func(t *testing.T) {
+		mock.ExpectQuery(cartSelect + " WHERE id = \\$1").
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows(cartRowColumns).AddRow(3, nil, "guest-token", "GONE", now))
+		mock.ExpectQuery("SELECT ci.product_id, p.name, .* FROM cart_items ci").
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows(cartItemRowColumns).AddRow(1, "Mouse Pad", "", 1, 15.0, 15.0, 10, 3, "standard", 0.0, 0.0))
+		mock.ExpectQuery("SELECT .* FROM promotions WHERE active AND code IS NULL").
+			WillReturnRows(sqlmock.NewRows(promotionRowColumns))
+		mock.ExpectQuery("SELECT .* FROM promotions WHERE active AND code = \\$1").
+			WithArgs("GONE").
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery("SELECT .* FROM promotions WHERE active AND code IS NULL").
+			WillReturnRows(sqlmock.NewRows(promotionRowColumns))
+
+		cart, err := model.Get(3)
+		assert.NoError(t, err)
+		assert.Equal(t, "coupon GONE does not exist", cart.CouponError)
+		assert.Equal(t, 15.0, cart.Total)
+	})
+
+	// Test case 4: Cart not found
+	t.Run("cart not found", func(t *testing.T) {
+		mock.ExpectQuery(cartSelect + " WHERE id = \\$1").
+			WithArgs(999).
+			WillReturnError(sql.ErrNoRows)
+
+		cart, err := model.Get(999)
+		assert.Nil(t, cart)
+		assert.Equal(t, "cart not found", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCart_ApplyTax(t *testing.T) {
+	cart := &Cart{
+		Items: []CartItem{
+			{ProductID: 1, Quantity: 1, UnitPrice: 119, TaxClass: "standard"},
+			{ProductID: 2, Quantity: 2, UnitPrice: 10, TaxClass: "zero"},
+		},
+	}
+	cart.computeTotals()
+	cart.Items[0].Discount = 11.9
+	cart.Total = 127.1
+
+	// Test case 1: Inclusive prices show the tax without changing the total
+	t.Run("inclusive", func(t *testing.T) {
+		calc := &tax.TableCalculator{Rates: vat, Mode: tax.Inclusive, Rounding: tax.PerLine}
+		err := cart.ApplyTax(context.Background(), calc, tax.Address{Country: "DE"})
+		assert.NoError(t, err)
+		assert.Equal(t, 17.1, cart.Tax)
+		assert.True(t, cart.TaxIncluded)
+		assert.Equal(t, 127.1, cart.Total)
+		assert.Equal(t, []tax.RateTotal{{Name: "VAT", Rate: 19, Taxable: 90, Tax: 17.1}}, cart.Taxes)
+	})
+
+	// Test case 2: No calculator leaves the cart untaxed
+	t.Run("no calculator", func(t *testing.T) {
+		empty := &Cart{}
+		err := empty.ApplyTax(context.Background(), nil, tax.Address{Country: "DE"})
+		assert.NoError(t, err)
+		assert.Equal(t, []tax.RateTotal{}, empty.Taxes)
+	})
+}
+
+func TestCartModel_AddItem(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := CartModel{DB: db}
+
+	// Test case 1: Successful addition
+	t.Run("successful addition", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO cart_items \\(cart_id, product_id, quantity, unit_price\\) SELECT \\$1, id, \\$3, effective_price\\(products\\) FROM products WHERE id = \\$2 AND is_published\\(products\\)").
+			WithArgs(1, 2, 3).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE carts SET updated_at = NOW\\(\\) WHERE id = \\$1").
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := model.AddItem(1, 2, 3)
+		assert.NoError(t, err)
+	})
+
+	// Test case 2: Product not found
+	t.Run("product not found", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO cart_items").
+			WithArgs(1, 999, 1).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := model.AddItem(1, 999, 1)
+		assert.Equal(t, "product not found", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCartModel_SetItemQuantity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := CartModel{DB: db}
+
+	// Test case 1: Zero quantity removes the item
+	t.Run("zero removes item", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM cart_items WHERE cart_id = \\$1 AND product_id = \\$2").
+			WithArgs(1, 2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE carts SET updated_at = NOW\\(\\) WHERE id = \\$1").
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := model.SetItemQuantity(1, 2, 0)
+		assert.NoError(t, err)
+	})
+
+	// Test case 2: Item not in cart
+	t.Run("item not found", func(t *testing.T) {
+		mock.ExpectExec("UPDATE cart_items SET quantity = \\$3 WHERE cart_id = \\$1 AND product_id = \\$2").
+			WithArgs(1, 999, 2).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := model.SetItemQuantity(1, 999, 2)
+		assert.Equal(t, "item not found", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCartModel_Merge(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := CartModel{DB: db}
+
+	// Test case 1: Guest items move into the user's cart
+	t.Run("successful merge", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT id FROM carts WHERE token = \\$1 AND user_id IS NULL FOR UPDATE").
+			WithArgs("guest-token").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+		mock.ExpectQuery("INSERT INTO carts \\(user_id, coupon_code\\) SELECT \\$1, coupon_code FROM carts WHERE id = \\$2 ON CONFLICT \\(user_id\\)").
+			WithArgs(7, 5).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectExec("INSERT INTO cart_items .* SELECT \\$1, product_id, quantity, unit_price FROM cart_items WHERE cart_id = \\$2").
+			WithArgs(3, 5).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("DELETE FROM carts WHERE id = \\$1").
+			WithArgs(5).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := model.Merge("guest-token", 7)
+		assert.NoError(t, err)
+	})
+
+	// Test case 2: Unknown token is ignored
+	t.Run("unknown token", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT id FROM carts WHERE token = \\$1 AND user_id IS NULL FOR UPDATE").
+			WithArgs("stale").
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		err := model.Merge("stale", 7)
+		assert.NoError(t, err)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// Category groups products in the catalog
+type Category struct {
+	ID   int    `json:"id" example:"1"`
+	Name string `json:"name" example:"Peripherals"`
+}
+
+// CategoryModelInterface defines the methods that a category model must implement
+type CategoryModelInterface interface {
+	GetAll() ([]Category, error)
+	Get(id int) (*Category, error)
+	Create(category *Category) error
+	Update(category *Category) error
+	Delete(id int) error
+}
+
+type CategoryModel struct {
+	DB *sql.DB
+}
+
+func (m CategoryModel) GetAll() ([]Category, error) {
+	stmt := `SELECT id, name FROM categories ORDER BY name`
+
+	rows, err := m.DB.Query(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []Category{}
+	for rows.Next() {
+		var category Category
+		if err := rows.Scan(&category.ID, &category.Name); err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, rows.Err()
+}
+
+func (m CategoryModel) Get(id int) (*Category, error) {
+	stmt := `SELECT id, name FROM categories WHERE id = $1`
+
+	var category Category
+	err := m.DB.QueryRow(stmt, id).Scan(&category.ID, &category.Name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("category not found")
+		}
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+func (m CategoryModel) Create(category *Category) error {
+	stmt := `INSERT INTO categories (name) VALUES ($1) RETURNING id`
+
+	return m.DB.QueryRow(stmt, category.Name).Scan(&category.ID)
+}
+
+func (m CategoryModel) Update(category *Category) error {
+	stmt := `UPDATE categories SET name = $1 WHERE id = $2`
+
+	result, err := m.DB.Exec(stmt, category.Name, category.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("category not found")
+	}
+
+	return nil
+}
+
+// Delete removes a category; its products become uncategorized
+func (m CategoryModel) Delete(id int) error {
+	stmt := `DELETE FROM categories WHERE id = $1`
+
+	result, err := m.DB.Exec(stmt, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("category not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategoryModel_Get(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := CategoryModel{DB: db}
+
+	// Test case 1: Successful retrieval
+	t.Run("successful retrieval", func(t *testing.T) {
+		mock.ExpectQuery("SELECT id, name FROM categories WHERE id = \\$1").
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "Peripherals"))
+
+		category, err := model.Get(2)
+		assert.NoError(t, err)
+		assert.Equal(t, "Peripherals", category.Name)
+	})
+
+	// Test case 2: Category not found
+	t.Run("category not found", func(t *testing.T) {
+		mock.ExpectQuery("SELECT id, name FROM categories WHERE id = \\$1").
+			WithArgs(999).
+			WillReturnError(sql.ErrNoRows)
+
+		category, err := model.Get(999)
+		assert.Nil(t, category)
+		assert.Equal(t, "category not found", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCategoryModel_Delete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := CategoryModel{DB: db}
+
+	// Test case 1: Successful deletion
+	t.Run("successful deletion", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM categories WHERE id = \\$1").
+			WithArgs(2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, model.Delete(2))
+	})
+
+	// Test case 2: Category not found
+	t.Run("category not found", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM categories WHERE id = \\$1").
+			WithArgs(999).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := model.Delete(999)
+		assert.Equal(t, "category not found", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
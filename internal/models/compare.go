@@ -0,0 +1,115 @@
+package models
+
+import (
+	"reflect"
+	"sort"
+)
+
+// MaxComparedProducts is the most products a comparison can hold
+const MaxComparedProducts = 4
+
+// ComparedProduct heads a column of a comparison
+type ComparedProduct struct {
+	ID        int    `json:"id" example:"1"`
+	Name      string `json:"name" example:"Gaming Monitor"`
+	Slug      string `json:"slug" example:"gaming-monitor"`
+	ImagePath string `json:"image_path,omitempty" example:"/images/monitor.jpg"`
+}
+
+// ComparisonRow holds one field or attribute of the compared products, with
+// a value per product in column order; null where a product has none
+type ComparisonRow struct {
+	// Key is the product field, or "attributes." and the attribute's code
+	Key     string        `json:"key" example:"attributes.refresh_rate"`
+	Label   string        `json:"label" example:"Refresh rate"`
+	Unit    string        `json:"unit,omitempty" example:"Hz"`
+	Values  []interface{} `json:"values" swaggertype:"array,object"`
+	Differs bool          `json:"differs" example:"true"`
+}
+
+// Comparison lays products side by side
+type Comparison struct {
+	Products []ComparedProduct `json:"products"`
+	Rows     []ComparisonRow   `json:"rows"`
+}
+
+// comparedField is a product field shown in comparisons
+type comparedField struct {
+	key, label, unit string
+	value            func(p *Product) interface{}
+}
+
+// comparedFields are the product fields compared, in display order. Unknown
+// measurements, stored as 0, compare as null.
+var comparedFields = []comparedField{
+	{"effective_price", "Price", "", func(p *Product) interface{} { return p.EffectivePrice }},
+	{"price", "Regular price", "", func(p *Product) interface{} { return p.Price }},
+	{"rating_average", "Rating", "", func(p *Product) interface{} { return p.RatingAverage }},
+	{"rating_count", "Reviews", "", func(p *Product) interface{} { return p.RatingCount }},
+	{"in_stock", "In stock", "", func(p *Product) interface{} { return p.Stock > 0 }},
+	{"category_id", "Category", "", func(p *Product) interface{} {
+		if p.CategoryID == nil {
+			return nil
+		}
+		return *p.CategoryID
+	}},
+	{"weight", "Weight", "kg", func(p *Product) interface{} { return knownMeasure(p.Weight) }},
+	{"length", "Length", "cm", func(p *Product) interface{} { return knownMeasure(p.Length) }},
+	{"width", "Width", "cm", func(p *Product) interface{} { return knownMeasure(p.Width) }},
+	{"height", "Height", "cm", func(p *Product) interface{} { return knownMeasure(p.Height) }},
+}
+
+func knownMeasure(v float64) interface{} {
+	if v == 0 {
+		return nil
+	}
+	return v
+}
+
+// CompareProducts builds the comparison of products, in the given order.
+// Rows cover the compared product fields, then the attributes defined by
+// the products' categories (defs) in display order. Rows without a value
+// for any product are left out.
+func CompareProducts(products []Product, defs []AttributeDefinition) *Comparison {
+	comparison := &Comparison{Products: make([]ComparedProduct, len(products)), Rows: []ComparisonRow{}}
+	for i, p := range products {
+		comparison.Products[i] = ComparedProduct{ID: p.ID, Name: p.Name, Slug: p.Slug, ImagePath: p.ImagePath}
+	}
+
+	add := func(key, label, unit string, value func(p *Product) interface{}) {
+		row := ComparisonRow{Key: key, Label: label, Unit: unit, Values: make([]interface{}, len(products))}
+		known := false
+		for i := range products {
+			row.Values[i] = value(&products[i])
+			known = known || row.Values[i] != nil
+			row.Differs = row.Differs || !reflect.DeepEqual(row.Values[i], row.Values[0])
+		}
+		if known {
+			comparison.Rows = append(comparison.Rows, row)
+		}
+	}
+
+	for _, f := range comparedFields {
+		add(f.key, f.label, f.unit, f.value)
+	}
+
+	// Categories may share attribute codes; the first definition labels the row
+	defs = append([]AttributeDefinition(nil), defs...)
+	sort.SliceStable(defs, func(i, j int) bool {
+		if defs[i].Position != defs[j].Position {
+			return defs[i].Position < defs[j].Position
+		}
+		return defs[i].Code < defs[j].Code
+	})
+	seen := make(map[string]bool)
+	for _, d := range defs {
+		if seen[d.Code] {
+			continue
+		}
+		seen[d.Code] = true
+		code := d.Code
+		add("attributes."+code, d.Name, d.Unit, func(p *Product) interface{} { return p.Attributes[code] })
+	}
+
+	return comparison
+}
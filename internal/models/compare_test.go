@@ -0,0 +1,69 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareProducts(t *testing.T) {
+	monitors, keyboards := 3, 4
+	products := []Product{
+		{ID: 5, Name: "Gaming Monitor", Slug: "gaming-monitor", EffectivePrice: 299.99, Price: 299.99, Stock: 4, CategoryID: &monitors,
+			Attributes: map[string]interface{}{"refresh_rate": 144.0, "resolution": "2560x1440"}},
+		{ID: 1, Name: "Office Monitor", Slug: "office-monitor", EffectivePrice: 149.99, Price: 179.99, Stock: 0, CategoryID: &monitors,
+			Attributes: map[string]interface{}{"refresh_rate": 60.0, "resolution": "2560x1440"}},
+		{ID: 4, Name: "Mechanical Keyboard", Slug: "mechanical-keyboard", EffectivePrice: 89.99, Price: 89.99, Stock: 9, CategoryID: &keyboards,
+			Attributes: map[string]interface{}{"switch_type": "tactile"}},
+	}
+	defs := []AttributeDefinition{
+		{CategoryID: 3, Code: "resolution", Name: "Resolution", Type: AttributeString, Position: 0},
+		{CategoryID: 3, Code: "refresh_rate", Name: "Refresh rate", Type: AttributeUnit, Unit: "Hz", Position: 1},
+		{CategoryID: 3, Code: "curved", Name: "Curved", Type: AttributeBoolean, Position: 2},
+		{CategoryID: 4, Code: "switch_type", Name: "Switch type", Type: AttributeEnum, Options: []string{"linear", "tactile"}, Position: 0},
+	}
+
+	comparison := CompareProducts(products, defs)
+
+	assert.Equal(t, []ComparedProduct{
+		{ID: 5, Name: "Gaming Monitor", Slug: "gaming-monitor"},
+		{ID: 1, Name: "Office Monitor", Slug: "office-monitor"},
+		{ID: 4, Name: "Mechanical Keyboard", Slug: "mechanical-keyboard"},
+	}, comparison.Products)
+
+	rows := make(map[string]ComparisonRow)
+	var keys []string
+	for _, row := range comparison.Rows {
+		rows[row.Key] = row
+		keys = append(keys, row.Key)
+	}
+
+	// Fields first, then attributes by position and code; rows nobody has a
+	// value for (measurements, curved) are left out
+	assert.Equal(t, []string{"effective_price", "price", "rating_average", "rating_count", "in_stock", "category_id",
+		"attributes.resolution", "attributes.switch_type", "attributes.refresh_rate"}, keys)
+
+	assert.Equal(t, []interface{}{299.99, 149.99, 89.99}, rows["effective_price"].Values)
+	assert.True(t, rows["effective_price"].Differs)
+	assert.Equal(t, []interface{}{0.0, 0.0, 0.0}, rows["rating_average"].Values)
+	assert.False(t, rows["rating_average"].Differs)
+	assert.Equal(t, []interface{}{true, false, true}, rows["in_stock"].Values)
+
+	assert.Equal(t, ComparisonRow{Key: "attributes.refresh_rate", Label: "Refresh rate", Unit: "Hz", Values: []interface{}{144.0, 60.0, nil}, Differs: true},
+		rows["attributes.refresh_rate"])
+	assert.Equal(t, []interface{}{"2560x1440", "2560x1440", nil}, rows["attributes.resolution"].Values)
+	assert.True(t, rows["attributes.resolution"].Differs)
+}
+
+func TestCompareProducts_Same(t *testing.T) {
+	products := []Product{
+		{ID: 1, Name: "Hammer", EffectivePrice: 29.99, Price: 29.99, Weight: 0.8},
+		{ID: 2, Name: "Hammer", EffectivePrice: 29.99, Price: 29.99, Weight: 0.8},
+	}
+
+	comparison := CompareProducts(products, nil)
+	for _, row := range comparison.Rows {
+		assert.False(t, row.Differs, row.Key)
+	}
+	assert.Equal(t, "weight", comparison.Rows[len(comparison.Rows)-1].Key)
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// DBTX is implemented by both *sql.DB and *sql.Tx, so model queries can run
+// either directly against the database or inside a transaction
+type DBTX interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// requireOne checks that a statement touched a row, returning notFound as an
+// error when it touched none
+func requireOne(result sql.Result, notFound string) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New(notFound)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// priceBucketBounds are the lower bounds of the price facet buckets; the last
+// bucket is open-ended
+var priceBucketBounds = []float64{0, 50, 100, 250, 500, 1000}
+
+// FacetCount is the number of products sharing a facet value
+type FacetCount struct {
+	Value string `json:"value" example:"rgb"`
+	Label string `json:"label,omitempty" example:"Peripherals"`
+	Count int    `json:"count" example:"3"`
+}
+
+// PriceBucketCount is the number of products priced in [Min, Max). Max is
+// omitted for the open-ended top bucket.
+type PriceBucketCount struct {
+	Min   float64  `json:"min" example:"50"`
+	Max   *float64 `json:"max,omitempty" example:"100"`
+	Count int      `json:"count" example:"4"`
+}
+
+// ProductFacets summarizes the products matching a filter, for building filter sidebars
+type ProductFacets struct {
+	Tags         []FacetCount       `json:"tags"`
+	Categories   []FacetCount       `json:"categories"`
+	PriceBuckets []PriceBucketCount `json:"price_buckets"`
+}
+
+// Facets counts the products matching filter per tag, category and price bucket
+func (m ProductModel) Facets(filter ProductFilter) (*ProductFacets, error) {
+	where, args := filter.where()
+	matching := `SELECT id FROM products` + where
+
+	facets := &ProductFacets{
+		Tags:         []FacetCount{},
+		Categories:   []FacetCount{},
+		PriceBuckets: []PriceBucketCount{},
+	}
+
+	stmt := `
+		SELECT t.name, COUNT(*)
+		FROM product_tags pt
+		JOIN tags t ON t.id = pt.tag_id
+		WHERE pt.product_id IN (` + matching + `)
+		GROUP BY t.name
+		ORDER BY COUNT(*) DESC, t.name`
+
+	rows, err := m.conn().Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var fc FacetCount
+		if err := rows.Scan(&fc.Value, &fc.Count); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		facets.Tags = append(facets.Tags, fc)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stmt = `
+		SELECT c.id, c.name, COUNT(*)
+		FROM products p
+		JOIN categories c ON c.id = p.category_id
+		WHERE p.id IN (` + matching + `)
+		GROUP BY c.id, c.name
+		ORDER BY COUNT(*) DESC, c.name`
+
+	rows, err = m.conn().Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id int
+		var fc FacetCount
+		if err := rows.Scan(&id, &fc.Label, &fc.Count); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		fc.Value = strconv.Itoa(id)
+		facets.Categories = append(facets.Categories, fc)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stmt = `SELECT ` + priceBucketCase() + ` AS bucket, COUNT(*) FROM products` + where + ` GROUP BY bucket ORDER BY bucket`
+
+	rows, err = m.conn().Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var bucket, count int
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return nil, err
+		}
+		pb := PriceBucketCount{Min: priceBucketBounds[bucket], Count: count}
+		if bucket+1 < len(priceBucketBounds) {
+			max := priceBucketBounds[bucket+1]
+			pb.Max = &max
+		}
+		facets.PriceBuckets = append(facets.PriceBuckets, pb)
+	}
+
+	return facets, rows.Err()
+}
+
+// priceBucketCase returns a SQL expression mapping price to its index in priceBucketBounds
+func priceBucketCase() string {
+	var b strings.Builder
+	b.WriteString("CASE")
+	for i := 1; i < len(priceBucketBounds); i++ {
+		fmt.Fprintf(&b, " WHEN effective_price(products) < %s THEN %d", strconv.FormatFloat(priceBucketBounds[i], 'f', -1, 64), i-1)
+	}
+	fmt.Fprintf(&b, " ELSE %d END", len(priceBucketBounds)-1)
+	return b.String()
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProductModel_Facets(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := ProductModel{DB: db}
+
+	mock.ExpectQuery("SELECT t.name, COUNT\\(\\*\\) FROM product_tags pt .+ WHERE pt.product_id IN \\(SELECT id FROM products WHERE effective_price\\(products\\) <= \\$1\\)").
+		WithArgs(200.0).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "count"}).
+			AddRow("rgb", 3).
+			AddRow("wireless", 1))
+	mock.ExpectQuery("SELECT c.id, c.name, COUNT\\(\\*\\) FROM products p JOIN categories c .+ WHERE p.id IN \\(SELECT id FROM products WHERE effective_price\\(products\\) <= \\$1\\)").
+		WithArgs(200.0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "count"}).
+			AddRow(2, "Peripherals", 4))
+	mock.ExpectQuery("SELECT CASE WHEN effective_price\\(products\\) < 50 THEN 0 WHEN effective_price\\(products\\) < 100 THEN 1 WHEN effective_price\\(products\\) < 250 THEN 2 WHEN effective_price\\(products\\) < 500 THEN 3 WHEN effective_price\\(products\\) < 1000 THEN 4 ELSE 5 END AS bucket, COUNT\\(\\*\\) FROM products WHERE effective_price\\(products\\) <= \\$1 GROUP BY bucket").
+		WithArgs(200.0).
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "count"}).
+			AddRow(0, 1).
+			AddRow(1, 1).
+			AddRow(2, 3))
+
+	facets, err := model.Facets(ProductFilter{MaxPrice: 200})
+	assert.NoError(t, err)
+	assert.Equal(t, []FacetCount{{Value: "rgb", Count: 3}, {Value: "wireless", Count: 1}}, facets.Tags)
+	assert.Equal(t, []FacetCount{{Value: "2", Label: "Peripherals", Count: 4}}, facets.Categories)
+	assert.Len(t, facets.PriceBuckets, 3)
+	assert.Equal(t, 50.0, facets.PriceBuckets[1].Min)
+	assert.Equal(t, 100.0, *facets.PriceBuckets[1].Max)
+	assert.Equal(t, 3, facets.PriceBuckets[2].Count)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
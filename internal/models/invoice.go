@@ -0,0 +1,115 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// InvoiceKind tells invoices and credit notes apart. Each kind has its own
+// number series.
+type InvoiceKind string
+
+const (
+	InvoiceKindInvoice    InvoiceKind = "invoice"
+	InvoiceKindCreditNote InvoiceKind = "credit_note"
+)
+
+// invoicePrefixes start the invoice numbers of each kind
+var invoicePrefixes = map[InvoiceKind]string{
+	InvoiceKindInvoice:    "INV",
+	InvoiceKindCreditNote: "CN",
+}
+
+// Invoice is an invoice or credit note issued for an order. Numbers run
+// without gaps per kind and year, e.g. INV-2024-000042.
+type Invoice struct {
+	ID       int         `json:"id" example:"1"`
+	OrderID  int         `json:"order_id" example:"11"`
+	Kind     InvoiceKind `json:"kind" example:"invoice"`
+	Number   string      `json:"number" example:"INV-2024-000042"`
+	Year     int         `json:"year" example:"2024"`
+	Sequence int         `json:"sequence" example:"42"`
+	// Credits is the number of the invoice a credit note reverses
+	Credits  string    `json:"credits,omitempty" example:""`
+	Total    float64   `json:"total" example:"1710"`
+	IssuedAt time.Time `json:"issued_at"`
+}
+
+// InvoiceModelInterface defines the methods that an invoice model must implement
+type InvoiceModelInterface interface {
+	Get(orderID int, kind InvoiceKind) (*Invoice, error)
+}
+
+type InvoiceModel struct {
+	DB *sql.DB
+}
+
+// invoiceKind returns the document an order moving to s is issued
+func (s OrderStatus) invoiceKind() (InvoiceKind, bool) {
+	switch s {
+	case OrderPaid:
+		return InvoiceKindInvoice, true
+	case OrderRefunded:
+		return InvoiceKindCreditNote, true
+	}
+	return "", false
+}
+
+// issueInvoice numbers and stores an invoice or credit note for an order.
+// It must run in the transaction that changes the order's status: the
+// sequence row stays locked until that commits, and a rollback returns the
+// number, so numbers have no gaps.
+func issueInvoice(tx DBTX, orderID int, kind InvoiceKind, at time.Time) (*Invoice, error) {
+	invoice := &Invoice{OrderID: orderID, Kind: kind, Year: at.Year(), IssuedAt: at}
+
+	stmt := `
+		INSERT INTO invoice_sequences (kind, year, last_number) VALUES ($1, $2, 1)
+		ON CONFLICT (kind, year) DO UPDATE SET last_number = invoice_sequences.last_number + 1
+		RETURNING last_number`
+	if err := tx.QueryRow(stmt, string(kind), invoice.Year).Scan(&invoice.Sequence); err != nil {
+		return nil, err
+	}
+	invoice.Number = fmt.Sprintf("%s-%d-%06d", invoicePrefixes[kind], invoice.Year, invoice.Sequence)
+
+	var credits sql.NullInt64
+	if kind == InvoiceKindCreditNote {
+		stmt = `SELECT id, number FROM invoices WHERE order_id = $1 AND kind = $2`
+		err := tx.QueryRow(stmt, orderID, string(InvoiceKindInvoice)).Scan(&credits, &invoice.Credits)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	stmt = `
+		INSERT INTO invoices (order_id, kind, number, year, sequence, credits_invoice_id, total, issued_at)
+		SELECT id, $2, $3, $4, $5, $6, total, $7 FROM orders WHERE id = $1
+		RETURNING id, total`
+	err := tx.QueryRow(stmt, orderID, string(kind), invoice.Number, invoice.Year, invoice.Sequence, credits, at).
+		Scan(&invoice.ID, &invoice.Total)
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// Get returns the invoice or credit note of an order
+func (m InvoiceModel) Get(orderID int, kind InvoiceKind) (*Invoice, error) {
+	stmt := `
+		SELECT i.id, i.order_id, i.kind, i.number, i.year, i.sequence, COALESCE(c.number, ''), i.total, i.issued_at
+		FROM invoices i
+		LEFT JOIN invoices c ON c.id = i.credits_invoice_id
+		WHERE i.order_id = $1 AND i.kind = $2`
+
+	var invoice Invoice
+	err := m.DB.QueryRow(stmt, orderID, string(kind)).Scan(&invoice.ID, &invoice.OrderID, &invoice.Kind, &invoice.Number,
+		&invoice.Year, &invoice.Sequence, &invoice.Credits, &invoice.Total, &invoice.IssuedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("invoice not found")
+		}
+		return nil, err
+	}
+	return &invoice, nil
+}
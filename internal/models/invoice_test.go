@@ -0,0 +1,95 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIssueInvoice(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	at := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	// Test case 1: The first invoice of a year starts the series at 1
+	t.Run("first invoice of the year", func(t *testing.T) {
+		mock.ExpectQuery("INSERT INTO invoice_sequences").
+			WithArgs("invoice", 2024).
+			WillReturnRows(sqlmock.NewRows([]string{"last_number"}).AddRow(1))
+		mock.ExpectQuery("INSERT INTO invoices .* SELECT id, \\$2, \\$3, \\$4, \\$5, \\$6, total, \\$7 FROM orders WHERE id = \\$1").
+			WithArgs(11, "invoice", "INV-2024-000001", 2024, 1, sql.NullInt64{}, at).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "total"}).AddRow(1, 1710.0))
+
+		invoice, err := issueInvoice(db, 11, InvoiceKindInvoice, at)
+		assert.NoError(t, err)
+		assert.Equal(t, "INV-2024-000001", invoice.Number)
+		assert.Equal(t, 1710.0, invoice.Total)
+	})
+
+	// Test case 2: A credit note has its own series and names the invoice it reverses
+	t.Run("credit note", func(t *testing.T) {
+		mock.ExpectQuery("INSERT INTO invoice_sequences").
+			WithArgs("credit_note", 2024).
+			WillReturnRows(sqlmock.NewRows([]string{"last_number"}).AddRow(3))
+		mock.ExpectQuery("SELECT id, number FROM invoices WHERE order_id = \\$1 AND kind = \\$2").
+			WithArgs(11, "invoice").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "number"}).AddRow(1, "INV-2024-000001"))
+		mock.ExpectQuery("INSERT INTO invoices").
+			WithArgs(11, "credit_note", "CN-2024-000003", 2024, 3, sql.NullInt64{Int64: 1, Valid: true}, at).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "total"}).AddRow(2, 1710.0))
+
+		invoice, err := issueInvoice(db, 11, InvoiceKindCreditNote, at)
+		assert.NoError(t, err)
+		assert.Equal(t, "CN-2024-000003", invoice.Number)
+		assert.Equal(t, "INV-2024-000001", invoice.Credits)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestInvoiceModel_Get(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := InvoiceModel{DB: db}
+	columns := []string{"id", "order_id", "kind", "number", "year", "sequence", "credits", "total", "issued_at"}
+
+	// Test case 1: The invoice of an order is found
+	t.Run("found", func(t *testing.T) {
+		mock.ExpectQuery("SELECT .* FROM invoices i LEFT JOIN invoices c").
+			WithArgs(11, "invoice").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 11, "invoice", "INV-2024-000001", 2024, 1, "", 1710.0, time.Now()))
+
+		invoice, err := model.Get(11, InvoiceKindInvoice)
+		assert.NoError(t, err)
+		assert.Equal(t, InvoiceKindInvoice, invoice.Kind)
+		assert.Equal(t, "INV-2024-000001", invoice.Number)
+	})
+
+	// Test case 2: An unpaid order has no invoice
+	t.Run("not found", func(t *testing.T) {
+		mock.ExpectQuery("SELECT .* FROM invoices i").
+			WithArgs(12, "invoice").
+			WillReturnError(sql.ErrNoRows)
+
+		invoice, err := model.Get(12, InvoiceKindInvoice)
+		assert.Nil(t, invoice)
+		assert.Equal(t, "invoice not found", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
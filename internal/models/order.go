@@ -0,0 +1,726 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"garage-api/internal/promotion"
+	"garage-api/internal/shipping"
+	"garage-api/internal/tax"
+)
+
+// OrderStatus is the lifecycle state of an order
+type OrderStatus string
+
+const (
+	OrderPending   OrderStatus = "pending"
+	OrderPaid      OrderStatus = "paid"
+	OrderShipped   OrderStatus = "shipped"
+	OrderDelivered OrderStatus = "delivered"
+	OrderCancelled OrderStatus = "cancelled"
+	OrderRefunded  OrderStatus = "refunded"
+)
+
+// orderTransitions lists the states each state may move to. Cancelled and
+// refunded orders are final.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderPending:   {OrderPaid, OrderCancelled},
+	OrderPaid:      {OrderShipped, OrderCancelled, OrderRefunded},
+	OrderShipped:   {OrderDelivered, OrderRefunded},
+	OrderDelivered: {OrderRefunded},
+}
+
+// Valid reports whether s is a known order status
+func (s OrderStatus) Valid() bool {
+	switch s {
+	case OrderPending, OrderPaid, OrderShipped, OrderDelivered, OrderCancelled, OrderRefunded:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo reports whether an order in state s may move to next
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// releasesStock reports whether moving to s puts the reserved stock back on
+// the shelf. Refunds do not: goods that were shipped come back through returns.
+func (s OrderStatus) releasesStock() bool {
+	return s == OrderCancelled
+}
+
+// OrderItem is a line of an order, snapshotting the product as it was sold.
+// ProductID is nil once the product has been deleted from the catalog.
+type OrderItem struct {
+	ID        int     `json:"id" example:"1"`
+	ProductID *int    `json:"product_id,omitempty" example:"1"`
+	SKU       string  `json:"sku,omitempty" example:"LAP-001"`
+	Name      string  `json:"name" example:"Gaming Laptop"`
+	UnitPrice float64 `json:"unit_price" example:"1899.99"`
+	Quantity  int     `json:"quantity" example:"1"`
+	LineTotal float64 `json:"line_total" example:"1899.99"`
+	// Discount is this line's share of the order's promotions
+	Discount float64 `json:"discount" example:"189.99"`
+	// TaxRate is in percent; Tax is the tax of the discounted line
+	TaxRate float64 `json:"tax_rate" example:"19"`
+	Tax     float64 `json:"tax" example:"272.99"`
+}
+
+// Order is a purchase made by a user from the contents of their cart
+type Order struct {
+	ID           int         `json:"id" example:"1"`
+	UserID       int         `json:"user_id" example:"1"`
+	Status       OrderStatus `json:"status" example:"pending"`
+	Items        []OrderItem `json:"items"`
+	Subtotal     float64     `json:"subtotal" example:"1899.99"`
+	Discount     float64     `json:"discount" example:"189.99"`
+	CouponCode   string      `json:"coupon_code,omitempty" example:"SUMMER10"`
+	FreeShipping bool        `json:"free_shipping" example:"false"`
+	Tax          float64     `json:"tax" example:"272.99"`
+	// PricesIncludeTax records the pricing mode at checkout: when set, Tax
+	// is contained in the prices rather than added to the total
+	PricesIncludeTax bool   `json:"prices_include_tax" example:"true"`
+	TaxCountry       string `json:"tax_country,omitempty" example:"DE"`
+	TaxRegion        string `json:"tax_region,omitempty" example:""`
+	// ShippingMethodID is nil when the order ships without a method, or
+	// once the method has been deleted; ShippingMethod keeps its name
+	ShippingMethodID *int    `json:"shipping_method_id,omitempty" example:"1"`
+	ShippingMethod   string  `json:"shipping_method,omitempty" example:"Standard"`
+	ShippingCost     float64 `json:"shipping_cost" example:"6.9"`
+	Total            float64 `json:"total" example:"1716.9"`
+	// ShippingAddress and BillingAddress are copies taken at checkout
+	ShippingAddress *OrderAddress `json:"shipping_address,omitempty"`
+	BillingAddress  *OrderAddress `json:"billing_address,omitempty"`
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+}
+
+// StockShortage describes a cart line that cannot be fulfilled
+type StockShortage struct {
+	ProductID int    `json:"product_id" example:"1"`
+	Name      string `json:"name" example:"Gaming Laptop"`
+	Requested int    `json:"requested" example:"3"`
+	Available int    `json:"available" example:"1"`
+}
+
+// StockError is returned by Checkout when some products do not have enough
+// stock for the quantities in the cart
+type StockError struct {
+	Shortages []StockShortage
+}
+
+func (e *StockError) Error() string {
+	return "insufficient stock"
+}
+
+// UnavailableProduct is a cart line whose product is no longer published
+type UnavailableProduct struct {
+	ProductID int    `json:"product_id" example:"1"`
+	Name      string `json:"name" example:"Gaming Laptop"`
+}
+
+// UnavailableError is returned by Checkout when some products in the cart
+// were archived or taken back to draft since they were added
+type UnavailableError struct {
+	Products []UnavailableProduct
+}
+
+func (e *UnavailableError) Error() string {
+	return "products not available"
+}
+
+// TransitionError is returned when an order is asked to move to a state its
+// current state does not allow
+type TransitionError struct {
+	From OrderStatus
+	To   OrderStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot move order from %s to %s", e.From, e.To)
+}
+
+// CheckoutOptions are the choices a shopper makes at checkout
+type CheckoutOptions struct {
+	// ShippingAddress is where the order ships to; it decides tax and
+	// shipping. Without one, Address is used instead.
+	ShippingAddress *OrderAddress
+	BillingAddress  *OrderAddress
+	Address         tax.Address
+	// ShippingMethodID picks one of the shipping options for Address. It
+	// is required whenever a shipping zone covers the address.
+	ShippingMethodID int
+}
+
+// OrderFilter narrows down order listings. Zero values are ignored.
+type OrderFilter struct {
+	UserID int
+	Status OrderStatus
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+}
+
+func (f OrderFilter) where() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+
+	if f.UserID > 0 {
+		args = append(args, f.UserID)
+		conds = append(conds, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if f.Status != "" {
+		args = append(args, string(f.Status))
+		conds = append(conds, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if !f.From.IsZero() {
+		args = append(args, f.From)
+		conds = append(conds, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if !f.To.IsZero() {
+		args = append(args, f.To)
+		conds = append(conds, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// OrderModelInterface defines the methods that an order model must implement
+type OrderModelInterface interface {
+	Checkout(userID int, opts CheckoutOptions) (*Order, error)
+	Get(id int) (*Order, error)
+	List(filter OrderFilter) ([]Order, error)
+	UpdateStatus(id int, status OrderStatus) (*Order, error)
+}
+
+type OrderModel struct {
+	DB *sql.DB
+	// Tax computes the tax of new orders; orders are untaxed when nil
+	Tax tax.TaxCalculator
+}
+
+const orderColumns = `id, user_id, status, subtotal, discount, COALESCE(coupon_code, ''), free_shipping,
+	tax, prices_include_tax, COALESCE(tax_country, ''), COALESCE(tax_region, ''),
+	shipping_method_id, COALESCE(shipping_method, ''), shipping_cost, total, shipping_address, billing_address, created_at, updated_at`
+
+func scanOrder(row rowScanner, order *Order) error {
+	var shippingAddress, billingAddress []byte
+	err := row.Scan(&order.ID, &order.UserID, &order.Status, &order.Subtotal, &order.Discount, &order.CouponCode, &order.FreeShipping,
+		&order.Tax, &order.PricesIncludeTax, &order.TaxCountry, &order.TaxRegion,
+		&order.ShippingMethodID, &order.ShippingMethod, &order.ShippingCost, &order.Total, &shippingAddress, &billingAddress, &order.CreatedAt, &order.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if order.ShippingAddress, err = unmarshalOrderAddress(shippingAddress); err != nil {
+		return err
+	}
+	order.BillingAddress, err = unmarshalOrderAddress(billingAddress)
+	return err
+}
+
+// recordStatus appends a state change to the order's history
+func recordStatus(db DBTX, orderID int, from, to OrderStatus) error {
+	var prev interface{}
+	if from != "" {
+		prev = string(from)
+	}
+	_, err := db.Exec(`INSERT INTO order_status_history (order_id, from_status, to_status) VALUES ($1, $2, $3)`, orderID, prev, string(to))
+	return err
+}
+
+// lockCartProducts locks every product row a checkout of the cart writes:
+// the cart's products, the components its bundles take stock from, and the
+// bundles whose derived stock and price follow those. Rows are locked in id
+// order so concurrent checkouts acquire them in the same order and cannot
+// deadlock, including on the rows the bundle triggers update.
+func lockCartProducts(tx DBTX, cartID int) error {
+	stmt := `
+		WITH moved AS (
+			SELECT ci.product_id AS id FROM cart_items ci WHERE ci.cart_id = $1
+			UNION
+			SELECT bi.component_id FROM cart_items ci JOIN bundle_items bi ON bi.bundle_id = ci.product_id WHERE ci.cart_id = $1
+		)
+		SELECT p.id FROM products p
+		WHERE p.id IN (SELECT id FROM moved UNION SELECT bi.bundle_id FROM bundle_items bi JOIN moved ON moved.id = bi.component_id)
+		ORDER BY p.id
+		FOR UPDATE`
+	_, err := tx.Exec(stmt, cartID)
+	return err
+}
+
+// bundleShortages checks the stock of the components of the cart's bundles.
+// A component is short when the bundles, together with the component bought
+// on its own, need more than it has, even if each line fits on its own.
+// Products already reported short are left out.
+func bundleShortages(tx DBTX, cartID int, items []OrderItem, reported []StockShortage) ([]StockShortage, error) {
+	stmt := `
+		SELECT c.id, c.name, c.stock, SUM(ci.quantity * bi.quantity)
+		FROM cart_items ci
+		JOIN bundle_items bi ON bi.bundle_id = ci.product_id
+		JOIN products c ON c.id = bi.component_id
+		WHERE ci.cart_id = $1
+		GROUP BY c.id, c.name, c.stock
+		ORDER BY c.id`
+	rows, err := tx.Query(stmt, cartID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	direct := make(map[int]int, len(items))
+	for _, item := range items {
+		direct[*item.ProductID] += item.Quantity
+	}
+	short := make(map[int]bool, len(reported))
+	for _, s := range reported {
+		short[s.ProductID] = true
+	}
+
+	var shortages []StockShortage
+	for rows.Next() {
+		var s StockShortage
+		if err := rows.Scan(&s.ProductID, &s.Name, &s.Available, &s.Requested); err != nil {
+			return nil, err
+		}
+		s.Requested += direct[s.ProductID]
+		if s.Requested > s.Available && !short[s.ProductID] {
+			shortages = append(shortages, s)
+		}
+	}
+	return shortages, rows.Err()
+}
+
+// Checkout turns the user's cart into a pending order. Stock for every line is
+// reserved in the same transaction, with the product rows locked so that
+// concurrent checkouts cannot oversell; bundles reserve their components'
+// stock. Promotions and the cart's coupon are
+// applied and redeemed; a coupon that no longer applies fails the checkout
+// with a *promotion.CouponError. Shipping is charged for the chosen method;
+// shipping itself is not taxed. The cart is emptied on success.
+func (m OrderModel) Checkout(userID int, opts CheckoutOptions) (*Order, error) {
+	addr := opts.Address
+	if opts.ShippingAddress != nil {
+		addr = tax.Address{Country: opts.ShippingAddress.Country, Region: opts.ShippingAddress.Region}
+	}
+	addr = addr.Normalize()
+
+	shippingAddress, err := marshalOrderAddress(opts.ShippingAddress)
+	if err != nil {
+		return nil, err
+	}
+	billingAddress, err := marshalOrderAddress(opts.BillingAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var cartID int
+	var couponCode string
+	err = tx.QueryRow(`SELECT id, COALESCE(coupon_code, '') FROM carts WHERE user_id = $1 FOR UPDATE`, userID).Scan(&cartID, &couponCode)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("cart is empty")
+		}
+		return nil, err
+	}
+
+	if err := lockCartProducts(tx, cartID); err != nil {
+		return nil, err
+	}
+
+	stmt := `
+		SELECT p.id, p.name, COALESCE(p.sku, ''), effective_price(p), p.stock, ci.quantity, COALESCE(p.category_id, 0), p.tax_class,
+			p.weight, p.length * p.width * p.height, is_published(p)
+		FROM cart_items ci
+		JOIN products p ON p.id = ci.product_id
+		WHERE ci.cart_id = $1
+		ORDER BY p.id`
+
+	rows, err := tx.Query(stmt, cartID)
+	if err != nil {
+		return nil, err
+	}
+
+	order := &Order{UserID: userID, Status: OrderPending, Items: []OrderItem{}, ShippingAddress: opts.ShippingAddress, BillingAddress: opts.BillingAddress}
+	var shortages []StockShortage
+	var unavailable []UnavailableProduct
+	var lines []promotion.Line
+	var taxClasses []string
+	var parcel shipping.Parcel
+	for rows.Next() {
+		var item OrderItem
+		var productID, stock, categoryID int
+		var taxClass string
+		var weight, volume float64
+		var published bool
+		err := rows.Scan(&productID, &item.Name, &item.SKU, &item.UnitPrice, &stock, &item.Quantity, &categoryID, &taxClass, &weight, &volume, &published)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if !published {
+			unavailable = append(unavailable, UnavailableProduct{ProductID: productID, Name: item.Name})
+		} else if stock < item.Quantity {
+			shortages = append(shortages, StockShortage{ProductID: productID, Name: item.Name, Requested: item.Quantity, Available: stock})
+		}
+		item.ProductID = &productID
+		item.LineTotal = RoundMoney(item.UnitPrice * float64(item.Quantity))
+		order.Items = append(order.Items, item)
+		lines = append(lines, promotion.Line{ProductID: productID, CategoryID: categoryID, Quantity: item.Quantity, UnitPrice: item.UnitPrice})
+		taxClasses = append(taxClasses, taxClass)
+		parcel.Weight += shipping.BillableWeight(weight, volume) * float64(item.Quantity)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(order.Items) == 0 {
+		return nil, errors.New("cart is empty")
+	}
+	componentShortages, err := bundleShortages(tx, cartID, order.Items, shortages)
+	if err != nil {
+		return nil, err
+	}
+	shortages = append(shortages, componentShortages...)
+	if len(unavailable) > 0 {
+		return nil, &UnavailableError{Products: unavailable}
+	}
+	if len(shortages) > 0 {
+		return nil, &StockError{Shortages: shortages}
+	}
+
+	discounts, err := evaluatePromotions(tx, lines, couponCode, userID, time.Now(), true)
+	if err != nil {
+		return nil, err
+	}
+	for i := range order.Items {
+		order.Items[i].Discount = discounts.LineDiscounts[i]
+	}
+	order.Subtotal = discounts.Subtotal
+	order.Discount = discounts.Discount
+	order.CouponCode = couponCode
+	order.FreeShipping = discounts.FreeShipping
+	order.Total = discounts.Total
+
+	if m.Tax != nil {
+		if err := order.applyTax(m.Tax, taxClasses, addr); err != nil {
+			return nil, err
+		}
+	}
+
+	parcel.Subtotal = RoundMoney(order.Subtotal - order.Discount)
+	options, err := quoteShipping(tx, addr.Country, addr.Region, parcel, order.FreeShipping)
+	if err != nil {
+		return nil, err
+	}
+	if err := order.applyShipping(options, opts.ShippingMethodID); err != nil {
+		return nil, err
+	}
+
+	stmt = `
+		UPDATE products p SET stock = p.stock - ci.quantity
+		FROM cart_items ci
+		WHERE ci.cart_id = $1 AND p.id = ci.product_id`
+	if _, err := tx.Exec(stmt, cartID); err != nil {
+		// The checks above hold the stock under lock; should a product
+		// still go below zero, it is a shortage rather than a failure
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23514" && pqErr.Constraint == "products_stock_check" {
+			return nil, &StockError{Shortages: []StockShortage{}}
+		}
+		return nil, err
+	}
+
+	stmt = `
+		INSERT INTO orders (user_id, status, subtotal, discount, coupon_code, free_shipping,
+			tax, prices_include_tax, tax_country, tax_region, shipping_method_id, shipping_method, shipping_cost, total,
+			shipping_address, billing_address)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''), $11, NULLIF($12, ''), $13, $14, $15, $16)
+		RETURNING id, created_at, updated_at`
+	err = tx.QueryRow(stmt, userID, string(order.Status), order.Subtotal, order.Discount, order.CouponCode, order.FreeShipping,
+		order.Tax, order.PricesIncludeTax, order.TaxCountry, order.TaxRegion, order.ShippingMethodID, order.ShippingMethod, order.ShippingCost, order.Total,
+		shippingAddress, billingAddress).
+		Scan(&order.ID, &order.CreatedAt, &order.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt = `
+		INSERT INTO order_items (order_id, product_id, sku, name, unit_price, quantity, line_total, discount, tax_rate, tax)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id`
+	for i := range order.Items {
+		item := &order.Items[i]
+		err := tx.QueryRow(stmt, order.ID, *item.ProductID, item.SKU, item.Name, item.UnitPrice, item.Quantity, item.LineTotal, item.Discount,
+			item.TaxRate, item.Tax).Scan(&item.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	stmt = `INSERT INTO promotion_redemptions (promotion_id, order_id, user_id, amount) VALUES ($1, $2, $3, $4)`
+	for _, applied := range discounts.Applied {
+		if _, err := tx.Exec(stmt, applied.PromotionID, order.ID, userID, applied.Amount); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := recordStatus(tx, order.ID, "", order.Status); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM cart_items WHERE cart_id = $1`, cartID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`UPDATE carts SET coupon_code = NULL WHERE id = $1`, cartID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// applyShipping charges the chosen shipping option. A method is required
+// when any is available; when none is, the order ships without one.
+func (o *Order) applyShipping(options []shipping.Option, methodID int) error {
+	if len(options) == 0 && methodID == 0 {
+		return nil
+	}
+	if methodID == 0 {
+		return errors.New("shipping method is required")
+	}
+
+	for _, option := range options {
+		if option.MethodID == methodID {
+			id := option.MethodID
+			o.ShippingMethodID = &id
+			o.ShippingMethod = option.Name
+			o.ShippingCost = option.Cost
+			o.Total = RoundMoney(o.Total + option.Cost)
+			return nil
+		}
+	}
+	return errors.New("shipping method not available")
+}
+
+// applyTax computes the tax of a priced and discounted order. Tax is added
+// to the total unless prices already include it.
+func (o *Order) applyTax(calc tax.TaxCalculator, classes []string, addr tax.Address) error {
+	addr = addr.Normalize()
+	lines := make([]tax.Line, len(o.Items))
+	for i, item := range o.Items {
+		lines[i] = tax.Line{Class: classes[i], Amount: RoundMoney(item.LineTotal - item.Discount)}
+	}
+
+	result, err := calc.Calculate(context.Background(), tax.Request{Address: addr, Lines: lines, At: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	for i := range o.Items {
+		o.Items[i].TaxRate = result.Lines[i].Rate
+		o.Items[i].Tax = result.Lines[i].Tax
+	}
+	o.Tax = result.Tax
+	o.PricesIncludeTax = result.Inclusive
+	o.TaxCountry = addr.Country
+	o.TaxRegion = addr.Region
+	if !result.Inclusive {
+		o.Total = RoundMoney(o.Total + result.Tax)
+	}
+	return nil
+}
+
+// Get returns an order with its items
+func (m OrderModel) Get(id int) (*Order, error) {
+	var order Order
+	stmt := `SELECT ` + orderColumns + ` FROM orders WHERE id = $1`
+	if err := scanOrder(m.DB.QueryRow(stmt, id), &order); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("order not found")
+		}
+		return nil, err
+	}
+
+	orders := []Order{order}
+	if err := m.loadItems(orders); err != nil {
+		return nil, err
+	}
+	return &orders[0], nil
+}
+
+// List returns the orders matching filter, newest first, with their items
+func (m OrderModel) List(filter OrderFilter) ([]Order, error) {
+	where, args := filter.where()
+	stmt := `SELECT ` + orderColumns + ` FROM orders` + where + ` ORDER BY created_at DESC, id DESC`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		stmt += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		stmt += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []Order{}
+	for rows.Next() {
+		var order Order
+		if err := scanOrder(rows, &order); err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := m.loadItems(orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// loadItems fills in the items of orders with a single query
+func (m OrderModel) loadItems(orders []Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(orders))
+	index := make(map[int]int, len(orders))
+	for i := range orders {
+		ids[i] = int64(orders[i].ID)
+		index[orders[i].ID] = i
+		orders[i].Items = []OrderItem{}
+	}
+
+	stmt := `
+		SELECT id, order_id, product_id, COALESCE(sku, ''), name, unit_price, quantity, line_total, discount, tax_rate, tax
+		FROM order_items
+		WHERE order_id = ANY($1)
+		ORDER BY id`
+
+	rows, err := m.DB.Query(stmt, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item OrderItem
+		var orderID int
+		var productID sql.NullInt64
+		err := rows.Scan(&item.ID, &orderID, &productID, &item.SKU, &item.Name, &item.UnitPrice, &item.Quantity, &item.LineTotal, &item.Discount, &item.TaxRate, &item.Tax)
+		if err != nil {
+			return err
+		}
+		if productID.Valid {
+			id := int(productID.Int64)
+			item.ProductID = &id
+		}
+		if i, ok := index[orderID]; ok {
+			orders[i].Items = append(orders[i].Items, item)
+		}
+	}
+
+	return rows.Err()
+}
+
+// UpdateStatus moves an order to a new state, enforcing the allowed
+// transitions. Cancelling an order returns its reserved stock. Paying an
+// order issues its invoice, and refunding it a credit note.
+func (m OrderModel) UpdateStatus(id int, status OrderStatus) (*Order, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := changeOrderStatus(tx, id, status); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return m.Get(id)
+}
+
+// changeOrderStatus does the work of UpdateStatus inside tx, so that other
+// models can move an order along with their own changes
+func changeOrderStatus(tx DBTX, id int, status OrderStatus) error {
+	var current OrderStatus
+	err := tx.QueryRow(`SELECT status FROM orders WHERE id = $1 FOR UPDATE`, id).Scan(&current)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("order not found")
+		}
+		return err
+	}
+
+	if !current.CanTransitionTo(status) {
+		return &TransitionError{From: current, To: status}
+	}
+
+	if status.releasesStock() {
+		stmt := `
+			UPDATE products p SET stock = p.stock + oi.quantity
+			FROM order_items oi
+			WHERE oi.order_id = $1 AND p.id = oi.product_id`
+		if _, err := tx.Exec(stmt, id); err != nil {
+			return err
+		}
+
+		// The promotions the order used no longer count towards their limits
+		if _, err := tx.Exec(`DELETE FROM promotion_redemptions WHERE order_id = $1`, id); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE orders SET status = $2, updated_at = NOW() WHERE id = $1`, id, string(status)); err != nil {
+		return err
+	}
+
+	if err := recordStatus(tx, id, current, status); err != nil {
+		return err
+	}
+
+	if kind, ok := status.invoiceKind(); ok {
+		if _, err := issueInvoice(tx, id, kind, time.Now()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,570 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"garage-api/internal/tax"
+)
+
+const orderSelect = "SELECT id, user_id, status, subtotal, discount, COALESCE\\(coupon_code, ''\\), free_shipping,\\s+tax, prices_include_tax, COALESCE\\(tax_country, ''\\), COALESCE\\(tax_region, ''\\),\\s+shipping_method_id, COALESCE\\(shipping_method, ''\\), shipping_cost, total, shipping_address, billing_address, created_at, updated_at FROM orders"
+
+var orderRowColumns = []string{"id", "user_id", "status", "subtotal", "discount", "coupon_code", "free_shipping", "tax", "prices_include_tax", "tax_country", "tax_region", "shipping_method_id", "shipping_method", "shipping_cost", "total", "shipping_address", "billing_address", "created_at", "updated_at"}
+var orderItemRowColumns = []string{"id", "order_id", "product_id", "sku", "name", "unit_price", "quantity", "line_total", "discount", "tax_rate", "tax"}
+
+func TestOrderStatus_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from, to OrderStatus
+		want     bool
+	}{
+		{OrderPending, OrderPaid, true},
+		{OrderPending, OrderCancelled, true},
+		{OrderPending, OrderShipped, false},
+		{OrderPaid, OrderShipped, true},
+		{OrderPaid, OrderRefunded, true},
+		{OrderPaid, OrderPending, false},
+		{OrderShipped, OrderDelivered, true},
+		{OrderShipped, OrderCancelled, false},
+		{OrderDelivered, OrderRefunded, true},
+		{OrderCancelled, OrderPaid, false},
+		{OrderRefunded, OrderPaid, false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.from.CanTransitionTo(tt.to), "%s -> %s", tt.from, tt.to)
+	}
+}
+
+// vat taxes standard-class products shipped to Germany
+var vat = tax.Table{{Country: "DE", Class: "standard", Name: "VAT", Rate: 19, EffectiveFrom: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}}
+
+// expectShippingRates expects the shipping zones and methods to be loaded:
+// Germany is in the Europe zone, served by weight-priced parcels and a
+// flat-rate express
+func expectShippingRates(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("SELECT id, name, countries FROM shipping_zones ORDER BY id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "countries"}).
+			AddRow(1, "Domestic", "{US}").
+			AddRow(2, "Europe", "{DE,FR}"))
+	mock.ExpectQuery("SELECT id, zone_id, name, type, cost, tiers, free_over, active FROM shipping_methods WHERE active ORDER BY id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "zone_id", "name", "type", "cost", "tiers", "free_over", "active"}).
+			AddRow(3, 1, "Ground", "flat", 4.99, "[]", 0.0, true).
+			AddRow(4, 2, "Parcel", "weight", 0.0, `[{"up_to": 5, "cost": 6.9}, {"up_to": 0, "cost": 12.9}]`, 0.0, true).
+			AddRow(5, 2, "Express", "flat", 19.9, "[]", 0.0, true))
+}
+
+var bundleComponentColumns = []string{"id", "name", "stock", "quantity"}
+
+// expectPricedCart expects a checkout to lock and price a one-line cart
+// without promotions
+func expectPricedCart(mock sqlmock.Sqlmock, userID int) {
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, COALESCE\\(coupon_code, ''\\) FROM carts WHERE user_id = \\$1 FOR UPDATE").
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "coupon_code"}).AddRow(5, ""))
+	mock.ExpectExec("WITH moved AS .+ SELECT p.id FROM products p .+ ORDER BY p.id\\s+FOR UPDATE").
+		WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT p.id, p.name, .* FROM cart_items ci").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "sku", "price", "stock", "quantity", "category_id", "tax_class", "weight", "volume", "published"}).
+			AddRow(2, "Mouse", "", 19.99, 10, 1, 3, "standard", 0.1, 0.0, true))
+	mock.ExpectQuery("SELECT c.id, c.name, c.stock, SUM\\(ci.quantity \\* bi.quantity\\)\\s+FROM cart_items ci\\s+JOIN bundle_items bi").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(bundleComponentColumns))
+	mock.ExpectExec("SELECT id FROM promotions\\s+WHERE active AND \\(code = \\$1 OR \\(code IS NULL AND \\(usage_limit > 0 OR per_user_limit > 0\\).+ORDER BY id\\s+FOR UPDATE").
+		WithArgs("", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT .* FROM promotions WHERE active AND code IS NULL").
+		WillReturnRows(sqlmock.NewRows(promotionRowColumns))
+	mock.ExpectQuery("SELECT promotion_id, COUNT\\(\\*\\) FROM promotion_redemptions WHERE user_id = \\$1").
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"promotion_id", "count"}))
+}
+
+func TestOrderModel_Checkout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := OrderModel{DB: db, Tax: &tax.TableCalculator{Rates: vat, Mode: tax.Exclusive, Rounding: tax.PerLine}}
+	germany := tax.Address{Country: "de"}
+	parcelID := 4
+	// The shipping address decides tax and shipping
+	berlin := &OrderAddress{Name: "Jane Doe", Line1: "Torstraße 1", City: "Berlin", PostalCode: "10119", Country: "DE"}
+	berlinJSON := `{"name":"Jane Doe","line1":"Torstraße 1","city":"Berlin","postal_code":"10119","country":"DE"}`
+	now := time.Now()
+	cartLineColumns := []string{"id", "name", "sku", "price", "stock", "quantity", "category_id", "tax_class", "weight", "volume", "published"}
+
+	// Test case 1: Successful checkout reserves stock, redeems the coupon and empties the cart
+	t.Run("successful checkout", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT id, COALESCE\\(coupon_code, ''\\) FROM carts WHERE user_id = \\$1 FOR UPDATE").
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "coupon_code"}).AddRow(3, "TEN"))
+		mock.ExpectExec("WITH moved AS .+ SELECT p.id FROM products p .+ ORDER BY p.id\\s+FOR UPDATE").
+			WithArgs(3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT p.id, p.name, .* FROM cart_items ci JOIN products p ON p.id = ci.product_id WHERE ci.cart_id = \\$1 ORDER BY p.id").
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows(cartLineColumns).
+				AddRow(1, "Gaming Laptop", "LAP-001", 1899.99, 5, 1, 2, "standard", 2.5, 50*35*5.0, true).
+				AddRow(2, "Mouse", "", 19.99, 10, 3, 3, "standard", 0.1, 0.0, true))
+		mock.ExpectQuery("SELECT c.id, c.name, c.stock, SUM\\(ci.quantity \\* bi.quantity\\)\\s+FROM cart_items ci\\s+JOIN bundle_items bi").
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows(bundleComponentColumns))
+		mock.ExpectExec("SELECT id FROM promotions\\s+WHERE active AND \\(code = \\$1 OR \\(code IS NULL AND \\(usage_limit > 0 OR per_user_limit > 0\\).+ORDER BY id\\s+FOR UPDATE").
+			WithArgs("TEN", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT .* FROM promotions WHERE active AND code IS NULL").
+			WillReturnRows(sqlmock.NewRows(promotionRowColumns))
+		mock.ExpectQuery("SELECT .* FROM promotions WHERE active AND code = \\$1").
+			WithArgs("TEN").
+			WillReturnRows(sqlmock.NewRows(promotionRowColumns).
+				AddRow(promotionRow(1, "Ten off", "TEN", "fixed_amount", 10, 0, "{}", 1)...))
+		mock.ExpectQuery("SELECT promotion_id, COUNT\\(\\*\\) FROM promotion_redemptions WHERE user_id = \\$1").
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"promotion_id", "count"}))
+		expectShippingRates(mock)
+		mock.ExpectExec("UPDATE products p SET stock = p.stock - ci.quantity FROM cart_items ci WHERE ci.cart_id = \\$1").
+			WithArgs(3).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery("INSERT INTO orders \\(user_id, status, subtotal, discount, coupon_code, free_shipping,\\s+tax, prices_include_tax, tax_country, tax_region, shipping_method_id, shipping_method, shipping_cost, total,\\s+shipping_address, billing_address\\) VALUES .* RETURNING id, created_at, updated_at").
+			WithArgs(7, "pending", 1959.96, 10.0, "TEN", false, 370.5, false, "DE", "", &parcelID, "Parcel", 6.9, 2327.36, []byte(berlinJSON), nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(11, now, now))
+		mock.ExpectQuery("INSERT INTO order_items").
+			WithArgs(11, 1, "LAP-001", "Gaming Laptop", 1899.99, 1, 1899.99, 9.69, 19.0, 359.16).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(21))
+		mock.ExpectQuery("INSERT INTO order_items").
+			WithArgs(11, 2, "", "Mouse", 19.99, 3, 59.97, 0.31, 19.0, 11.34).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(22))
+		mock.ExpectExec("INSERT INTO promotion_redemptions \\(promotion_id, order_id, user_id, amount\\)").
+			WithArgs(1, 11, 7, 10.0).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO order_status_history \\(order_id, from_status, to_status\\)").
+			WithArgs(11, nil, "pending").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("DELETE FROM cart_items WHERE cart_id = \\$1").
+			WithArgs(3).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("UPDATE carts SET coupon_code = NULL WHERE id = \\$1").
+			WithArgs(3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		order, err := model.Checkout(7, CheckoutOptions{ShippingAddress: berlin, ShippingMethodID: parcelID})
+		assert.NoError(t, err)
+		assert.Equal(t, 11, order.ID)
+		assert.Equal(t, OrderPending, order.Status)
+		assert.Len(t, order.Items, 2)
+		assert.Equal(t, 22, order.Items[1].ID)
+		assert.Equal(t, 10.0, order.Discount)
+		assert.Equal(t, 370.5, order.Tax)
+		assert.Equal(t, "Parcel", order.ShippingMethod)
+		assert.Equal(t, 6.9, order.ShippingCost)
+		assert.Equal(t, 2327.36, order.Total)
+		assert.Equal(t, berlin, order.ShippingAddress)
+	})
+
+	// Test case 2: Insufficient stock rolls back and reports the shortages
+	t.Run("insufficient stock", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT id, COALESCE\\(coupon_code, ''\\) FROM carts WHERE user_id = \\$1 FOR UPDATE").
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "coupon_code"}).AddRow(3, ""))
+		mock.ExpectExec("WITH moved AS .+ SELECT p.id FROM products p .+ ORDER BY p.id\\s+FOR UPDATE").
+			WithArgs(3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT p.id, p.name, .* FROM cart_items ci").
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows(cartLineColumns).
+				AddRow(1, "Gaming Laptop", "LAP-001", 1899.99, 1, 2, 2, "standard", 2.5, 0.0, true))
+		mock.ExpectQuery("SELECT c.id, c.name, c.stock, SUM\\(ci.quantity \\* bi.quantity\\)\\s+FROM cart_items ci\\s+JOIN bundle_items bi").
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows(bundleComponentColumns))
+		mock.ExpectRollback()
+
+		order, err := model.Checkout(7, CheckoutOptions{Address: germany})
+		assert.Nil(t, order)
+		var stockErr *StockError
+		assert.True(t, errors.As(err, &stockErr))
+		assert.Equal(t, []StockShortage{{ProductID: 1, Name: "Gaming Laptop", Requested: 2, Available: 1}}, stockErr.Shortages)
+	})
+
+	// Test case 3: Products no longer published cannot be bought
+	t.Run("unpublished product", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT id, COALESCE\\(coupon_code, ''\\) FROM carts WHERE user_id = \\$1 FOR UPDATE").
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "coupon_code"}).AddRow(3, ""))
+		mock.ExpectExec("WITH moved AS .+ SELECT p.id FROM products p .+ ORDER BY p.id\\s+FOR UPDATE").
+			WithArgs(3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT p.id, p.name, .*, is_published\\(p\\)\\s+FROM cart_items ci").
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows(cartLineColumns).
+				AddRow(1, "Gaming Laptop", "LAP-001", 1899.99, 5, 1, 2, "standard", 2.5, 0.0, false).
+				AddRow(2, "Mouse", "", 19.99, 10, 3, 3, "standard", 0.1, 0.0, true))
+		mock.ExpectQuery("SELECT c.id, c.name, c.stock, SUM\\(ci.quantity \\* bi.quantity\\)\\s+FROM cart_items ci\\s+JOIN bundle_items bi").
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows(bundleComponentColumns))
+		mock.ExpectRollback()
+
+		order, err := model.Checkout(7, CheckoutOptions{Address: germany})
+		assert.Nil(t, order)
+		var unavailableErr *UnavailableError
+		assert.True(t, errors.As(err, &unavailableErr))
+		assert.Equal(t, []UnavailableProduct{{ProductID: 1, Name: "Gaming Laptop"}}, unavailableErr.Products)
+	})
+
+	// Test case 4: Empty cart
+	t.Run("empty cart", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT id, COALESCE\\(coupon_code, ''\\) FROM carts WHERE user_id = \\$1 FOR UPDATE").
+			WithArgs(8).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "coupon_code"}).AddRow(4, ""))
+		mock.ExpectExec("WITH moved AS .+ SELECT p.id FROM products p .+ ORDER BY p.id\\s+FOR UPDATE").
+			WithArgs(4).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT p.id, p.name, .* FROM cart_items ci").
+			WithArgs(4).
+			WillReturnRows(sqlmock.NewRows(cartLineColumns))
+		mock.ExpectRollback()
+
+		order, err := model.Checkout(8, CheckoutOptions{Address: germany})
+		assert.Nil(t, order)
+		assert.Equal(t, "cart is empty", err.Error())
+	})
+
+	// Test case 5: User without a cart
+	t.Run("no cart", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT id, COALESCE\\(coupon_code, ''\\) FROM carts WHERE user_id = \\$1 FOR UPDATE").
+			WithArgs(9).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		order, err := model.Checkout(9, CheckoutOptions{Address: germany})
+		assert.Nil(t, order)
+		assert.Equal(t, "cart is empty", err.Error())
+	})
+
+	// Test case 6: A method must be picked when the address has shipping options
+	t.Run("shipping method required", func(t *testing.T) {
+		expectPricedCart(mock, 10)
+		expectShippingRates(mock)
+		mock.ExpectRollback()
+
+		order, err := model.Checkout(10, CheckoutOptions{Address: germany})
+		assert.Nil(t, order)
+		assert.Equal(t, "shipping method is required", err.Error())
+	})
+
+	// Test case 7: The method does not ship to the address
+	t.Run("shipping method not available", func(t *testing.T) {
+		expectPricedCart(mock, 10)
+		expectShippingRates(mock)
+		mock.ExpectRollback()
+
+		order, err := model.Checkout(10, CheckoutOptions{Address: germany, ShippingMethodID: 99})
+		assert.Nil(t, order)
+		assert.Equal(t, "shipping method not available", err.Error())
+	})
+
+	// Test case 8: A bundle and its component bought together need more of
+	// the component than it has, though each line fits on its own
+	t.Run("bundle component shortage", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT id, COALESCE\\(coupon_code, ''\\) FROM carts WHERE user_id = \\$1 FOR UPDATE").
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "coupon_code"}).AddRow(3, ""))
+		mock.ExpectExec("WITH moved AS .+ SELECT p.id FROM products p .+ ORDER BY p.id\\s+FOR UPDATE").
+			WithArgs(3).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery("SELECT p.id, p.name, .* FROM cart_items ci").
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows(cartLineColumns).
+				AddRow(2, "Mouse", "", 19.99, 1, 1, 3, "standard", 0.1, 0.0, true).
+				AddRow(6, "Desk Set", "", 49.99, 1, 1, 3, "standard", 1.0, 0.0, true))
+		mock.ExpectQuery("SELECT c.id, c.name, c.stock, SUM\\(ci.quantity \\* bi.quantity\\)\\s+FROM cart_items ci\\s+JOIN bundle_items bi").
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows(bundleComponentColumns).AddRow(2, "Mouse", 1, 1))
+		mock.ExpectRollback()
+
+		order, err := model.Checkout(7, CheckoutOptions{Address: germany})
+		assert.Nil(t, order)
+		var stockErr *StockError
+		assert.True(t, errors.As(err, &stockErr))
+		assert.Equal(t, []StockShortage{{ProductID: 2, Name: "Mouse", Requested: 2, Available: 1}}, stockErr.Shortages)
+	})
+
+	// Test case 9: Stock going below zero when it is reserved is a shortage
+	t.Run("stock check violation", func(t *testing.T) {
+		expectPricedCart(mock, 10)
+		expectShippingRates(mock)
+		mock.ExpectExec("UPDATE products p SET stock = p.stock - ci.quantity FROM cart_items ci WHERE ci.cart_id = \\$1").
+			WithArgs(5).
+			WillReturnError(&pq.Error{Code: "23514", Constraint: "products_stock_check"})
+		mock.ExpectRollback()
+
+		order, err := model.Checkout(10, CheckoutOptions{Address: germany, ShippingMethodID: parcelID})
+		assert.Nil(t, order)
+		var stockErr *StockError
+		assert.True(t, errors.As(err, &stockErr))
+		assert.Empty(t, stockErr.Shortages)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestOrderModel_Get(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := OrderModel{DB: db}
+	now := time.Now()
+
+	// Test case 1: Successful retrieval, including an item whose product was deleted
+	t.Run("successful retrieval", func(t *testing.T) {
+		mock.ExpectQuery(orderSelect + " WHERE id = \\$1").
+			WithArgs(11).
+			WillReturnRows(sqlmock.NewRows(orderRowColumns).AddRow(11, 7, "paid", 1919.98, 0.0, "", false, 0.0, false, "", "", nil, "", 0.0, 1919.98,
+				[]byte(`{"name":"Jane Doe","line1":"Torstraße 1","city":"Berlin","postal_code":"10119","country":"DE"}`), nil, now, now))
+		mock.ExpectQuery("SELECT id, order_id, product_id, .* FROM order_items WHERE order_id = ANY\\(\\$1\\)").
+			WillReturnRows(sqlmock.NewRows(orderItemRowColumns).
+				AddRow(21, 11, 1, "LAP-001", "Gaming Laptop", 1899.99, 1, 1899.99, 0.0, 0.0, 0.0).
+				AddRow(22, 11, nil, "", "Mouse", 19.99, 1, 19.99, 0.0, 0.0, 0.0))
+
+		order, err := model.Get(11)
+		assert.NoError(t, err)
+		assert.Equal(t, OrderPaid, order.Status)
+		assert.Equal(t, "Berlin", order.ShippingAddress.City)
+		assert.Nil(t, order.BillingAddress)
+		assert.Len(t, order.Items, 2)
+		assert.Equal(t, 1, *order.Items[0].ProductID)
+		assert.Nil(t, order.Items[1].ProductID)
+	})
+
+	// Test case 2: Order not found
+	t.Run("order not found", func(t *testing.T) {
+		mock.ExpectQuery(orderSelect + " WHERE id = \\$1").
+			WithArgs(999).
+			WillReturnError(sql.ErrNoRows)
+
+		order, err := model.Get(999)
+		assert.Nil(t, order)
+		assert.Equal(t, "order not found", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestOrderModel_List(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := OrderModel{DB: db}
+	now := time.Now()
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Test case 1: Filters and paging become placeholders in order
+	t.Run("filtered list", func(t *testing.T) {
+		mock.ExpectQuery(orderSelect+" WHERE user_id = \\$1 AND status = \\$2 AND created_at >= \\$3 ORDER BY created_at DESC, id DESC LIMIT \\$4 OFFSET \\$5").
+			WithArgs(7, "shipped", from, 10, 20).
+			WillReturnRows(sqlmock.NewRows(orderRowColumns).
+				AddRow(12, 7, "shipped", 19.99, 0.0, "", false, 0.0, false, "", "", nil, "", 0.0, 19.99, nil, nil, now, now).
+				AddRow(11, 7, "shipped", 1899.99, 0.0, "", false, 0.0, false, "", "", nil, "", 0.0, 1899.99, nil, nil, now, now))
+		mock.ExpectQuery("SELECT id, order_id, product_id, .* FROM order_items WHERE order_id = ANY\\(\\$1\\)").
+			WillReturnRows(sqlmock.NewRows(orderItemRowColumns).
+				AddRow(21, 11, 1, "LAP-001", "Gaming Laptop", 1899.99, 1, 1899.99, 0.0, 0.0, 0.0).
+				AddRow(23, 12, 2, "", "Mouse", 19.99, 1, 19.99, 0.0, 0.0, 0.0))
+
+		orders, err := model.List(OrderFilter{UserID: 7, Status: OrderShipped, From: from, Limit: 10, Offset: 20})
+		assert.NoError(t, err)
+		assert.Len(t, orders, 2)
+		assert.Equal(t, "Mouse", orders[0].Items[0].Name)
+		assert.Equal(t, "Gaming Laptop", orders[1].Items[0].Name)
+	})
+
+	// Test case 2: No orders skips the item query
+	t.Run("empty list", func(t *testing.T) {
+		mock.ExpectQuery(orderSelect + " ORDER BY created_at DESC, id DESC").
+			WillReturnRows(sqlmock.NewRows(orderRowColumns))
+
+		orders, err := model.List(OrderFilter{})
+		assert.NoError(t, err)
+		assert.Empty(t, orders)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestOrderModel_UpdateStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := OrderModel{DB: db}
+	now := time.Now()
+
+	// Test case 1: Cancelling releases the reserved stock and the promotions used
+	t.Run("cancel releases stock", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT status FROM orders WHERE id = \\$1 FOR UPDATE").
+			WithArgs(11).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("pending"))
+		mock.ExpectExec("UPDATE products p SET stock = p.stock \\+ oi.quantity FROM order_items oi WHERE oi.order_id = \\$1").
+			WithArgs(11).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM promotion_redemptions WHERE order_id = \\$1").
+			WithArgs(11).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE orders SET status = \\$2, updated_at = NOW\\(\\) WHERE id = \\$1").
+			WithArgs(11, "cancelled").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO order_status_history").
+			WithArgs(11, "pending", "cancelled").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery(orderSelect + " WHERE id = \\$1").
+			WithArgs(11).
+			WillReturnRows(sqlmock.NewRows(orderRowColumns).AddRow(11, 7, "cancelled", 19.99, 0.0, "", false, 0.0, false, "", "", nil, "", 0.0, 19.99, nil, nil, now, now))
+		mock.ExpectQuery("SELECT id, order_id, product_id, .* FROM order_items").
+			WillReturnRows(sqlmock.NewRows(orderItemRowColumns).AddRow(21, 11, 2, "", "Mouse", 19.99, 1, 19.99, 0.0, 0.0, 0.0))
+
+		order, err := model.UpdateStatus(11, OrderCancelled)
+		assert.NoError(t, err)
+		assert.Equal(t, OrderCancelled, order.Status)
+	})
+
+	// Test case 2: Shipping leaves stock alone
+	t.Run("ship paid order", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT status FROM orders WHERE id = \\$1 FOR UPDATE").
+			WithArgs(12).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("paid"))
+		mock.ExpectExec("UPDATE orders SET status = \\$2, updated_at = NOW\\(\\) WHERE id = \\$1").
+			WithArgs(12, "shipped").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO order_status_history").
+			WithArgs(12, "paid", "shipped").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery(orderSelect + " WHERE id = \\$1").
+			WithArgs(12).
+			WillReturnRows(sqlmock.NewRows(orderRowColumns).AddRow(12, 7, "shipped", 19.99, 0.0, "", false, 0.0, false, "", "", nil, "", 0.0, 19.99, nil, nil, now, now))
+		mock.ExpectQuery("SELECT id, order_id, product_id, .* FROM order_items").
+			WillReturnRows(sqlmock.NewRows(orderItemRowColumns))
+
+		order, err := model.UpdateStatus(12, OrderShipped)
+		assert.NoError(t, err)
+		assert.Equal(t, OrderShipped, order.Status)
+	})
+
+	// Test case 3: Transition not allowed
+	t.Run("invalid transition", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT status FROM orders WHERE id = \\$1 FOR UPDATE").
+			WithArgs(13).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("delivered"))
+		mock.ExpectRollback()
+
+		order, err := model.UpdateStatus(13, OrderPending)
+		assert.Nil(t, order)
+		var transitionErr *TransitionError
+		assert.True(t, errors.As(err, &transitionErr))
+		assert.Equal(t, "cannot move order from delivered to pending", err.Error())
+	})
+
+	// Test case 4: Order not found
+	t.Run("order not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT status FROM orders WHERE id = \\$1 FOR UPDATE").
+			WithArgs(999).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		order, err := model.UpdateStatus(999, OrderPaid)
+		assert.Nil(t, order)
+		assert.Equal(t, "order not found", err.Error())
+	})
+
+	// Test case 5: Paying an order issues the next invoice number of the year
+	t.Run("paying issues an invoice", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT status FROM orders WHERE id = \\$1 FOR UPDATE").
+			WithArgs(14).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("pending"))
+		mock.ExpectExec("UPDATE orders SET status = \\$2, updated_at = NOW\\(\\) WHERE id = \\$1").
+			WithArgs(14, "paid").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO order_status_history").
+			WithArgs(14, "pending", "paid").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery("INSERT INTO invoice_sequences \\(kind, year, last_number\\) VALUES \\(\\$1, \\$2, 1\\) ON CONFLICT \\(kind, year\\) DO UPDATE").
+			WithArgs("invoice", now.Year()).
+			WillReturnRows(sqlmock.NewRows([]string{"last_number"}).AddRow(42))
+		mock.ExpectQuery("INSERT INTO invoices \\(order_id, kind, number, year, sequence, credits_invoice_id, total, issued_at\\)").
+			WithArgs(14, "invoice", fmt.Sprintf("INV-%d-000042", now.Year()), now.Year(), 42, sql.NullInt64{}, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "total"}).AddRow(5, 19.99))
+		mock.ExpectCommit()
+		mock.ExpectQuery(orderSelect + " WHERE id = \\$1").
+			WithArgs(14).
+			WillReturnRows(sqlmock.NewRows(orderRowColumns).AddRow(14, 7, "paid", 19.99, 0.0, "", false, 0.0, false, "", "", nil, "", 0.0, 19.99, nil, nil, now, now))
+		mock.ExpectQuery("SELECT id, order_id, product_id, .* FROM order_items").
+			WillReturnRows(sqlmock.NewRows(orderItemRowColumns))
+
+		order, err := model.UpdateStatus(14, OrderPaid)
+		assert.NoError(t, err)
+		assert.Equal(t, OrderPaid, order.Status)
+	})
+
+	// Test case 6: A failed invoice rolls the payment back, number included
+	t.Run("invoice failure rolls back", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT status FROM orders WHERE id = \\$1 FOR UPDATE").
+			WithArgs(15).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("pending"))
+		mock.ExpectExec("UPDATE orders SET status").
+			WithArgs(15, "paid").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO order_status_history").
+			WithArgs(15, "pending", "paid").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery("INSERT INTO invoice_sequences").
+			WillReturnRows(sqlmock.NewRows([]string{"last_number"}).AddRow(43))
+		mock.ExpectQuery("INSERT INTO invoices").
+			WillReturnError(errors.New("connection reset"))
+		mock.ExpectRollback()
+
+		order, err := model.UpdateStatus(15, OrderPaid)
+		assert.Nil(t, order)
+		assert.EqualError(t, err, "connection reset")
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Payment is an attempt to charge an order through a payment provider.
+// Status mirrors the provider's intent status.
+type Payment struct {
+	ID        int       `json:"id" example:"1"`
+	OrderID   int       `json:"order_id" example:"1"`
+	Provider  string    `json:"provider" example:"stripe"`
+	IntentID  string    `json:"intent_id" example:"pi_3MtwBwLkdIwHu7ix28a3tqPa"`
+	Status    string    `json:"status" example:"succeeded"`
+	Amount    float64   `json:"amount" example:"1899.99"`
+	Currency  string    `json:"currency" example:"USD"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PaymentModelInterface defines the methods that a payment model must implement
+type PaymentModelInterface interface {
+	Create(payment *Payment) error
+	GetByIntent(provider, intentID string) (*Payment, error)
+	GetLatestForOrder(orderID int, status string) (*Payment, error)
+	SetStatus(id int, status string) error
+	ClaimEvent(provider, eventID, eventType string) (bool, error)
+	MoveOrder(orderID int, status OrderStatus) error
+}
+
+// TxPaymentModelInterface is a PaymentModelInterface that can also run its
+// operations inside a database transaction
+type TxPaymentModelInterface interface {
+	PaymentModelInterface
+	Begin() (*sql.Tx, error)
+	WithTx(tx *sql.Tx) PaymentModelInterface
+}
+
+type PaymentModel struct {
+	DB *sql.DB
+	tx *sql.Tx
+}
+
+// Begin starts a new transaction on the underlying database
+func (m PaymentModel) Begin() (*sql.Tx, error) {
+	return m.DB.Begin()
+}
+
+// WithTx returns a copy of the model whose queries run inside tx
+func (m PaymentModel) WithTx(tx *sql.Tx) PaymentModelInterface {
+	return PaymentModel{DB: m.DB, tx: tx}
+}
+
+func (m PaymentModel) conn() DBTX {
+	if m.tx != nil {
+		return m.tx
+	}
+	return m.DB
+}
+
+const paymentColumns = `id, order_id, provider, intent_id, status, amount, currency, created_at, updated_at`
+
+func scanPayment(row rowScanner) (*Payment, error) {
+	var p Payment
+	err := row.Scan(&p.ID, &p.OrderID, &p.Provider, &p.IntentID, &p.Status, &p.Amount, &p.Currency, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("payment not found")
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Create stores a new payment. Creating the same provider intent twice
+// returns the stored payment instead.
+func (m PaymentModel) Create(payment *Payment) error {
+	stmt := `
+		INSERT INTO payments (order_id, provider, intent_id, status, amount, currency)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (provider, intent_id) DO UPDATE SET updated_at = NOW()
+		RETURNING id, created_at, updated_at`
+
+	return m.conn().QueryRow(stmt, payment.OrderID, payment.Provider, payment.IntentID, payment.Status, payment.Amount, payment.Currency).
+		Scan(&payment.ID, &payment.CreatedAt, &payment.UpdatedAt)
+}
+
+func (m PaymentModel) GetByIntent(provider, intentID string) (*Payment, error) {
+	stmt := `SELECT ` + paymentColumns + ` FROM payments WHERE provider = $1 AND intent_id = $2`
+
+	return scanPayment(m.conn().QueryRow(stmt, provider, intentID))
+}
+
+// GetLatestForOrder returns the most recent payment of an order in the given status
+func (m PaymentModel) GetLatestForOrder(orderID int, status string) (*Payment, error) {
+	stmt := `SELECT ` + paymentColumns + ` FROM payments WHERE order_id = $1 AND status = $2 ORDER BY created_at DESC, id DESC LIMIT 1`
+
+	return scanPayment(m.conn().QueryRow(stmt, orderID, status))
+}
+
+func (m PaymentModel) SetStatus(id int, status string) error {
+	stmt := `UPDATE payments SET status = $2, updated_at = NOW() WHERE id = $1`
+
+	result, err := m.conn().Exec(stmt, id, status)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("payment not found")
+	}
+
+	return nil
+}
+
+// ClaimEvent records a webhook event as handled and reports whether this
+// call recorded it. Claiming inside the transaction that applies the event
+// makes concurrent deliveries wait for each other: only one of them claims
+// the event, and a rolled back claim leaves it to the provider's retry.
+func (m PaymentModel) ClaimEvent(provider, eventID, eventType string) (bool, error) {
+	stmt := `INSERT INTO payment_events (provider, event_id, type) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+	result, err := m.conn().Exec(stmt, provider, eventID, eventType)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
+
+// MoveOrder changes the status of a payment's order, within the model's
+// transaction when it has one
+func (m PaymentModel) MoveOrder(orderID int, status OrderStatus) error {
+	if m.tx != nil {
+		return changeOrderStatus(m.tx, orderID, status)
+	}
+	_, err := OrderModel{DB: m.DB}.UpdateStatus(orderID, status)
+	return err
+}
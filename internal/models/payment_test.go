@@ -0,0 +1,141 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var paymentRowColumns = []string{"id", "order_id", "provider", "intent_id", "status", "amount", "currency", "created_at", "updated_at"}
+
+func TestPaymentModel_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := PaymentModel{DB: db}
+	now := time.Now()
+
+	mock.ExpectQuery("INSERT INTO payments \\(order_id, provider, intent_id, status, amount, currency\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6\\) ON CONFLICT \\(provider, intent_id\\)").
+		WithArgs(11, "stripe", "pi_1", "requires_action", 19.99, "USD").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(5, now, now))
+
+	payment := &Payment{OrderID: 11, Provider: "stripe", IntentID: "pi_1", Status: "requires_action", Amount: 19.99, Currency: "USD"}
+	err = model.Create(payment)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, payment.ID)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPaymentModel_GetByIntent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := PaymentModel{DB: db}
+	now := time.Now()
+
+	// Test case 1: Successful retrieval
+	t.Run("successful retrieval", func(t *testing.T) {
+		mock.ExpectQuery("SELECT .* FROM payments WHERE provider = \\$1 AND intent_id = \\$2").
+			WithArgs("stripe", "pi_1").
+			WillReturnRows(sqlmock.NewRows(paymentRowColumns).AddRow(5, 11, "stripe", "pi_1", "authorized", 19.99, "USD", now, now))
+
+		payment, err := model.GetByIntent("stripe", "pi_1")
+		assert.NoError(t, err)
+		assert.Equal(t, 11, payment.OrderID)
+		assert.Equal(t, "authorized", payment.Status)
+	})
+
+	// Test case 2: Unknown intent
+	t.Run("payment not found", func(t *testing.T) {
+		mock.ExpectQuery("SELECT .* FROM payments WHERE provider = \\$1 AND intent_id = \\$2").
+			WithArgs("stripe", "pi_unknown").
+			WillReturnError(sql.ErrNoRows)
+
+		payment, err := model.GetByIntent("stripe", "pi_unknown")
+		assert.Nil(t, payment)
+		assert.Equal(t, "payment not found", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPaymentModel_ClaimEvent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := PaymentModel{DB: db}
+
+	// Test case 1: New event
+	t.Run("claimed", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO payment_events \\(provider, event_id, type\\) VALUES \\(\\$1, \\$2, \\$3\\) ON CONFLICT DO NOTHING").
+			WithArgs("stripe", "evt_1", "payment.succeeded").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		claimed, err := model.ClaimEvent("stripe", "evt_1", "payment.succeeded")
+		assert.NoError(t, err)
+		assert.True(t, claimed)
+	})
+
+	// Test case 2: Redelivered event
+	t.Run("already claimed", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO payment_events").
+			WithArgs("stripe", "evt_1", "payment.succeeded").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		claimed, err := model.ClaimEvent("stripe", "evt_1", "payment.succeeded")
+		assert.NoError(t, err)
+		assert.False(t, claimed)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPaymentModel_MoveOrderWithTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	// The order is locked in the caller's transaction; a cancelled order
+	// cannot be paid
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT status FROM orders WHERE id = \\$1 FOR UPDATE").
+		WithArgs(11).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("cancelled"))
+	mock.ExpectRollback()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = PaymentModel{DB: db}.WithTx(tx).MoveOrder(11, OrderPaid)
+	var transitionErr *TransitionError
+	if assert.ErrorAs(t, err, &transitionErr) {
+		assert.Equal(t, OrderCancelled, transitionErr.From)
+	}
+	assert.NoError(t, tx.Rollback())
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
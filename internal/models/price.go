@@ -0,0 +1,250 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// PriceChangeStatus is the state of a scheduled price change
+type PriceChangeStatus string
+
+const (
+	PriceChangePending PriceChangeStatus = "pending"
+	PriceChangeApplied PriceChangeStatus = "applied"
+	// PriceChangeSuperseded marks a change overtaken by a price set by hand
+	// after it fell due
+	PriceChangeSuperseded PriceChangeStatus = "superseded"
+	PriceChangeCancelled  PriceChangeStatus = "cancelled"
+)
+
+// Sale is a product's sale price, optionally limited to a time window
+type Sale struct {
+	Price    float64
+	StartsAt *time.Time
+	EndsAt   *time.Time
+}
+
+// PriceChange is a regular price planned to take effect at a given time.
+// Reads use it as soon as it falls due; the scheduler then stores it in the
+// product.
+type PriceChange struct {
+	ID          int               `json:"id" example:"1"`
+	ProductID   int               `json:"product_id" example:"1"`
+	Price       float64           `json:"price" example:"1799.99"`
+	EffectiveAt time.Time         `json:"effective_at"`
+	Status      PriceChangeStatus `json:"status" example:"pending"`
+	Note        string            `json:"note,omitempty" example:"Black Friday"`
+	CreatedBy   int               `json:"created_by,omitempty" example:"1"`
+	CreatedAt   time.Time         `json:"created_at"`
+	AppliedAt   *time.Time        `json:"applied_at,omitempty"`
+}
+
+// PriceRevision records a change of a product's regular price. Source is
+// manual, scheduled (with the change applied) or bundle, for bundles
+// repriced after a component changed.
+type PriceRevision struct {
+	ID            int       `json:"id" example:"1"`
+	ProductID     int       `json:"product_id" example:"1"`
+	OldPrice      float64   `json:"old_price" example:"1999.99"`
+	NewPrice      float64   `json:"new_price" example:"1799.99"`
+	Source        string    `json:"source" example:"scheduled"`
+	PriceChangeID *int      `json:"price_change_id,omitempty" example:"1"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// PriceModelInterface defines the methods that a price model must implement
+type PriceModelInterface interface {
+	SetSale(productID int, sale Sale) error
+	ClearSale(productID int) error
+	ListChanges(productID int) ([]PriceChange, error)
+	ScheduleChange(change *PriceChange) error
+	CancelChange(productID, id int) error
+	History(productID, limit int) ([]PriceRevision, error)
+	ApplyDue(limit int) (int, error)
+}
+
+type PriceModel struct {
+	DB *sql.DB
+}
+
+// SetSale puts a product on sale
+func (m PriceModel) SetSale(productID int, sale Sale) error {
+	stmt := `UPDATE products SET sale_price = $2, sale_starts_at = $3, sale_ends_at = $4 WHERE id = $1`
+	result, err := m.DB.Exec(stmt, productID, sale.Price, sale.StartsAt, sale.EndsAt)
+	if err != nil {
+		return err
+	}
+	return requireOne(result, "product not found")
+}
+
+// ClearSale ends a product's sale
+func (m PriceModel) ClearSale(productID int) error {
+	stmt := `UPDATE products SET sale_price = NULL, sale_starts_at = NULL, sale_ends_at = NULL WHERE id = $1`
+	result, err := m.DB.Exec(stmt, productID)
+	if err != nil {
+		return err
+	}
+	return requireOne(result, "product not found")
+}
+
+// ListChanges returns a product's scheduled price changes, latest first
+func (m PriceModel) ListChanges(productID int) ([]PriceChange, error) {
+	stmt := `
+		SELECT id, product_id, price, effective_at, status, note, COALESCE(created_by, 0), created_at, applied_at
+		FROM scheduled_price_changes
+		WHERE product_id = $1
+		ORDER BY effective_at DESC, id DESC`
+	rows, err := m.DB.Query(stmt, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []PriceChange{}
+	for rows.Next() {
+		var c PriceChange
+		var appliedAt sql.NullTime
+		if err := rows.Scan(&c.ID, &c.ProductID, &c.Price, &c.EffectiveAt, &c.Status, &c.Note, &c.CreatedBy, &c.CreatedAt, &appliedAt); err != nil {
+			return nil, err
+		}
+		if appliedAt.Valid {
+			c.AppliedAt = &appliedAt.Time
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// ScheduleChange plans a regular price change. Bundles are priced from their
+// components and cannot be scheduled.
+func (m PriceModel) ScheduleChange(change *PriceChange) error {
+	stmt := `
+		INSERT INTO scheduled_price_changes (product_id, price, effective_at, note, created_by)
+		SELECT p.id, $2, $3, $4, NULLIF($5, 0) FROM products p
+		WHERE p.id = $1 AND NOT EXISTS (SELECT 1 FROM bundles b WHERE b.product_id = p.id)
+		RETURNING id, status, created_at`
+	err := m.DB.QueryRow(stmt, change.ProductID, change.Price, change.EffectiveAt, change.Note, change.CreatedBy).
+		Scan(&change.ID, &change.Status, &change.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		var exists bool
+		if err := m.DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM products WHERE id = $1)`, change.ProductID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return errors.New("product not found")
+		}
+		return errors.New("product is a bundle")
+	}
+	return err
+}
+
+// CancelChange cancels a pending price change
+func (m PriceModel) CancelChange(productID, id int) error {
+	stmt := `UPDATE scheduled_price_changes SET status = 'cancelled' WHERE id = $2 AND product_id = $1 AND status = 'pending'`
+	result, err := m.DB.Exec(stmt, productID, id)
+	if err != nil {
+		return err
+	}
+	return requireOne(result, "price change not found")
+}
+
+// History returns the latest changes of a product's regular price
+func (m PriceModel) History(productID, limit int) ([]PriceRevision, error) {
+	stmt := `
+		SELECT id, product_id, old_price, new_price, source, price_change_id, created_at
+		FROM product_price_revisions
+		WHERE product_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`
+	rows, err := m.DB.Query(stmt, productID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []PriceRevision{}
+	for rows.Next() {
+		var r PriceRevision
+		var changeID sql.NullInt64
+		if err := rows.Scan(&r.ID, &r.ProductID, &r.OldPrice, &r.NewPrice, &r.Source, &changeID, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		if changeID.Valid {
+			id := int(changeID.Int64)
+			r.PriceChangeID = &id
+		}
+		revisions = append(revisions, r)
+	}
+	return revisions, rows.Err()
+}
+
+// ApplyDue stores up to limit price changes that fell due in their products,
+// oldest first, and returns how many were applied. A change is superseded
+// when the price was set by hand after it fell due. SKIP LOCKED lets several
+// API instances run the scheduler side by side.
+func (m PriceModel) ApplyDue(limit int) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt := `
+		SELECT id, product_id, price, effective_at FROM scheduled_price_changes
+		WHERE status = 'pending' AND effective_at <= NOW()
+		ORDER BY effective_at, id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`
+	rows, err := tx.Query(stmt, limit)
+	if err != nil {
+		return 0, err
+	}
+	var due []PriceChange
+	for rows.Next() {
+		var c PriceChange
+		if err := rows.Scan(&c.ID, &c.ProductID, &c.Price, &c.EffectiveAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		due = append(due, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, c := range due {
+		// The revision trigger reads the change being applied from this
+		// transaction-local setting
+		if _, err := tx.Exec(`SELECT set_config('garage.price_change_id', $1, true)`, strconv.Itoa(c.ID)); err != nil {
+			return 0, err
+		}
+
+		stmt := `UPDATE products SET price = $2, price_changed_at = $3 WHERE id = $1 AND price_changed_at < $3`
+		result, err := tx.Exec(stmt, c.ProductID, c.Price, c.EffectiveAt)
+		if err != nil {
+			return 0, err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+
+		status := PriceChangeSuperseded
+		if rowsAffected > 0 {
+			status = PriceChangeApplied
+			applied++
+		}
+		if _, err := tx.Exec(`UPDATE scheduled_price_changes SET status = $2, applied_at = NOW() WHERE id = $1`, c.ID, string(status)); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return applied, nil
+}
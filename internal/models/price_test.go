@@ -0,0 +1,100 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriceModel_ScheduleChange(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := PriceModel{DB: db}
+	now := time.Now()
+	blackFriday := time.Date(2024, 11, 29, 0, 0, 0, 0, time.UTC)
+
+	// Test case 1: A change is planned
+	t.Run("schedule change", func(t *testing.T) {
+		mock.ExpectQuery("INSERT INTO scheduled_price_changes \\(product_id, price, effective_at, note, created_by\\)").
+			WithArgs(1, 1799.99, blackFriday, "Black Friday", 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "created_at"}).AddRow(1, "pending", now))
+
+		change := &PriceChange{ProductID: 1, Price: 1799.99, EffectiveAt: blackFriday, Note: "Black Friday", CreatedBy: 2}
+		err := model.ScheduleChange(change)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, change.ID)
+		assert.Equal(t, PriceChangePending, change.Status)
+	})
+
+	// Test case 2: Bundles are priced from their components
+	t.Run("bundle", func(t *testing.T) {
+		mock.ExpectQuery("INSERT INTO scheduled_price_changes").
+			WithArgs(9, 99.0, blackFriday, "", 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "created_at"}))
+		mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM products WHERE id = \\$1\\)").
+			WithArgs(9).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+		err := model.ScheduleChange(&PriceChange{ProductID: 9, Price: 99, EffectiveAt: blackFriday, CreatedBy: 2})
+		assert.Equal(t, "product is a bundle", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPriceModel_ApplyDue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := PriceModel{DB: db}
+	due := time.Date(2024, 11, 29, 0, 0, 0, 0, time.UTC)
+
+	// Test case 1: Due changes are stored; a change overtaken by a price set
+	// by hand is superseded
+	t.Run("apply due changes", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT id, product_id, price, effective_at FROM scheduled_price_changes\\s+WHERE status = 'pending' AND effective_at <= NOW\\(\\).*FOR UPDATE SKIP LOCKED").
+			WithArgs(100).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "price", "effective_at"}).
+				AddRow(1, 1, 1799.99, due).
+				AddRow(2, 4, 449.99, due))
+		mock.ExpectExec("SELECT set_config\\('garage.price_change_id', \\$1, true\\)").
+			WithArgs("1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE products SET price = \\$2, price_changed_at = \\$3 WHERE id = \\$1 AND price_changed_at < \\$3").
+			WithArgs(1, 1799.99, due).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE scheduled_price_changes SET status = \\$2, applied_at = NOW\\(\\) WHERE id = \\$1").
+			WithArgs(1, "applied").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("SELECT set_config").
+			WithArgs("2").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE products SET price").
+			WithArgs(4, 449.99, due).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("UPDATE scheduled_price_changes SET status").
+			WithArgs(2, "superseded").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		applied, err := model.ApplyDue(100)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, applied)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -2,36 +2,282 @@ package models
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/lib/pq"
+	"garage-api/internal/tax"
 )
 
 // Product represents a product in the garage
 type Product struct {
 	ID          int     `json:"id" example:"1"`
 	Name        string  `json:"name" example:"Hammer"`
+	// Slug names the product in URLs. It is derived from the name unless
+	// set explicitly, and follows renames; old slugs redirect.
+	Slug        string  `json:"slug" example:"hammer"`
 	Description string  `json:"description,omitempty" example:"A sturdy hammer for construction"`
+	// Price is the regular price, with scheduled changes that fell due
 	Price       float64 `json:"price" example:"29.99"`
 	ImagePath   string  `json:"image_path,omitempty" example:"/images/hammer.jpg"`
 	HTMLContent string  `json:"html_content,omitempty" example:"<p>Product details in HTML</p>"`
+	SKU         string  `json:"sku,omitempty" example:"HAM-001"`
+	Stock       int      `json:"stock" example:"25"`
+	CategoryID  *int     `json:"category_id,omitempty" example:"2"`
+	Tags        []string `json:"tags,omitempty" example:"rgb,wireless"`
+	// Attributes holds values for the attribute definitions of the
+	// product's category, keyed by code
+	Attributes map[string]interface{} `json:"attributes,omitempty" swaggertype:"object"`
+	TaxClass    string   `json:"tax_class" example:"standard"`
+	// Weight is in kilograms and dimensions in centimetres; 0 means unknown
+	Weight float64 `json:"weight,omitempty" example:"0.8"`
+	Length float64 `json:"length,omitempty" example:"30"`
+	Width  float64 `json:"width,omitempty" example:"20"`
+	Height float64 `json:"height,omitempty" example:"5"`
+	// RatingAverage and RatingCount summarize the approved reviews
+	RatingAverage float64 `json:"rating_average" example:"4.5"`
+	RatingCount   int     `json:"rating_count" example:"12"`
+	// EffectivePrice is what customers pay now: the sale price while a sale
+	// runs, otherwise the regular price
+	EffectivePrice float64    `json:"effective_price" example:"24.99"`
+	SalePrice      *float64   `json:"sale_price,omitempty" example:"24.99"`
+	SaleStartsAt   *time.Time `json:"sale_starts_at,omitempty"`
+	SaleEndsAt     *time.Time `json:"sale_ends_at,omitempty"`
+	// Status is where the product is in the publishing workflow. A
+	// published product with a PublishAt in the future is not live yet.
+	Status    ProductStatus `json:"status" example:"published"`
+	PublishAt *time.Time    `json:"publish_at,omitempty"`
+	// Locale is the language of Name and the descriptions; it is only set
+	// on responses negotiated through Accept-Language
+	Locale string `json:"locale,omitempty" example:"pt"`
+	// Relations and Bundle are only filled in on a single product's page
+	Relations []RelatedProduct `json:"relations,omitempty"`
+	Bundle    *Bundle          `json:"bundle,omitempty"`
+}
+
+// ProductFilter narrows down product listings. Zero values mean no filter.
+type ProductFilter struct {
+	IDs        []int
+	Search     string
+	MinPrice   float64
+	MaxPrice   float64
+	CategoryID int
+	// Tags only keeps products carrying all of the given tags
+	Tags   []string
+	// Attributes keeps products whose attribute has one of the given
+	// values, for every attribute code given
+	Attributes map[string][]string
+	Status     ProductStatus
+	// PublishedOnly keeps the products customers can see right now
+	PublishedOnly bool
+}
+
+// where returns the SQL WHERE clause (possibly empty) and its arguments
+func (f ProductFilter) where() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+
+	if len(f.IDs) > 0 {
+		ids := make([]int64, len(f.IDs))
+		for i, id := range f.IDs {
+			ids[i] = int64(id)
+		}
+		args = append(args, pq.Array(ids))
+		conds = append(conds, fmt.Sprintf("id = ANY($%d)", len(args)))
+	}
+	if f.Search != "" {
+		args = append(args, "%"+f.Search+"%")
+		conds = append(conds, fmt.Sprintf("(name ILIKE $%d OR description ILIKE $%d)", len(args), len(args)))
+	}
+	if f.MinPrice > 0 {
+		args = append(args, f.MinPrice)
+		conds = append(conds, fmt.Sprintf("effective_price(products) >= $%d", len(args)))
+	}
+	if f.MaxPrice > 0 {
+		args = append(args, f.MaxPrice)
+		conds = append(conds, fmt.Sprintf("effective_price(products) <= $%d", len(args)))
+	}
+	if f.CategoryID > 0 {
+		args = append(args, f.CategoryID)
+		conds = append(conds, fmt.Sprintf("category_id = $%d", len(args)))
+	}
+	if len(f.Tags) > 0 {
+		args = append(args, pq.Array(f.Tags), len(f.Tags))
+		conds = append(conds, fmt.Sprintf(`id IN (
+			SELECT pt.product_id FROM product_tags pt JOIN tags t ON t.id = pt.tag_id
+			WHERE t.name = ANY($%d) GROUP BY pt.product_id HAVING COUNT(DISTINCT t.name) = $%d)`, len(args)-1, len(args)))
+	}
+	if len(f.Attributes) > 0 {
+		codes := make([]string, 0, len(f.Attributes))
+		for code := range f.Attributes {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			// One containment test per value and type keeps every
+			// alternative answerable from the GIN index
+			var alts []string
+			for _, value := range f.Attributes[code] {
+				for _, v := range attributeFilterValues(value) {
+					doc, _ := json.Marshal(map[string]interface{}{code: v})
+					args = append(args, string(doc))
+					alts = append(alts, fmt.Sprintf("attributes @> $%d::jsonb", len(args)))
+				}
+			}
+			if len(alts) > 0 {
+				conds = append(conds, "("+strings.Join(alts, " OR ")+")")
+			}
+		}
+	}
+	if f.Status != "" {
+		args = append(args, string(f.Status))
+		conds = append(conds, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if f.PublishedOnly {
+		conds = append(conds, "is_published(products)")
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
 }
 
 // ProductModelInterface defines the methods that a product model must implement
 type ProductModelInterface interface {
 	GetAll() ([]Product, error)
+	List(filter ProductFilter) ([]Product, error)
+	Stream(filter ProductFilter, batchSize int, fn func(product *Product) error) error
+	Facets(filter ProductFilter) (*ProductFacets, error)
 	Get(id int) (*Product, error)
+	Lock(id int) (*Product, error)
+	GetBySKU(sku string) (*Product, error)
+	GetByName(name string) (*Product, error)
+	GetBySlug(slug string) (*Product, error)
+	GetByOldSlug(slug string) (*Product, error)
 	Create(product *Product) error
 	Update(product *Product) error
+	SetStock(id, stock int) error
+	SetPrice(id int, price float64) error
 	Delete(id int) error
 }
 
+// TxProductModelInterface is a ProductModelInterface that can also run its
+// operations inside a database transaction
+type TxProductModelInterface interface {
+	ProductModelInterface
+	Begin() (*sql.Tx, error)
+	WithTx(tx *sql.Tx) ProductModelInterface
+}
+
+// productColumns is the column list read into a Product by scanProduct.
+// Prices are resolved at read time so sales and scheduled changes apply on
+// the minute.
+const productColumns = `id, name, description, regular_price(products), image_path, html_content, COALESCE(sku, ''), stock, category_id,
+	ARRAY(SELECT t.name FROM product_tags pt JOIN tags t ON t.id = pt.tag_id WHERE pt.product_id = products.id ORDER BY t.name), tax_class,
+	weight, length, width, height, rating_average, rating_count, effective_price(products), sale_price, sale_starts_at, sale_ends_at,
+	status, publish_at, slug, attributes`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanProduct(row rowScanner, product *Product) error {
+	var categoryID sql.NullInt64
+	var salePrice sql.NullFloat64
+	var saleStartsAt, saleEndsAt, publishAt sql.NullTime
+	var attributes []byte
+	err := row.Scan(&product.ID, &product.Name, &product.Description, &product.Price, &product.ImagePath, &product.HTMLContent, &product.SKU, &product.Stock, &categoryID, pq.Array(&product.Tags), &product.TaxClass,
+		&product.Weight, &product.Length, &product.Width, &product.Height, &product.RatingAverage, &product.RatingCount,
+		&product.EffectivePrice, &salePrice, &saleStartsAt, &saleEndsAt,
+		&product.Status, &publishAt, &product.Slug, &attributes)
+	if err != nil {
+		return err
+	}
+
+	product.SalePrice, product.SaleStartsAt, product.SaleEndsAt = nil, nil, nil
+	if salePrice.Valid {
+		product.SalePrice = &salePrice.Float64
+	}
+	if saleStartsAt.Valid {
+		product.SaleStartsAt = &saleStartsAt.Time
+	}
+	if saleEndsAt.Valid {
+		product.SaleEndsAt = &saleEndsAt.Time
+	}
+	product.PublishAt = nil
+	if publishAt.Valid {
+		product.PublishAt = &publishAt.Time
+	}
+
+	product.Attributes = nil
+	if len(attributes) > 0 {
+		if err := json.Unmarshal(attributes, &product.Attributes); err != nil {
+			return err
+		}
+		if len(product.Attributes) == 0 {
+			product.Attributes = nil
+		}
+	}
+
+	product.CategoryID = nil
+	if categoryID.Valid {
+		id := int(categoryID.Int64)
+		product.CategoryID = &id
+	}
+	return nil
+}
+
+// writeError reports a product referring to a tax class that does not
+// exist, or taking a slug another product got first
+func writeError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" && pqErr.Constraint == "products_tax_class_fkey" {
+		return errors.New("tax class not found")
+	}
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "products_slug_key" {
+		return errors.New("slug taken")
+	}
+	return err
+}
+
 type ProductModel struct {
 	DB *sql.DB
+	tx *sql.Tx
+}
+
+// Begin starts a new transaction on the underlying database
+func (m ProductModel) Begin() (*sql.Tx, error) {
+	return m.DB.Begin()
+}
+
+// WithTx returns a copy of the model whose queries run inside tx
+func (m ProductModel) WithTx(tx *sql.Tx) ProductModelInterface {
+	return ProductModel{DB: m.DB, tx: tx}
+}
+
+func (m ProductModel) conn() DBTX {
+	if m.tx != nil {
+		return m.tx
+	}
+	return m.DB
 }
 
 func (m ProductModel) GetAll() ([]Product, error) {
-	stmt := `SELECT id, name, description, price, image_path, html_content FROM products`
-	
-	rows, err := m.DB.Query(stmt)
+	return m.List(ProductFilter{})
+}
+
+// List returns the products matching filter, ordered by ID
+func (m ProductModel) List(filter ProductFilter) ([]Product, error) {
+	where, args := filter.where()
+	stmt := `SELECT ` + productColumns + ` FROM products` + where + ` ORDER BY id`
+
+	rows, err := m.conn().Query(stmt, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -41,21 +287,139 @@ func (m ProductModel) GetAll() ([]Product, error) {
 
 	for rows.Next() {
 		var product Product
-		err := rows.Scan(&product.ID, &product.Name, &product.Description, &product.Price, &product.ImagePath, &product.HTMLContent)
+		err := scanProduct(rows, &product)
 		if err != nil {
 			return nil, err
 		}
 		products = append(products, product)
 	}
 
-	return products, nil
+	return products, rows.Err()
+}
+
+// streamCursors numbers Stream's cursors, so streams sharing a transaction,
+// or nested in one another, each get a cursor of their own
+var streamCursors atomic.Uint64
+
+// Stream calls fn for every product matching filter, ordered by ID. Rows are
+// read through a server-side cursor in batches of batchSize, so the whole
+// catalog is never held in memory. Iteration stops at the first error.
+// Cursors only live inside a transaction: the model's own when it has one,
+// otherwise one that Stream begins and ends itself.
+func (m ProductModel) Stream(filter ProductFilter, batchSize int, fn func(product *Product) error) error {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	tx := m.tx
+	if tx == nil {
+		own, err := m.DB.Begin()
+		if err != nil {
+			return err
+		}
+		defer own.Rollback()
+		tx = own
+	}
+
+	cursor := fmt.Sprintf("product_export_%d", streamCursors.Add(1))
+	where, args := filter.where()
+	stmt := `DECLARE ` + cursor + ` NO SCROLL CURSOR FOR SELECT ` + productColumns + ` FROM products` + where + ` ORDER BY id`
+	if _, err := tx.Exec(stmt, args...); err != nil {
+		return err
+	}
+
+	fetch := fmt.Sprintf(`FETCH FORWARD %d FROM %s`, batchSize, cursor)
+	for {
+		rows, err := tx.Query(fetch)
+		if err != nil {
+			return err
+		}
+
+		n := 0
+		for rows.Next() {
+			var product Product
+			if err := scanProduct(rows, &product); err != nil {
+				rows.Close()
+				return err
+			}
+			n++
+			if err := fn(&product); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if n < batchSize {
+			break
+		}
+	}
+
+	if _, err := tx.Exec(`CLOSE ` + cursor); err != nil {
+		return err
+	}
+	if m.tx != nil {
+		return nil
+	}
+	return tx.Commit()
 }
 
 func (m ProductModel) Get(id int) (*Product, error) {
-	stmt := `SELECT id, name, description, price, image_path, html_content FROM products WHERE id = $1`
+	stmt := `SELECT ` + productColumns + ` FROM products WHERE id = $1`
 	
 	var product Product
-	err := m.DB.QueryRow(stmt, id).Scan(&product.ID, &product.Name, &product.Description, &product.Price, &product.ImagePath, &product.HTMLContent)
+	err := scanProduct(m.conn().QueryRow(stmt, id), &product)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("product not found")
+		}
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// Lock returns a product and locks its row until the model's transaction
+// ends, so its status cannot change while the caller decides what to write
+func (m ProductModel) Lock(id int) (*Product, error) {
+	stmt := `SELECT ` + productColumns + ` FROM products WHERE id = $1 FOR UPDATE`
+
+	var product Product
+	err := scanProduct(m.conn().QueryRow(stmt, id), &product)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("product not found")
+		}
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// GetBySKU returns the product with the given SKU
+func (m ProductModel) GetBySKU(sku string) (*Product, error) {
+	stmt := `SELECT ` + productColumns + ` FROM products WHERE sku = $1`
+
+	var product Product
+	err := scanProduct(m.conn().QueryRow(stmt, sku), &product)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("product not found")
+		}
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// GetByName returns the oldest product with the given name, ignoring case
+func (m ProductModel) GetByName(name string) (*Product, error) {
+	stmt := `SELECT ` + productColumns + ` FROM products WHERE LOWER(name) = LOWER($1) ORDER BY id LIMIT 1`
+
+	var product Product
+	err := scanProduct(m.conn().QueryRow(stmt, name), &product)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errors.New("product not found")
@@ -68,41 +432,162 @@ func (m ProductModel) Get(id int) (*Product, error) {
 
 func (m ProductModel) Create(product *Product) error {
 	stmt := `
-		INSERT INTO products (name, description, price, image_path, html_content)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO products (name, description, price, image_path, html_content, sku, stock, category_id, tax_class,
+			weight, length, width, height, slug, attributes)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9, $10, $11, $12, $13, $14, $15)
 		RETURNING id`
 
-	return m.DB.QueryRow(stmt, product.Name, product.Description, product.Price, product.ImagePath, product.HTMLContent).Scan(&product.ID)
+	if product.TaxClass == "" {
+		product.TaxClass = tax.DefaultClass
+	}
+	attributes, err := m.checkAttributes(product)
+	if err != nil {
+		return err
+	}
+	if err := m.assignSlug(product, "", ""); err != nil {
+		return err
+	}
+	err = m.conn().QueryRow(stmt, product.Name, product.Description, product.Price, product.ImagePath, product.HTMLContent, product.SKU, product.Stock, product.CategoryID, product.TaxClass,
+		product.Weight, product.Length, product.Width, product.Height, product.Slug, attributes).Scan(&product.ID)
+	return writeError(err)
 }
 
+// Update saves a product. Its stock and price are not written but refreshed
+// from the database; SetStock and SetPrice change them. When its slug
+// changes, the old one is kept as a redirect to the product.
 func (m ProductModel) Update(product *Product) error {
-	stmt := `
-		UPDATE products 
-		SET name = $1, description = $2, price = $3, image_path = $4, html_content = $5
-		WHERE id = $6`
+	if m.tx == nil {
+		tx, err := m.DB.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
 
-	result, err := m.DB.Exec(stmt, product.Name, product.Description, product.Price, product.ImagePath, product.HTMLContent, product.ID)
+		if err := (ProductModel{DB: m.DB, tx: tx}).Update(product); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	// Stock moves with checkouts and returns, and the price with scheduled
+	// changes, so both are re-read under the lock rather than written back
+	// from the caller's copy
+	var oldName, oldSlug string
+	stmt := `SELECT name, slug, stock, regular_price(products) FROM products WHERE id = $1 FOR UPDATE`
+	err := m.tx.QueryRow(stmt, product.ID).Scan(&oldName, &oldSlug, &product.Stock, &product.Price)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("product not found")
+		}
 		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	if product.TaxClass == "" {
+		product.TaxClass = tax.DefaultClass
+	}
+	attributes, err := m.checkAttributes(product)
 	if err != nil {
 		return err
 	}
+	if err := m.assignSlug(product, oldName, oldSlug); err != nil {
+		return err
+	}
+
+	stmt = `
+		UPDATE products 
+		SET name = $1, description = $2, image_path = $3, html_content = $4, sku = NULLIF($5, ''), category_id = $6, tax_class = $7,
+			weight = $8, length = $9, width = $10, height = $11, slug = $12, attributes = $13
+		WHERE id = $14`
+	_, err = m.tx.Exec(stmt, product.Name, product.Description, product.ImagePath, product.HTMLContent, product.SKU, product.CategoryID, product.TaxClass,
+		product.Weight, product.Length, product.Width, product.Height, product.Slug, attributes, product.ID)
+	if err != nil {
+		return writeError(err)
+	}
+
+	if product.Slug != oldSlug {
+		return m.redirectSlug(product.ID, oldSlug, product.Slug)
+	}
+	return nil
+}
 
+// SetStock sets a product's stock, e.g. after a stock count. Update leaves
+// stock alone.
+func (m ProductModel) SetStock(id, stock int) error {
+	result, err := m.conn().Exec(`UPDATE products SET stock = $1 WHERE id = $2`, stock, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
 	if rowsAffected == 0 {
 		return errors.New("product not found")
 	}
+	return nil
+}
 
+// SetPrice sets a product's regular price. The stored price is compared,
+// not the one resolved with scheduled changes: setting the price a product
+// is stored with changes nothing and records no revision.
+func (m ProductModel) SetPrice(id int, price float64) error {
+	result, err := m.conn().Exec(`UPDATE products SET price = $1 WHERE id = $2 AND price <> $1`, price, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	var exists bool
+	if err := m.conn().QueryRow(`SELECT EXISTS (SELECT 1 FROM products WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("product not found")
+	}
 	return nil
 }
 
+// checkAttributes validates a product's attributes against the definitions
+// of its category, normalizes them and returns them encoded for storage
+func (m ProductModel) checkAttributes(product *Product) (string, error) {
+	var defs []AttributeDefinition
+	if product.CategoryID != nil {
+		var err error
+		if defs, err = listAttributes(m.conn(), *product.CategoryID); err != nil {
+			return "", err
+		}
+	}
+	if len(defs) == 0 && len(product.Attributes) == 0 {
+		product.Attributes = nil
+		return "{}", nil
+	}
+
+	attributes, err := ValidateAttributes(defs, product.Attributes)
+	if err != nil {
+		return "", err
+	}
+	if len(attributes) == 0 {
+		attributes = nil
+	}
+	product.Attributes = attributes
+	return encodeAttributes(attributes)
+}
+
 func (m ProductModel) Delete(id int) error {
 	stmt := `DELETE FROM products WHERE id = $1`
 
-	result, err := m.DB.Exec(stmt, id)
+	result, err := m.conn().Exec(stmt, id)
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" && pqErr.Constraint == "bundle_items_component_id_fkey" {
+			return errors.New("product in bundle")
+		}
 		return err
 	}
 
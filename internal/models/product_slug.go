@@ -0,0 +1,118 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+
+	"garage-api/internal/slug"
+)
+
+// GetBySlug returns the product currently known by slug
+func (m ProductModel) GetBySlug(s string) (*Product, error) {
+	stmt := `SELECT ` + productColumns + ` FROM products WHERE slug = $1`
+
+	var product Product
+	err := scanProduct(m.conn().QueryRow(stmt, s), &product)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("product not found")
+		}
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// GetByOldSlug returns the product that was known by slug before it was
+// renamed
+func (m ProductModel) GetByOldSlug(s string) (*Product, error) {
+	stmt := `SELECT ` + productColumns + ` FROM products WHERE id = (SELECT product_id FROM product_slug_redirects WHERE slug = $1)`
+
+	var product Product
+	err := scanProduct(m.conn().QueryRow(stmt, s), &product)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("product not found")
+		}
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// assignSlug settles the slug of a product about to be saved. A slug given
+// explicitly is normalized and must be free. Otherwise new products get one
+// derived from their name, as do renamed products whose slug still followed
+// their old name; a collision adds the first free numeric suffix.
+func (m ProductModel) assignSlug(product *Product, oldName, oldSlug string) error {
+	if product.Slug != "" && product.Slug != oldSlug {
+		wanted := slug.Make(product.Slug)
+		if wanted == oldSlug {
+			product.Slug = oldSlug
+			return nil
+		}
+		taken, err := m.takenSlugs(wanted, product.ID)
+		if err != nil {
+			return err
+		}
+		if taken[wanted] {
+			return errors.New("slug taken")
+		}
+		product.Slug = wanted
+		return nil
+	}
+
+	base := slug.Make(product.Name)
+	if oldSlug != "" && (slug.IsVariant(oldSlug, base) || !slug.IsVariant(oldSlug, slug.Make(oldName))) {
+		product.Slug = oldSlug
+		return nil
+	}
+
+	taken, err := m.takenSlugs(base, product.ID)
+	if err != nil {
+		return err
+	}
+	for n := 1; ; n++ {
+		if candidate := slug.WithSuffix(base, n); !taken[candidate] {
+			product.Slug = candidate
+			return nil
+		}
+	}
+}
+
+// takenSlugs returns base and its suffixed variants in use by other
+// products, or kept as their redirects
+func (m ProductModel) takenSlugs(base string, productID int) (map[string]bool, error) {
+	stmt := `
+		SELECT slug FROM products WHERE (slug = $1 OR slug LIKE $2) AND id <> $3
+		UNION
+		SELECT slug FROM product_slug_redirects WHERE (slug = $1 OR slug LIKE $2) AND product_id <> $3`
+	rows, err := m.conn().Query(stmt, base, base+"-%", productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	taken := make(map[string]bool)
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		taken[s] = true
+	}
+	return taken, rows.Err()
+}
+
+// redirectSlug keeps a product's old slug pointing at it. A product going
+// back to one of its old slugs drops that redirect.
+func (m ProductModel) redirectSlug(productID int, oldSlug, newSlug string) error {
+	if _, err := m.conn().Exec(`DELETE FROM product_slug_redirects WHERE slug = $1`, newSlug); err != nil {
+		return err
+	}
+	stmt := `
+		INSERT INTO product_slug_redirects (slug, product_id) VALUES ($1, $2)
+		ON CONFLICT (slug) DO UPDATE SET product_id = EXCLUDED.product_id, created_at = NOW()`
+	_, err := m.conn().Exec(stmt, oldSlug, productID)
+	return err
+}
@@ -2,12 +2,21 @@ package models
 
 import (
 	"database/sql"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 )
 
+const productSelect = "SELECT id, name, description, regular_price\\(products\\), image_path, html_content, COALESCE\\(sku, ''\\), stock, category_id,\\s+ARRAY\\(.+\\), tax_class,\\s+weight, length, width, height, rating_average, rating_count,\\s+effective_price\\(products\\), sale_price, sale_starts_at, sale_ends_at,\\s+status, publish_at, slug, attributes FROM products"
+
+const takenSlugsQuery = "SELECT slug FROM products WHERE \\(slug = \\$1 OR slug LIKE \\$2\\) AND id <> \\$3"
+
+var productRowColumns = []string{"id", "name", "description", "price", "image_path", "html_content", "sku", "stock", "category_id", "tags", "tax_class", "weight", "length", "width", "height", "rating_average", "rating_count", "effective_price", "sale_price", "sale_starts_at", "sale_ends_at", "status", "publish_at", "slug", "attributes"}
+
 func TestProductModel_GetAll(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -19,11 +28,11 @@ func TestProductModel_GetAll(t *testing.T) {
 
 	// Test case 1: Successful retrieval
 	t.Run("successful retrieval", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "image_path", "html_content"}).
-			AddRow(1, "Hammer", "A sturdy hammer", 29.99, "/images/hammer.jpg", "<p>Hammer details</p>").
-			AddRow(2, "Screwdriver", "A useful tool", 19.99, "/images/screwdriver.jpg", "<p>Screwdriver details</p>")
+		rows := sqlmock.NewRows(productRowColumns).
+			AddRow(1, "Hammer", "A sturdy hammer", 29.99, "/images/hammer.jpg", "<p>Hammer details</p>", "HAM-001", 10, nil, "{}", "standard", 0.0, 0.0, 0.0, 0.0, 0.0, 0, 29.99, nil, nil, nil, "published", nil, "hammer", "{}").
+			AddRow(2, "Screwdriver", "A useful tool", 19.99, "/images/screwdriver.jpg", "<p>Screwdriver details</p>", "", 10, nil, "{}", "standard", 0.0, 0.0, 0.0, 0.0, 0.0, 0, 19.99, nil, nil, nil, "published", nil, "screwdriver", "{}")
 
-		mock.ExpectQuery("SELECT id, name, description, price, image_path, html_content FROM products").
+		mock.ExpectQuery(productSelect).
 			WillReturnRows(rows)
 
 		products, err := model.GetAll()
@@ -35,7 +44,7 @@ func TestProductModel_GetAll(t *testing.T) {
 
 	// Test case 2: Database error
 	t.Run("database error", func(t *testing.T) {
-		mock.ExpectQuery("SELECT id, name, description, price, image_path, html_content FROM products").
+		mock.ExpectQuery(productSelect).
 			WillReturnError(sql.ErrConnDone)
 
 		products, err := model.GetAll()
@@ -48,6 +57,198 @@ func TestProductModel_GetAll(t *testing.T) {
 	}
 }
 
+func TestProductModel_List(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := ProductModel{DB: db}
+
+	// Test case 1: All filters applied
+	t.Run("filtered retrieval", func(t *testing.T) {
+		rows := sqlmock.NewRows(productRowColumns).
+			AddRow(1, "Hammer", "A sturdy hammer", 29.99, "/images/hammer.jpg", "<p>Hammer details</p>", "HAM-001", 10, nil, "{}", "standard", 0.0, 0.0, 0.0, 0.0, 0.0, 0, 29.99, nil, nil, nil, "published", nil, "hammer", "{}")
+
+		mock.ExpectQuery(productSelect + " WHERE \\(name ILIKE \\$1 OR description ILIKE \\$1\\) AND effective_price\\(products\\) >= \\$2 AND effective_price\\(products\\) <= \\$3 ORDER BY id").
+			WithArgs("%ham%", 10.0, 50.0).
+			WillReturnRows(rows)
+
+		products, err := model.List(ProductFilter{Search: "ham", MinPrice: 10, MaxPrice: 50})
+		assert.NoError(t, err)
+		assert.Len(t, products, 1)
+		assert.Equal(t, "Hammer", products[0].Name)
+	})
+
+	// Test case 2: Only a maximum price
+	t.Run("max price only", func(t *testing.T) {
+		mock.ExpectQuery(productSelect + " WHERE effective_price\\(products\\) <= \\$1 ORDER BY id").
+			WithArgs(5.0).
+			WillReturnRows(sqlmock.NewRows(productRowColumns))
+
+		products, err := model.List(ProductFilter{MaxPrice: 5})
+		assert.NoError(t, err)
+		assert.Empty(t, products)
+	})
+
+	// Test case 3: Category and tags
+	t.Run("category and tags", func(t *testing.T) {
+		mock.ExpectQuery(productSelect + " WHERE category_id = \\$1 AND id IN \\(.+ANY\\(\\$2\\).+HAVING COUNT\\(DISTINCT t.name\\) = \\$3\\) ORDER BY id").
+			WithArgs(2, pq.Array([]string{"rgb", "wireless"}), 2).
+			WillReturnRows(sqlmock.NewRows(productRowColumns).
+				AddRow(3, "Wireless Mouse", "", 79.99, "", "", "", 5, 2, "{rgb,wireless}", "standard", 0.0, 0.0, 0.0, 0.0, 0.0, 0, 79.99, nil, nil, nil, "published", nil, "wireless-mouse", "{}"))
+
+		products, err := model.List(ProductFilter{CategoryID: 2, Tags: []string{"rgb", "wireless"}})
+		assert.NoError(t, err)
+		assert.Len(t, products, 1)
+		assert.Equal(t, []string{"rgb", "wireless"}, products[0].Tags)
+	})
+
+	// Test case 4: Only published products that are live
+	t.Run("published only", func(t *testing.T) {
+		mock.ExpectQuery(productSelect + " WHERE \\(name ILIKE \\$1 OR description ILIKE \\$1\\) AND is_published\\(products\\) ORDER BY id").
+			WithArgs("%ham%").
+			WillReturnRows(sqlmock.NewRows(productRowColumns))
+
+		products, err := model.List(ProductFilter{Search: "ham", PublishedOnly: true})
+		assert.NoError(t, err)
+		assert.Empty(t, products)
+	})
+
+	// Test case 5: A given status
+	t.Run("by status", func(t *testing.T) {
+		mock.ExpectQuery(productSelect + " WHERE status = \\$1 ORDER BY id").
+			WithArgs("in_review").
+			WillReturnRows(sqlmock.NewRows(productRowColumns).
+				AddRow(4, "Desk Lamp", "", 39.99, "", "", "", 3, nil, "{}", "standard", 0.0, 0.0, 0.0, 0.0, 0.0, 0, 39.99, nil, nil, nil, "in_review", nil, "desk-lamp", "{}"))
+
+		products, err := model.List(ProductFilter{Status: ProductInReview})
+		assert.NoError(t, err)
+		assert.Len(t, products, 1)
+		assert.Equal(t, ProductInReview, products[0].Status)
+	})
+
+	// Test case 6: Given products
+	t.Run("by ids", func(t *testing.T) {
+		mock.ExpectQuery(productSelect + " WHERE id = ANY\\(\\$1\\) AND is_published\\(products\\) ORDER BY id").
+			WithArgs(pq.Array([]int64{4, 1})).
+			WillReturnRows(sqlmock.NewRows(productRowColumns).
+				AddRow(1, "Hammer", "", 29.99, "", "", "", 10, nil, "{}", "standard", 0.0, 0.0, 0.0, 0.0, 0.0, 0, 29.99, nil, nil, nil, "published", nil, "hammer", "{}"))
+
+		products, err := model.List(ProductFilter{IDs: []int{4, 1}, PublishedOnly: true})
+		assert.NoError(t, err)
+		assert.Len(t, products, 1)
+		assert.Nil(t, products[0].Attributes)
+	})
+
+	// Test case 7: Attribute values, as text, number or boolean
+	t.Run("by attributes", func(t *testing.T) {
+		mock.ExpectQuery(productSelect + " WHERE \\(attributes @> \\$1::jsonb OR attributes @> \\$2::jsonb OR attributes @> \\$3::jsonb OR attributes @> \\$4::jsonb\\) AND \\(attributes @> \\$5::jsonb\\) ORDER BY id").
+			WithArgs(`{"refresh_rate":"144"}`, `{"refresh_rate":144}`, `{"refresh_rate":"165"}`, `{"refresh_rate":165}`, `{"resolution":"2560x1440"}`).
+			WillReturnRows(sqlmock.NewRows(productRowColumns).
+				AddRow(5, "Gaming Monitor", "", 299.99, "", "", "", 4, 3, "{}", "standard", 0.0, 0.0, 0.0, 0.0, 0.0, 0, 299.99, nil, nil, nil, "published", nil, "gaming-monitor", `{"refresh_rate": 144, "resolution": "2560x1440"}`))
+
+		products, err := model.List(ProductFilter{Attributes: map[string][]string{
+			"resolution":   {"2560x1440"},
+			"refresh_rate": {"144", "165"},
+		}})
+		assert.NoError(t, err)
+		assert.Len(t, products, 1)
+		assert.Equal(t, map[string]interface{}{"refresh_rate": 144.0, "resolution": "2560x1440"}, products[0].Attributes)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductModel_Stream(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := ProductModel{DB: db}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DECLARE product_export_\\d+ NO SCROLL CURSOR FOR " + productSelect + " WHERE effective_price\\(products\\) >= \\$1 ORDER BY id").
+		WithArgs(10.0).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FETCH FORWARD 2 FROM product_export_\\d+").
+		WillReturnRows(sqlmock.NewRows(productRowColumns).
+			AddRow(1, "Hammer", "", 29.99, "", "", "", 10, nil, "{}", "standard", 0.0, 0.0, 0.0, 0.0, 0.0, 0, 29.99, nil, nil, nil, "published", nil, "hammer", "{}").
+			AddRow(2, "Screwdriver", "", 19.99, "", "", "", 0, nil, "{}", "standard", 0.0, 0.0, 0.0, 0.0, 0.0, 0, 19.99, nil, nil, nil, "published", nil, "screwdriver", "{}"))
+	mock.ExpectQuery("FETCH FORWARD 2 FROM product_export_\\d+").
+		WillReturnRows(sqlmock.NewRows(productRowColumns).
+			AddRow(3, "Wrench", "", 14.99, "", "", "", 0, nil, "{}", "standard", 0.0, 0.0, 0.0, 0.0, 0.0, 0, 14.99, nil, nil, nil, "published", nil, "wrench", "{}"))
+	mock.ExpectExec("CLOSE product_export_\\d+").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	var names []string
+	err = model.Stream(ProductFilter{MinPrice: 10}, 2, func(p *Product) error {
+		names = append(names, p.Name)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Hammer", "Screwdriver", "Wrench"}, names)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductModel_StreamWithTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	// The cursors run in the caller's transaction, which Stream leaves open.
+	// The first stream stops early and leaves its cursor open; the second
+	// declares a cursor of its own.
+	mock.ExpectBegin()
+	mock.ExpectExec("DECLARE product_export_\\d+ NO SCROLL CURSOR FOR " + productSelect + " ORDER BY id").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FETCH FORWARD 500 FROM product_export_\\d+").
+		WillReturnRows(sqlmock.NewRows(productRowColumns).
+			AddRow(1, "Hammer", "", 29.99, "", "", "", 10, nil, "{}", "standard", 0.0, 0.0, 0.0, 0.0, 0.0, 0, 29.99, nil, nil, nil, "published", nil, "hammer", "{}"))
+	mock.ExpectExec("DECLARE product_export_\\d+ NO SCROLL CURSOR FOR " + productSelect + " ORDER BY id").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FETCH FORWARD 500 FROM product_export_\\d+").
+		WillReturnRows(sqlmock.NewRows(productRowColumns).
+			AddRow(1, "Hammer", "", 29.99, "", "", "", 10, nil, "{}", "standard", 0.0, 0.0, 0.0, 0.0, 0.0, 0, 29.99, nil, nil, nil, "published", nil, "hammer", "{}"))
+	mock.ExpectExec("CLOSE product_export_\\d+").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := ProductModel{DB: db}.WithTx(tx)
+	err = model.Stream(ProductFilter{}, 0, func(p *Product) error {
+		return errors.New("stop")
+	})
+	assert.EqualError(t, err, "stop")
+
+	var names []string
+	err = model.Stream(ProductFilter{}, 0, func(p *Product) error {
+		names = append(names, p.Name)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Hammer"}, names)
+	assert.NoError(t, tx.Commit())
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func TestProductModel_Get(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -59,10 +260,10 @@ func TestProductModel_Get(t *testing.T) {
 
 	// Test case 1: Successful retrieval
 	t.Run("successful retrieval", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "image_path", "html_content"}).
-			AddRow(1, "Hammer", "A sturdy hammer", 29.99, "/images/hammer.jpg", "<p>Hammer details</p>")
+		rows := sqlmock.NewRows(productRowColumns).
+			AddRow(1, "Hammer", "A sturdy hammer", 29.99, "/images/hammer.jpg", "<p>Hammer details</p>", "HAM-001", 10, 2, "{heavy-duty,steel}", "standard", 0.0, 0.0, 0.0, 0.0, 0.0, 0, 29.99, nil, nil, nil, "published", nil, "hammer", "{}")
 
-		mock.ExpectQuery("SELECT id, name, description, price, image_path, html_content FROM products WHERE id = \\$1").
+		mock.ExpectQuery(productSelect + " WHERE id = \\$1").
 			WithArgs(1).
 			WillReturnRows(rows)
 
@@ -71,11 +272,13 @@ func TestProductModel_Get(t *testing.T) {
 		assert.NotNil(t, product)
 		assert.Equal(t, "Hammer", product.Name)
 		assert.Equal(t, 29.99, product.Price)
+		assert.Equal(t, 2, *product.CategoryID)
+		assert.Equal(t, []string{"heavy-duty", "steel"}, product.Tags)
 	})
 
 	// Test case 2: Product not found
 	t.Run("product not found", func(t *testing.T) {
-		mock.ExpectQuery("SELECT id, name, description, price, image_path, html_content FROM products WHERE id = \\$1").
+		mock.ExpectQuery(productSelect + " WHERE id = \\$1").
 			WithArgs(999).
 			WillReturnError(sql.ErrNoRows)
 
@@ -85,6 +288,154 @@ func TestProductModel_Get(t *testing.T) {
 		assert.Equal(t, "product not found", err.Error())
 	})
 
+	// Test case 3: A running sale lowers the effective price only
+	t.Run("product on sale", func(t *testing.T) {
+		ends := time.Date(2024, 11, 30, 0, 0, 0, 0, time.UTC)
+		mock.ExpectQuery(productSelect + " WHERE id = \\$1").
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows(productRowColumns).
+				AddRow(2, "Screwdriver", "", 19.99, "", "", "", 10, nil, "{}", "standard", 0.0, 0.0, 0.0, 0.0, 0.0, 0, 14.99, 14.99, nil, ends, "published", nil, "screwdriver", "{}"))
+
+		product, err := model.Get(2)
+		assert.NoError(t, err)
+		assert.Equal(t, 19.99, product.Price)
+		assert.Equal(t, 14.99, product.EffectivePrice)
+		assert.Equal(t, 14.99, *product.SalePrice)
+		assert.Nil(t, product.SaleStartsAt)
+		assert.Equal(t, ends, *product.SaleEndsAt)
+	})
+
+	// Test case 4: A product published for later is not live before its time
+	t.Run("scheduled publication", func(t *testing.T) {
+		publishAt := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+		mock.ExpectQuery(productSelect + " WHERE id = \\$1").
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows(productRowColumns).
+				AddRow(3, "Desk Lamp", "", 39.99, "", "", "", 3, nil, "{}", "standard", 0.0, 0.0, 0.0, 0.0, 0.0, 0, 39.99, nil, nil, nil, "published", publishAt, "desk-lamp", "{}"))
+
+		product, err := model.Get(3)
+		assert.NoError(t, err)
+		assert.Equal(t, ProductPublished, product.Status)
+		assert.Equal(t, publishAt, *product.PublishAt)
+		assert.False(t, product.IsPublished(publishAt.Add(-time.Minute)))
+		assert.True(t, product.IsPublished(publishAt))
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductModel_Lock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := ProductModel{DB: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(productSelect + " WHERE id = \\$1 FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(productRowColumns).
+			AddRow(1, "Hammer", "", 29.99, "", "", "", 10, nil, "{}", "standard", 0.0, 0.0, 0.0, 0.0, 0.0, 0, 29.99, nil, nil, nil, "in_review", nil, "hammer", "{}"))
+	mock.ExpectQuery(productSelect + " WHERE id = \\$1 FOR UPDATE").
+		WithArgs(999).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	tx, err := model.Begin()
+	assert.NoError(t, err)
+	txModel := model.WithTx(tx)
+
+	product, err := txModel.Lock(1)
+	assert.NoError(t, err)
+	assert.Equal(t, ProductInReview, product.Status)
+
+	_, err = txModel.Lock(999)
+	assert.EqualError(t, err, "product not found")
+
+	assert.NoError(t, tx.Rollback())
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductModel_GetByOldSlug(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := ProductModel{DB: db}
+
+	// Test case 1: A renamed product is found by its old slug
+	t.Run("redirected slug", func(t *testing.T) {
+		mock.ExpectQuery(productSelect + " WHERE id = \\(SELECT product_id FROM product_slug_redirects WHERE slug = \\$1\\)").
+			WithArgs("hammer").
+			WillReturnRows(sqlmock.NewRows(productRowColumns).
+				AddRow(1, "Claw Hammer", "", 29.99, "", "", "", 10, nil, "{}", "standard", 0.0, 0.0, 0.0, 0.0, 0.0, 0, 29.99, nil, nil, nil, "published", nil, "claw-hammer", "{}"))
+
+		product, err := model.GetByOldSlug("hammer")
+		assert.NoError(t, err)
+		assert.Equal(t, "claw-hammer", product.Slug)
+	})
+
+	// Test case 2: Unknown slug
+	t.Run("product not found", func(t *testing.T) {
+		mock.ExpectQuery(productSelect + " WHERE id = \\(SELECT product_id FROM product_slug_redirects WHERE slug = \\$1\\)").
+			WithArgs("anvil").
+			WillReturnError(sql.ErrNoRows)
+
+		product, err := model.GetByOldSlug("anvil")
+		assert.Nil(t, product)
+		assert.Equal(t, "product not found", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductModel_GetBySKU(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := ProductModel{DB: db}
+
+	// Test case 1: Successful retrieval
+	t.Run("successful retrieval", func(t *testing.T) {
+		rows := sqlmock.NewRows(productRowColumns).
+			AddRow(1, "Hammer", "A sturdy hammer", 29.99, "/images/hammer.jpg", "<p>Hammer details</p>", "HAM-001", 10, nil, "{}", "standard", 0.0, 0.0, 0.0, 0.0, 0.0, 0, 29.99, nil, nil, nil, "published", nil, "hammer", "{}")
+
+		mock.ExpectQuery(productSelect + " WHERE sku = \\$1").
+			WithArgs("HAM-001").
+			WillReturnRows(rows)
+
+		product, err := model.GetBySKU("HAM-001")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, product.ID)
+		assert.Equal(t, "HAM-001", product.SKU)
+	})
+
+	// Test case 2: Product not found
+	t.Run("product not found", func(t *testing.T) {
+		mock.ExpectQuery(productSelect + " WHERE sku = \\$1").
+			WithArgs("NOPE").
+			WillReturnError(sql.ErrNoRows)
+
+		product, err := model.GetBySKU("NOPE")
+		assert.Error(t, err)
+		assert.Nil(t, product)
+		assert.Equal(t, "product not found", err.Error())
+	})
+
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
@@ -110,13 +461,17 @@ func TestProductModel_Create(t *testing.T) {
 		}
 
 		rows := sqlmock.NewRows([]string{"id"}).AddRow(1)
+		mock.ExpectQuery(takenSlugsQuery).
+			WithArgs("hammer", "hammer-%", 0).
+			WillReturnRows(sqlmock.NewRows([]string{"slug"}))
 		mock.ExpectQuery("INSERT INTO products").
-			WithArgs(product.Name, product.Description, product.Price, product.ImagePath, product.HTMLContent).
+			WithArgs(product.Name, product.Description, product.Price, product.ImagePath, product.HTMLContent, product.SKU, product.Stock, product.CategoryID, "standard", 0.0, 0.0, 0.0, 0.0, "hammer", "{}").
 			WillReturnRows(rows)
 
 		err := model.Create(product)
 		assert.NoError(t, err)
 		assert.Equal(t, 1, product.ID)
+		assert.Equal(t, "hammer", product.Slug)
 	})
 
 	// Test case 2: Database error
@@ -127,14 +482,97 @@ func TestProductModel_Create(t *testing.T) {
 			Price:       29.99,
 		}
 
+		mock.ExpectQuery(takenSlugsQuery).
+			WithArgs("hammer", "hammer-%", 0).
+			WillReturnRows(sqlmock.NewRows([]string{"slug"}))
 		mock.ExpectQuery("INSERT INTO products").
-			WithArgs(product.Name, product.Description, product.Price, product.ImagePath, product.HTMLContent).
+			WithArgs(product.Name, product.Description, product.Price, product.ImagePath, product.HTMLContent, product.SKU, product.Stock, product.CategoryID, "standard", 0.0, 0.0, 0.0, 0.0, "hammer", "{}").
 			WillReturnError(sql.ErrConnDone)
 
 		err := model.Create(product)
 		assert.Error(t, err)
 	})
 
+	// Test case 3: Unknown tax class
+	t.Run("unknown tax class", func(t *testing.T) {
+		product := &Product{Name: "Book", Description: "A paperback", Price: 9.99, TaxClass: "books"}
+
+		mock.ExpectQuery(takenSlugsQuery).
+			WithArgs("book", "book-%", 0).
+			WillReturnRows(sqlmock.NewRows([]string{"slug"}))
+		mock.ExpectQuery("INSERT INTO products").
+			WithArgs(product.Name, product.Description, product.Price, "", "", "", 0, nil, "books", 0.0, 0.0, 0.0, 0.0, "book", "{}").
+			WillReturnError(&pq.Error{Code: "23503", Constraint: "products_tax_class_fkey"})
+
+		err := model.Create(product)
+		assert.EqualError(t, err, "tax class not found")
+	})
+
+	// Test case 4: Taken slugs get the first free suffix
+	t.Run("slug collision", func(t *testing.T) {
+		product := &Product{Name: "Hammer", Description: "Another hammer", Price: 19.99}
+
+		mock.ExpectQuery(takenSlugsQuery).
+			WithArgs("hammer", "hammer-%", 0).
+			WillReturnRows(sqlmock.NewRows([]string{"slug"}).AddRow("hammer").AddRow("hammer-2").AddRow("hammer-drill"))
+		mock.ExpectQuery("INSERT INTO products").
+			WithArgs(product.Name, product.Description, product.Price, "", "", "", 0, nil, "standard", 0.0, 0.0, 0.0, 0.0, "hammer-3", "{}").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+
+		err := model.Create(product)
+		assert.NoError(t, err)
+		assert.Equal(t, "hammer-3", product.Slug)
+	})
+
+	// Test case 5: An explicit slug must be free
+	t.Run("explicit slug taken", func(t *testing.T) {
+		product := &Product{Name: "Hammer", Description: "Another hammer", Price: 19.99, Slug: "Best Hammer"}
+
+		mock.ExpectQuery(takenSlugsQuery).
+			WithArgs("best-hammer", "best-hammer-%", 0).
+			WillReturnRows(sqlmock.NewRows([]string{"slug"}).AddRow("best-hammer"))
+
+		err := model.Create(product)
+		assert.EqualError(t, err, "slug taken")
+	})
+
+	// Test case 6: Attributes are checked against the category's definitions
+	t.Run("attributes", func(t *testing.T) {
+		categoryID := 3
+		product := &Product{Name: "Gaming Monitor", Price: 299.99, CategoryID: &categoryID, Attributes: map[string]interface{}{
+			"refresh_rate": 144,
+			"resolution":   "2560x1440",
+			"curved":       nil,
+		}}
+
+		mock.ExpectQuery("SELECT id, category_id, code, name, type, unit, options, required, position\\s+FROM attribute_definitions\\s+WHERE category_id = \\$1").
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows(attributeRowColumns).
+				AddRow(1, 3, "resolution", "Resolution", "string", "", "{}", true, 0).
+				AddRow(2, 3, "refresh_rate", "Refresh rate", "unit", "Hz", "{}", false, 1).
+				AddRow(3, 3, "curved", "Curved", "boolean", "", "{}", false, 2))
+		mock.ExpectQuery(takenSlugsQuery).
+			WithArgs("gaming-monitor", "gaming-monitor-%", 0).
+			WillReturnRows(sqlmock.NewRows([]string{"slug"}))
+		mock.ExpectQuery("INSERT INTO products").
+			WithArgs(product.Name, "", product.Price, "", "", "", 0, product.CategoryID, "standard", 0.0, 0.0, 0.0, 0.0, "gaming-monitor", `{"refresh_rate":144,"resolution":"2560x1440"}`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+
+		err := model.Create(product)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"refresh_rate": 144.0, "resolution": "2560x1440"}, product.Attributes)
+	})
+
+	// Test case 7: Products without a category carry no attributes
+	t.Run("attributes without category", func(t *testing.T) {
+		product := &Product{Name: "Keyboard", Price: 89.99, Attributes: map[string]interface{}{"switch_type": "tactile"}}
+
+		err := model.Create(product)
+		var attrErr *AttributeError
+		assert.ErrorAs(t, err, &attrErr)
+		assert.Equal(t, []AttributeProblem{{Code: "switch_type", Problem: "not defined for the product's category"}}, attrErr.Problems)
+	})
+
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
@@ -160,12 +598,29 @@ func TestProductModel_Update(t *testing.T) {
 			HTMLContent: "<p>Updated hammer details</p>",
 		}
 
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT name, slug, stock, regular_price\\(products\\) FROM products WHERE id = \\$1 FOR UPDATE").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"name", "slug", "stock", "price"}).AddRow("Hammer", "hammer", 8, 29.99))
+		mock.ExpectQuery(takenSlugsQuery).
+			WithArgs("updated-hammer", "updated-hammer-%", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"slug"}))
 		mock.ExpectExec("UPDATE products").
-			WithArgs(product.Name, product.Description, product.Price, product.ImagePath, product.HTMLContent, product.ID).
+			WithArgs(product.Name, product.Description, product.ImagePath, product.HTMLContent, product.SKU, product.CategoryID, "standard", 0.0, 0.0, 0.0, 0.0, "updated-hammer", "{}", product.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM product_slug_redirects WHERE slug = \\$1").
+			WithArgs("updated-hammer").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO product_slug_redirects \\(slug, product_id\\)").
+			WithArgs("hammer", 1).
 			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
 
 		err := model.Update(product)
 		assert.NoError(t, err)
+		assert.Equal(t, "updated-hammer", product.Slug)
+		assert.Equal(t, 8, product.Stock)
+		assert.Equal(t, 29.99, product.Price)
 	})
 
 	// Test case 2: Product not found
@@ -175,15 +630,35 @@ func TestProductModel_Update(t *testing.T) {
 			Name:        "Non-existent Product",
 		}
 
-		mock.ExpectExec("UPDATE products").
-			WithArgs(product.Name, product.Description, product.Price, product.ImagePath, product.HTMLContent, product.ID).
-			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT name, slug, stock, regular_price\\(products\\) FROM products WHERE id = \\$1 FOR UPDATE").
+			WithArgs(999).
+			WillReturnRows(sqlmock.NewRows([]string{"name", "slug", "stock", "price"}))
+		mock.ExpectRollback()
 
 		err := model.Update(product)
 		assert.Error(t, err)
 		assert.Equal(t, "product not found", err.Error())
 	})
 
+	// Test case 3: A slug set by hand survives a rename
+	t.Run("custom slug kept", func(t *testing.T) {
+		product := &Product{ID: 2, Name: "Claw Hammer", Description: "A sturdy hammer", Price: 29.99, Slug: "best-hammer"}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT name, slug, stock, regular_price\\(products\\) FROM products WHERE id = \\$1 FOR UPDATE").
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"name", "slug", "stock", "price"}).AddRow("Hammer", "best-hammer", 0, 29.99))
+		mock.ExpectExec("UPDATE products").
+			WithArgs(product.Name, product.Description, "", "", "", nil, "standard", 0.0, 0.0, 0.0, 0.0, "best-hammer", "{}", 2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := model.Update(product)
+		assert.NoError(t, err)
+		assert.Equal(t, "best-hammer", product.Slug)
+	})
+
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
@@ -219,7 +694,156 @@ func TestProductModel_Delete(t *testing.T) {
 		assert.Equal(t, "product not found", err.Error())
 	})
 
+	// Test case 3: Components of a bundle cannot be deleted
+	t.Run("product in bundle", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM products WHERE id = \\$1").
+			WithArgs(5).
+			WillReturnError(&pq.Error{Code: "23503", Constraint: "bundle_items_component_id_fkey"})
+
+		err := model.Delete(5)
+		assert.Equal(t, "product in bundle", err.Error())
+	})
+
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
-} 
\ No newline at end of file
+}
+
+func TestProductModel_SetStock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := ProductModel{DB: db}
+
+	// Test case 1: Stock set
+	t.Run("stock set", func(t *testing.T) {
+		mock.ExpectExec("UPDATE products SET stock = \\$1 WHERE id = \\$2").
+			WithArgs(25, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, model.SetStock(1, 25))
+	})
+
+	// Test case 2: Product not found
+	t.Run("product not found", func(t *testing.T) {
+		mock.ExpectExec("UPDATE products SET stock = \\$1 WHERE id = \\$2").
+			WithArgs(25, 999).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := model.SetStock(999, 25)
+		assert.Error(t, err)
+		assert.Equal(t, "product not found", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductModel_SetPrice(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := ProductModel{DB: db}
+
+	// Test case 1: Price changed
+	t.Run("price changed", func(t *testing.T) {
+		mock.ExpectExec("UPDATE products SET price = \\$1 WHERE id = \\$2 AND price <> \\$1").
+			WithArgs(34.99, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, model.SetPrice(1, 34.99))
+	})
+
+	// Test case 2: The stored price is kept as it is
+	t.Run("same price", func(t *testing.T) {
+		mock.ExpectExec("UPDATE products SET price = \\$1 WHERE id = \\$2 AND price <> \\$1").
+			WithArgs(29.99, 1).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM products WHERE id = \\$1\\)").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+		assert.NoError(t, model.SetPrice(1, 29.99))
+	})
+
+	// Test case 3: Product not found
+	t.Run("product not found", func(t *testing.T) {
+		mock.ExpectExec("UPDATE products SET price = \\$1 WHERE id = \\$2 AND price <> \\$1").
+			WithArgs(29.99, 999).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM products WHERE id = \\$1\\)").
+			WithArgs(999).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		err := model.SetPrice(999, 29.99)
+		assert.Error(t, err)
+		assert.Equal(t, "product not found", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProductModel_WithTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := ProductModel{DB: db}
+
+	// Test case 1: Operations committed together
+	t.Run("commit", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(takenSlugsQuery).
+			WithArgs("hammer", "hammer-%", 0).
+			WillReturnRows(sqlmock.NewRows([]string{"slug"}))
+		mock.ExpectQuery("INSERT INTO products").
+			WithArgs("Hammer", "A sturdy hammer", 29.99, "", "", "", 0, nil, "standard", 0.0, 0.0, 0.0, 0.0, "hammer", "{}").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectExec("DELETE FROM products WHERE id = \\$1").
+			WithArgs(2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		tx, err := model.Begin()
+		assert.NoError(t, err)
+
+		txModel := model.WithTx(tx)
+		product := &Product{Name: "Hammer", Description: "A sturdy hammer", Price: 29.99}
+		assert.NoError(t, txModel.Create(product))
+		assert.Equal(t, 1, product.ID)
+		assert.NoError(t, txModel.Delete(2))
+		assert.NoError(t, tx.Commit())
+	})
+
+	// Test case 2: Rollback after a failed operation
+	t.Run("rollback", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM products WHERE id = \\$1").
+			WithArgs(999).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		tx, err := model.Begin()
+		assert.NoError(t, err)
+
+		err = model.WithTx(tx).Delete(999)
+		assert.Error(t, err)
+		assert.Equal(t, "product not found", err.Error())
+		assert.NoError(t, tx.Rollback())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,321 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"garage-api/internal/promotion"
+)
+
+// Promotion is a coupon, when it has a code, or an automatic promotion
+type Promotion struct {
+	ID          int            `json:"id" example:"1"`
+	Name        string         `json:"name" example:"Buy 2 mouse pads, get 10% off"`
+	Code        string         `json:"code,omitempty" example:"SUMMER10"`
+	Type        promotion.Type `json:"type" example:"percentage"`
+	Value       float64        `json:"value" example:"10"`
+	StartsAt    *time.Time     `json:"starts_at,omitempty"`
+	EndsAt      *time.Time     `json:"ends_at,omitempty"`
+	MinSubtotal float64        `json:"min_subtotal" example:"0"`
+	MinQuantity int            `json:"min_quantity" example:"2"`
+	ProductIDs  []int          `json:"product_ids" example:"4"`
+	CategoryIDs []int          `json:"category_ids"`
+	// UsageLimit and PerUserLimit of 0 mean unlimited
+	UsageLimit   int       `json:"usage_limit" example:"100"`
+	PerUserLimit int       `json:"per_user_limit" example:"1"`
+	Priority     int       `json:"priority" example:"0"`
+	Active       bool      `json:"active" example:"true"`
+	TimesUsed    int       `json:"times_used" example:"12"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// PromotionModelInterface defines the methods that a promotion model must implement
+type PromotionModelInterface interface {
+	GetAll() ([]Promotion, error)
+	Get(id int) (*Promotion, error)
+	Create(p *Promotion) error
+	Update(p *Promotion) error
+	Delete(id int) error
+}
+
+type PromotionModel struct {
+	DB *sql.DB
+}
+
+// NormalizeCouponCode makes coupon codes case-insensitive
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+const promotionColumns = `id, name, COALESCE(code, ''), type, value, starts_at, ends_at, min_subtotal, min_quantity,
+	product_ids, category_ids, usage_limit, per_user_limit, priority, active,
+	(SELECT COUNT(*) FROM promotion_redemptions r WHERE r.promotion_id = promotions.id), created_at`
+
+func scanPromotion(row rowScanner, p *Promotion) error {
+	var startsAt, endsAt sql.NullTime
+	var productIDs, categoryIDs []int64
+	err := row.Scan(&p.ID, &p.Name, &p.Code, &p.Type, &p.Value, &startsAt, &endsAt, &p.MinSubtotal, &p.MinQuantity,
+		pq.Array(&productIDs), pq.Array(&categoryIDs), &p.UsageLimit, &p.PerUserLimit, &p.Priority, &p.Active,
+		&p.TimesUsed, &p.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	p.StartsAt, p.EndsAt = nil, nil
+	if startsAt.Valid {
+		p.StartsAt = &startsAt.Time
+	}
+	if endsAt.Valid {
+		p.EndsAt = &endsAt.Time
+	}
+	p.ProductIDs = toInts(productIDs)
+	p.CategoryIDs = toInts(categoryIDs)
+	return nil
+}
+
+func toInts(ids []int64) []int {
+	out := make([]int, len(ids))
+	for i, id := range ids {
+		out[i] = int(id)
+	}
+	return out
+}
+
+func toInt64s(ids []int) []int64 {
+	out := make([]int64, len(ids))
+	for i, id := range ids {
+		out[i] = int64(id)
+	}
+	return out
+}
+
+// rule converts a stored promotion into an engine rule
+func (p *Promotion) rule() promotion.Rule {
+	return promotion.Rule{
+		ID:           p.ID,
+		Code:         p.Code,
+		Name:         p.Name,
+		Type:         p.Type,
+		Value:        p.Value,
+		StartsAt:     p.StartsAt,
+		EndsAt:       p.EndsAt,
+		MinSubtotal:  p.MinSubtotal,
+		MinQuantity:  p.MinQuantity,
+		ProductIDs:   p.ProductIDs,
+		CategoryIDs:  p.CategoryIDs,
+		UsageLimit:   p.UsageLimit,
+		PerUserLimit: p.PerUserLimit,
+		Priority:     p.Priority,
+		TimesUsed:    p.TimesUsed,
+	}
+}
+
+func (m PromotionModel) GetAll() ([]Promotion, error) {
+	stmt := `SELECT ` + promotionColumns + ` FROM promotions ORDER BY id`
+
+	rows, err := m.DB.Query(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	promotions := []Promotion{}
+	for rows.Next() {
+		var p Promotion
+		if err := scanPromotion(rows, &p); err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, p)
+	}
+
+	return promotions, rows.Err()
+}
+
+func (m PromotionModel) Get(id int) (*Promotion, error) {
+	stmt := `SELECT ` + promotionColumns + ` FROM promotions WHERE id = $1`
+
+	var p Promotion
+	if err := scanPromotion(m.DB.QueryRow(stmt, id), &p); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("promotion not found")
+		}
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func promotionArgs(p *Promotion) []interface{} {
+	return []interface{}{
+		p.Name, p.Code, string(p.Type), p.Value, p.StartsAt, p.EndsAt, p.MinSubtotal, p.MinQuantity,
+		pq.Array(toInt64s(p.ProductIDs)), pq.Array(toInt64s(p.CategoryIDs)), p.UsageLimit, p.PerUserLimit, p.Priority, p.Active,
+	}
+}
+
+func uniqueCodeError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return errors.New("code already exists")
+	}
+	return err
+}
+
+func (m PromotionModel) Create(p *Promotion) error {
+	p.Code = NormalizeCouponCode(p.Code)
+
+	stmt := `
+		INSERT INTO promotions (name, code, type, value, starts_at, ends_at, min_subtotal, min_quantity,
+			product_ids, category_ids, usage_limit, per_user_limit, priority, active)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id, created_at`
+
+	err := m.DB.QueryRow(stmt, promotionArgs(p)...).Scan(&p.ID, &p.CreatedAt)
+	return uniqueCodeError(err)
+}
+
+func (m PromotionModel) Update(p *Promotion) error {
+	p.Code = NormalizeCouponCode(p.Code)
+
+	stmt := `
+		UPDATE promotions SET name = $1, code = NULLIF($2, ''), type = $3, value = $4, starts_at = $5, ends_at = $6,
+			min_subtotal = $7, min_quantity = $8, product_ids = $9, category_ids = $10, usage_limit = $11,
+			per_user_limit = $12, priority = $13, active = $14
+		WHERE id = $15`
+
+	result, err := m.DB.Exec(stmt, append(promotionArgs(p), p.ID)...)
+	if err != nil {
+		return uniqueCodeError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("promotion not found")
+	}
+
+	return nil
+}
+
+func (m PromotionModel) Delete(id int) error {
+	stmt := `DELETE FROM promotions WHERE id = $1`
+
+	result, err := m.DB.Exec(stmt, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("promotion not found")
+	}
+
+	return nil
+}
+
+// evaluatePromotions runs the active automatic promotions and the given
+// coupon against lines. With lock set every running promotion with a usage
+// limit, and the coupon, is locked so that limits hold under concurrent
+// checkouts; this requires db to be a transaction.
+func evaluatePromotions(db DBTX, lines []promotion.Line, code string, userID int, now time.Time, lock bool) (*promotion.Result, error) {
+	// Lock in a statement of its own, in ID order so that checkouts cannot
+	// deadlock: usage counts must be read by later statements so they see
+	// redemptions committed while we waited
+	if lock {
+		if err := lockPromotions(db, code, now); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := db.Query(`SELECT ` + promotionColumns + ` FROM promotions WHERE active AND code IS NULL`)
+	if err != nil {
+		return nil, err
+	}
+
+	var automatic []promotion.Rule
+	for rows.Next() {
+		var p Promotion
+		if err := scanPromotion(rows, &p); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		automatic = append(automatic, p.rule())
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var coupon *promotion.Rule
+	if code != "" {
+		stmt := `SELECT ` + promotionColumns + ` FROM promotions WHERE active AND code = $1`
+		var p Promotion
+		if err := scanPromotion(db.QueryRow(stmt, code), &p); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, &promotion.CouponError{Code: code, Reason: "does not exist"}
+			}
+			return nil, err
+		}
+		rule := p.rule()
+		coupon = &rule
+	}
+
+	if userID != 0 {
+		if err := countUserRedemptions(db, userID, automatic, coupon); err != nil {
+			return nil, err
+		}
+	}
+
+	return promotion.Evaluate(lines, automatic, coupon, userID, now)
+}
+
+// lockPromotions locks the coupon and the running automatic promotions
+// that are limited overall or per customer
+func lockPromotions(db DBTX, code string, now time.Time) error {
+	stmt := `
+		SELECT id FROM promotions
+		WHERE active AND (code = $1 OR (code IS NULL AND (usage_limit > 0 OR per_user_limit > 0)
+			AND (starts_at IS NULL OR starts_at <= $2) AND (ends_at IS NULL OR ends_at > $2)))
+		ORDER BY id
+		FOR UPDATE`
+	_, err := db.Exec(stmt, code, now)
+	return err
+}
+
+// countUserRedemptions fills in how often the user has already used each rule
+func countUserRedemptions(db DBTX, userID int, automatic []promotion.Rule, coupon *promotion.Rule) error {
+	rows, err := db.Query(`SELECT promotion_id, COUNT(*) FROM promotion_redemptions WHERE user_id = $1 GROUP BY promotion_id`, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	used := map[int]int{}
+	for rows.Next() {
+		var id, count int
+		if err := rows.Scan(&id, &count); err != nil {
+			return err
+		}
+		used[id] = count
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range automatic {
+		automatic[i].TimesUsedByUser = used[automatic[i].ID]
+	}
+	if coupon != nil {
+		coupon.TimesUsedByUser = used[coupon.ID]
+	}
+	return nil
+}
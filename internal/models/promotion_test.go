@@ -0,0 +1,117 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"garage-api/internal/promotion"
+)
+
+var promotionRowColumns = []string{"id", "name", "code", "type", "value", "starts_at", "ends_at", "min_subtotal", "min_quantity",
+	"product_ids", "category_ids", "usage_limit", "per_user_limit", "priority", "active", "times_used", "created_at"}
+
+// promotionRow builds a promotions row with no validity window or usage limit
+func promotionRow(id int, name, code, typ string, value float64, minQuantity int, productIDs string, perUserLimit int) []driver.Value {
+	return []driver.Value{id, name, code, typ, value, nil, nil, 0.0, minQuantity, productIDs, "{}", 0, perUserLimit, 0, true, 0, time.Now()}
+}
+
+func TestPromotionModel_Get(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := PromotionModel{DB: db}
+
+	// Test case 1: Successful retrieval
+	t.Run("successful retrieval", func(t *testing.T) {
+		mock.ExpectQuery("SELECT .* FROM promotions WHERE id = \\$1").
+			WithArgs(20).
+			WillReturnRows(sqlmock.NewRows(promotionRowColumns).
+				AddRow(promotionRow(20, "Buy 2 mouse pads, get 10% off", "", "percentage", 10, 2, "{1,4}", 0)...))
+
+		p, err := model.Get(20)
+		assert.NoError(t, err)
+		assert.Equal(t, promotion.Percentage, p.Type)
+		assert.Equal(t, []int{1, 4}, p.ProductIDs)
+		assert.Equal(t, []int{}, p.CategoryIDs)
+		assert.Nil(t, p.StartsAt)
+	})
+
+	// Test case 2: Promotion not found
+	t.Run("promotion not found", func(t *testing.T) {
+		mock.ExpectQuery("SELECT .* FROM promotions WHERE id = \\$1").
+			WithArgs(999).
+			WillReturnError(sql.ErrNoRows)
+
+		p, err := model.Get(999)
+		assert.Nil(t, p)
+		assert.Equal(t, "promotion not found", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPromotionModel_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := PromotionModel{DB: db}
+	ends := time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("INSERT INTO promotions \\(name, code, type, value, .*\\) VALUES \\(\\$1, NULLIF\\(\\$2, ''\\), .*\\) RETURNING id, created_at").
+		WithArgs("Summer sale", "SUMMER10", "percentage", 10.0, nil, &ends, 50.0, 0, sqlmock.AnyArg(), sqlmock.AnyArg(), 100, 1, 0, true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+
+	p := &Promotion{Name: "Summer sale", Code: " summer10 ", Type: promotion.Percentage, Value: 10, EndsAt: &ends,
+		MinSubtotal: 50, CategoryIDs: []int{3}, UsageLimit: 100, PerUserLimit: 1, Active: true}
+	err = model.Create(p)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, p.ID)
+	assert.Equal(t, "SUMMER10", p.Code)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestEvaluatePromotions_LocksCoupon(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	lines := []promotion.Line{{ProductID: 1, Quantity: 1, UnitPrice: 15}}
+
+	mock.ExpectExec("SELECT id FROM promotions\\s+WHERE active AND \\(code = \\$1 OR \\(code IS NULL AND \\(usage_limit > 0 OR per_user_limit > 0\\).+ORDER BY id\\s+FOR UPDATE").
+		WithArgs("ONCE", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT .* FROM promotions WHERE active AND code IS NULL").
+		WillReturnRows(sqlmock.NewRows(promotionRowColumns))
+	mock.ExpectQuery("SELECT .* FROM promotions WHERE active AND code = \\$1").
+		WithArgs("ONCE").
+		WillReturnRows(sqlmock.NewRows(promotionRowColumns).
+			AddRow(promotionRow(5, "Once per customer", "ONCE", "fixed_amount", 5, 0, "{}", 1)...))
+	mock.ExpectQuery("SELECT promotion_id, COUNT\\(\\*\\) FROM promotion_redemptions WHERE user_id = \\$1").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"promotion_id", "count"}).AddRow(5, 1))
+
+	result, err := evaluatePromotions(db, lines, "ONCE", 7, time.Now(), true)
+	assert.Nil(t, result)
+	assert.EqualError(t, err, "coupon ONCE has already been used the maximum number of times")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,171 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ProductStatus is where a product is in the publishing workflow
+type ProductStatus string
+
+const (
+	ProductDraft     ProductStatus = "draft"
+	ProductInReview  ProductStatus = "in_review"
+	ProductPublished ProductStatus = "published"
+	ProductArchived  ProductStatus = "archived"
+)
+
+// productTransitions lists the states each state may move to. Rejected
+// reviews, unpublished and restored products all go back to draft.
+var productTransitions = map[ProductStatus][]ProductStatus{
+	ProductDraft:     {ProductInReview, ProductPublished, ProductArchived},
+	ProductInReview:  {ProductDraft, ProductPublished, ProductArchived},
+	ProductPublished: {ProductDraft, ProductArchived},
+	ProductArchived:  {ProductDraft},
+}
+
+// Valid reports whether s is a known product status
+func (s ProductStatus) Valid() bool {
+	switch s {
+	case ProductDraft, ProductInReview, ProductPublished, ProductArchived:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo reports whether a product in state s may move to next
+func (s ProductStatus) CanTransitionTo(next ProductStatus) bool {
+	for _, allowed := range productTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// needsPublisher reports whether moving from s to next changes what
+// customers see, which only admins may do
+func (s ProductStatus) needsPublisher(next ProductStatus) bool {
+	return s == ProductPublished || next == ProductPublished
+}
+
+// Unreleased reports whether a product in state s has never reached
+// customers, so editors may still change or delete it
+func (s ProductStatus) Unreleased() bool {
+	return s == ProductDraft || s == ProductInReview
+}
+
+// IsPublished reports whether customers can see the product at now
+func (p *Product) IsPublished(now time.Time) bool {
+	return p.Status == ProductPublished && (p.PublishAt == nil || !p.PublishAt.After(now))
+}
+
+// ProductTransitionError is returned when a product is asked to move to a
+// state its current state does not allow
+type ProductTransitionError struct {
+	From ProductStatus
+	To   ProductStatus
+}
+
+func (e *ProductTransitionError) Error() string {
+	return fmt.Sprintf("cannot move product from %s to %s", e.From, e.To)
+}
+
+// ProductStatusChange records a move of a product between two states.
+// PublishAt is only set when the product was published for a later time.
+type ProductStatusChange struct {
+	ID         int           `json:"id" example:"1"`
+	ProductID  int           `json:"product_id" example:"1"`
+	FromStatus ProductStatus `json:"from_status" example:"in_review"`
+	ToStatus   ProductStatus `json:"to_status" example:"published"`
+	PublishAt  *time.Time    `json:"publish_at,omitempty"`
+	Note       string        `json:"note,omitempty" example:"Approved for the spring catalog"`
+	ChangedBy  int           `json:"changed_by,omitempty" example:"1"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+// PublishingModelInterface defines the methods that a publishing model must implement
+type PublishingModelInterface interface {
+	SetStatus(change *ProductStatusChange, canPublish bool) error
+	History(productID int) ([]ProductStatusChange, error)
+}
+
+type PublishingModel struct {
+	DB *sql.DB
+}
+
+// SetStatus moves a product to change.ToStatus, enforcing the allowed
+// transitions, and records the change. Publishing and unpublishing need
+// canPublish. change is filled in with the previous state and the record's
+// ID and time.
+func (m PublishingModel) SetStatus(change *ProductStatusChange, canPublish bool) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`SELECT status FROM products WHERE id = $1 FOR UPDATE`, change.ProductID).Scan(&change.FromStatus)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("product not found")
+		}
+		return err
+	}
+
+	if !change.FromStatus.CanTransitionTo(change.ToStatus) {
+		return &ProductTransitionError{From: change.FromStatus, To: change.ToStatus}
+	}
+	if change.FromStatus.needsPublisher(change.ToStatus) && !canPublish {
+		return errors.New("publisher required")
+	}
+	if change.ToStatus != ProductPublished {
+		change.PublishAt = nil
+	}
+
+	if _, err := tx.Exec(`UPDATE products SET status = $2, publish_at = $3 WHERE id = $1`, change.ProductID, string(change.ToStatus), change.PublishAt); err != nil {
+		return err
+	}
+
+	stmt := `
+		INSERT INTO product_status_history (product_id, from_status, to_status, publish_at, note, changed_by)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0))
+		RETURNING id, created_at`
+	err = tx.QueryRow(stmt, change.ProductID, string(change.FromStatus), string(change.ToStatus), change.PublishAt, change.Note, change.ChangedBy).
+		Scan(&change.ID, &change.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// History returns a product's status changes, latest first
+func (m PublishingModel) History(productID int) ([]ProductStatusChange, error) {
+	stmt := `
+		SELECT id, product_id, from_status, to_status, publish_at, note, COALESCE(changed_by, 0), created_at
+		FROM product_status_history
+		WHERE product_id = $1
+		ORDER BY created_at DESC, id DESC`
+	rows, err := m.DB.Query(stmt, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []ProductStatusChange{}
+	for rows.Next() {
+		var c ProductStatusChange
+		var publishAt sql.NullTime
+		if err := rows.Scan(&c.ID, &c.ProductID, &c.FromStatus, &c.ToStatus, &publishAt, &c.Note, &c.ChangedBy, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		if publishAt.Valid {
+			c.PublishAt = &publishAt.Time
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
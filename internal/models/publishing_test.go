@@ -0,0 +1,121 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProductStatus_CanTransitionTo(t *testing.T) {
+	assert.True(t, ProductDraft.CanTransitionTo(ProductInReview))
+	assert.True(t, ProductInReview.CanTransitionTo(ProductPublished))
+	assert.True(t, ProductPublished.CanTransitionTo(ProductArchived))
+	assert.True(t, ProductArchived.CanTransitionTo(ProductDraft))
+	assert.False(t, ProductArchived.CanTransitionTo(ProductPublished))
+	assert.False(t, ProductPublished.CanTransitionTo(ProductInReview))
+}
+
+func TestProductStatus_Unreleased(t *testing.T) {
+	assert.True(t, ProductDraft.Unreleased())
+	assert.True(t, ProductInReview.Unreleased())
+	assert.False(t, ProductPublished.Unreleased())
+	assert.False(t, ProductArchived.Unreleased())
+}
+
+func TestPublishingModel_SetStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := PublishingModel{DB: db}
+	now := time.Now()
+	launch := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	// Test case 1: An editor submits a draft for review
+	t.Run("submit for review", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT status FROM products WHERE id = \\$1 FOR UPDATE").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("draft"))
+		mock.ExpectExec("UPDATE products SET status = \\$2, publish_at = \\$3 WHERE id = \\$1").
+			WithArgs(1, "in_review", nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO product_status_history").
+			WithArgs(1, "draft", "in_review", nil, "", 3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, now))
+		mock.ExpectCommit()
+
+		change := &ProductStatusChange{ProductID: 1, ToStatus: ProductInReview, ChangedBy: 3}
+		err := model.SetStatus(change, false)
+		assert.NoError(t, err)
+		assert.Equal(t, ProductDraft, change.FromStatus)
+		assert.Equal(t, 1, change.ID)
+	})
+
+	// Test case 2: Only publishers may approve
+	t.Run("publisher required", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT status FROM products WHERE id = \\$1 FOR UPDATE").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("in_review"))
+		mock.ExpectRollback()
+
+		err := model.SetStatus(&ProductStatusChange{ProductID: 1, ToStatus: ProductPublished, ChangedBy: 3}, false)
+		assert.Equal(t, "publisher required", err.Error())
+	})
+
+	// Test case 3: An admin publishes for a later time
+	t.Run("scheduled publication", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT status FROM products WHERE id = \\$1 FOR UPDATE").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("in_review"))
+		mock.ExpectExec("UPDATE products SET status = \\$2, publish_at = \\$3 WHERE id = \\$1").
+			WithArgs(1, "published", launch).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO product_status_history").
+			WithArgs(1, "in_review", "published", launch, "Spring launch", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(2, now))
+		mock.ExpectCommit()
+
+		change := &ProductStatusChange{ProductID: 1, ToStatus: ProductPublished, PublishAt: &launch, Note: "Spring launch", ChangedBy: 1}
+		err := model.SetStatus(change, true)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, change.ID)
+	})
+
+	// Test case 4: Transition not allowed
+	t.Run("invalid transition", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT status FROM products WHERE id = \\$1 FOR UPDATE").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("archived"))
+		mock.ExpectRollback()
+
+		err := model.SetStatus(&ProductStatusChange{ProductID: 1, ToStatus: ProductPublished, ChangedBy: 1}, true)
+		var transitionErr *ProductTransitionError
+		assert.True(t, errors.As(err, &transitionErr))
+		assert.Equal(t, ProductArchived, transitionErr.From)
+	})
+
+	// Test case 5: Product not found
+	t.Run("product not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT status FROM products WHERE id = \\$1 FOR UPDATE").
+			WithArgs(999).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}))
+		mock.ExpectRollback()
+
+		err := model.SetStatus(&ProductStatusChange{ProductID: 999, ToStatus: ProductInReview}, false)
+		assert.Equal(t, "product not found", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
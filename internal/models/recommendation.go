@@ -0,0 +1,191 @@
+package models
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+	"garage-api/internal/recommend"
+)
+
+// Recommendation sources
+const (
+	// RecommendationSimilar products were bought or viewed by the same customers
+	RecommendationSimilar = "similar"
+	// RecommendationBestseller products fill in for products with too few
+	// similar ones: the bestsellers of the same category
+	RecommendationBestseller = "bestseller"
+)
+
+// RecommendedProduct is a product suggested alongside another one
+type RecommendedProduct struct {
+	ProductID int     `json:"product_id" example:"2"`
+	Name      string  `json:"name" example:"Monitor Arm"`
+	Slug      string  `json:"slug" example:"monitor-arm"`
+	Price     float64 `json:"price" example:"49.99"`
+	ImagePath string  `json:"image_path,omitempty" example:"/images/monitor-arm.jpg"`
+	Stock     int     `json:"stock" example:"12"`
+	Source    string  `json:"source" example:"similar"`
+	// Score is only set on similar products; higher is stronger
+	Score float64 `json:"score,omitempty" example:"0.91"`
+}
+
+// RecommendationModelInterface defines the methods that a recommendation model must implement
+type RecommendationModelInterface interface {
+	Rebuild(opts recommend.Options) (int, error)
+	List(productID, limit int) ([]RecommendedProduct, error)
+	Bestsellers(categoryID int, exclude []int, limit int) ([]RecommendedProduct, error)
+}
+
+type RecommendationModel struct {
+	DB *sql.DB
+}
+
+// soldStatuses are the order statuses whose lines count as purchases
+const soldStatuses = `('paid', 'shipped', 'delivered')`
+
+// Rebuild recomputes every product's recommendations from what customers
+// bought and viewed, and replaces the stored ones. It returns how many
+// recommendations were computed.
+func (m RecommendationModel) Rebuild(opts recommend.Options) (int, error) {
+	purchases, err := m.baskets(`
+		SELECT array_agg(DISTINCT oi.product_id ORDER BY oi.product_id)
+		FROM orders o JOIN order_items oi ON oi.order_id = o.id
+		WHERE o.status IN ` + soldStatuses + ` AND oi.product_id IS NOT NULL
+		GROUP BY o.user_id
+		HAVING COUNT(DISTINCT oi.product_id) > 1`)
+	if err != nil {
+		return 0, err
+	}
+	views, err := m.baskets(`
+		SELECT array_agg(DISTINCT product_id ORDER BY product_id)
+		FROM product_views
+		WHERE user_id IS NOT NULL
+		GROUP BY user_id
+		HAVING COUNT(DISTINCT product_id) > 1`)
+	if err != nil {
+		return 0, err
+	}
+
+	recs := recommend.Compute(purchases, views, opts)
+	return len(recs), m.replace(recs)
+}
+
+// baskets reads one basket of product IDs per row
+func (m RecommendationModel) baskets(stmt string) ([]recommend.Basket, error) {
+	rows, err := m.DB.Query(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var baskets []recommend.Basket
+	for rows.Next() {
+		var ids []int64
+		if err := rows.Scan(pq.Array(&ids)); err != nil {
+			return nil, err
+		}
+		basket := make(recommend.Basket, len(ids))
+		for i, id := range ids {
+			basket[i] = int(id)
+		}
+		baskets = append(baskets, basket)
+	}
+	return baskets, rows.Err()
+}
+
+// replace swaps the stored recommendations for recs in one transaction, so
+// readers see either the old or the new ones
+func (m RecommendationModel) replace(recs []recommend.Recommendation) error {
+	productIDs := make([]int64, len(recs))
+	recommendedIDs := make([]int64, len(recs))
+	scores := make([]float64, len(recs))
+	for i, r := range recs {
+		productIDs[i], recommendedIDs[i], scores[i] = int64(r.ProductID), int64(r.RecommendedID), r.Score
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM product_recommendations`); err != nil {
+		return err
+	}
+	// Products deleted since the baskets were read are skipped
+	stmt := `
+		INSERT INTO product_recommendations (product_id, recommended_id, score)
+		SELECT r.product_id, r.recommended_id, r.score
+		FROM unnest($1::integer[], $2::integer[], $3::double precision[]) AS r (product_id, recommended_id, score)
+		JOIN products a ON a.id = r.product_id
+		JOIN products b ON b.id = r.recommended_id`
+	if _, err := tx.Exec(stmt, pq.Array(productIDs), pq.Array(recommendedIDs), pq.Array(scores)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// List returns the live products recommended alongside a product,
+// strongest first
+func (m RecommendationModel) List(productID, limit int) ([]RecommendedProduct, error) {
+	stmt := `
+		SELECT p.id, p.name, p.slug, effective_price(p), COALESCE(p.image_path, ''), p.stock, r.score
+		FROM product_recommendations r
+		JOIN products p ON p.id = r.recommended_id
+		WHERE r.product_id = $1 AND is_published(p)
+		ORDER BY r.score DESC, p.id
+		LIMIT $2`
+	rows, err := m.DB.Query(stmt, productID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []RecommendedProduct{}
+	for rows.Next() {
+		p := RecommendedProduct{Source: RecommendationSimilar}
+		if err := rows.Scan(&p.ProductID, &p.Name, &p.Slug, &p.Price, &p.ImagePath, &p.Stock, &p.Score); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+// Bestsellers returns the live products of a category that sold the most
+// units, or of the whole catalog when categoryID is 0, leaving out the
+// excluded products
+func (m RecommendationModel) Bestsellers(categoryID int, exclude []int, limit int) ([]RecommendedProduct, error) {
+	excluded := make([]int64, len(exclude))
+	for i, id := range exclude {
+		excluded[i] = int64(id)
+	}
+
+	stmt := `
+		SELECT p.id, p.name, p.slug, effective_price(p), COALESCE(p.image_path, ''), p.stock
+		FROM (
+			SELECT oi.product_id, SUM(oi.quantity) AS units
+			FROM order_items oi JOIN orders o ON o.id = oi.order_id
+			WHERE o.status IN ` + soldStatuses + `
+			GROUP BY oi.product_id
+		) s
+		JOIN products p ON p.id = s.product_id
+		WHERE ($1 = 0 OR p.category_id = $1) AND p.id <> ALL($2::integer[]) AND is_published(p)
+		ORDER BY s.units DESC, p.id
+		LIMIT $3`
+	rows, err := m.DB.Query(stmt, categoryID, pq.Array(excluded), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []RecommendedProduct{}
+	for rows.Next() {
+		p := RecommendedProduct{Source: RecommendationBestseller}
+		if err := rows.Scan(&p.ProductID, &p.Name, &p.Slug, &p.Price, &p.ImagePath, &p.Stock); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
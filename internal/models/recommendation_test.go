@@ -0,0 +1,90 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"garage-api/internal/recommend"
+)
+
+func TestRecommendationModel_Rebuild(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := RecommendationModel{DB: db}
+
+	// Two customers bought a monitor (1) and an arm (2), one of them a
+	// cable (3) too; nobody viewed anything while signed in
+	mock.ExpectQuery("SELECT array_agg\\(DISTINCT oi.product_id ORDER BY oi.product_id\\)\\s+FROM orders o JOIN order_items oi .+WHERE o.status IN \\('paid', 'shipped', 'delivered'\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"products"}).AddRow("{1,2,3}").AddRow("{1,2}"))
+	mock.ExpectQuery("SELECT array_agg\\(DISTINCT product_id ORDER BY product_id\\)\\s+FROM product_views").
+		WillReturnRows(sqlmock.NewRows([]string{"products"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM product_recommendations").
+		WillReturnResult(sqlmock.NewResult(0, 40))
+	mock.ExpectExec("INSERT INTO product_recommendations \\(product_id, recommended_id, score\\)\\s+SELECT .+FROM unnest\\(\\$1::integer\\[\\], \\$2::integer\\[\\], \\$3::double precision\\[\\]\\)").
+		WithArgs(pq.Array([]int64{1, 2}), pq.Array([]int64{2, 1}), pq.Array([]float64{1, 1})).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	n, err := model.Rebuild(recommend.Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRecommendationModel_List(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := RecommendationModel{DB: db}
+
+	mock.ExpectQuery("FROM product_recommendations r\\s+JOIN products p ON p.id = r.recommended_id\\s+WHERE r.product_id = \\$1 AND is_published\\(p\\)\\s+ORDER BY r.score DESC, p.id\\s+LIMIT \\$2").
+		WithArgs(1, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "slug", "price", "image_path", "stock", "score"}).
+			AddRow(2, "Monitor Arm", "monitor-arm", 49.99, "", 12, 0.91))
+
+	products, err := model.List(1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []RecommendedProduct{{ProductID: 2, Name: "Monitor Arm", Slug: "monitor-arm", Price: 49.99, Stock: 12, Source: RecommendationSimilar, Score: 0.91}}, products)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRecommendationModel_Bestsellers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := RecommendationModel{DB: db}
+
+	mock.ExpectQuery("SUM\\(oi.quantity\\) AS units.+WHERE \\(\\$1 = 0 OR p.category_id = \\$1\\) AND p.id <> ALL\\(\\$2::integer\\[\\]\\) AND is_published\\(p\\)\\s+ORDER BY s.units DESC, p.id\\s+LIMIT \\$3").
+		WithArgs(3, pq.Array([]int64{1, 2}), 8).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "slug", "price", "image_path", "stock"}).
+			AddRow(7, "Office Monitor", "office-monitor", 149.99, "", 3))
+
+	products, err := model.Bestsellers(3, []int{1, 2}, 8)
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, RecommendationBestseller, products[0].Source)
+	assert.Zero(t, products[0].Score)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
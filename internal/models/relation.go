@@ -0,0 +1,130 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// RelationType says how a product relates to another
+type RelationType string
+
+const (
+	// RelationRelated suggests a similar product
+	RelationRelated RelationType = "related"
+	// RelationAccessory suggests a product to buy along with this one
+	RelationAccessory RelationType = "accessory"
+	// RelationUpsell suggests a better, usually pricier, alternative
+	RelationUpsell RelationType = "upsell"
+	// RelationReplacement suggests a successor of a discontinued product
+	RelationReplacement RelationType = "replacement"
+)
+
+// Valid reports whether t is a known relation type
+func (t RelationType) Valid() bool {
+	switch t {
+	case RelationRelated, RelationAccessory, RelationUpsell, RelationReplacement:
+		return true
+	}
+	return false
+}
+
+// ProductRelation links a product to another one
+type ProductRelation struct {
+	Type      RelationType `json:"type" example:"accessory"`
+	ProductID int          `json:"product_id" example:"5"`
+}
+
+// RelatedProduct is a product suggested on another product's page
+type RelatedProduct struct {
+	Type      RelationType `json:"type" example:"accessory"`
+	ProductID int          `json:"product_id" example:"5"`
+	Name      string       `json:"name" example:"Gaming Headset"`
+	Price     float64      `json:"price" example:"149.99"`
+	ImagePath string       `json:"image_path,omitempty" example:"/images/headset.jpg"`
+	Stock     int          `json:"stock" example:"12"`
+}
+
+// RelationModelInterface defines the methods that a relation model must implement
+type RelationModelInterface interface {
+	List(productID int, publishedOnly bool) ([]RelatedProduct, error)
+	Set(productID int, relations []ProductRelation) ([]RelatedProduct, error)
+}
+
+type RelationModel struct {
+	DB *sql.DB
+}
+
+// List returns the products related to a product, grouped by type in the
+// order they were given. With publishedOnly, products customers cannot see
+// are left out.
+func (m RelationModel) List(productID int, publishedOnly bool) ([]RelatedProduct, error) {
+	stmt := `
+		SELECT r.type, p.id, p.name, effective_price(p), COALESCE(p.image_path, ''), p.stock
+		FROM product_relations r
+		JOIN products p ON p.id = r.related_product_id
+		WHERE r.product_id = $1 AND (NOT $2 OR is_published(p))
+		ORDER BY r.type, r.position, p.id`
+	rows, err := m.DB.Query(stmt, productID, publishedOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	related := []RelatedProduct{}
+	for rows.Next() {
+		var r RelatedProduct
+		if err := rows.Scan(&r.Type, &r.ProductID, &r.Name, &r.Price, &r.ImagePath, &r.Stock); err != nil {
+			return nil, err
+		}
+		related = append(related, r)
+	}
+	return related, rows.Err()
+}
+
+// Set replaces a product's relations. Relations listed twice are kept once.
+func (m RelationModel) Set(productID int, relations []ProductRelation) ([]RelatedProduct, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM products WHERE id = $1)`, productID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New("product not found")
+	}
+
+	if _, err := tx.Exec(`DELETE FROM product_relations WHERE product_id = $1`, productID); err != nil {
+		return nil, err
+	}
+
+	positions := make(map[RelationType]int)
+	seen := make(map[ProductRelation]bool)
+	for _, r := range relations {
+		if seen[r] {
+			continue
+		}
+		seen[r] = true
+
+		stmt := `INSERT INTO product_relations (product_id, related_product_id, type, position) VALUES ($1, $2, $3, $4)`
+		if _, err := tx.Exec(stmt, productID, r.ProductID, string(r.Type), positions[r.Type]); err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+				return nil, errors.New("related product not found")
+			}
+			return nil, err
+		}
+		positions[r.Type]++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return m.List(productID, false)
+}
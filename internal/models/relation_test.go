@@ -0,0 +1,79 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelationModel_Set(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := RelationModel{DB: db}
+
+	// Test case 1: Relations are replaced and numbered per type
+	t.Run("replace relations", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM products WHERE id = \\$1\\)").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectExec("DELETE FROM product_relations WHERE product_id = \\$1").
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec("INSERT INTO product_relations \\(product_id, related_product_id, type, position\\)").
+			WithArgs(1, 5, "accessory", 0).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO product_relations").
+			WithArgs(1, 7, "accessory", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO product_relations").
+			WithArgs(1, 4, "upsell", 0).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT r.type, p.id, .* FROM product_relations r\\s+JOIN products p ON p.id = r.related_product_id\\s+WHERE r.product_id = \\$1").
+			WithArgs(1, false).
+			WillReturnRows(sqlmock.NewRows([]string{"type", "id", "name", "price", "image_path", "stock"}).
+				AddRow("accessory", 5, "Gaming Headset", 149.99, "/images/headset.jpg", 12).
+				AddRow("accessory", 7, "RGB Mouse Pad", 29.99, "/images/mousepad.jpg", 40).
+				AddRow("upsell", 4, "4K Monitor", 499.99, "/images/monitor.jpg", 3))
+
+		related, err := model.Set(1, []ProductRelation{
+			{Type: RelationAccessory, ProductID: 5},
+			{Type: RelationAccessory, ProductID: 7},
+			{Type: RelationAccessory, ProductID: 5},
+			{Type: RelationUpsell, ProductID: 4},
+		})
+		assert.NoError(t, err)
+		assert.Len(t, related, 3)
+		assert.Equal(t, RelationUpsell, related[2].Type)
+	})
+
+	// Test case 2: Related products must exist
+	t.Run("related product not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectExec("DELETE FROM product_relations").
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO product_relations").
+			WithArgs(1, 999, "related", 0).
+			WillReturnError(&pq.Error{Code: "23503", Constraint: "product_relations_related_product_id_fkey"})
+		mock.ExpectRollback()
+
+		related, err := model.Set(1, []ProductRelation{{Type: RelationRelated, ProductID: 999}})
+		assert.Nil(t, related)
+		assert.Equal(t, "related product not found", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
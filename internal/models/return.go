@@ -0,0 +1,473 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ReturnStatus is the state of a return request
+type ReturnStatus string
+
+const (
+	ReturnRequested ReturnStatus = "requested"
+	ReturnApproved  ReturnStatus = "approved"
+	ReturnRejected  ReturnStatus = "rejected"
+	ReturnReceived  ReturnStatus = "received"
+	ReturnRefunded  ReturnStatus = "refunded"
+)
+
+// returnTransitions lists the states each state may move to. Rejected and
+// refunded returns are final.
+var returnTransitions = map[ReturnStatus][]ReturnStatus{
+	ReturnRequested: {ReturnApproved, ReturnRejected},
+	ReturnApproved:  {ReturnReceived, ReturnRejected},
+	ReturnReceived:  {ReturnRefunded},
+}
+
+// Valid reports whether s is a known return status
+func (s ReturnStatus) Valid() bool {
+	switch s {
+	case ReturnRequested, ReturnApproved, ReturnRejected, ReturnReceived, ReturnRefunded:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo reports whether a return in state s may move to next
+func (s ReturnStatus) CanTransitionTo(next ReturnStatus) bool {
+	for _, allowed := range returnTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ReturnReason is why a customer sends an item back
+type ReturnReason string
+
+const (
+	ReasonDamaged        ReturnReason = "damaged"
+	ReasonDefective      ReturnReason = "defective"
+	ReasonWrongItem      ReturnReason = "wrong_item"
+	ReasonNotAsDescribed ReturnReason = "not_as_described"
+	ReasonNoLongerNeeded ReturnReason = "no_longer_needed"
+	ReasonOther          ReturnReason = "other"
+)
+
+// Valid reports whether r is a known return reason
+func (r ReturnReason) Valid() bool {
+	switch r {
+	case ReasonDamaged, ReasonDefective, ReasonWrongItem, ReasonNotAsDescribed, ReasonNoLongerNeeded, ReasonOther:
+		return true
+	}
+	return false
+}
+
+// ReturnItem is an order line, or part of one, being sent back
+type ReturnItem struct {
+	ID          int          `json:"id" example:"1"`
+	OrderItemID int          `json:"order_item_id" example:"3"`
+	ProductID   *int         `json:"product_id,omitempty" example:"7"`
+	SKU         string       `json:"sku,omitempty" example:"HS-002"`
+	Name        string       `json:"name" example:"Wireless Headset"`
+	Quantity    int          `json:"quantity" example:"1"`
+	Reason      ReturnReason `json:"reason" example:"defective"`
+	Comment     string       `json:"comment,omitempty" example:"Left ear cup is silent"`
+}
+
+// Return is a customer's request to send back items of a delivered order.
+// RefundAmount is fixed when the return is requested: the returned lines'
+// share of what was paid, or, for the return that sends back the last
+// items of an order, whatever of the order total is left, shipping included.
+type Return struct {
+	ID           int          `json:"id" example:"1"`
+	OrderID      int          `json:"order_id" example:"11"`
+	UserID       int          `json:"user_id" example:"1"`
+	Status       ReturnStatus `json:"status" example:"requested"`
+	Items        []ReturnItem `json:"items"`
+	Note         string       `json:"note,omitempty" example:"Bought the wrong size"`
+	AdminNote    string       `json:"admin_note,omitempty" example:""`
+	RefundAmount float64      `json:"refund_amount" example:"59.99"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+// ReturnTransitionError is returned when a return is asked to move to a
+// state its current state does not allow
+type ReturnTransitionError struct {
+	From ReturnStatus
+	To   ReturnStatus
+}
+
+func (e *ReturnTransitionError) Error() string {
+	return fmt.Sprintf("cannot move return from %s to %s", e.From, e.To)
+}
+
+// ReturnQuantityError is returned when a return asks for more of an order
+// line than was bought and not already returned
+type ReturnQuantityError struct {
+	OrderItemID int
+	Requested   int
+	Available   int
+}
+
+func (e *ReturnQuantityError) Error() string {
+	return fmt.Sprintf("only %d of order item %d can be returned, %d requested", e.Available, e.OrderItemID, e.Requested)
+}
+
+// ReturnFilter narrows down return listings. Zero values are ignored.
+type ReturnFilter struct {
+	UserID int
+	Status ReturnStatus
+	Limit  int
+	Offset int
+}
+
+func (f ReturnFilter) where() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+
+	if f.UserID > 0 {
+		args = append(args, f.UserID)
+		conds = append(conds, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if f.Status != "" {
+		args = append(args, string(f.Status))
+		conds = append(conds, fmt.Sprintf("status = $%d", len(args)))
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// ReturnModelInterface defines the methods that a return model must implement
+type ReturnModelInterface interface {
+	Create(ret *Return) error
+	Get(id int) (*Return, error)
+	List(filter ReturnFilter) ([]Return, error)
+	UpdateStatus(id int, status ReturnStatus, note string) (*Return, error)
+}
+
+type ReturnModel struct {
+	DB *sql.DB
+}
+
+const returnColumns = `id, order_id, user_id, status, note, admin_note, refund_amount, created_at, updated_at`
+
+func scanReturn(row rowScanner, ret *Return) error {
+	return row.Scan(&ret.ID, &ret.OrderID, &ret.UserID, &ret.Status, &ret.Note, &ret.AdminNote, &ret.RefundAmount, &ret.CreatedAt, &ret.UpdatedAt)
+}
+
+// returnableLine is an order line with what can still be sent back of it
+type returnableLine struct {
+	item      OrderItem
+	paid      float64
+	available int
+}
+
+// Create records a return request for lines of one of the user's delivered
+// orders. Items need only OrderItemID, Quantity, Reason and Comment; the
+// rest is filled in from the order. Returns still open or done count
+// against the quantities that can be returned; rejected ones do not.
+func (m ReturnModel) Create(ret *Return) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Locking the order serializes return requests for it, so two requests
+	// cannot both return the last unit of a line
+	var userID int
+	var status OrderStatus
+	var pricesIncludeTax bool
+	var total float64
+	stmt := `SELECT user_id, status, prices_include_tax, total FROM orders WHERE id = $1 FOR UPDATE`
+	err = tx.QueryRow(stmt, ret.OrderID).Scan(&userID, &status, &pricesIncludeTax, &total)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("order not found")
+		}
+		return err
+	}
+	if userID != ret.UserID {
+		return errors.New("order not found")
+	}
+	if status != OrderDelivered {
+		return errors.New("order not delivered")
+	}
+
+	stmt = `
+		SELECT oi.id, oi.product_id, COALESCE(oi.sku, ''), oi.name, oi.quantity, oi.line_total, oi.discount, oi.tax,
+			oi.quantity - COALESCE((
+				SELECT SUM(ri.quantity) FROM return_items ri JOIN returns r ON r.id = ri.return_id
+				WHERE ri.order_item_id = oi.id AND r.status <> 'rejected'), 0)
+		FROM order_items oi
+		WHERE oi.order_id = $1
+		ORDER BY oi.id`
+	rows, err := tx.Query(stmt, ret.OrderID)
+	if err != nil {
+		return err
+	}
+	lines := map[int]*returnableLine{}
+	for rows.Next() {
+		var line returnableLine
+		var productID sql.NullInt64
+		item := &line.item
+		if err := rows.Scan(&item.ID, &productID, &item.SKU, &item.Name, &item.Quantity, &item.LineTotal, &item.Discount, &item.Tax, &line.available); err != nil {
+			rows.Close()
+			return err
+		}
+		if productID.Valid {
+			id := int(productID.Int64)
+			item.ProductID = &id
+		}
+		// Inclusive prices already contain the tax
+		line.paid = item.LineTotal - item.Discount
+		if !pricesIncludeTax {
+			line.paid += item.Tax
+		}
+		lines[item.ID] = &line
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	ret.RefundAmount = 0
+	for i := range ret.Items {
+		item := &ret.Items[i]
+		line, ok := lines[item.OrderItemID]
+		if !ok {
+			return errors.New("order item not found")
+		}
+		if item.Quantity > line.available {
+			return &ReturnQuantityError{OrderItemID: item.OrderItemID, Requested: item.Quantity, Available: line.available}
+		}
+		line.available -= item.Quantity
+		item.ProductID, item.SKU, item.Name = line.item.ProductID, line.item.SKU, line.item.Name
+		ret.RefundAmount += line.paid * float64(item.Quantity) / float64(line.item.Quantity)
+	}
+	ret.RefundAmount = RoundMoney(ret.RefundAmount)
+
+	// The return that sends back the last items refunds the rest of the
+	// order, so shipping and rounding come out even
+	complete := true
+	for _, line := range lines {
+		if line.available > 0 {
+			complete = false
+			break
+		}
+	}
+	if complete {
+		var refunded float64
+		stmt = `SELECT COALESCE(SUM(refund_amount), 0) FROM returns WHERE order_id = $1 AND status <> 'rejected'`
+		if err := tx.QueryRow(stmt, ret.OrderID).Scan(&refunded); err != nil {
+			return err
+		}
+		ret.RefundAmount = RoundMoney(total - refunded)
+	}
+
+	stmt = `
+		INSERT INTO returns (order_id, user_id, note, refund_amount)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, status, created_at, updated_at`
+	err = tx.QueryRow(stmt, ret.OrderID, ret.UserID, ret.Note, ret.RefundAmount).Scan(&ret.ID, &ret.Status, &ret.CreatedAt, &ret.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	for i := range ret.Items {
+		item := &ret.Items[i]
+		stmt = `
+			INSERT INTO return_items (return_id, order_item_id, quantity, reason, comment)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id`
+		err := tx.QueryRow(stmt, ret.ID, item.OrderItemID, item.Quantity, string(item.Reason), item.Comment).Scan(&item.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Get returns a return with its items
+func (m ReturnModel) Get(id int) (*Return, error) {
+	var ret Return
+	stmt := `SELECT ` + returnColumns + ` FROM returns WHERE id = $1`
+	if err := scanReturn(m.DB.QueryRow(stmt, id), &ret); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("return not found")
+		}
+		return nil, err
+	}
+
+	returns := []Return{ret}
+	if err := m.loadItems(returns); err != nil {
+		return nil, err
+	}
+	return &returns[0], nil
+}
+
+// List returns the returns matching filter, newest first, with their items
+func (m ReturnModel) List(filter ReturnFilter) ([]Return, error) {
+	where, args := filter.where()
+	stmt := `SELECT ` + returnColumns + ` FROM returns` + where + ` ORDER BY created_at DESC, id DESC`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		stmt += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		stmt += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	returns := []Return{}
+	for rows.Next() {
+		var ret Return
+		if err := scanReturn(rows, &ret); err != nil {
+			return nil, err
+		}
+		returns = append(returns, ret)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := m.loadItems(returns); err != nil {
+		return nil, err
+	}
+	return returns, nil
+}
+
+// loadItems fills in the items of returns with a single query
+func (m ReturnModel) loadItems(returns []Return) error {
+	if len(returns) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(returns))
+	index := make(map[int]int, len(returns))
+	for i := range returns {
+		ids[i] = int64(returns[i].ID)
+		index[returns[i].ID] = i
+		returns[i].Items = []ReturnItem{}
+	}
+
+	stmt := `
+		SELECT ri.id, ri.return_id, ri.order_item_id, oi.product_id, COALESCE(oi.sku, ''), oi.name, ri.quantity, ri.reason, ri.comment
+		FROM return_items ri
+		JOIN order_items oi ON oi.id = ri.order_item_id
+		WHERE ri.return_id = ANY($1)
+		ORDER BY ri.id`
+
+	rows, err := m.DB.Query(stmt, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item ReturnItem
+		var returnID int
+		var productID sql.NullInt64
+		err := rows.Scan(&item.ID, &returnID, &item.OrderItemID, &productID, &item.SKU, &item.Name, &item.Quantity, &item.Reason, &item.Comment)
+		if err != nil {
+			return err
+		}
+		if productID.Valid {
+			id := int(productID.Int64)
+			item.ProductID = &id
+		}
+		if i, ok := index[returnID]; ok {
+			returns[i].Items = append(returns[i].Items, item)
+		}
+	}
+
+	return rows.Err()
+}
+
+// UpdateStatus moves a return to a new state, enforcing the allowed
+// transitions; a non-empty note replaces the admin note. Receiving a return
+// puts its items back in stock. Refunding the return that completes the
+// order's returns moves the order to refunded, which issues its credit note.
+func (m ReturnModel) UpdateStatus(id int, status ReturnStatus, note string) (*Return, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var orderID int
+	var current ReturnStatus
+	err = tx.QueryRow(`SELECT order_id, status FROM returns WHERE id = $1 FOR UPDATE`, id).Scan(&orderID, &current)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("return not found")
+		}
+		return nil, err
+	}
+
+	if !current.CanTransitionTo(status) {
+		return nil, &ReturnTransitionError{From: current, To: status}
+	}
+
+	if status == ReturnReceived {
+		stmt := `
+			UPDATE products p SET stock = p.stock + ri.quantity
+			FROM return_items ri
+			JOIN order_items oi ON oi.id = ri.order_item_id
+			WHERE ri.return_id = $1 AND p.id = oi.product_id`
+		if _, err := tx.Exec(stmt, id); err != nil {
+			return nil, err
+		}
+	}
+
+	stmt := `UPDATE returns SET status = $2, admin_note = COALESCE(NULLIF($3, ''), admin_note), updated_at = NOW() WHERE id = $1`
+	if _, err := tx.Exec(stmt, id, string(status), note); err != nil {
+		return nil, err
+	}
+
+	if status == ReturnRefunded {
+		var complete bool
+		stmt := `
+			SELECT NOT EXISTS (
+				SELECT 1 FROM order_items oi
+				WHERE oi.order_id = $1 AND oi.quantity > (
+					SELECT COALESCE(SUM(ri.quantity), 0) FROM return_items ri JOIN returns r ON r.id = ri.return_id
+					WHERE ri.order_item_id = oi.id AND r.status = 'refunded'))`
+		if err := tx.QueryRow(stmt, orderID).Scan(&complete); err != nil {
+			return nil, err
+		}
+
+		// An order refunded as a whole in the meantime stays as it is
+		if complete {
+			err := changeOrderStatus(tx, orderID, OrderRefunded)
+			var transitionErr *TransitionError
+			if err != nil && !(errors.As(err, &transitionErr) && transitionErr.From == OrderRefunded) {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return m.Get(id)
+}
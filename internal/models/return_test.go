@@ -0,0 +1,275 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var returnRowColumns = []string{"id", "order_id", "user_id", "status", "note", "admin_note", "refund_amount", "created_at", "updated_at"}
+var returnItemRowColumns = []string{"id", "return_id", "order_item_id", "product_id", "sku", "name", "quantity", "reason", "comment"}
+var returnableRowColumns = []string{"id", "product_id", "sku", "name", "quantity", "line_total", "discount", "tax", "available"}
+
+func TestReturnStatus_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from, to ReturnStatus
+		want     bool
+	}{
+		{ReturnRequested, ReturnApproved, true},
+		{ReturnRequested, ReturnRejected, true},
+		{ReturnRequested, ReturnReceived, false},
+		{ReturnApproved, ReturnReceived, true},
+		{ReturnApproved, ReturnRefunded, false},
+		{ReturnReceived, ReturnRefunded, true},
+		{ReturnRejected, ReturnApproved, false},
+		{ReturnRefunded, ReturnReceived, false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.from.CanTransitionTo(tt.to), "%s -> %s", tt.from, tt.to)
+	}
+}
+
+func TestReturnModel_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := ReturnModel{DB: db}
+	now := time.Now()
+
+	// Test case 1: A partial return refunds the lines' share of what was paid, tax included
+	t.Run("partial return", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT user_id, status, prices_include_tax, total FROM orders WHERE id = \\$1 FOR UPDATE").
+			WithArgs(11).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "status", "prices_include_tax", "total"}).AddRow(7, "delivered", false, 226.9))
+		mock.ExpectQuery("SELECT oi.id, oi.product_id, .* FROM order_items oi WHERE oi.order_id = \\$1").
+			WithArgs(11).
+			WillReturnRows(sqlmock.NewRows(returnableRowColumns).
+				AddRow(3, 7, "HS-002", "Wireless Headset", 2, 160.0, 10.0, 28.5, 2).
+				AddRow(4, 8, "KB-001", "Keyboard", 1, 50.0, 0.0, 9.5, 1))
+		mock.ExpectQuery("INSERT INTO returns \\(order_id, user_id, note, refund_amount\\)").
+			WithArgs(11, 7, "", 89.25).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "created_at", "updated_at"}).AddRow(1, "requested", now, now))
+		mock.ExpectQuery("INSERT INTO return_items \\(return_id, order_item_id, quantity, reason, comment\\)").
+			WithArgs(1, 3, 1, "defective", "Left ear cup is silent").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		ret := &Return{OrderID: 11, UserID: 7, Items: []ReturnItem{{OrderItemID: 3, Quantity: 1, Reason: ReasonDefective, Comment: "Left ear cup is silent"}}}
+		err := model.Create(ret)
+		assert.NoError(t, err)
+		assert.Equal(t, ReturnRequested, ret.Status)
+		assert.Equal(t, 89.25, ret.RefundAmount)
+		assert.Equal(t, "Wireless Headset", ret.Items[0].Name)
+	})
+
+	// Test case 2: Returning the last items refunds the rest of the order, shipping included
+	t.Run("return completes the order", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT user_id, status, prices_include_tax, total FROM orders").
+			WithArgs(11).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "status", "prices_include_tax", "total"}).AddRow(7, "delivered", false, 226.9))
+		mock.ExpectQuery("SELECT oi.id, oi.product_id, .* FROM order_items oi").
+			WithArgs(11).
+			WillReturnRows(sqlmock.NewRows(returnableRowColumns).
+				AddRow(3, 7, "HS-002", "Wireless Headset", 2, 160.0, 10.0, 28.5, 1).
+				AddRow(4, 8, "KB-001", "Keyboard", 1, 50.0, 0.0, 9.5, 1))
+		mock.ExpectQuery("SELECT COALESCE\\(SUM\\(refund_amount\\), 0\\) FROM returns WHERE order_id = \\$1 AND status <> 'rejected'").
+			WithArgs(11).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(89.25))
+		mock.ExpectQuery("INSERT INTO returns").
+			WithArgs(11, 7, "", 137.65).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "created_at", "updated_at"}).AddRow(2, "requested", now, now))
+		mock.ExpectQuery("INSERT INTO return_items").
+			WithArgs(2, 3, 1, "no_longer_needed", "").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectQuery("INSERT INTO return_items").
+			WithArgs(2, 4, 1, "no_longer_needed", "").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectCommit()
+
+		ret := &Return{OrderID: 11, UserID: 7, Items: []ReturnItem{
+			{OrderItemID: 3, Quantity: 1, Reason: ReasonNoLongerNeeded},
+			{OrderItemID: 4, Quantity: 1, Reason: ReasonNoLongerNeeded},
+		}}
+		err := model.Create(ret)
+		assert.NoError(t, err)
+		assert.Equal(t, 137.65, ret.RefundAmount)
+	})
+
+	// Test case 3: More than is left to return is refused
+	t.Run("quantity exceeds what is left", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT user_id, status, prices_include_tax, total FROM orders").
+			WithArgs(11).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "status", "prices_include_tax", "total"}).AddRow(7, "delivered", false, 226.9))
+		mock.ExpectQuery("SELECT oi.id, oi.product_id, .* FROM order_items oi").
+			WithArgs(11).
+			WillReturnRows(sqlmock.NewRows(returnableRowColumns).AddRow(3, 7, "HS-002", "Wireless Headset", 2, 160.0, 10.0, 28.5, 1))
+		mock.ExpectRollback()
+
+		ret := &Return{OrderID: 11, UserID: 7, Items: []ReturnItem{{OrderItemID: 3, Quantity: 2, Reason: ReasonDamaged}}}
+		err := model.Create(ret)
+		assert.Equal(t, &ReturnQuantityError{OrderItemID: 3, Requested: 2, Available: 1}, err)
+	})
+
+	// Test case 4: Orders that have not been delivered cannot be returned
+	t.Run("order not delivered", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT user_id, status, prices_include_tax, total FROM orders").
+			WithArgs(12).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "status", "prices_include_tax", "total"}).AddRow(7, "shipped", false, 50.0))
+		mock.ExpectRollback()
+
+		err := model.Create(&Return{OrderID: 12, UserID: 7, Items: []ReturnItem{{OrderItemID: 5, Quantity: 1, Reason: ReasonOther}}})
+		assert.Equal(t, "order not delivered", err.Error())
+	})
+
+	// Test case 5: Other users' orders are not found
+	t.Run("order of another user", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT user_id, status, prices_include_tax, total FROM orders").
+			WithArgs(11).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "status", "prices_include_tax", "total"}).AddRow(7, "delivered", false, 226.9))
+		mock.ExpectRollback()
+
+		err := model.Create(&Return{OrderID: 11, UserID: 8, Items: []ReturnItem{{OrderItemID: 3, Quantity: 1, Reason: ReasonOther}}})
+		assert.Equal(t, "order not found", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestReturnModel_UpdateStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := ReturnModel{DB: db}
+	now := time.Now()
+
+	expectGet := func(id int, status string) {
+		mock.ExpectQuery("SELECT id, order_id, user_id, status, note, admin_note, refund_amount, created_at, updated_at FROM returns WHERE id = \\$1").
+			WithArgs(id).
+			WillReturnRows(sqlmock.NewRows(returnRowColumns).AddRow(id, 11, 7, status, "", "", 89.25, now, now))
+		mock.ExpectQuery("SELECT ri.id, ri.return_id, .* FROM return_items ri").
+			WillReturnRows(sqlmock.NewRows(returnItemRowColumns).AddRow(1, id, 3, 7, "HS-002", "Wireless Headset", 1, "defective", ""))
+	}
+
+	// Test case 1: Receiving a return puts its items back in stock
+	t.Run("receive restocks", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT order_id, status FROM returns WHERE id = \\$1 FOR UPDATE").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"order_id", "status"}).AddRow(11, "approved"))
+		mock.ExpectExec("UPDATE products p SET stock = p.stock \\+ ri.quantity FROM return_items ri").
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE returns SET status = \\$2").
+			WithArgs(1, "received", "").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		expectGet(1, "received")
+
+		ret, err := model.UpdateStatus(1, ReturnReceived, "")
+		assert.NoError(t, err)
+		assert.Equal(t, ReturnReceived, ret.Status)
+	})
+
+	// Test case 2: A partial refund leaves the order as it is
+	t.Run("partial refund", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT order_id, status FROM returns WHERE id = \\$1 FOR UPDATE").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"order_id", "status"}).AddRow(11, "received"))
+		mock.ExpectExec("UPDATE returns SET status = \\$2").
+			WithArgs(1, "refunded", "").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT NOT EXISTS").
+			WithArgs(11).
+			WillReturnRows(sqlmock.NewRows([]string{"complete"}).AddRow(false))
+		mock.ExpectCommit()
+		expectGet(1, "refunded")
+
+		ret, err := model.UpdateStatus(1, ReturnRefunded, "")
+		assert.NoError(t, err)
+		assert.Equal(t, ReturnRefunded, ret.Status)
+	})
+
+	// Test case 3: Refunding the last return refunds the order, which issues a credit note
+	t.Run("refund completes the order", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT order_id, status FROM returns WHERE id = \\$1 FOR UPDATE").
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"order_id", "status"}).AddRow(11, "received"))
+		mock.ExpectExec("UPDATE returns SET status = \\$2").
+			WithArgs(2, "refunded", "").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT NOT EXISTS").
+			WithArgs(11).
+			WillReturnRows(sqlmock.NewRows([]string{"complete"}).AddRow(true))
+		mock.ExpectQuery("SELECT status FROM orders WHERE id = \\$1 FOR UPDATE").
+			WithArgs(11).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("delivered"))
+		mock.ExpectExec("UPDATE orders SET status").
+			WithArgs(11, "refunded").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO order_status_history").
+			WithArgs(11, "delivered", "refunded").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery("INSERT INTO invoice_sequences").
+			WithArgs("credit_note", now.Year()).
+			WillReturnRows(sqlmock.NewRows([]string{"last_number"}).AddRow(1))
+		mock.ExpectQuery("SELECT id, number FROM invoices").
+			WithArgs(11, "invoice").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "number"}).AddRow(4, "INV-2024-000004"))
+		mock.ExpectQuery("INSERT INTO invoices").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "total"}).AddRow(5, 226.9))
+		mock.ExpectCommit()
+		expectGet(2, "refunded")
+
+		ret, err := model.UpdateStatus(2, ReturnRefunded, "")
+		assert.NoError(t, err)
+		assert.Equal(t, ReturnRefunded, ret.Status)
+	})
+
+	// Test case 4: Transition not allowed
+	t.Run("transition not allowed", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT order_id, status FROM returns WHERE id = \\$1 FOR UPDATE").
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"order_id", "status"}).AddRow(11, "requested"))
+		mock.ExpectRollback()
+
+		ret, err := model.UpdateStatus(3, ReturnRefunded, "")
+		assert.Nil(t, ret)
+		assert.Equal(t, &ReturnTransitionError{From: ReturnRequested, To: ReturnRefunded}, err)
+	})
+
+	// Test case 5: Return not found
+	t.Run("return not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT order_id, status FROM returns WHERE id = \\$1 FOR UPDATE").
+			WithArgs(999).
+			WillReturnRows(sqlmock.NewRows([]string{"order_id", "status"}))
+		mock.ExpectRollback()
+
+		ret, err := model.UpdateStatus(999, ReturnApproved, "")
+		assert.Nil(t, ret)
+		assert.Equal(t, "return not found", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
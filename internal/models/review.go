@@ -0,0 +1,319 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ReviewStatus is the moderation state of a review
+type ReviewStatus string
+
+const (
+	ReviewPending  ReviewStatus = "pending"
+	ReviewApproved ReviewStatus = "approved"
+	ReviewRejected ReviewStatus = "rejected"
+)
+
+// Valid reports whether s is a known review status
+func (s ReviewStatus) Valid() bool {
+	switch s {
+	case ReviewPending, ReviewApproved, ReviewRejected:
+		return true
+	}
+	return false
+}
+
+// Review is a customer's rating of a product they bought. Only approved
+// reviews are shown on the product and count towards its rating.
+type Review struct {
+	ID        int    `json:"id" example:"1"`
+	ProductID int    `json:"product_id" example:"7"`
+	UserID    int    `json:"user_id" example:"3"`
+	Username  string `json:"username" example:"john_doe"`
+	// Rating is from 1 to 5 stars
+	Rating         int          `json:"rating" example:"4"`
+	Title          string       `json:"title" example:"Comfortable for long sessions"`
+	Body           string       `json:"body,omitempty" example:"Great sound, the microphone could be better."`
+	Status         ReviewStatus `json:"status" example:"approved"`
+	ModerationNote string       `json:"moderation_note,omitempty" example:""`
+	HelpfulCount   int          `json:"helpful_count" example:"5"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+// reviewSorts maps the sort orders of review listings to their ORDER BY
+var reviewSorts = map[string]string{
+	"newest":  "r.created_at DESC, r.id DESC",
+	"oldest":  "r.created_at, r.id",
+	"helpful": "r.helpful_count DESC, r.created_at DESC, r.id DESC",
+	"highest": "r.rating DESC, r.created_at DESC, r.id DESC",
+	"lowest":  "r.rating, r.created_at DESC, r.id DESC",
+}
+
+// ValidReviewSort reports whether sort is a known review sort order
+func ValidReviewSort(sort string) bool {
+	_, ok := reviewSorts[sort]
+	return ok
+}
+
+// ReviewFilter narrows down review listings. Zero values are ignored; Sort
+// defaults to newest first.
+type ReviewFilter struct {
+	ProductID int
+	UserID    int
+	Status    ReviewStatus
+	Sort      string
+	Limit     int
+	Offset    int
+}
+
+func (f ReviewFilter) where() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+
+	if f.ProductID > 0 {
+		args = append(args, f.ProductID)
+		conds = append(conds, fmt.Sprintf("r.product_id = $%d", len(args)))
+	}
+	if f.UserID > 0 {
+		args = append(args, f.UserID)
+		conds = append(conds, fmt.Sprintf("r.user_id = $%d", len(args)))
+	}
+	if f.Status != "" {
+		args = append(args, string(f.Status))
+		conds = append(conds, fmt.Sprintf("r.status = $%d", len(args)))
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// ReviewModelInterface defines the methods that a review model must implement
+type ReviewModelInterface interface {
+	Create(review *Review) error
+	Get(id int) (*Review, error)
+	List(filter ReviewFilter) ([]Review, error)
+	UpdateStatus(id int, status ReviewStatus, note string) (*Review, error)
+	Delete(id int) error
+	Vote(id, userID int) (int, error)
+}
+
+type ReviewModel struct {
+	DB *sql.DB
+}
+
+const reviewSelect = `
+	SELECT r.id, r.product_id, r.user_id, u.username, r.rating, r.title, r.body, r.status, r.moderation_note,
+		r.helpful_count, r.created_at, r.updated_at
+	FROM reviews r
+	JOIN users u ON u.id = r.user_id`
+
+func scanReview(row rowScanner, review *Review) error {
+	return row.Scan(&review.ID, &review.ProductID, &review.UserID, &review.Username, &review.Rating, &review.Title, &review.Body,
+		&review.Status, &review.ModerationNote, &review.HelpfulCount, &review.CreatedAt, &review.UpdatedAt)
+}
+
+// reviewError reports a review of a missing product or a second review of
+// the same product
+func reviewError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code == "23503" && pqErr.Constraint == "reviews_product_id_fkey":
+			return errors.New("product not found")
+		case pqErr.Code == "23505":
+			return errors.New("product already reviewed")
+		}
+	}
+	return err
+}
+
+// updateRating recomputes a product's rating from its approved reviews
+func updateRating(db DBTX, productID int) error {
+	stmt := `
+		UPDATE products SET
+			rating_average = COALESCE((SELECT ROUND(AVG(rating), 2) FROM reviews WHERE product_id = $1 AND status = 'approved'), 0),
+			rating_count = (SELECT COUNT(*) FROM reviews WHERE product_id = $1 AND status = 'approved')
+		WHERE id = $1`
+	_, err := db.Exec(stmt, productID)
+	return err
+}
+
+// Create stores a pending review. Only users with a paid, shipped or
+// delivered order of the product may review it, once.
+func (m ReviewModel) Create(review *Review) error {
+	var purchased bool
+	stmt := `
+		SELECT EXISTS (
+			SELECT 1 FROM orders o JOIN order_items oi ON oi.order_id = o.id
+			WHERE o.user_id = $1 AND oi.product_id = $2 AND o.status IN ('paid', 'shipped', 'delivered'))`
+	if err := m.DB.QueryRow(stmt, review.UserID, review.ProductID).Scan(&purchased); err != nil {
+		return err
+	}
+	if !purchased {
+		return errors.New("product not purchased")
+	}
+
+	stmt = `
+		INSERT INTO reviews (product_id, user_id, rating, title, body)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, status, helpful_count, created_at, updated_at`
+	err := m.DB.QueryRow(stmt, review.ProductID, review.UserID, review.Rating, review.Title, review.Body).
+		Scan(&review.ID, &review.Status, &review.HelpfulCount, &review.CreatedAt, &review.UpdatedAt)
+	return reviewError(err)
+}
+
+// Get returns a review, whatever its status
+func (m ReviewModel) Get(id int) (*Review, error) {
+	var review Review
+	if err := scanReview(m.DB.QueryRow(reviewSelect+` WHERE r.id = $1`, id), &review); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("review not found")
+		}
+		return nil, err
+	}
+	return &review, nil
+}
+
+// List returns the reviews matching filter
+func (m ReviewModel) List(filter ReviewFilter) ([]Review, error) {
+	orderBy, ok := reviewSorts[filter.Sort]
+	if !ok {
+		orderBy = reviewSorts["newest"]
+	}
+
+	where, args := filter.where()
+	stmt := reviewSelect + where + ` ORDER BY ` + orderBy
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		stmt += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		stmt += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := []Review{}
+	for rows.Next() {
+		var review Review
+		if err := scanReview(rows, &review); err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+	return reviews, rows.Err()
+}
+
+// UpdateStatus moderates a review; a non-empty note replaces the moderation
+// note. The product's rating is recomputed in the same transaction.
+func (m ReviewModel) UpdateStatus(id int, status ReviewStatus, note string) (*Review, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var productID int
+	stmt := `
+		UPDATE reviews SET status = $2, moderation_note = COALESCE(NULLIF($3, ''), moderation_note), updated_at = NOW()
+		WHERE id = $1
+		RETURNING product_id`
+	if err := tx.QueryRow(stmt, id, string(status), note).Scan(&productID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("review not found")
+		}
+		return nil, err
+	}
+
+	if err := updateRating(tx, productID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return m.Get(id)
+}
+
+// Delete removes a review and its votes, updating the product's rating
+func (m ReviewModel) Delete(id int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var productID int
+	if err := tx.QueryRow(`DELETE FROM reviews WHERE id = $1 RETURNING product_id`, id).Scan(&productID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("review not found")
+		}
+		return err
+	}
+
+	if err := updateRating(tx, productID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Vote records a user finding an approved review helpful and returns its new
+// helpful count. Users get one vote per review and cannot vote on their own.
+func (m ReviewModel) Vote(id, userID int) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Locking the review keeps helpful_count in step with the votes
+	var authorID int
+	var status ReviewStatus
+	err = tx.QueryRow(`SELECT user_id, status FROM reviews WHERE id = $1 FOR UPDATE`, id).Scan(&authorID, &status)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && status != ReviewApproved) {
+		return 0, errors.New("review not found")
+	}
+	if err != nil {
+		return 0, err
+	}
+	if authorID == userID {
+		return 0, errors.New("cannot vote on own review")
+	}
+
+	result, err := tx.Exec(`INSERT INTO review_votes (review_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, id, userID)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if rowsAffected == 0 {
+		return 0, errors.New("already voted")
+	}
+
+	var count int
+	if err := tx.QueryRow(`UPDATE reviews SET helpful_count = helpful_count + 1 WHERE id = $1 RETURNING helpful_count`, id).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,184 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var reviewRowColumns = []string{"id", "product_id", "user_id", "username", "rating", "title", "body", "status", "moderation_note", "helpful_count", "created_at", "updated_at"}
+
+func TestReviewModel_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := ReviewModel{DB: db}
+	now := time.Now()
+
+	// Test case 1: A buyer's review waits for moderation
+	t.Run("buyer reviews", func(t *testing.T) {
+		mock.ExpectQuery("SELECT EXISTS \\(\\s+SELECT 1 FROM orders o JOIN order_items oi ON oi.order_id = o.id").
+			WithArgs(3, 7).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery("INSERT INTO reviews \\(product_id, user_id, rating, title, body\\)").
+			WithArgs(7, 3, 4, "Comfortable", "").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "helpful_count", "created_at", "updated_at"}).AddRow(1, "pending", 0, now, now))
+
+		review := &Review{ProductID: 7, UserID: 3, Rating: 4, Title: "Comfortable"}
+		err := model.Create(review)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, review.ID)
+		assert.Equal(t, ReviewPending, review.Status)
+	})
+
+	// Test case 2: Users who did not buy the product cannot review it
+	t.Run("not purchased", func(t *testing.T) {
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(4, 7).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		err := model.Create(&Review{ProductID: 7, UserID: 4, Rating: 5, Title: "Great"})
+		assert.Equal(t, "product not purchased", err.Error())
+	})
+
+	// Test case 3: A product is reviewed once per user
+	t.Run("already reviewed", func(t *testing.T) {
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(3, 7).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery("INSERT INTO reviews").
+			WillReturnError(&pq.Error{Code: "23505", Constraint: "reviews_product_id_user_id_key"})
+
+		err := model.Create(&Review{ProductID: 7, UserID: 3, Rating: 2, Title: "Changed my mind"})
+		assert.Equal(t, "product already reviewed", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestReviewModel_UpdateStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := ReviewModel{DB: db}
+	now := time.Now()
+
+	// Test case 1: Approving a review recomputes the product's rating
+	t.Run("approve", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("UPDATE reviews SET status = \\$2, moderation_note = COALESCE\\(NULLIF\\(\\$3, ''\\), moderation_note\\)").
+			WithArgs(1, "approved", "").
+			WillReturnRows(sqlmock.NewRows([]string{"product_id"}).AddRow(7))
+		mock.ExpectExec("UPDATE products SET\\s+rating_average = .* rating_count = .* WHERE id = \\$1").
+			WithArgs(7).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT r.id, .* FROM reviews r\\s+JOIN users u ON u.id = r.user_id WHERE r.id = \\$1").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(reviewRowColumns).AddRow(1, 7, 3, "john_doe", 4, "Comfortable", "", "approved", "", 0, now, now))
+
+		review, err := model.UpdateStatus(1, ReviewApproved, "")
+		assert.NoError(t, err)
+		assert.Equal(t, ReviewApproved, review.Status)
+	})
+
+	// Test case 2: Review not found
+	t.Run("review not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("UPDATE reviews SET status").
+			WithArgs(999, "rejected", "Off topic").
+			WillReturnRows(sqlmock.NewRows([]string{"product_id"}))
+		mock.ExpectRollback()
+
+		review, err := model.UpdateStatus(999, ReviewRejected, "Off topic")
+		assert.Nil(t, review)
+		assert.Equal(t, "review not found", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestReviewModel_Vote(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := ReviewModel{DB: db}
+
+	// Test case 1: A vote counts once
+	t.Run("vote", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT user_id, status FROM reviews WHERE id = \\$1 FOR UPDATE").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "status"}).AddRow(3, "approved"))
+		mock.ExpectExec("INSERT INTO review_votes \\(review_id, user_id\\) VALUES \\(\\$1, \\$2\\) ON CONFLICT DO NOTHING").
+			WithArgs(1, 5).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("UPDATE reviews SET helpful_count = helpful_count \\+ 1 WHERE id = \\$1 RETURNING helpful_count").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"helpful_count"}).AddRow(6))
+		mock.ExpectCommit()
+
+		count, err := model.Vote(1, 5)
+		assert.NoError(t, err)
+		assert.Equal(t, 6, count)
+	})
+
+	// Test case 2: A second vote by the same user is refused
+	t.Run("already voted", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT user_id, status FROM reviews").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "status"}).AddRow(3, "approved"))
+		mock.ExpectExec("INSERT INTO review_votes").
+			WithArgs(1, 5).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		_, err := model.Vote(1, 5)
+		assert.Equal(t, "already voted", err.Error())
+	})
+
+	// Test case 3: Authors cannot vote on their own review
+	t.Run("own review", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT user_id, status FROM reviews").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "status"}).AddRow(3, "approved"))
+		mock.ExpectRollback()
+
+		_, err := model.Vote(1, 3)
+		assert.Equal(t, "cannot vote on own review", err.Error())
+	})
+
+	// Test case 4: Reviews awaiting moderation cannot be voted on
+	t.Run("pending review", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT user_id, status FROM reviews").
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "status"}).AddRow(3, "pending"))
+		mock.ExpectRollback()
+
+		_, err := model.Vote(2, 5)
+		assert.Equal(t, "review not found", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
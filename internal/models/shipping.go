@@ -0,0 +1,181 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/lib/pq"
+	"garage-api/internal/shipping"
+)
+
+// ShippingModelInterface defines the methods that a shipping model must implement
+type ShippingModelInterface interface {
+	GetZones() ([]shipping.Zone, error)
+	CreateZone(zone *shipping.Zone) error
+	UpdateZone(zone *shipping.Zone) error
+	DeleteZone(id int) error
+	GetMethods() ([]shipping.Method, error)
+	CreateMethod(method *shipping.Method) error
+	UpdateMethod(method *shipping.Method) error
+	DeleteMethod(id int) error
+	Quote(cart *Cart, country, region string) ([]shipping.Option, error)
+}
+
+type ShippingModel struct {
+	DB *sql.DB
+}
+
+// loadZones returns all shipping zones
+func loadZones(db DBTX) ([]shipping.Zone, error) {
+	rows, err := db.Query(`SELECT id, name, countries FROM shipping_zones ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	zones := []shipping.Zone{}
+	for rows.Next() {
+		var zone shipping.Zone
+		if err := rows.Scan(&zone.ID, &zone.Name, pq.Array(&zone.Countries)); err != nil {
+			return nil, err
+		}
+		if zone.Countries == nil {
+			zone.Countries = []string{}
+		}
+		zones = append(zones, zone)
+	}
+
+	return zones, rows.Err()
+}
+
+// loadMethods returns all shipping methods, or only the active ones
+func loadMethods(db DBTX, activeOnly bool) ([]shipping.Method, error) {
+	stmt := `SELECT id, zone_id, name, type, cost, tiers, free_over, active FROM shipping_methods`
+	if activeOnly {
+		stmt += ` WHERE active`
+	}
+	stmt += ` ORDER BY id`
+
+	rows, err := db.Query(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	methods := []shipping.Method{}
+	for rows.Next() {
+		var method shipping.Method
+		var tiers []byte
+		err := rows.Scan(&method.ID, &method.ZoneID, &method.Name, &method.Type, &method.Cost, &tiers, &method.FreeOver, &method.Active)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(tiers, &method.Tiers); err != nil {
+			return nil, err
+		}
+		if method.Tiers == nil {
+			method.Tiers = []shipping.Tier{}
+		}
+		methods = append(methods, method)
+	}
+
+	return methods, rows.Err()
+}
+
+// quoteShipping lists the shipping options for a parcel
+func quoteShipping(db DBTX, country, region string, parcel shipping.Parcel, freeShipping bool) ([]shipping.Option, error) {
+	zones, err := loadZones(db)
+	if err != nil {
+		return nil, err
+	}
+	methods, err := loadMethods(db, true)
+	if err != nil {
+		return nil, err
+	}
+	return shipping.Quote(zones, methods, country, region, parcel, freeShipping), nil
+}
+
+// Quote lists the shipping options for a priced cart
+func (m ShippingModel) Quote(cart *Cart, country, region string) ([]shipping.Option, error) {
+	return quoteShipping(m.DB, country, region, cart.Parcel(), cart.FreeShipping)
+}
+
+func (m ShippingModel) GetZones() ([]shipping.Zone, error) {
+	return loadZones(m.DB)
+}
+
+func (m ShippingModel) CreateZone(zone *shipping.Zone) error {
+	stmt := `INSERT INTO shipping_zones (name, countries) VALUES ($1, $2) RETURNING id`
+	return m.DB.QueryRow(stmt, zone.Name, pq.Array(zone.Countries)).Scan(&zone.ID)
+}
+
+func (m ShippingModel) UpdateZone(zone *shipping.Zone) error {
+	stmt := `UPDATE shipping_zones SET name = $1, countries = $2 WHERE id = $3`
+	result, err := m.DB.Exec(stmt, zone.Name, pq.Array(zone.Countries), zone.ID)
+	if err != nil {
+		return err
+	}
+	return requireOne(result, "shipping zone not found")
+}
+
+func (m ShippingModel) DeleteZone(id int) error {
+	result, err := m.DB.Exec(`DELETE FROM shipping_zones WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return requireOne(result, "shipping zone not found")
+}
+
+func (m ShippingModel) GetMethods() ([]shipping.Method, error) {
+	return loadMethods(m.DB, false)
+}
+
+// zoneError reports a method referring to a zone that does not exist
+func zoneError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return errors.New("shipping zone not found")
+	}
+	return err
+}
+
+func (m ShippingModel) CreateMethod(method *shipping.Method) error {
+	tiers, err := json.Marshal(method.Tiers)
+	if err != nil {
+		return err
+	}
+
+	stmt := `
+		INSERT INTO shipping_methods (zone_id, name, type, cost, tiers, free_over, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`
+
+	err = m.DB.QueryRow(stmt, method.ZoneID, method.Name, string(method.Type), method.Cost, tiers, method.FreeOver, method.Active).Scan(&method.ID)
+	return zoneError(err)
+}
+
+func (m ShippingModel) UpdateMethod(method *shipping.Method) error {
+	tiers, err := json.Marshal(method.Tiers)
+	if err != nil {
+		return err
+	}
+
+	stmt := `
+		UPDATE shipping_methods SET zone_id = $1, name = $2, type = $3, cost = $4, tiers = $5, free_over = $6, active = $7
+		WHERE id = $8`
+
+	result, err := m.DB.Exec(stmt, method.ZoneID, method.Name, string(method.Type), method.Cost, tiers, method.FreeOver, method.Active, method.ID)
+	if err != nil {
+		return zoneError(err)
+	}
+	return requireOne(result, "shipping method not found")
+}
+
+func (m ShippingModel) DeleteMethod(id int) error {
+	result, err := m.DB.Exec(`DELETE FROM shipping_methods WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return requireOne(result, "shipping method not found")
+}
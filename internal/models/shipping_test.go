@@ -0,0 +1,101 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"garage-api/internal/shipping"
+)
+
+func TestShippingModel_Quote(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := ShippingModel{DB: db}
+
+	// Two laptops of 2.5 kg and a bulky 1 kg box billed at its volumetric
+	// 8.75 kg come to 13.75 kg, past the first parcel tier
+	cart := &Cart{
+		Items: []CartItem{
+			{ProductID: 1, Quantity: 2, Weight: 2.5},
+			{ProductID: 2, Quantity: 1, Weight: shipping.BillableWeight(1, 50*35*25)},
+		},
+		Subtotal: 120,
+		Discount: 20,
+	}
+	assert.Equal(t, shipping.Parcel{Weight: 13.75, Subtotal: 100}, cart.Parcel())
+
+	expectShippingRates(mock)
+
+	options, err := model.Quote(cart, "fr", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []shipping.Option{
+		{MethodID: 4, Name: "Parcel", Zone: "Europe", Cost: 12.9},
+		{MethodID: 5, Name: "Express", Zone: "Europe", Cost: 19.9},
+	}, options)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestShippingModel_CreateMethod(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := ShippingModel{DB: db}
+
+	// Test case 1: Tiers are stored as JSON
+	t.Run("successful creation", func(t *testing.T) {
+		mock.ExpectQuery("INSERT INTO shipping_methods \\(zone_id, name, type, cost, tiers, free_over, active\\)").
+			WithArgs(2, "Parcel", "weight", 0.0, []byte(`[{"up_to":5,"cost":6.9}]`), 100.0, true).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+
+		method := &shipping.Method{ZoneID: 2, Name: "Parcel", Type: shipping.ByWeight, Tiers: []shipping.Tier{{UpTo: 5, Cost: 6.9}}, FreeOver: 100, Active: true}
+		err := model.CreateMethod(method)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, method.ID)
+	})
+
+	// Test case 2: Unknown zone
+	t.Run("zone not found", func(t *testing.T) {
+		mock.ExpectQuery("INSERT INTO shipping_methods").
+			WillReturnError(&pq.Error{Code: "23503"})
+
+		err := model.CreateMethod(&shipping.Method{ZoneID: 99, Name: "Parcel", Type: shipping.Flat, Active: true})
+		assert.Equal(t, "shipping zone not found", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestShippingModel_DeleteZone(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := ShippingModel{DB: db}
+
+	mock.ExpectExec("DELETE FROM shipping_zones WHERE id = \\$1").
+		WithArgs(99).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = model.DeleteZone(99)
+	assert.Equal(t, "shipping zone not found", err.Error())
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Tag is a free-form label such as "rgb" or "wireless" attached to products
+type Tag struct {
+	ID           int    `json:"id" example:"1"`
+	Name         string `json:"name" example:"rgb"`
+	ProductCount int    `json:"product_count" example:"3"`
+}
+
+// TagModelInterface defines the methods that a tag model must implement
+type TagModelInterface interface {
+	GetAll() ([]Tag, error)
+	Create(tag *Tag) error
+	Delete(id int) error
+	SetProductTags(productID int, names []string) ([]string, error)
+}
+
+type TagModel struct {
+	DB *sql.DB
+}
+
+// NormalizeTagName lowercases a tag name and collapses its whitespace
+func NormalizeTagName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+func (m TagModel) GetAll() ([]Tag, error) {
+	stmt := `
+		SELECT t.id, t.name, COUNT(pt.product_id)
+		FROM tags t
+		LEFT JOIN product_tags pt ON pt.tag_id = t.id
+		GROUP BY t.id, t.name
+		ORDER BY t.name`
+
+	rows, err := m.DB.Query(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []Tag{}
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.ProductCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+func (m TagModel) Create(tag *Tag) error {
+	tag.Name = NormalizeTagName(tag.Name)
+	stmt := `INSERT INTO tags (name) VALUES ($1) RETURNING id`
+
+	return m.DB.QueryRow(stmt, tag.Name).Scan(&tag.ID)
+}
+
+// Delete removes a tag and unassigns it from all products
+func (m TagModel) Delete(id int) error {
+	stmt := `DELETE FROM tags WHERE id = $1`
+
+	result, err := m.DB.Exec(stmt, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("tag not found")
+	}
+
+	return nil
+}
+
+// SetProductTags replaces the tags of a product, creating tags that do not
+// exist yet, and returns the normalized tag names now assigned
+func (m TagModel) SetProductTags(productID int, names []string) ([]string, error) {
+	seen := make(map[string]bool, len(names))
+	normalized := []string{}
+	for _, name := range names {
+		name = NormalizeTagName(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		normalized = append(normalized, name)
+	}
+	sort.Strings(normalized)
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM product_tags WHERE product_id = $1`, productID); err != nil {
+		return nil, err
+	}
+
+	if len(normalized) > 0 {
+		stmt := `INSERT INTO tags (name) SELECT unnest($1::text[]) ON CONFLICT (name) DO NOTHING`
+		if _, err := tx.Exec(stmt, pq.Array(normalized)); err != nil {
+			return nil, err
+		}
+
+		stmt = `INSERT INTO product_tags (product_id, tag_id) SELECT $1, id FROM tags WHERE name = ANY($2)`
+		if _, err := tx.Exec(stmt, productID, pq.Array(normalized)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
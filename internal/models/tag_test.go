@@ -0,0 +1,94 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagModel_GetAll(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := TagModel{DB: db}
+
+	rows := sqlmock.NewRows([]string{"id", "name", "count"}).
+		AddRow(1, "rgb", 3).
+		AddRow(2, "wireless", 0)
+	mock.ExpectQuery("SELECT t.id, t.name, COUNT\\(pt.product_id\\) FROM tags t").
+		WillReturnRows(rows)
+
+	tags, err := model.GetAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []Tag{{ID: 1, Name: "rgb", ProductCount: 3}, {ID: 2, Name: "wireless"}}, tags)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTagModel_SetProductTags(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := TagModel{DB: db}
+
+	// Test case 1: Tags are normalized, deduplicated and replaced
+	t.Run("replace tags", func(t *testing.T) {
+		names := pq.Array([]string{"ergonomic", "rgb"})
+
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM product_tags WHERE product_id = \\$1").
+			WithArgs(3).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("INSERT INTO tags \\(name\\) SELECT unnest").
+			WithArgs(names).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO product_tags \\(product_id, tag_id\\)").
+			WithArgs(3, names).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		tags, err := model.SetProductTags(3, []string{" RGB ", "Ergonomic", "rgb", ""})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"ergonomic", "rgb"}, tags)
+	})
+
+	// Test case 2: Clearing all tags
+	t.Run("clear tags", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM product_tags WHERE product_id = \\$1").
+			WithArgs(3).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		tags, err := model.SetProductTags(3, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, tags)
+	})
+
+	// Test case 3: Database error rolls back
+	t.Run("database error", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM product_tags WHERE product_id = \\$1").
+			WithArgs(3).
+			WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		_, err := model.SetProductTags(3, []string{"rgb"})
+		assert.Error(t, err)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
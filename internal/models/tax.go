@@ -0,0 +1,160 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"garage-api/internal/tax"
+)
+
+// TaxClass groups products that are taxed at the same rates
+type TaxClass struct {
+	Code string `json:"code" example:"reduced"`
+	Name string `json:"name" example:"Reduced rate"`
+}
+
+// TaxModelInterface defines the methods that a tax model must implement.
+// It doubles as the rate source of the table-driven tax calculator.
+type TaxModelInterface interface {
+	tax.RateSource
+	GetClasses() ([]TaxClass, error)
+	CreateClass(class *TaxClass) error
+	GetRates(country string) ([]tax.Rate, error)
+	CreateRate(rate *tax.Rate) error
+	DeleteRate(id int) error
+}
+
+type TaxModel struct {
+	DB *sql.DB
+}
+
+const taxRateColumns = `id, country, region, tax_class, name, rate, effective_from, effective_to`
+
+func scanTaxRate(row rowScanner, r *tax.Rate) error {
+	var effectiveTo sql.NullTime
+	if err := row.Scan(&r.ID, &r.Country, &r.Region, &r.Class, &r.Name, &r.Rate, &r.EffectiveFrom, &effectiveTo); err != nil {
+		return err
+	}
+	r.EffectiveTo = nil
+	if effectiveTo.Valid {
+		r.EffectiveTo = &effectiveTo.Time
+	}
+	return nil
+}
+
+func (m TaxModel) GetClasses() ([]TaxClass, error) {
+	rows, err := m.DB.Query(`SELECT code, name FROM tax_classes ORDER BY code`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	classes := []TaxClass{}
+	for rows.Next() {
+		var class TaxClass
+		if err := rows.Scan(&class.Code, &class.Name); err != nil {
+			return nil, err
+		}
+		classes = append(classes, class)
+	}
+
+	return classes, rows.Err()
+}
+
+func (m TaxModel) CreateClass(class *TaxClass) error {
+	class.Code = strings.ToLower(strings.TrimSpace(class.Code))
+
+	_, err := m.DB.Exec(`INSERT INTO tax_classes (code, name) VALUES ($1, $2)`, class.Code, class.Name)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return errors.New("tax class already exists")
+	}
+	return err
+}
+
+// GetRates returns all rates, past and future, optionally for one country
+func (m TaxModel) GetRates(country string) ([]tax.Rate, error) {
+	stmt := `SELECT ` + taxRateColumns + ` FROM tax_rates WHERE ($1 = '' OR country = $1) ORDER BY country, region, tax_class, effective_from`
+
+	rows, err := m.DB.Query(stmt, strings.ToUpper(country))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []tax.Rate{}
+	for rows.Next() {
+		var r tax.Rate
+		if err := scanTaxRate(rows, &r); err != nil {
+			return nil, err
+		}
+		rates = append(rates, r)
+	}
+
+	return rates, rows.Err()
+}
+
+// RatesFor returns the rates of a country in effect at a time
+func (m TaxModel) RatesFor(ctx context.Context, country string, at time.Time) ([]tax.Rate, error) {
+	stmt := `
+		SELECT ` + taxRateColumns + `
+		FROM tax_rates
+		WHERE country = $1 AND effective_from <= $2 AND (effective_to IS NULL OR effective_to > $2)
+		ORDER BY id`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, strings.ToUpper(country), at)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []tax.Rate
+	for rows.Next() {
+		var r tax.Rate
+		if err := scanTaxRate(rows, &r); err != nil {
+			return nil, err
+		}
+		rates = append(rates, r)
+	}
+
+	return rates, rows.Err()
+}
+
+func (m TaxModel) CreateRate(r *tax.Rate) error {
+	r.Country = strings.ToUpper(r.Country)
+	r.Region = strings.ToUpper(r.Region)
+
+	stmt := `
+		INSERT INTO tax_rates (country, region, tax_class, name, rate, effective_from, effective_to)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`
+
+	err := m.DB.QueryRow(stmt, r.Country, r.Region, r.Class, r.Name, r.Rate, r.EffectiveFrom, r.EffectiveTo).Scan(&r.ID)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return errors.New("tax class not found")
+	}
+	return err
+}
+
+func (m TaxModel) DeleteRate(id int) error {
+	result, err := m.DB.Exec(`DELETE FROM tax_rates WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("tax rate not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"garage-api/internal/tax"
+)
+
+var taxRateRowColumns = []string{"id", "country", "region", "tax_class", "name", "rate", "effective_from", "effective_to"}
+
+func TestTaxModel_RatesFor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := TaxModel{DB: db}
+	at := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT .* FROM tax_rates WHERE country = \\$1 AND effective_from <= \\$2 AND \\(effective_to IS NULL OR effective_to > \\$2\\)").
+		WithArgs("DE", at).
+		WillReturnRows(sqlmock.NewRows(taxRateRowColumns).
+			AddRow(1, "DE", "", "standard", "VAT", 19.0, from, nil).
+			AddRow(2, "DE", "", "reduced", "VAT", 7.0, from, at.AddDate(1, 0, 0)))
+
+	rates, err := model.RatesFor(context.Background(), "de", at)
+	assert.NoError(t, err)
+	assert.Len(t, rates, 2)
+	assert.Nil(t, rates[0].EffectiveTo)
+	assert.NotNil(t, rates[1].EffectiveTo)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTaxModel_CreateRate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := TaxModel{DB: db}
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Test case 1: Successful creation
+	t.Run("successful creation", func(t *testing.T) {
+		mock.ExpectQuery("INSERT INTO tax_rates \\(country, region, tax_class, name, rate, effective_from, effective_to\\)").
+			WithArgs("US", "CA", "standard", "Sales tax", 7.25, from, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+
+		rate := &tax.Rate{Country: "us", Region: "ca", Class: "standard", Name: "Sales tax", Rate: 7.25, EffectiveFrom: from}
+		err := model.CreateRate(rate)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, rate.ID)
+		assert.Equal(t, "US", rate.Country)
+	})
+
+	// Test case 2: Unknown tax class
+	t.Run("unknown tax class", func(t *testing.T) {
+		mock.ExpectQuery("INSERT INTO tax_rates").
+			WillReturnError(&pq.Error{Code: "23503"})
+
+		err := model.CreateRate(&tax.Rate{Country: "DE", Class: "books", Name: "VAT", Rate: 7, EffectiveFrom: from})
+		assert.EqualError(t, err, "tax class not found")
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
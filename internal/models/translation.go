@@ -0,0 +1,195 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ProductTranslation holds a product's content in a locale other than the
+// store's default. Empty descriptions fall back to the product's own.
+type ProductTranslation struct {
+	ProductID   int       `json:"product_id" example:"1"`
+	Locale      string    `json:"locale" example:"pt"`
+	Name        string    `json:"name" example:"Martelo"`
+	Description string    `json:"description,omitempty" example:"Um martelo robusto para construção"`
+	HTMLContent string    `json:"html_content,omitempty" example:"<p>Detalhes do produto em HTML</p>"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// MissingTranslation is a product whose content is not, or only partly,
+// translated into a locale. MissingFields names the untranslated fields.
+type MissingTranslation struct {
+	ProductID     int           `json:"product_id" example:"1"`
+	Name          string        `json:"name" example:"Hammer"`
+	Status        ProductStatus `json:"status" example:"published"`
+	MissingFields []string      `json:"missing_fields" example:"name,description"`
+}
+
+// TranslationModelInterface defines the methods that a translation model must implement
+type TranslationModelInterface interface {
+	List(productID int) ([]ProductTranslation, error)
+	Set(translation *ProductTranslation) error
+	Delete(productID int, locale string) error
+	Localize(products []Product, locales []string) error
+	Missing(locale string) ([]MissingTranslation, error)
+}
+
+type TranslationModel struct {
+	DB *sql.DB
+}
+
+// List returns a product's translations, ordered by locale
+func (m TranslationModel) List(productID int) ([]ProductTranslation, error) {
+	stmt := `
+		SELECT product_id, locale, name, description, html_content, updated_at
+		FROM product_translations
+		WHERE product_id = $1
+		ORDER BY locale`
+	rows, err := m.DB.Query(stmt, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	translations := []ProductTranslation{}
+	for rows.Next() {
+		var t ProductTranslation
+		if err := rows.Scan(&t.ProductID, &t.Locale, &t.Name, &t.Description, &t.HTMLContent, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		translations = append(translations, t)
+	}
+	return translations, rows.Err()
+}
+
+// Set creates or replaces a product's translation into a locale
+func (m TranslationModel) Set(translation *ProductTranslation) error {
+	stmt := `
+		INSERT INTO product_translations (product_id, locale, name, description, html_content)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (product_id, locale) DO UPDATE
+		SET name = EXCLUDED.name, description = EXCLUDED.description, html_content = EXCLUDED.html_content, updated_at = NOW()
+		RETURNING updated_at`
+	err := m.DB.QueryRow(stmt, translation.ProductID, translation.Locale, translation.Name, translation.Description, translation.HTMLContent).
+		Scan(&translation.UpdatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return errors.New("product not found")
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes a product's translation into a locale
+func (m TranslationModel) Delete(productID int, locale string) error {
+	stmt := `DELETE FROM product_translations WHERE product_id = $1 AND locale = $2`
+	result, err := m.DB.Exec(stmt, productID, locale)
+	if err != nil {
+		return err
+	}
+	return requireOne(result, "translation not found")
+}
+
+// Localize replaces the content of products with their translation into
+// the first of locales they have one for. The last locale is the default
+// one, the products' own content, and sets each product's Locale when no
+// translation matched.
+func (m TranslationModel) Localize(products []Product, locales []string) error {
+	if len(products) == 0 || len(locales) == 0 {
+		return nil
+	}
+	fallback := locales[len(locales)-1]
+	for i := range products {
+		products[i].Locale = fallback
+	}
+	if len(locales) == 1 {
+		return nil
+	}
+
+	index := make(map[int][]int, len(products))
+	ids := make([]int64, 0, len(products))
+	for i, p := range products {
+		if _, ok := index[p.ID]; !ok {
+			ids = append(ids, int64(p.ID))
+		}
+		index[p.ID] = append(index[p.ID], i)
+	}
+
+	stmt := `
+		SELECT DISTINCT ON (product_id) product_id, locale, name, description, html_content
+		FROM product_translations
+		WHERE product_id = ANY($1) AND locale = ANY($2)
+		ORDER BY product_id, array_position($2::text[], locale::text)`
+	rows, err := m.DB.Query(stmt, pq.Array(ids), pq.Array(locales[:len(locales)-1]))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t ProductTranslation
+		if err := rows.Scan(&t.ProductID, &t.Locale, &t.Name, &t.Description, &t.HTMLContent); err != nil {
+			return err
+		}
+		for _, i := range index[t.ProductID] {
+			p := &products[i]
+			p.Locale, p.Name = t.Locale, t.Name
+			if t.Description != "" {
+				p.Description = t.Description
+			}
+			if t.HTMLContent != "" {
+				p.HTMLContent = t.HTMLContent
+			}
+		}
+	}
+	return rows.Err()
+}
+
+// Missing lists the products, archived ones aside, that lack a translation
+// into locale or leave a description untranslated, ordered by ID
+func (m TranslationModel) Missing(locale string) ([]MissingTranslation, error) {
+	stmt := `
+		SELECT id, name, status, missing_name, missing_description, missing_html_content
+		FROM (
+			SELECT p.id, p.name, p.status,
+				t.product_id IS NULL AS missing_name,
+				COALESCE(t.description, '') = '' AND COALESCE(p.description, '') <> '' AS missing_description,
+				COALESCE(t.html_content, '') = '' AND COALESCE(p.html_content, '') <> '' AS missing_html_content
+			FROM products p
+			LEFT JOIN product_translations t ON t.product_id = p.id AND t.locale = $1
+			WHERE p.status <> 'archived'
+		) report
+		WHERE missing_name OR missing_description OR missing_html_content
+		ORDER BY id`
+	rows, err := m.DB.Query(stmt, locale)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	missing := []MissingTranslation{}
+	for rows.Next() {
+		var mt MissingTranslation
+		var name, description, htmlContent bool
+		if err := rows.Scan(&mt.ProductID, &mt.Name, &mt.Status, &name, &description, &htmlContent); err != nil {
+			return nil, err
+		}
+		mt.MissingFields = []string{}
+		if name {
+			mt.MissingFields = append(mt.MissingFields, "name")
+		}
+		if description {
+			mt.MissingFields = append(mt.MissingFields, "description")
+		}
+		if htmlContent {
+			mt.MissingFields = append(mt.MissingFields, "html_content")
+		}
+		missing = append(missing, mt)
+	}
+	return missing, rows.Err()
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslationModel_Localize(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := TranslationModel{DB: db}
+
+	// Test case 1: The best translation wins; untranslated products keep the default locale
+	t.Run("fallback chain", func(t *testing.T) {
+		products := []Product{
+			{ID: 1, Name: "Hammer", Description: "A sturdy hammer", HTMLContent: "<p>Hammer</p>"},
+			{ID: 2, Name: "Screwdriver", Description: "A useful tool"},
+		}
+		mock.ExpectQuery("SELECT DISTINCT ON \\(product_id\\) product_id, locale, name, description, html_content\\s+FROM product_translations\\s+WHERE product_id = ANY\\(\\$1\\) AND locale = ANY\\(\\$2\\)").
+			WithArgs(pq.Array([]int64{1, 2}), pq.Array([]string{"pt-BR", "pt"})).
+			WillReturnRows(sqlmock.NewRows([]string{"product_id", "locale", "name", "description", "html_content"}).
+				AddRow(1, "pt", "Martelo", "Um martelo robusto", ""))
+
+		err := model.Localize(products, []string{"pt-BR", "pt", "en"})
+		assert.NoError(t, err)
+		assert.Equal(t, "pt", products[0].Locale)
+		assert.Equal(t, "Martelo", products[0].Name)
+		assert.Equal(t, "Um martelo robusto", products[0].Description)
+		assert.Equal(t, "<p>Hammer</p>", products[0].HTMLContent)
+		assert.Equal(t, "en", products[1].Locale)
+		assert.Equal(t, "Screwdriver", products[1].Name)
+	})
+
+	// Test case 2: The default locale needs no query
+	t.Run("default locale", func(t *testing.T) {
+		products := []Product{{ID: 1, Name: "Hammer"}}
+
+		err := model.Localize(products, []string{"en"})
+		assert.NoError(t, err)
+		assert.Equal(t, "en", products[0].Locale)
+		assert.Equal(t, "Hammer", products[0].Name)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTranslationModel_Set(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := TranslationModel{DB: db}
+
+	// Test case 1: Product not found
+	t.Run("product not found", func(t *testing.T) {
+		mock.ExpectQuery("INSERT INTO product_translations \\(product_id, locale, name, description, html_content\\)").
+			WithArgs(999, "pt", "Martelo", "", "").
+			WillReturnError(&pq.Error{Code: "23503", Constraint: "product_translations_product_id_fkey"})
+
+		err := model.Set(&ProductTranslation{ProductID: 999, Locale: "pt", Name: "Martelo"})
+		assert.Equal(t, "product not found", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTranslationModel_Missing(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := TranslationModel{DB: db}
+
+	mock.ExpectQuery("LEFT JOIN product_translations t ON t.product_id = p.id AND t.locale = \\$1").
+		WithArgs("pt").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "status", "missing_name", "missing_description", "missing_html_content"}).
+			AddRow(2, "Screwdriver", "published", true, true, false).
+			AddRow(3, "Desk Lamp", "draft", false, false, true))
+
+	missing, err := model.Missing("pt")
+	assert.NoError(t, err)
+	assert.Len(t, missing, 2)
+	assert.Equal(t, []string{"name", "description"}, missing[0].MissingFields)
+	assert.Equal(t, []string{"html_content"}, missing[1].MissingFields)
+	assert.Equal(t, ProductDraft, missing[1].Status)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -3,6 +3,8 @@ package models
 import (
 	"database/sql"
 	"errors"
+
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -10,6 +12,21 @@ type User struct {
 	ID           int    `json:"id"`
 	Username     string `json:"username"`
 	PasswordHash string `json:"-"`
+	Role         string `json:"role"`
+}
+
+// User roles. Customers manage their own cart and orders; editors draft
+// products and submit them for review; admins manage the store.
+const (
+	RoleCustomer = "customer"
+	RoleEditor   = "editor"
+	RoleAdmin    = "admin"
+)
+
+// UserModelInterface defines the methods that a user model must implement
+type UserModelInterface interface {
+	Create(username, password string) error
+	Authenticate(username, password string) (*User, error)
 }
 
 type UserModel struct {
@@ -25,6 +42,10 @@ func (m UserModel) Create(username, password string) error {
 	stmt := `INSERT INTO users (username, password_hash) VALUES ($1, $2)`
 	_, err = m.DB.Exec(stmt, username, string(hashedPassword))
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return errors.New("username already exists")
+		}
 		return err
 	}
 
@@ -34,8 +55,8 @@ func (m UserModel) Create(username, password string) error {
 func (m UserModel) Authenticate(username, password string) (*User, error) {
 	var user User
 
-	stmt := `SELECT id, username, password_hash FROM users WHERE username = $1`
-	err := m.DB.QueryRow(stmt, username).Scan(&user.ID, &user.Username, &user.PasswordHash)
+	stmt := `SELECT id, username, password_hash, role FROM users WHERE username = $1`
+	err := m.DB.QueryRow(stmt, username).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errors.New("invalid credentials")
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"garage-api/internal/analytics"
+)
+
+// TrendingProduct is a product with its page views over a window
+type TrendingProduct struct {
+	ProductID int     `json:"product_id" example:"5"`
+	Name      string  `json:"name" example:"Gaming Monitor"`
+	Slug      string  `json:"slug" example:"gaming-monitor"`
+	Price     float64 `json:"price" example:"299.99"`
+	ImagePath string  `json:"image_path,omitempty" example:"/images/monitor.jpg"`
+	Stock     int     `json:"stock" example:"4"`
+	Views     int     `json:"views" example:"132"`
+}
+
+// ViewedProduct is a product a user looked at
+type ViewedProduct struct {
+	ProductID int       `json:"product_id" example:"5"`
+	Name      string    `json:"name" example:"Gaming Monitor"`
+	Slug      string    `json:"slug" example:"gaming-monitor"`
+	Price     float64   `json:"price" example:"299.99"`
+	ImagePath string    `json:"image_path,omitempty" example:"/images/monitor.jpg"`
+	Stock     int       `json:"stock" example:"4"`
+	ViewedAt  time.Time `json:"viewed_at"`
+}
+
+// ViewModelInterface defines the methods that a view model must implement
+type ViewModelInterface interface {
+	InsertViews(views []analytics.View) error
+	Trending(since time.Time, limit int) ([]TrendingProduct, error)
+	RecentlyViewed(userID, limit int) ([]ViewedProduct, error)
+	Rollup(before time.Time) (int64, error)
+}
+
+type ViewModel struct {
+	DB *sql.DB
+}
+
+// InsertViews writes a batch of product views in a single statement
+func (m ViewModel) InsertViews(views []analytics.View) error {
+	if len(views) == 0 {
+		return nil
+	}
+
+	values := make([]string, len(views))
+	args := make([]interface{}, 0, 3*len(views))
+	for i, v := range views {
+		values[i] = fmt.Sprintf("($%d::integer, NULLIF($%d::integer, 0), $%d::timestamptz)", 3*i+1, 3*i+2, 3*i+3)
+		args = append(args, v.ProductID, v.UserID, v.ViewedAt)
+	}
+	// Views of products deleted in the meantime are skipped
+	stmt := `
+		INSERT INTO product_views (product_id, user_id, viewed_at)
+		SELECT v.product_id, v.user_id, v.viewed_at
+		FROM (VALUES ` + strings.Join(values, ", ") + `) AS v (product_id, user_id, viewed_at)
+		WHERE EXISTS (SELECT 1 FROM products p WHERE p.id = v.product_id)`
+	_, err := m.DB.Exec(stmt, args...)
+	return err
+}
+
+// Trending returns the live products viewed most since the given time, most
+// viewed first. Views already rolled up count per whole day, so windows
+// reaching past the retention are rounded to the day.
+func (m ViewModel) Trending(since time.Time, limit int) ([]TrendingProduct, error) {
+	stmt := `
+		SELECT p.id, p.name, p.slug, effective_price(p), COALESCE(p.image_path, ''), p.stock, v.views
+		FROM (
+			SELECT product_id, SUM(views) AS views FROM (
+				SELECT product_id, COUNT(*) AS views FROM product_views
+				WHERE viewed_at >= $1::timestamptz GROUP BY product_id
+				UNION ALL
+				SELECT product_id, SUM(views) FROM product_view_daily
+				WHERE day >= ($1::timestamptz AT TIME ZONE 'UTC')::date GROUP BY product_id
+			) counts
+			GROUP BY product_id
+		) v
+		JOIN products p ON p.id = v.product_id
+		WHERE is_published(p)
+		ORDER BY v.views DESC, p.id
+		LIMIT $2`
+	rows, err := m.DB.Query(stmt, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []TrendingProduct{}
+	for rows.Next() {
+		var p TrendingProduct
+		if err := rows.Scan(&p.ProductID, &p.Name, &p.Slug, &p.Price, &p.ImagePath, &p.Stock, &p.Views); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+// RecentlyViewed returns the live products a user viewed, latest first, each
+// once. Only views within the retention are remembered.
+func (m ViewModel) RecentlyViewed(userID, limit int) ([]ViewedProduct, error) {
+	stmt := `
+		SELECT p.id, p.name, p.slug, effective_price(p), COALESCE(p.image_path, ''), p.stock, v.viewed_at
+		FROM (
+			SELECT product_id, MAX(viewed_at) AS viewed_at FROM product_views
+			WHERE user_id = $1 GROUP BY product_id
+		) v
+		JOIN products p ON p.id = v.product_id
+		WHERE is_published(p)
+		ORDER BY v.viewed_at DESC, p.id
+		LIMIT $2`
+	rows, err := m.DB.Query(stmt, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []ViewedProduct{}
+	for rows.Next() {
+		var p ViewedProduct
+		if err := rows.Scan(&p.ProductID, &p.Name, &p.Slug, &p.Price, &p.ImagePath, &p.Stock, &p.ViewedAt); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+// Rollup moves the views older than before into the daily counts, in one
+// statement so no view is counted twice or lost. It returns how many daily
+// counts were created or updated.
+func (m ViewModel) Rollup(before time.Time) (int64, error) {
+	stmt := `
+		WITH rolled AS (
+			DELETE FROM product_views WHERE viewed_at < $1 RETURNING product_id, viewed_at
+		)
+		INSERT INTO product_view_daily (product_id, day, views)
+		SELECT product_id, (viewed_at AT TIME ZONE 'UTC')::date, COUNT(*) FROM rolled GROUP BY 1, 2
+		ON CONFLICT (product_id, day) DO UPDATE SET views = product_view_daily.views + EXCLUDED.views`
+	result, err := m.DB.Exec(stmt, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
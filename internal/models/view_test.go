@@ -0,0 +1,119 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"garage-api/internal/analytics"
+)
+
+func TestViewModel_InsertViews(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := ViewModel{DB: db}
+	now := time.Now()
+
+	// Test case 1: A batch is written in one statement
+	t.Run("batch", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO product_views \\(product_id, user_id, viewed_at\\)\\s+SELECT .+ FROM \\(VALUES \\(\\$1::integer, NULLIF\\(\\$2::integer, 0\\), \\$3::timestamptz\\), \\(\\$4::integer, NULLIF\\(\\$5::integer, 0\\), \\$6::timestamptz\\)\\)").
+			WithArgs(5, 0, now, 7, 3, now).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		err := model.InsertViews([]analytics.View{
+			{ProductID: 5, ViewedAt: now},
+			{ProductID: 7, UserID: 3, ViewedAt: now},
+		})
+		assert.NoError(t, err)
+	})
+
+	// Test case 2: An empty batch needs no query
+	t.Run("empty", func(t *testing.T) {
+		assert.NoError(t, model.InsertViews(nil))
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestViewModel_Trending(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := ViewModel{DB: db}
+	since := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("FROM product_views\\s+WHERE viewed_at >= \\$1::timestamptz .+UNION ALL.+FROM product_view_daily.+WHERE is_published\\(p\\)\\s+ORDER BY v.views DESC, p.id\\s+LIMIT \\$2").
+		WithArgs(since, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "slug", "price", "image_path", "stock", "views"}).
+			AddRow(5, "Gaming Monitor", "gaming-monitor", 299.99, "", 4, 132).
+			AddRow(1, "Hammer", "hammer", 29.99, "/images/hammer.jpg", 10, 17))
+
+	products, err := model.Trending(since, 10)
+	assert.NoError(t, err)
+	assert.Len(t, products, 2)
+	assert.Equal(t, 132, products[0].Views)
+	assert.Equal(t, "hammer", products[1].Slug)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestViewModel_RecentlyViewed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := ViewModel{DB: db}
+	viewedAt := time.Now()
+
+	mock.ExpectQuery("SELECT product_id, MAX\\(viewed_at\\) AS viewed_at FROM product_views\\s+WHERE user_id = \\$1 GROUP BY product_id.+ORDER BY v.viewed_at DESC, p.id\\s+LIMIT \\$2").
+		WithArgs(3, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "slug", "price", "image_path", "stock", "viewed_at"}).
+			AddRow(7, "Mechanical Keyboard", "mechanical-keyboard", 89.99, "", 9, viewedAt))
+
+	products, err := model.RecentlyViewed(3, 20)
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, 7, products[0].ProductID)
+	assert.Equal(t, viewedAt, products[0].ViewedAt)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestViewModel_Rollup(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := ViewModel{DB: db}
+	before := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectExec("WITH rolled AS \\(\\s+DELETE FROM product_views WHERE viewed_at < \\$1 RETURNING product_id, viewed_at\\s+\\)\\s+INSERT INTO product_view_daily .+ON CONFLICT \\(product_id, day\\) DO UPDATE SET views = product_view_daily.views \\+ EXCLUDED.views").
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 12))
+
+	n, err := model.Rollup(before)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12), n)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,234 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"garage-api/internal/notify"
+)
+
+// WishlistItem is a product saved to a user's wishlist
+type WishlistItem struct {
+	ProductID int     `json:"product_id" example:"5"`
+	Name      string  `json:"name" example:"Gaming Headset"`
+	Price     float64 `json:"price" example:"149.99"`
+	ImagePath string  `json:"image_path,omitempty" example:"/images/headset.jpg"`
+	Stock     int     `json:"stock" example:"0"`
+	// Subscribed says whether the user will be told when the product is
+	// back in stock
+	Subscribed bool      `json:"subscribed" example:"true"`
+	AddedAt    time.Time `json:"added_at"`
+}
+
+// StockSubscription asks for one notification when an out-of-stock product
+// is back. Subscriptions end once the notification is queued.
+type StockSubscription struct {
+	ID          int       `json:"id" example:"1"`
+	UserID      int       `json:"user_id" example:"3"`
+	ProductID   int       `json:"product_id" example:"5"`
+	ProductName string    `json:"product_name" example:"Gaming Headset"`
+	Email       string    `json:"email" example:"jane@example.com"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// WishlistModelInterface defines the methods that a wishlist model must implement
+type WishlistModelInterface interface {
+	List(userID int) ([]WishlistItem, error)
+	Add(userID, productID int) (*WishlistItem, error)
+	Remove(userID, productID int) error
+	ListSubscriptions(userID int) ([]StockSubscription, error)
+	Subscribe(userID, productID int, email string) (*StockSubscription, error)
+	Unsubscribe(userID, productID int) error
+}
+
+type WishlistModel struct {
+	DB *sql.DB
+}
+
+const wishlistSelect = `
+	SELECT p.id, p.name, effective_price(p), COALESCE(p.image_path, ''), p.stock,
+		EXISTS (SELECT 1 FROM stock_subscriptions s WHERE s.user_id = w.user_id AND s.product_id = w.product_id),
+		w.created_at
+	FROM wishlist_items w
+	JOIN products p ON p.id = w.product_id`
+
+func scanWishlistItem(row rowScanner, item *WishlistItem) error {
+	return row.Scan(&item.ProductID, &item.Name, &item.Price, &item.ImagePath, &item.Stock, &item.Subscribed, &item.AddedAt)
+}
+
+// List returns a user's wishlist, most recently added first
+func (m WishlistModel) List(userID int) ([]WishlistItem, error) {
+	rows, err := m.DB.Query(wishlistSelect+` WHERE w.user_id = $1 ORDER BY w.created_at DESC, p.id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []WishlistItem{}
+	for rows.Next() {
+		var item WishlistItem
+		if err := scanWishlistItem(rows, &item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// Add saves a product to a user's wishlist. Adding it again is a no-op.
+func (m WishlistModel) Add(userID, productID int) (*WishlistItem, error) {
+	stmt := `INSERT INTO wishlist_items (user_id, product_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	if _, err := m.DB.Exec(stmt, userID, productID); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" && pqErr.Constraint == "wishlist_items_product_id_fkey" {
+			return nil, errors.New("product not found")
+		}
+		return nil, err
+	}
+
+	var item WishlistItem
+	if err := scanWishlistItem(m.DB.QueryRow(wishlistSelect+` WHERE w.user_id = $1 AND w.product_id = $2`, userID, productID), &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// Remove takes a product off a user's wishlist
+func (m WishlistModel) Remove(userID, productID int) error {
+	result, err := m.DB.Exec(`DELETE FROM wishlist_items WHERE user_id = $1 AND product_id = $2`, userID, productID)
+	if err != nil {
+		return err
+	}
+	return requireOne(result, "wishlist item not found")
+}
+
+// ListSubscriptions returns a user's pending back-in-stock subscriptions
+func (m WishlistModel) ListSubscriptions(userID int) ([]StockSubscription, error) {
+	stmt := `
+		SELECT s.id, s.user_id, s.product_id, p.name, s.email, s.created_at
+		FROM stock_subscriptions s
+		JOIN products p ON p.id = s.product_id
+		WHERE s.user_id = $1
+		ORDER BY s.created_at DESC, s.id DESC`
+	rows, err := m.DB.Query(stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []StockSubscription{}
+	for rows.Next() {
+		var s StockSubscription
+		if err := rows.Scan(&s.ID, &s.UserID, &s.ProductID, &s.ProductName, &s.Email, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+	return subs, rows.Err()
+}
+
+// Subscribe asks to be told at email when an out-of-stock product is back.
+// Subscribing again updates the email address.
+func (m WishlistModel) Subscribe(userID, productID int, email string) (*StockSubscription, error) {
+	// The stock check is part of the insert so a restock racing with the
+	// subscription cannot leave it waiting for the next one
+	stmt := `
+		WITH product AS (SELECT id, name, stock FROM products WHERE id = $2)
+		INSERT INTO stock_subscriptions (user_id, product_id, email)
+		SELECT $1, id, $3 FROM product WHERE stock <= 0
+		ON CONFLICT (user_id, product_id) DO UPDATE SET email = EXCLUDED.email
+		RETURNING id, (SELECT name FROM product), created_at`
+	s := StockSubscription{UserID: userID, ProductID: productID, Email: email}
+	err := m.DB.QueryRow(stmt, userID, productID, email).Scan(&s.ID, &s.ProductName, &s.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		var exists bool
+		if err := m.DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM products WHERE id = $1)`, productID).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, errors.New("product not found")
+		}
+		return nil, errors.New("product in stock")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Unsubscribe cancels a back-in-stock subscription
+func (m WishlistModel) Unsubscribe(userID, productID int) error {
+	result, err := m.DB.Exec(`DELETE FROM stock_subscriptions WHERE user_id = $1 AND product_id = $2`, userID, productID)
+	if err != nil {
+		return err
+	}
+	return requireOne(result, "subscription not found")
+}
+
+// maxNotificationAttempts is how often a notification is tried before it is
+// given up
+const maxNotificationAttempts = 5
+
+// notificationClaim is how long a claimed notification is left to its
+// sender before it is handed out again
+const notificationClaim = "10 minutes"
+
+// NotificationModel is the database queue of back-in-stock notifications.
+// The products_back_in_stock trigger fills it whenever a stock movement
+// brings a product above zero.
+type NotificationModel struct {
+	DB *sql.DB
+}
+
+var _ notify.Queue = NotificationModel{}
+
+// Claim hands out up to limit due notifications. SKIP LOCKED lets several
+// API instances dispatch side by side without sending twice.
+func (m NotificationModel) Claim(limit int) ([]notify.BackInStock, error) {
+	stmt := fmt.Sprintf(`
+		UPDATE stock_notifications SET attempts = attempts + 1, next_attempt_at = NOW() + INTERVAL '%s'
+		WHERE id IN (
+			SELECT id FROM stock_notifications
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at, id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED)
+		RETURNING id, email, product_id, product_name, attempts`, notificationClaim)
+	rows, err := m.DB.Query(stmt, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	batch := []notify.BackInStock{}
+	for rows.Next() {
+		var n notify.BackInStock
+		if err := rows.Scan(&n.ID, &n.Email, &n.ProductID, &n.ProductName, &n.Attempts); err != nil {
+			return nil, err
+		}
+		batch = append(batch, n)
+	}
+	return batch, rows.Err()
+}
+
+// Done marks a notification as sent
+func (m NotificationModel) Done(id int) error {
+	_, err := m.DB.Exec(`UPDATE stock_notifications SET status = 'sent', sent_at = NOW() WHERE id = $1`, id)
+	return err
+}
+
+// Fail records a failed delivery. The notification is retried with a growing
+// delay and given up after maxNotificationAttempts.
+func (m NotificationModel) Fail(id int, cause error) error {
+	stmt := `
+		UPDATE stock_notifications SET
+			last_error = $2,
+			status = CASE WHEN attempts >= $3 THEN 'failed' ELSE status END,
+			next_attempt_at = NOW() + attempts * attempts * INTERVAL '1 minute'
+		WHERE id = $1`
+	_, err := m.DB.Exec(stmt, id, cause.Error(), maxNotificationAttempts)
+	return err
+}
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var wishlistRowColumns = []string{"id", "name", "price", "image_path", "stock", "subscribed", "created_at"}
+
+func TestWishlistModel_Add(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := WishlistModel{DB: db}
+	now := time.Now()
+
+	// Test case 1: A product is saved once, whatever the number of adds
+	t.Run("add product", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO wishlist_items \\(user_id, product_id\\) VALUES \\(\\$1, \\$2\\) ON CONFLICT DO NOTHING").
+			WithArgs(3, 5).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT p.id, .* FROM wishlist_items w\\s+JOIN products p ON p.id = w.product_id WHERE w.user_id = \\$1 AND w.product_id = \\$2").
+			WithArgs(3, 5).
+			WillReturnRows(sqlmock.NewRows(wishlistRowColumns).AddRow(5, "Gaming Headset", 149.99, "/images/headset.jpg", 0, false, now))
+
+		item, err := model.Add(3, 5)
+		assert.NoError(t, err)
+		assert.Equal(t, "Gaming Headset", item.Name)
+		assert.Equal(t, 0, item.Stock)
+	})
+
+	// Test case 2: Unknown products cannot be saved
+	t.Run("product not found", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO wishlist_items").
+			WithArgs(3, 999).
+			WillReturnError(&pq.Error{Code: "23503", Constraint: "wishlist_items_product_id_fkey"})
+
+		item, err := model.Add(3, 999)
+		assert.Nil(t, item)
+		assert.Equal(t, "product not found", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestWishlistModel_Subscribe(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := WishlistModel{DB: db}
+	now := time.Now()
+
+	// Test case 1: Out-of-stock products can be subscribed to
+	t.Run("subscribe", func(t *testing.T) {
+		mock.ExpectQuery("INSERT INTO stock_subscriptions \\(user_id, product_id, email\\)\\s+SELECT \\$1, id, \\$3 FROM product WHERE stock <= 0").
+			WithArgs(3, 5, "jane@example.com").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at"}).AddRow(1, "Gaming Headset", now))
+
+		sub, err := model.Subscribe(3, 5, "jane@example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, sub.ID)
+		assert.Equal(t, "Gaming Headset", sub.ProductName)
+	})
+
+	// Test case 2: Products in stock need no subscription
+	t.Run("product in stock", func(t *testing.T) {
+		mock.ExpectQuery("INSERT INTO stock_subscriptions").
+			WithArgs(3, 6, "jane@example.com").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at"}))
+		mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM products WHERE id = \\$1\\)").
+			WithArgs(6).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+		sub, err := model.Subscribe(3, 6, "jane@example.com")
+		assert.Nil(t, sub)
+		assert.Equal(t, "product in stock", err.Error())
+	})
+
+	// Test case 3: Product not found
+	t.Run("product not found", func(t *testing.T) {
+		mock.ExpectQuery("INSERT INTO stock_subscriptions").
+			WithArgs(3, 999, "jane@example.com").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at"}))
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(999).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		sub, err := model.Subscribe(3, 999, "jane@example.com")
+		assert.Nil(t, sub)
+		assert.Equal(t, "product not found", err.Error())
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestNotificationModel_Queue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	model := NotificationModel{DB: db}
+
+	// Test case 1: Claiming pushes the next attempt back and counts it
+	t.Run("claim", func(t *testing.T) {
+		mock.ExpectQuery("UPDATE stock_notifications SET attempts = attempts \\+ 1, next_attempt_at = NOW\\(\\) \\+ INTERVAL '10 minutes'.*FOR UPDATE SKIP LOCKED").
+			WithArgs(50).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "product_id", "product_name", "attempts"}).
+				AddRow(1, "jane@example.com", 5, "Gaming Headset", 1))
+
+		batch, err := model.Claim(50)
+		assert.NoError(t, err)
+		if assert.Len(t, batch, 1) {
+			assert.Equal(t, "jane@example.com", batch[0].Email)
+			assert.Equal(t, 1, batch[0].Attempts)
+		}
+	})
+
+	// Test case 2: Failures are retried until the attempts run out
+	t.Run("fail", func(t *testing.T) {
+		mock.ExpectExec("UPDATE stock_notifications SET\\s+last_error = \\$2,\\s+status = CASE WHEN attempts >= \\$3 THEN 'failed' ELSE status END").
+			WithArgs(1, "relay down", maxNotificationAttempts).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, model.Fail(1, errors.New("relay down")))
+	})
+
+	// Test case 3: Sent notifications leave the queue
+	t.Run("done", func(t *testing.T) {
+		mock.ExpectExec("UPDATE stock_notifications SET status = 'sent', sent_at = NOW\\(\\) WHERE id = \\$1").
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, model.Done(1))
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,120 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// BackInStock tells a subscriber that a product can be ordered again
+type BackInStock struct {
+	ID          int
+	Email       string
+	ProductID   int
+	ProductName string
+	// Attempts counts deliveries tried so far, including the current one
+	Attempts int
+}
+
+// Notifier delivers notifications to customers
+type Notifier interface {
+	BackInStock(ctx context.Context, n BackInStock) error
+}
+
+// EmailNotifier delivers notifications by email
+type EmailNotifier struct {
+	Mailer    Mailer
+	StoreName string
+	// SiteURL is used to link to products
+	SiteURL string
+}
+
+func (e *EmailNotifier) BackInStock(ctx context.Context, n BackInStock) error {
+	link := fmt.Sprintf("%s/products/%d", strings.TrimRight(e.SiteURL, "/"), n.ProductID)
+	return e.Mailer.Send(ctx, Message{
+		To:      n.Email,
+		Subject: fmt.Sprintf("%s is back in stock", n.ProductName),
+		Body: fmt.Sprintf("Good news: %s is available again at %s.\n\n%s\n\nYou asked to be told once, so you will not hear about this product again unless you subscribe anew.\n",
+			n.ProductName, e.StoreName, link),
+	})
+}
+
+// Queue holds the notifications waiting to be sent
+type Queue interface {
+	// Claim hands out up to limit due notifications. Claimed notifications
+	// are not handed out again until they are marked or their claim lapses.
+	Claim(limit int) ([]BackInStock, error)
+	Done(id int) error
+	// Fail records a failed delivery; the queue decides whether to retry
+	Fail(id int, cause error) error
+}
+
+// Dispatcher sends queued notifications through a Notifier
+type Dispatcher struct {
+	Queue    Queue
+	Notifier Notifier
+	// Interval between polls of the queue; defaults to 30 seconds
+	Interval time.Duration
+	// BatchSize caps the notifications claimed per poll; defaults to 50
+	BatchSize int
+}
+
+// Run dispatches notifications until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	interval := d.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while batches come back full so a backlog drains quickly
+		for {
+			n, err := d.DispatchOnce(ctx)
+			if err != nil {
+				log.Printf("⚠️ Notification dispatch failed: %v", err)
+			}
+			if err != nil || n < d.batchSize() || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchOnce claims one batch of notifications and sends them, returning
+// how many were claimed
+func (d *Dispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	batch, err := d.Queue.Claim(d.batchSize())
+	if err != nil {
+		return 0, err
+	}
+
+	for _, n := range batch {
+		if err := d.Notifier.BackInStock(ctx, n); err != nil {
+			if err := d.Queue.Fail(n.ID, err); err != nil {
+				return len(batch), err
+			}
+			continue
+		}
+		if err := d.Queue.Done(n.ID); err != nil {
+			return len(batch), err
+		}
+	}
+	return len(batch), nil
+}
+
+func (d *Dispatcher) batchSize() int {
+	if d.BatchSize <= 0 {
+		return 50
+	}
+	return d.BatchSize
+}
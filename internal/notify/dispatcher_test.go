@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryQueue struct {
+	pending []BackInStock
+	done    []int
+	failed  map[int]error
+}
+
+func (q *memoryQueue) Claim(limit int) ([]BackInStock, error) {
+	if limit > len(q.pending) {
+		limit = len(q.pending)
+	}
+	batch := q.pending[:limit]
+	q.pending = q.pending[limit:]
+	return batch, nil
+}
+
+func (q *memoryQueue) Done(id int) error {
+	q.done = append(q.done, id)
+	return nil
+}
+
+func (q *memoryQueue) Fail(id int, cause error) error {
+	if q.failed == nil {
+		q.failed = make(map[int]error)
+	}
+	q.failed[id] = cause
+	return nil
+}
+
+type failingMailer struct{}
+
+func (failingMailer) Send(ctx context.Context, msg Message) error {
+	return errors.New("relay down")
+}
+
+func TestEmailNotifier_BackInStock(t *testing.T) {
+	mailer := &LogMailer{}
+	notifier := &EmailNotifier{Mailer: mailer, StoreName: "Garage", SiteURL: "https://shop.example/"}
+
+	err := notifier.BackInStock(context.Background(), BackInStock{ID: 1, Email: "jane@example.com", ProductID: 7, ProductName: "Headset"})
+	assert.NoError(t, err)
+
+	sent := mailer.Sent()
+	if assert.Len(t, sent, 1) {
+		assert.Equal(t, "jane@example.com", sent[0].To)
+		assert.Equal(t, "Headset is back in stock", sent[0].Subject)
+		assert.Contains(t, sent[0].Body, "https://shop.example/products/7")
+	}
+}
+
+func TestDispatcher_DispatchOnce(t *testing.T) {
+	// Test case 1: Sent notifications are marked done, in batches
+	t.Run("sends a batch", func(t *testing.T) {
+		queue := &memoryQueue{pending: []BackInStock{
+			{ID: 1, Email: "a@example.com", ProductID: 7, ProductName: "Headset"},
+			{ID: 2, Email: "b@example.com", ProductID: 7, ProductName: "Headset"},
+			{ID: 3, Email: "c@example.com", ProductID: 8, ProductName: "Mouse"},
+		}}
+		mailer := &LogMailer{}
+		d := &Dispatcher{Queue: queue, Notifier: &EmailNotifier{Mailer: mailer}, BatchSize: 2}
+
+		n, err := d.DispatchOnce(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+		assert.Equal(t, []int{1, 2}, queue.done)
+		assert.Len(t, mailer.Sent(), 2)
+		assert.Len(t, queue.pending, 1)
+	})
+
+	// Test case 2: Failed deliveries are handed back to the queue
+	t.Run("delivery fails", func(t *testing.T) {
+		queue := &memoryQueue{pending: []BackInStock{{ID: 4, Email: "d@example.com", ProductID: 7, ProductName: "Headset"}}}
+		d := &Dispatcher{Queue: queue, Notifier: &EmailNotifier{Mailer: failingMailer{}}}
+
+		n, err := d.DispatchOnce(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Empty(t, queue.done)
+		assert.EqualError(t, queue.failed[4], "relay down")
+	})
+}
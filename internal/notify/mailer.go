@@ -0,0 +1,83 @@
+// Package notify delivers customer notifications. Notifications are queued
+// in the database and sent by a Dispatcher through a Notifier, so the
+// delivery channel can change without touching the code that queues them.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/smtp"
+	"strings"
+	"sync"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends email
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer logs messages instead of sending them and keeps them for
+// inspection. It is meant for development and tests.
+type LogMailer struct {
+	// Logger defaults to the standard logger
+	Logger *log.Logger
+
+	mu   sync.Mutex
+	sent []Message
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	m.sent = append(m.sent, msg)
+	m.mu.Unlock()
+
+	logger := m.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	logger.Printf("📧 Mail to %s: %s", msg.To, msg.Subject)
+	return nil
+}
+
+// Sent returns the messages sent so far
+func (m *LogMailer) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.sent...)
+}
+
+// SMTPMailer sends email through an SMTP relay
+type SMTPMailer struct {
+	// Addr is the relay's host:port
+	Addr string
+	From string
+	// Auth is optional; relays on a trusted network often need none
+	Auth smtp.Auth
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return fmt.Errorf("smtp: invalid header value")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", m.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	if err := smtp.SendMail(m.Addr, m.Auth, m.From, []string{msg.To}, []byte(b.String())); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	return nil
+}
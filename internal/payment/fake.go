@@ -0,0 +1,176 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// FakeSignatureHeader carries the signature of fake provider webhooks
+const FakeSignatureHeader = "X-Fake-Signature"
+
+// Fake is an in-memory provider for development and tests. It never moves
+// money: intents are authorized by posting a signed payment.authorized event
+// to the webhook endpoint, as a real provider would after the customer paid.
+type Fake struct {
+	WebhookSecret string
+
+	mu      sync.Mutex
+	seq     int
+	intents map[string]*Intent
+	refunds map[string]int64
+}
+
+// FakeEvent is the webhook payload understood by the fake provider
+type FakeEvent struct {
+	ID       string    `json:"id"`
+	Type     EventType `json:"type"`
+	IntentID string    `json:"intent_id"`
+	OrderID  int       `json:"order_id,omitempty"`
+	Amount   int64     `json:"amount"`
+}
+
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+func (f *Fake) nextID(prefix string) string {
+	f.seq++
+	return fmt.Sprintf("%s_%d", prefix, f.seq)
+}
+
+func (f *Fake) CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.intents == nil {
+		f.intents = make(map[string]*Intent)
+		f.refunds = make(map[string]int64)
+	}
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("fake: amount must be positive")
+	}
+
+	// Honour idempotency keys like a real provider so retries return the same intent
+	if req.IdempotencyKey != "" {
+		if intent, ok := f.intents[req.IdempotencyKey]; ok {
+			out := *intent
+			return &out, nil
+		}
+	}
+
+	id := f.nextID("fake_pi")
+	intent := &Intent{
+		ID:           id,
+		Status:       IntentRequiresAction,
+		Amount:       req.Amount,
+		Currency:     strings.ToLower(req.Currency),
+		ClientSecret: id + "_secret",
+	}
+	f.intents[id] = intent
+	if req.IdempotencyKey != "" {
+		f.intents[req.IdempotencyKey] = intent
+	}
+
+	out := *intent
+	return &out, nil
+}
+
+func (f *Fake) Capture(ctx context.Context, intentID string, amount int64) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, ok := f.intents[intentID]
+	if !ok {
+		return nil, fmt.Errorf("fake: no such intent %s", intentID)
+	}
+	if intent.Status == IntentSucceeded || intent.Status == IntentCanceled {
+		return nil, fmt.Errorf("fake: intent %s cannot be captured in status %s", intentID, intent.Status)
+	}
+	if amount > 0 {
+		if amount > intent.Amount {
+			return nil, fmt.Errorf("fake: capture amount exceeds authorized amount")
+		}
+		intent.Amount = amount
+	}
+	intent.Status = IntentSucceeded
+
+	out := *intent
+	return &out, nil
+}
+
+func (f *Fake) Refund(ctx context.Context, intentID string, amount int64) (*Refund, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, ok := f.intents[intentID]
+	if !ok || intent.Status != IntentSucceeded {
+		return nil, fmt.Errorf("fake: intent %s has not been captured", intentID)
+	}
+	if amount == 0 {
+		amount = intent.Amount - f.refunds[intentID]
+	}
+	if amount <= 0 || f.refunds[intentID]+amount > intent.Amount {
+		return nil, fmt.Errorf("fake: refund exceeds captured amount")
+	}
+	f.refunds[intentID] += amount
+
+	return &Refund{ID: f.nextID("fake_re"), IntentID: intentID, Amount: amount, Status: "succeeded"}, nil
+}
+
+func (f *Fake) authorize(intentID string, amount int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.intents == nil {
+		f.intents = make(map[string]*Intent)
+		f.refunds = make(map[string]int64)
+	}
+	if intent, ok := f.intents[intentID]; ok {
+		intent.Status = IntentAuthorized
+		return
+	}
+	f.intents[intentID] = &Intent{ID: intentID, Status: IntentAuthorized, Amount: amount}
+}
+
+// Sign returns the signature header value for a webhook payload
+func (f *Fake) Sign(payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(f.WebhookSecret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook checks the X-Fake-Signature header, a hex HMAC-SHA256 of the
+// payload keyed with the webhook secret
+func (f *Fake) VerifyWebhook(payload []byte, header http.Header) (*Event, error) {
+	if !hmac.Equal([]byte(header.Get(FakeSignatureHeader)), []byte(f.Sign(payload))) {
+		return nil, ErrInvalidSignature
+	}
+
+	var raw FakeEvent
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, fmt.Errorf("invalid event payload: %w", err)
+	}
+	if raw.ID == "" || raw.IntentID == "" {
+		return nil, fmt.Errorf("invalid event payload: id and intent_id are required")
+	}
+
+	event := &Event{ID: raw.ID, Type: raw.Type, IntentID: raw.IntentID, OrderID: raw.OrderID, Amount: raw.Amount}
+	switch event.Type {
+	case EventPaymentAuthorized:
+		// Mirror the authorization so a later Capture succeeds, even for
+		// intents created before the process restarted
+		f.authorize(raw.IntentID, raw.Amount)
+	case EventPaymentSucceeded, EventPaymentFailed, EventRefunded:
+	default:
+		event.Type = EventIgnored
+	}
+
+	return event, nil
+}
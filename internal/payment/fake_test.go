@@ -0,0 +1,70 @@
+package payment
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFake_PaymentFlow(t *testing.T) {
+	ctx := context.Background()
+	f := &Fake{WebhookSecret: "secret"}
+
+	intent, err := f.CreateIntent(ctx, IntentRequest{OrderID: 1, Amount: 1999, Currency: "USD", IdempotencyKey: "order-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, IntentRequiresAction, intent.Status)
+
+	// Retrying with the same key returns the same intent
+	again, err := f.CreateIntent(ctx, IntentRequest{OrderID: 1, Amount: 1999, Currency: "USD", IdempotencyKey: "order-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, intent.ID, again.ID)
+
+	payload := []byte(`{"id":"evt_1","type":"payment.authorized","intent_id":"` + intent.ID + `","order_id":1,"amount":1999}`)
+	header := http.Header{}
+	header.Set(FakeSignatureHeader, f.Sign(payload))
+	event, err := f.VerifyWebhook(payload, header)
+	assert.NoError(t, err)
+	assert.Equal(t, &Event{ID: "evt_1", Type: EventPaymentAuthorized, IntentID: intent.ID, OrderID: 1, Amount: 1999}, event)
+
+	captured, err := f.Capture(ctx, intent.ID, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, IntentSucceeded, captured.Status)
+
+	_, err = f.Capture(ctx, intent.ID, 0)
+	assert.Error(t, err)
+
+	refund, err := f.Refund(ctx, intent.ID, 500)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(500), refund.Amount)
+
+	refund, err = f.Refund(ctx, intent.ID, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1499), refund.Amount)
+
+	_, err = f.Refund(ctx, intent.ID, 1)
+	assert.Error(t, err)
+}
+
+func TestFake_VerifyWebhook(t *testing.T) {
+	f := &Fake{WebhookSecret: "secret"}
+	payload := []byte(`{"id":"evt_1","type":"something.else","intent_id":"fake_pi_1"}`)
+
+	// Test case 1: Unknown event types are ignored
+	t.Run("ignored type", func(t *testing.T) {
+		header := http.Header{}
+		header.Set(FakeSignatureHeader, f.Sign(payload))
+		event, err := f.VerifyWebhook(payload, header)
+		assert.NoError(t, err)
+		assert.Equal(t, EventIgnored, event.Type)
+	})
+
+	// Test case 2: Bad signature
+	t.Run("bad signature", func(t *testing.T) {
+		header := http.Header{}
+		header.Set(FakeSignatureHeader, (&Fake{WebhookSecret: "other"}).Sign(payload))
+		_, err := f.VerifyWebhook(payload, header)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+}
@@ -0,0 +1,96 @@
+// Package payment abstracts the payment service providers used to charge
+// orders, so checkout does not depend on any single provider's API.
+package payment
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+)
+
+// ErrInvalidSignature is returned by VerifyWebhook when a webhook was not
+// signed by the provider
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// IntentStatus is the state of a payment intent at the provider
+type IntentStatus string
+
+const (
+	// IntentRequiresAction means the customer still has to confirm the payment
+	IntentRequiresAction IntentStatus = "requires_action"
+	// IntentAuthorized means the funds are held and can be captured
+	IntentAuthorized IntentStatus = "authorized"
+	IntentSucceeded  IntentStatus = "succeeded"
+	IntentFailed     IntentStatus = "failed"
+	IntentCanceled   IntentStatus = "canceled"
+)
+
+// EventType is the provider-independent meaning of a webhook event
+type EventType string
+
+const (
+	EventPaymentAuthorized EventType = "payment.authorized"
+	EventPaymentSucceeded  EventType = "payment.succeeded"
+	EventPaymentFailed     EventType = "payment.failed"
+	EventRefunded          EventType = "payment.refunded"
+	// EventIgnored covers provider events the shop does not act on
+	EventIgnored EventType = "ignored"
+)
+
+// IntentRequest asks the provider to prepare a payment for an order.
+// Amounts are in the currency's minor unit (cents).
+type IntentRequest struct {
+	OrderID  int
+	Amount   int64
+	Currency string
+	// IdempotencyKey makes retried requests return the same intent
+	IdempotencyKey string
+}
+
+// Intent is a payment being collected by a provider
+type Intent struct {
+	ID           string       `json:"id" example:"pi_3MtwBwLkdIwHu7ix28a3tqPa"`
+	Status       IntentStatus `json:"status" example:"requires_action"`
+	Amount       int64        `json:"amount" example:"189999"`
+	Currency     string       `json:"currency" example:"usd"`
+	ClientSecret string       `json:"client_secret,omitempty" example:"pi_3MtwBwLkdIwHu7ix28a3tqPa_secret_YrKJUKribcBjcG8HVhfZluoGH"`
+}
+
+// Refund is money returned to the customer for a captured intent
+type Refund struct {
+	ID       string `json:"id" example:"re_1Nispe2eZvKYlo2Cd31jOCgZ"`
+	IntentID string `json:"intent_id" example:"pi_3MtwBwLkdIwHu7ix28a3tqPa"`
+	Amount   int64  `json:"amount" example:"189999"`
+	Status   string `json:"status" example:"succeeded"`
+}
+
+// Event is a verified webhook notification
+type Event struct {
+	ID       string
+	Type     EventType
+	IntentID string
+	// OrderID is taken from the intent's metadata when the provider sends it
+	OrderID int
+	Amount  int64
+}
+
+// PaymentProvider is implemented by each payment service provider.
+// Intents are created for manual capture: the provider only authorizes the
+// amount, and the shop captures it once it has accepted the order.
+type PaymentProvider interface {
+	// Name is the provider's key in webhook URLs and stored payments
+	Name() string
+	CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error)
+	// Capture collects an authorized intent; amount 0 captures the full amount
+	Capture(ctx context.Context, intentID string, amount int64) (*Intent, error)
+	// Refund returns money for a captured intent; amount 0 refunds everything
+	Refund(ctx context.Context, intentID string, amount int64) (*Refund, error)
+	// VerifyWebhook checks the signature of a webhook request and decodes it
+	VerifyWebhook(payload []byte, header http.Header) (*Event, error)
+}
+
+// ToMinorUnits converts an amount to cents
+func ToMinorUnits(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
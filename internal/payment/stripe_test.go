@@ -0,0 +1,117 @@
+package payment
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func signedStripeHeader(secret string, ts time.Time, payload []byte) http.Header {
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	header := http.Header{}
+	header.Set("Stripe-Signature", "t="+timestamp+",v1="+stripeSignature(secret, timestamp, payload))
+	return header
+}
+
+func TestStripe_VerifyWebhook(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := &Stripe{WebhookSecret: "whsec_test", now: func() time.Time { return now }}
+
+	intentEvent := []byte(`{"id":"evt_1","type":"payment_intent.succeeded","data":{"object":{"id":"pi_1","object":"payment_intent","amount":1999,"metadata":{"order_id":"42"}}}}`)
+	refundEvent := []byte(`{"id":"evt_2","type":"charge.refunded","data":{"object":{"id":"ch_1","object":"charge","amount":1999,"amount_refunded":1999,"payment_intent":"pi_1"}}}`)
+
+	// Test case 1: Valid payment intent event
+	t.Run("valid signature", func(t *testing.T) {
+		event, err := s.VerifyWebhook(intentEvent, signedStripeHeader("whsec_test", now, intentEvent))
+		assert.NoError(t, err)
+		assert.Equal(t, &Event{ID: "evt_1", Type: EventPaymentSucceeded, IntentID: "pi_1", OrderID: 42, Amount: 1999}, event)
+	})
+
+	// Test case 2: Refund events carry the intent of the charge
+	t.Run("refund event", func(t *testing.T) {
+		event, err := s.VerifyWebhook(refundEvent, signedStripeHeader("whsec_test", now, refundEvent))
+		assert.NoError(t, err)
+		assert.Equal(t, EventRefunded, event.Type)
+		assert.Equal(t, "pi_1", event.IntentID)
+		assert.Equal(t, int64(1999), event.Amount)
+	})
+
+	// Test case 3: Partial refunds are ignored
+	t.Run("partial refund event", func(t *testing.T) {
+		partial := []byte(`{"id":"evt_3","type":"charge.refunded","data":{"object":{"id":"ch_1","object":"charge","amount":1999,"amount_refunded":500,"payment_intent":"pi_1"}}}`)
+		event, err := s.VerifyWebhook(partial, signedStripeHeader("whsec_test", now, partial))
+		assert.NoError(t, err)
+		assert.Equal(t, EventIgnored, event.Type)
+	})
+
+	// Test case 4: Signed with another secret
+	t.Run("wrong secret", func(t *testing.T) {
+		_, err := s.VerifyWebhook(intentEvent, signedStripeHeader("whsec_other", now, intentEvent))
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	// Test case 5: Payload tampered with after signing
+	t.Run("tampered payload", func(t *testing.T) {
+		header := signedStripeHeader("whsec_test", now, intentEvent)
+		_, err := s.VerifyWebhook(refundEvent, header)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	// Test case 6: Replayed old event
+	t.Run("stale timestamp", func(t *testing.T) {
+		header := signedStripeHeader("whsec_test", now.Add(-10*time.Minute), intentEvent)
+		_, err := s.VerifyWebhook(intentEvent, header)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	// Test case 7: Missing header
+	t.Run("missing signature", func(t *testing.T) {
+		_, err := s.VerifyWebhook(intentEvent, http.Header{})
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+}
+
+func TestStripe_CreateIntent(t *testing.T) {
+	var gotForm url.Values
+	var gotKey, gotUser string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/payment_intents", r.URL.Path)
+		body, _ := io.ReadAll(r.Body)
+		gotForm, _ = url.ParseQuery(string(body))
+		gotKey = r.Header.Get("Idempotency-Key")
+		gotUser, _, _ = r.BasicAuth()
+		w.Write([]byte(`{"id":"pi_1","status":"requires_payment_method","amount":1999,"currency":"usd","client_secret":"pi_1_secret"}`))
+	}))
+	defer server.Close()
+
+	s := &Stripe{SecretKey: "sk_test", BaseURL: server.URL}
+	intent, err := s.CreateIntent(context.Background(), IntentRequest{OrderID: 42, Amount: 1999, Currency: "USD", IdempotencyKey: "order-42"})
+	assert.NoError(t, err)
+	assert.Equal(t, &Intent{ID: "pi_1", Status: IntentFailed, Amount: 1999, Currency: "usd", ClientSecret: "pi_1_secret"}, intent)
+
+	assert.Equal(t, "sk_test", gotUser)
+	assert.Equal(t, "order-42", gotKey)
+	assert.Equal(t, "1999", gotForm.Get("amount"))
+	assert.Equal(t, "usd", gotForm.Get("currency"))
+	assert.Equal(t, "manual", gotForm.Get("capture_method"))
+	assert.Equal(t, "42", gotForm.Get("metadata[order_id]"))
+}
+
+func TestStripe_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"type":"invalid_request_error","message":"This PaymentIntent could not be captured"}}`))
+	}))
+	defer server.Close()
+
+	s := &Stripe{SecretKey: "sk_test", BaseURL: server.URL}
+	_, err := s.Capture(context.Background(), "pi_1", 0)
+	assert.EqualError(t, err, "stripe: This PaymentIntent could not be captured")
+}
@@ -0,0 +1,50 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocument_WriteTo(t *testing.T) {
+	doc := &Document{Title: "Invoice (draft)"}
+	doc.Text(50, 800, HelveticaBold, 16, "Invoice")
+	doc.AddPage()
+	doc.TextRight(545, 800, Helvetica, 10, "1.234,56 €")
+	doc.Line(50, 790, 545, 790)
+
+	var buf bytes.Buffer
+	_, err := doc.WriteTo(&buf)
+	assert.NoError(t, err)
+	out := buf.String()
+
+	assert.True(t, strings.HasPrefix(out, "%PDF-1.4\n"))
+	assert.True(t, strings.HasSuffix(out, "%%EOF\n"))
+	assert.Contains(t, out, "/Count 2")
+	assert.Contains(t, out, "/Title (Invoice \\(draft\\))")
+	assert.Contains(t, out, "(1.234,56 \x80) Tj")
+
+	// Every xref entry points at the start of its object
+	xref := regexp.MustCompile(`startxref\n(\d+)`).FindStringSubmatch(out)
+	start, _ := strconv.Atoi(xref[1])
+	entries := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllStringSubmatch(out[start:], -1)
+	assert.Len(t, entries, 8)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(entry[1])
+		assert.True(t, strings.HasPrefix(out[offset:], fmt.Sprintf("%d 0 obj", i+1)), "object %d", i+1)
+	}
+}
+
+func TestWidth(t *testing.T) {
+	assert.Equal(t, 5.56, Width(Helvetica, 10, "0"))
+	assert.InDelta(t, 19.46, Width(Helvetica, 10, "9.99"), 0.001)
+}
+
+func TestEscape(t *testing.T) {
+	assert.Equal(t, "Stra\xdfe \\(1\\) \\\\ ?", escape("Straße (1) \\ 你"))
+}
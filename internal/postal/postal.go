@@ -0,0 +1,84 @@
+// Package postal validates and normalizes postal codes by country
+package postal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// formats holds the postal code pattern of the countries we know. Codes are
+// matched after being uppercased with their spaces and dashes removed.
+var formats = map[string]*regexp.Regexp{
+	"AT": regexp.MustCompile(`^\d{4}$`),
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"BE": regexp.MustCompile(`^\d{4}$`),
+	"CA": regexp.MustCompile(`^[ABCEGHJ-NPRSTVXY]\d[A-Z]\d[A-Z]\d$`),
+	"CH": regexp.MustCompile(`^\d{4}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"DK": regexp.MustCompile(`^\d{4}$`),
+	"ES": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"GB": regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]?\d[A-Z]{2}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+	"JP": regexp.MustCompile(`^\d{7}$`),
+	"NL": regexp.MustCompile(`^\d{4}[A-Z]{2}$`),
+	"PL": regexp.MustCompile(`^\d{5}$`),
+	"SE": regexp.MustCompile(`^\d{5}$`),
+	"US": regexp.MustCompile(`^\d{5}(\d{4})?$`),
+}
+
+// optional lists countries without a postal code system
+var optional = map[string]bool{
+	"AE": true,
+	"HK": true,
+	"IE": true,
+	"QA": true,
+}
+
+// Normalize validates a postal code for a country and returns it in the
+// country's usual notation, e.g. "1234 AB" in the Netherlands. Codes of
+// countries we have no pattern for are only trimmed and uppercased.
+func Normalize(country, code string) (string, error) {
+	country = strings.ToUpper(country)
+	code = strings.ToUpper(strings.Join(strings.Fields(code), " "))
+
+	if code == "" {
+		if optional[country] {
+			return "", nil
+		}
+		return "", fmt.Errorf("postal code is required for %s", country)
+	}
+
+	format, ok := formats[country]
+	if !ok {
+		if len(code) > 16 {
+			return "", fmt.Errorf("invalid postal code %q for %s", code, country)
+		}
+		return code, nil
+	}
+
+	compact := strings.NewReplacer(" ", "", "-", "").Replace(code)
+	if !format.MatchString(compact) {
+		return "", fmt.Errorf("invalid postal code %q for %s", code, country)
+	}
+
+	switch country {
+	case "CA", "GB":
+		// The inward code is always the last three characters
+		return compact[:len(compact)-3] + " " + compact[len(compact)-3:], nil
+	case "NL":
+		return compact[:4] + " " + compact[4:], nil
+	case "SE":
+		return compact[:3] + " " + compact[3:], nil
+	case "PL":
+		return compact[:2] + "-" + compact[2:], nil
+	case "JP":
+		return compact[:3] + "-" + compact[3:], nil
+	case "US":
+		if len(compact) == 9 {
+			return compact[:5] + "-" + compact[5:], nil
+		}
+	}
+	return compact, nil
+}
@@ -0,0 +1,41 @@
+package postal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		country, code string
+		want          string
+		wantErr       string
+	}{
+		{"US", "94105", "94105", ""},
+		{"us", "94105-1234", "94105-1234", ""},
+		{"US", "9410", "", `invalid postal code "9410" for US`},
+		{"DE", " 10115 ", "10115", ""},
+		{"DE", "1011", "", `invalid postal code "1011" for DE`},
+		{"NL", "1012ab", "1012 AB", ""},
+		{"GB", "sw1a1aa", "SW1A 1AA", ""},
+		{"GB", "M1 1AE", "M1 1AE", ""},
+		{"CA", "k1a 0b1", "K1A 0B1", ""},
+		{"CA", "D1A 0B1", "", `invalid postal code "D1A 0B1" for CA`},
+		{"PL", "00950", "00-950", ""},
+		{"JP", "100-0001", "100-0001", ""},
+		{"FR", "", "", "postal code is required for FR"},
+		{"IE", "", "", ""},
+		{"BR", "01310-100", "01310-100", ""},
+	}
+
+	for _, tt := range tests {
+		got, err := Normalize(tt.country, tt.code)
+		if tt.wantErr != "" {
+			assert.EqualError(t, err, tt.wantErr, tt.country+" "+tt.code)
+			continue
+		}
+		assert.NoError(t, err, tt.country+" "+tt.code)
+		assert.Equal(t, tt.want, got, tt.country+" "+tt.code)
+	}
+}
@@ -0,0 +1,249 @@
+// Package promotion evaluates discount codes and automatic promotions
+// against a set of order lines. It does no I/O: callers load the rules and
+// usage counts, so evaluation is deterministic and easy to test.
+package promotion
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Type is the kind of benefit a rule grants
+type Type string
+
+const (
+	Percentage   Type = "percentage"
+	FixedAmount  Type = "fixed_amount"
+	FreeShipping Type = "free_shipping"
+)
+
+// Valid reports whether t is a known rule type
+func (t Type) Valid() bool {
+	return t == Percentage || t == FixedAmount || t == FreeShipping
+}
+
+// Rule is a coupon (when Code is set) or an automatic promotion. Zero values
+// of the optional limits mean "no limit".
+type Rule struct {
+	ID   int
+	Code string
+	Name string
+	Type Type
+	// Value is a percentage (0-100] or an amount, depending on Type
+	Value float64
+
+	StartsAt *time.Time
+	EndsAt   *time.Time
+
+	// MinSubtotal is the minimum cart subtotal, before discounts
+	MinSubtotal float64
+	// MinQuantity is the minimum number of units of in-scope products
+	MinQuantity int
+
+	// ProductIDs and CategoryIDs restrict the rule to some products; a line
+	// is in scope when it matches either list. Both empty means every line.
+	ProductIDs  []int
+	CategoryIDs []int
+
+	UsageLimit   int
+	PerUserLimit int
+	// Priority orders automatic promotions; higher goes first
+	Priority int
+
+	// Usage counters, filled in by the caller before evaluation
+	TimesUsed       int
+	TimesUsedByUser int
+}
+
+// Line is an order line the rules are evaluated against
+type Line struct {
+	ProductID  int
+	CategoryID int
+	Quantity   int
+	UnitPrice  float64
+}
+
+// Applied is a rule that took effect, with the discount it granted
+type Applied struct {
+	PromotionID  int     `json:"promotion_id" example:"1"`
+	Code         string  `json:"code,omitempty" example:"SUMMER10"`
+	Name         string  `json:"name" example:"Summer sale"`
+	Type         Type    `json:"type" example:"percentage"`
+	Amount       float64 `json:"amount" example:"12.5"`
+	FreeShipping bool    `json:"free_shipping,omitempty" example:"false"`
+}
+
+// Result is the outcome of evaluating the rules against a set of lines
+type Result struct {
+	Subtotal     float64
+	Discount     float64
+	Total        float64
+	FreeShipping bool
+	Applied      []Applied
+	// LineDiscounts holds the discount allocated to each line, in input order
+	LineDiscounts []float64
+}
+
+// CouponError explains why a coupon cannot be applied
+type CouponError struct {
+	Code   string
+	Reason string
+}
+
+func (e *CouponError) Error() string {
+	return fmt.Sprintf("coupon %s %s", e.Code, e.Reason)
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func (r *Rule) inScope(l Line) bool {
+	if len(r.ProductIDs) == 0 && len(r.CategoryIDs) == 0 {
+		return true
+	}
+	for _, id := range r.ProductIDs {
+		if id == l.ProductID {
+			return true
+		}
+	}
+	for _, id := range r.CategoryIDs {
+		if l.CategoryID != 0 && id == l.CategoryID {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns why the rule does not apply, or "" when it does
+func (r *Rule) check(lines []Line, subtotal float64, userID int, now time.Time) string {
+	if r.StartsAt != nil && now.Before(*r.StartsAt) {
+		return "is not active yet"
+	}
+	if r.EndsAt != nil && !now.Before(*r.EndsAt) {
+		return "has expired"
+	}
+	if r.UsageLimit > 0 && r.TimesUsed >= r.UsageLimit {
+		return "has reached its usage limit"
+	}
+	if r.PerUserLimit > 0 && userID != 0 && r.TimesUsedByUser >= r.PerUserLimit {
+		return "has already been used the maximum number of times"
+	}
+	if r.MinSubtotal > 0 && subtotal < r.MinSubtotal {
+		return fmt.Sprintf("requires a subtotal of at least %.2f", r.MinSubtotal)
+	}
+
+	units := 0
+	for _, l := range lines {
+		if r.inScope(l) {
+			units += l.Quantity
+		}
+	}
+	if units == 0 {
+		return "does not apply to any item in the cart"
+	}
+	if r.MinQuantity > 0 && units < r.MinQuantity {
+		return fmt.Sprintf("requires at least %d eligible items", r.MinQuantity)
+	}
+	return ""
+}
+
+// Evaluate applies every eligible automatic promotion and then the coupon,
+// if any. Automatic promotions run by descending priority, then ascending
+// ID, so the same input always gives the same result. Each discount is taken
+// from what earlier rules left of the in-scope lines, so the total never goes
+// below zero. An ineligible coupon yields a *CouponError; ineligible
+// automatic promotions are skipped silently.
+func Evaluate(lines []Line, automatic []Rule, coupon *Rule, userID int, now time.Time) (*Result, error) {
+	result := &Result{LineDiscounts: make([]float64, len(lines)), Applied: []Applied{}}
+
+	remaining := make([]float64, len(lines))
+	for i, l := range lines {
+		remaining[i] = round(l.UnitPrice * float64(l.Quantity))
+		result.Subtotal += remaining[i]
+	}
+	result.Subtotal = round(result.Subtotal)
+
+	rules := make([]Rule, len(automatic))
+	copy(rules, automatic)
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority > rules[j].Priority
+		}
+		return rules[i].ID < rules[j].ID
+	})
+
+	for i := range rules {
+		if rules[i].check(lines, result.Subtotal, userID, now) != "" {
+			continue
+		}
+		result.apply(&rules[i], lines, remaining)
+	}
+
+	if coupon != nil {
+		if reason := coupon.check(lines, result.Subtotal, userID, now); reason != "" {
+			return nil, &CouponError{Code: coupon.Code, Reason: reason}
+		}
+		result.apply(coupon, lines, remaining)
+	}
+
+	result.Discount = round(result.Discount)
+	result.Total = round(result.Subtotal - result.Discount)
+	return result, nil
+}
+
+// apply grants a rule's benefit, allocating the discount over the in-scope
+// lines in proportion to what is left of each
+func (res *Result) apply(r *Rule, lines []Line, remaining []float64) {
+	applied := Applied{PromotionID: r.ID, Code: r.Code, Name: r.Name, Type: r.Type}
+
+	if r.Type == FreeShipping {
+		applied.FreeShipping = true
+		res.FreeShipping = true
+		res.Applied = append(res.Applied, applied)
+		return
+	}
+
+	var eligible []int
+	base := 0.0
+	for i, l := range lines {
+		if r.inScope(l) && remaining[i] > 0 {
+			eligible = append(eligible, i)
+			base += remaining[i]
+		}
+	}
+	base = round(base)
+	if base <= 0 {
+		return
+	}
+
+	var amount float64
+	switch r.Type {
+	case Percentage:
+		amount = round(base * math.Min(r.Value, 100) / 100)
+	case FixedAmount:
+		amount = round(math.Min(r.Value, base))
+	}
+	if amount <= 0 {
+		return
+	}
+
+	// The last eligible line absorbs rounding so the shares add up exactly
+	left := amount
+	for n, i := range eligible {
+		share := left
+		if n < len(eligible)-1 {
+			share = math.Min(round(amount*remaining[i]/base), left)
+		}
+		share = math.Min(share, remaining[i])
+		remaining[i] = round(remaining[i] - share)
+		res.LineDiscounts[i] = round(res.LineDiscounts[i] + share)
+		left = round(left - share)
+	}
+
+	applied.Amount = round(amount - left)
+	res.Discount += applied.Amount
+	res.Applied = append(res.Applied, applied)
+}
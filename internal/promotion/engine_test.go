@@ -0,0 +1,175 @@
+package promotion
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	mousePad  = 1
+	laptop    = 2
+	keyboard  = 3
+	accessory = 10
+	computers = 20
+)
+
+var now = time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+
+func cart() []Line {
+	return []Line{
+		{ProductID: laptop, CategoryID: computers, Quantity: 1, UnitPrice: 1000},
+		{ProductID: mousePad, CategoryID: accessory, Quantity: 2, UnitPrice: 15},
+		{ProductID: keyboard, CategoryID: accessory, Quantity: 1, UnitPrice: 70},
+	}
+}
+
+func TestEvaluate_Coupons(t *testing.T) {
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	tests := []struct {
+		name          string
+		coupon        Rule
+		wantDiscount  float64
+		wantLines     []float64
+		wantFreeShip  bool
+		wantErrReason string
+	}{
+		{
+			name:         "percentage on whole cart",
+			coupon:       Rule{ID: 1, Code: "TEN", Type: Percentage, Value: 10},
+			wantDiscount: 110,
+			wantLines:    []float64{100, 3, 7},
+		},
+		{
+			name:         "fixed amount spread over scoped lines",
+			coupon:       Rule{ID: 2, Code: "ACC20", Type: FixedAmount, Value: 20, CategoryIDs: []int{accessory}},
+			wantDiscount: 20,
+			wantLines:    []float64{0, 6, 14},
+		},
+		{
+			name:         "fixed amount capped at scoped value",
+			coupon:       Rule{ID: 3, Code: "PAD50", Type: FixedAmount, Value: 50, ProductIDs: []int{mousePad}},
+			wantDiscount: 30,
+			wantLines:    []float64{0, 30, 0},
+		},
+		{
+			name:         "free shipping",
+			coupon:       Rule{ID: 4, Code: "SHIP", Type: FreeShipping},
+			wantDiscount: 0,
+			wantLines:    []float64{0, 0, 0},
+			wantFreeShip: true,
+		},
+		{
+			name:          "not started",
+			coupon:        Rule{ID: 5, Code: "SOON", Type: Percentage, Value: 10, StartsAt: &future},
+			wantErrReason: "is not active yet",
+		},
+		{
+			name:          "expired",
+			coupon:        Rule{ID: 6, Code: "OLD", Type: Percentage, Value: 10, EndsAt: &past},
+			wantErrReason: "has expired",
+		},
+		{
+			name:          "usage limit reached",
+			coupon:        Rule{ID: 7, Code: "ONCE", Type: Percentage, Value: 10, UsageLimit: 100, TimesUsed: 100},
+			wantErrReason: "has reached its usage limit",
+		},
+		{
+			name:          "per user limit reached",
+			coupon:        Rule{ID: 8, Code: "MINE", Type: Percentage, Value: 10, PerUserLimit: 1, TimesUsedByUser: 1},
+			wantErrReason: "has already been used the maximum number of times",
+		},
+		{
+			name:          "minimum subtotal",
+			coupon:        Rule{ID: 9, Code: "BIG", Type: FixedAmount, Value: 100, MinSubtotal: 2000},
+			wantErrReason: "requires a subtotal of at least 2000.00",
+		},
+		{
+			name:          "out of scope",
+			coupon:        Rule{ID: 10, Code: "CHAIR", Type: Percentage, Value: 10, ProductIDs: []int{99}},
+			wantErrReason: "does not apply to any item in the cart",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coupon := tt.coupon
+			result, err := Evaluate(cart(), nil, &coupon, 7, now)
+
+			if tt.wantErrReason != "" {
+				var couponErr *CouponError
+				assert.True(t, errors.As(err, &couponErr))
+				assert.Equal(t, tt.wantErrReason, couponErr.Reason)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, 1100.0, result.Subtotal)
+			assert.Equal(t, tt.wantDiscount, result.Discount)
+			assert.Equal(t, 1100-tt.wantDiscount, result.Total)
+			assert.Equal(t, tt.wantLines, result.LineDiscounts)
+			assert.Equal(t, tt.wantFreeShip, result.FreeShipping)
+			assert.Len(t, result.Applied, 1)
+		})
+	}
+}
+
+func TestEvaluate_AutomaticPromotions(t *testing.T) {
+	buyTwoPads := Rule{ID: 20, Name: "Buy 2 mouse pads, get 10% off", Type: Percentage, Value: 10, ProductIDs: []int{mousePad}, MinQuantity: 2}
+	buyThreePads := Rule{ID: 21, Name: "Buy 3 mouse pads, get 50% off", Type: Percentage, Value: 50, ProductIDs: []int{mousePad}, MinQuantity: 3}
+	accessoriesFive := Rule{ID: 22, Name: "5 off accessories", Type: FixedAmount, Value: 5, CategoryIDs: []int{accessory}, Priority: 10}
+
+	// Test case 1: Quantity threshold met, higher threshold skipped
+	t.Run("quantity threshold", func(t *testing.T) {
+		result, err := Evaluate(cart(), []Rule{buyThreePads, buyTwoPads}, nil, 7, now)
+		assert.NoError(t, err)
+		assert.Equal(t, 3.0, result.Discount)
+		assert.Equal(t, []Applied{{PromotionID: 20, Name: buyTwoPads.Name, Type: Percentage, Amount: 3}}, result.Applied)
+	})
+
+	// Test case 2: Priority decides order; later rules see reduced amounts
+	t.Run("stacking order", func(t *testing.T) {
+		result, err := Evaluate(cart(), []Rule{buyTwoPads, accessoriesFive}, nil, 7, now)
+		assert.NoError(t, err)
+		assert.Equal(t, 22, result.Applied[0].PromotionID)
+		assert.Equal(t, 5.0, result.Applied[0].Amount)
+		// Pads after the fixed discount: 30 - 1.5 = 28.5, 10% of which is 2.85
+		assert.Equal(t, 2.85, result.Applied[1].Amount)
+		assert.Equal(t, 7.85, result.Discount)
+		assert.Equal(t, 1092.15, result.Total)
+	})
+
+	// Test case 3: Coupon applies after automatic promotions
+	t.Run("with coupon", func(t *testing.T) {
+		coupon := Rule{ID: 1, Code: "TEN", Type: Percentage, Value: 10}
+		result, err := Evaluate(cart(), []Rule{buyTwoPads}, &coupon, 7, now)
+		assert.NoError(t, err)
+		assert.Equal(t, "TEN", result.Applied[1].Code)
+		assert.Equal(t, 109.7, result.Applied[1].Amount)
+		assert.Equal(t, 112.7, result.Discount)
+	})
+
+	// Test case 4: Same input in any order gives the same result
+	t.Run("deterministic", func(t *testing.T) {
+		a, _ := Evaluate(cart(), []Rule{buyTwoPads, accessoriesFive, buyThreePads}, nil, 7, now)
+		b, _ := Evaluate(cart(), []Rule{buyThreePads, accessoriesFive, buyTwoPads}, nil, 7, now)
+		assert.Equal(t, a, b)
+	})
+
+	// Test case 5: Discounts never exceed the subtotal
+	t.Run("never negative", func(t *testing.T) {
+		huge := Rule{ID: 30, Name: "Huge", Type: FixedAmount, Value: 5000}
+		result, err := Evaluate(cart(), []Rule{huge, buyTwoPads}, nil, 7, now)
+		assert.NoError(t, err)
+		assert.Equal(t, 1100.0, result.Discount)
+		assert.Equal(t, 0.0, result.Total)
+		// The pad promotion has the lower ID, so it runs first and the
+		// fixed discount is capped at what is left
+		assert.Equal(t, 3.0, result.Applied[0].Amount)
+		assert.Equal(t, 1097.0, result.Applied[1].Amount)
+	})
+}
@@ -0,0 +1,140 @@
+// Package recommend computes "customers who bought this also bought"
+// recommendations from co-occurrence: products bought, or viewed, by the
+// same customers. It does no I/O: callers load the baskets and store the
+// result, so computations are deterministic and easy to test.
+package recommend
+
+import (
+	"math"
+	"sort"
+)
+
+// Basket is the set of products one customer bought, or viewed
+type Basket []int
+
+// Options tunes a computation. Zero values take the defaults.
+type Options struct {
+	// TopN caps the recommendations kept per product; defaults to 10
+	TopN int
+	// MinSupport is the fewest customers two products must share to be
+	// related; defaults to 2
+	MinSupport int
+	// ViewWeight scales view similarity against purchase similarity, which
+	// weighs 1; defaults to 0.25
+	ViewWeight float64
+	// MaxBasketSize ignores larger baskets, e.g. crawlers viewing the whole
+	// catalog; defaults to 100
+	MaxBasketSize int
+}
+
+func (o Options) withDefaults() Options {
+	if o.TopN <= 0 {
+		o.TopN = 10
+	}
+	if o.MinSupport <= 0 {
+		o.MinSupport = 2
+	}
+	if o.ViewWeight <= 0 {
+		o.ViewWeight = 0.25
+	}
+	if o.MaxBasketSize <= 0 {
+		o.MaxBasketSize = 100
+	}
+	return o
+}
+
+// Recommendation relates a product to another one customers also bought or
+// viewed. Higher scores are stronger.
+type Recommendation struct {
+	ProductID     int
+	RecommendedID int
+	Score         float64
+}
+
+type pair struct{ a, b int }
+
+// Compute returns up to TopN recommendations per product, ordered by
+// product, then by score and recommended product. The score of two products
+// is the cosine similarity of the customers who bought them, plus
+// ViewWeight times that of the customers who viewed them.
+func Compute(purchases, views []Basket, opts Options) []Recommendation {
+	opts = opts.withDefaults()
+
+	scores := make(map[pair]float64)
+	for pr, s := range similarity(purchases, opts) {
+		scores[pr] += s
+	}
+	for pr, s := range similarity(views, opts) {
+		scores[pr] += opts.ViewWeight * s
+	}
+
+	byProduct := make(map[int][]Recommendation)
+	for pr, s := range scores {
+		byProduct[pr.a] = append(byProduct[pr.a], Recommendation{ProductID: pr.a, RecommendedID: pr.b, Score: s})
+		byProduct[pr.b] = append(byProduct[pr.b], Recommendation{ProductID: pr.b, RecommendedID: pr.a, Score: s})
+	}
+
+	products := make([]int, 0, len(byProduct))
+	for id := range byProduct {
+		products = append(products, id)
+	}
+	sort.Ints(products)
+
+	var recs []Recommendation
+	for _, id := range products {
+		related := byProduct[id]
+		sort.Slice(related, func(i, j int) bool {
+			if related[i].Score != related[j].Score {
+				return related[i].Score > related[j].Score
+			}
+			return related[i].RecommendedID < related[j].RecommendedID
+		})
+		if len(related) > opts.TopN {
+			related = related[:opts.TopN]
+		}
+		recs = append(recs, related...)
+	}
+	return recs
+}
+
+// similarity returns the cosine similarity of every pair of products shared
+// by at least MinSupport baskets, keyed with the lower product ID first
+func similarity(baskets []Basket, opts Options) map[pair]float64 {
+	counts := make(map[int]int)
+	shared := make(map[pair]int)
+	for _, basket := range baskets {
+		products := distinct(basket)
+		if len(products) > opts.MaxBasketSize {
+			continue
+		}
+		for i, a := range products {
+			counts[a]++
+			for _, b := range products[i+1:] {
+				shared[pair{a, b}]++
+			}
+		}
+	}
+
+	sims := make(map[pair]float64)
+	for pr, n := range shared {
+		if n < opts.MinSupport {
+			continue
+		}
+		sims[pr] = float64(n) / math.Sqrt(float64(counts[pr.a])*float64(counts[pr.b]))
+	}
+	return sims
+}
+
+// distinct returns the products of a basket sorted, without repeats
+func distinct(basket Basket) []int {
+	products := append([]int(nil), basket...)
+	sort.Ints(products)
+	n := 0
+	for i, id := range products {
+		if i == 0 || id != products[n-1] {
+			products[n] = id
+			n++
+		}
+	}
+	return products[:n]
+}
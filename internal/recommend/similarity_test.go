@@ -0,0 +1,121 @@
+package recommend
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	monitor    = 1
+	monitorArm = 2
+	hdmiCable  = 3
+	keyboard   = 4
+	mouse      = 5
+	mousePad   = 6
+)
+
+// purchases holds what each of seven customers bought. Customer 7's
+// monitor and keyboard are bought together only once, too rarely to relate.
+var purchases = []Basket{
+	{monitor, monitorArm, hdmiCable},
+	{monitor, monitorArm},
+	{monitor, hdmiCable},
+	{keyboard, mouse, mousePad},
+	{keyboard, mouse},
+	{mouse, mousePad},
+	{monitor, keyboard},
+}
+
+// views holds what signed-in customers viewed; the last one crawled the
+// whole catalog and is ignored
+var views = []Basket{
+	{monitor, monitorArm, hdmiCable},
+	{monitorArm, hdmiCable},
+	{monitorArm, hdmiCable, monitor, monitor},
+	{mousePad, keyboard},
+	{keyboard, mousePad},
+	crawl(150),
+}
+
+func crawl(n int) Basket {
+	basket := make(Basket, n)
+	for i := range basket {
+		basket[i] = i + 1
+	}
+	return basket
+}
+
+func TestCompute(t *testing.T) {
+	// Cosine similarities: monitor and arm share 2 of 4 and 2 buyers, and 2
+	// of 2 and 3 viewers; views weigh a quarter
+	monitorAndArm := 2/math.Sqrt(4*2) + 0.25*2/math.Sqrt(2*3)
+	armAndCable := 0.25 * 3 / math.Sqrt(3*3)
+	keyboardAndMouse := 2 / math.Sqrt(3*3)
+	mouseAndPad := 2 / math.Sqrt(3*2)
+	keyboardAndPad := 0.25 * 2 / math.Sqrt(2*2)
+
+	want := []Recommendation{
+		{monitor, monitorArm, monitorAndArm},
+		{monitor, hdmiCable, monitorAndArm},
+		{monitorArm, monitor, monitorAndArm},
+		{monitorArm, hdmiCable, armAndCable},
+		{hdmiCable, monitor, monitorAndArm},
+		{hdmiCable, monitorArm, armAndCable},
+		{keyboard, mouse, keyboardAndMouse},
+		{keyboard, mousePad, keyboardAndPad},
+		{mouse, mousePad, mouseAndPad},
+		{mouse, keyboard, keyboardAndMouse},
+		{mousePad, mouse, mouseAndPad},
+		{mousePad, keyboard, keyboardAndPad},
+	}
+
+	// Test case 1: Recommendations per product, strongest first, ties by ID
+	t.Run("synthetic dataset", func(t *testing.T) {
+		got := Compute(purchases, views, Options{})
+		if assert.Len(t, got, len(want)) {
+			for i := range want {
+				assert.Equal(t, want[i].ProductID, got[i].ProductID, "row %d", i)
+				assert.Equal(t, want[i].RecommendedID, got[i].RecommendedID, "row %d", i)
+				assert.InDelta(t, want[i].Score, got[i].Score, 1e-9, "row %d", i)
+			}
+		}
+	})
+
+	// Test case 2: The same data always gives the same result
+	t.Run("deterministic", func(t *testing.T) {
+		first := Compute(purchases, views, Options{})
+		for i := 0; i < 20; i++ {
+			assert.Equal(t, first, Compute(purchases, views, Options{}))
+		}
+	})
+
+	// Test case 3: TopN keeps the strongest recommendation of each product
+	t.Run("top n", func(t *testing.T) {
+		got := Compute(purchases, views, Options{TopN: 1})
+		var pairs [][2]int
+		for _, r := range got {
+			pairs = append(pairs, [2]int{r.ProductID, r.RecommendedID})
+		}
+		assert.Equal(t, [][2]int{
+			{monitor, monitorArm}, {monitorArm, monitor}, {hdmiCable, monitor},
+			{keyboard, mouse}, {mouse, mousePad}, {mousePad, mouse},
+		}, pairs)
+	})
+
+	// Test case 4: Lowering the support relates products bought together once
+	t.Run("min support", func(t *testing.T) {
+		got := Compute(purchases, nil, Options{MinSupport: 1})
+		related := false
+		for _, r := range got {
+			related = related || (r.ProductID == monitor && r.RecommendedID == keyboard)
+		}
+		assert.True(t, related)
+	})
+
+	// Test case 5: No data, no recommendations
+	t.Run("empty", func(t *testing.T) {
+		assert.Empty(t, Compute(nil, nil, Options{}))
+	})
+}
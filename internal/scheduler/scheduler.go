@@ -0,0 +1,57 @@
+// Package scheduler runs periodic background jobs, such as storing scheduled
+// price changes. Each job runs in its own goroutine, so a slow job delays
+// only its own next run.
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Job is a task run at a fixed interval
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Start runs each job right away and then every Interval until ctx is
+// cancelled. Errors are logged and the job runs again at its next tick. The
+// returned channel is closed once every job has stopped, runs in progress
+// when ctx was cancelled included.
+func Start(ctx context.Context, jobs ...Job) <-chan struct{} {
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func(job Job) {
+			defer wg.Done()
+			run(ctx, job)
+		}(job)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return done
+}
+
+func run(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job.Run(ctx); err != nil {
+			log.Printf("⚠️ Job %s failed: %v", job.Name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
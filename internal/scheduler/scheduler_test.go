@@ -0,0 +1,45 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runs := make(chan int, 10)
+	count := 0
+	done := Start(ctx, Job{
+		Name:     "counter",
+		Interval: 5 * time.Millisecond,
+		Run: func(ctx context.Context) error {
+			count++
+			runs <- count
+			// A failing run does not stop the job
+			return errors.New("transient")
+		},
+	})
+
+	for want := 1; want <= 3; want++ {
+		select {
+		case got := <-runs:
+			assert.Equal(t, want, got)
+		case <-time.After(time.Second):
+			t.Fatalf("job ran %d times, want at least 3", want-1)
+		}
+	}
+
+	// Cancelling stops the job
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("job did not stop after cancel")
+	}
+}
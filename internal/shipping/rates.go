@@ -0,0 +1,196 @@
+// Package shipping quotes shipping methods for a parcel and destination. Like
+// the promotion engine it does no I/O: callers load zones and methods.
+package shipping
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// VolumetricDivisor converts a volume in cubic centimetres into the weight
+// in kilograms carriers bill bulky parcels at
+const VolumetricDivisor = 5000
+
+// RateType is how a method computes its cost
+type RateType string
+
+const (
+	// Flat costs the same for every parcel
+	Flat RateType = "flat"
+	// ByWeight looks the billable weight up in the method's tiers
+	ByWeight RateType = "weight"
+	// ByPrice looks the discounted subtotal up in the method's tiers
+	ByPrice RateType = "price"
+)
+
+// Valid reports whether t is a known rate type
+func (t RateType) Valid() bool {
+	return t == Flat || t == ByWeight || t == ByPrice
+}
+
+// Tier is a row of a rate table: parcels up to UpTo (inclusive) cost Cost.
+// An UpTo of 0 means no upper bound.
+type Tier struct {
+	UpTo float64 `json:"up_to" example:"5"`
+	Cost float64 `json:"cost" example:"6.9"`
+}
+
+// Zone groups destinations. Countries holds ISO country codes ("DE"),
+// country-region codes ("US-CA") or "*" for everywhere else.
+type Zone struct {
+	ID        int      `json:"id" example:"1"`
+	Name      string   `json:"name" example:"Domestic"`
+	Countries []string `json:"countries" example:"US"`
+}
+
+// Method is a way of shipping to a zone
+type Method struct {
+	ID     int      `json:"id" example:"1"`
+	ZoneID int      `json:"zone_id" example:"1"`
+	Name   string   `json:"name" example:"Standard"`
+	Type   RateType `json:"type" example:"weight"`
+	// Cost is the price of flat methods
+	Cost float64 `json:"cost" example:"4.99"`
+	// Tiers are the rate table of weight and price methods, in any order
+	Tiers []Tier `json:"tiers"`
+	// FreeOver makes the method free from this subtotal on; 0 disables it
+	FreeOver float64 `json:"free_over" example:"100"`
+	Active   bool    `json:"active" example:"true"`
+}
+
+// Parcel is what is being shipped
+type Parcel struct {
+	// Weight is the billable weight in kilograms
+	Weight float64
+	// Subtotal is the value of the goods after discounts
+	Subtotal float64
+}
+
+// Option is a method available for a parcel, with its cost
+type Option struct {
+	MethodID int     `json:"method_id" example:"1"`
+	Name     string  `json:"name" example:"Standard"`
+	Zone     string  `json:"zone" example:"Domestic"`
+	Cost     float64 `json:"cost" example:"6.9"`
+}
+
+// BillableWeight is the weight a carrier charges for: the actual weight or
+// the volumetric weight, whichever is higher
+func BillableWeight(weight, volume float64) float64 {
+	return math.Max(weight, volume/VolumetricDivisor)
+}
+
+// MatchZone returns the zone covering a destination. A zone naming the
+// region beats one naming the country, which beats "*"; ties go to the
+// lowest ID.
+func MatchZone(zones []Zone, country, region string) (*Zone, bool) {
+	country = strings.ToUpper(country)
+	region = strings.ToUpper(region)
+
+	var best *Zone
+	bestScore := -1
+	for i := range zones {
+		score := -1
+		for _, c := range zones[i].Countries {
+			s := -1
+			switch c = strings.ToUpper(c); {
+			case region != "" && c == country+"-"+region:
+				s = 2
+			case c == country:
+				s = 1
+			case c == "*":
+				s = 0
+			}
+			if s > score {
+				score = s
+			}
+		}
+		if score > bestScore || (score == bestScore && score >= 0 && zones[i].ID < best.ID) {
+			best, bestScore = &zones[i], score
+		}
+	}
+	return best, best != nil
+}
+
+// Price returns what the method charges for a parcel, and false when the
+// parcel is outside its rate table
+func (m Method) Price(p Parcel) (float64, bool) {
+	var cost float64
+	switch m.Type {
+	case Flat:
+		cost = m.Cost
+	case ByWeight, ByPrice:
+		value := p.Weight
+		if m.Type == ByPrice {
+			value = p.Subtotal
+		}
+		tier, ok := lookup(m.Tiers, value)
+		if !ok {
+			return 0, false
+		}
+		cost = tier.Cost
+	default:
+		return 0, false
+	}
+
+	if m.FreeOver > 0 && p.Subtotal >= m.FreeOver {
+		cost = 0
+	}
+	return round(cost), true
+}
+
+// lookup finds the smallest tier covering value
+func lookup(tiers []Tier, value float64) (Tier, bool) {
+	sorted := append([]Tier(nil), tiers...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		// Unbounded tiers go last
+		if sorted[i].UpTo == 0 || sorted[j].UpTo == 0 {
+			return sorted[j].UpTo == 0 && sorted[i].UpTo != 0
+		}
+		return sorted[i].UpTo < sorted[j].UpTo
+	})
+	for _, t := range sorted {
+		if t.UpTo == 0 || value <= t.UpTo {
+			return t, true
+		}
+	}
+	return Tier{}, false
+}
+
+// Quote lists the methods available for a parcel shipped to a destination,
+// cheapest first. With freeShipping set, for instance by a promotion, every
+// option costs nothing.
+func Quote(zones []Zone, methods []Method, country, region string, p Parcel, freeShipping bool) []Option {
+	options := []Option{}
+	zone, ok := MatchZone(zones, country, region)
+	if !ok {
+		return options
+	}
+
+	for _, m := range methods {
+		if m.ZoneID != zone.ID || !m.Active {
+			continue
+		}
+		cost, ok := m.Price(p)
+		if !ok {
+			continue
+		}
+		if freeShipping {
+			cost = 0
+		}
+		options = append(options, Option{MethodID: m.ID, Name: m.Name, Zone: zone.Name, Cost: cost})
+	}
+
+	sort.SliceStable(options, func(i, j int) bool {
+		if options[i].Cost != options[j].Cost {
+			return options[i].Cost < options[j].Cost
+		}
+		return options[i].MethodID < options[j].MethodID
+	})
+	return options
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
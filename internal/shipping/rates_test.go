@@ -0,0 +1,115 @@
+package shipping
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var zones = []Zone{
+	{ID: 1, Name: "Domestic", Countries: []string{"US"}},
+	{ID: 2, Name: "West Coast", Countries: []string{"US-CA", "US-OR", "US-WA"}},
+	{ID: 3, Name: "Europe", Countries: []string{"DE", "FR", "NL"}},
+	{ID: 4, Name: "Rest of world", Countries: []string{"*"}},
+}
+
+var methods = []Method{
+	{ID: 1, ZoneID: 1, Name: "Ground", Type: ByWeight, Tiers: []Tier{{UpTo: 0, Cost: 25}, {UpTo: 1, Cost: 5}, {UpTo: 10, Cost: 12}}, Active: true},
+	{ID: 2, ZoneID: 1, Name: "Express", Type: Flat, Cost: 30, Active: true},
+	{ID: 3, ZoneID: 1, Name: "Retired", Type: Flat, Cost: 1, Active: false},
+	{ID: 4, ZoneID: 2, Name: "Courier", Type: ByPrice, Tiers: []Tier{{UpTo: 50, Cost: 8}, {UpTo: 200, Cost: 4}}, Active: true},
+	{ID: 5, ZoneID: 3, Name: "Parcel", Type: ByWeight, Tiers: []Tier{{UpTo: 5, Cost: 15}}, FreeOver: 150, Active: true},
+	{ID: 6, ZoneID: 4, Name: "International", Type: Flat, Cost: 45, Active: true},
+}
+
+func TestMatchZone(t *testing.T) {
+	tests := []struct {
+		country, region string
+		want            string
+	}{
+		{"US", "NY", "Domestic"},
+		{"us", "ca", "West Coast"},
+		{"US", "", "Domestic"},
+		{"DE", "", "Europe"},
+		{"JP", "", "Rest of world"},
+	}
+
+	for _, tt := range tests {
+		zone, ok := MatchZone(zones, tt.country, tt.region)
+		assert.True(t, ok)
+		assert.Equal(t, tt.want, zone.Name, tt.country+"-"+tt.region)
+	}
+
+	_, ok := MatchZone(zones[:3], "JP", "")
+	assert.False(t, ok)
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		name         string
+		country      string
+		region       string
+		parcel       Parcel
+		freeShipping bool
+		want         []Option
+	}{
+		{
+			name:    "weight tiers and flat, cheapest first",
+			country: "US",
+			parcel:  Parcel{Weight: 0.8, Subtotal: 40},
+			want: []Option{
+				{MethodID: 1, Name: "Ground", Zone: "Domestic", Cost: 5},
+				{MethodID: 2, Name: "Express", Zone: "Domestic", Cost: 30},
+			},
+		},
+		{
+			name:    "unbounded tier catches heavy parcels",
+			country: "US",
+			parcel:  Parcel{Weight: 25, Subtotal: 40},
+			want: []Option{
+				{MethodID: 1, Name: "Ground", Zone: "Domestic", Cost: 25},
+				{MethodID: 2, Name: "Express", Zone: "Domestic", Cost: 30},
+			},
+		},
+		{
+			name:    "price tiers",
+			country: "US",
+			region:  "CA",
+			parcel:  Parcel{Weight: 3, Subtotal: 120},
+			want:    []Option{{MethodID: 4, Name: "Courier", Zone: "West Coast", Cost: 4}},
+		},
+		{
+			name:    "outside the rate table",
+			country: "US",
+			region:  "CA",
+			parcel:  Parcel{Weight: 3, Subtotal: 500},
+			want:    []Option{},
+		},
+		{
+			name:    "free over threshold",
+			country: "DE",
+			parcel:  Parcel{Weight: 2, Subtotal: 150},
+			want:    []Option{{MethodID: 5, Name: "Parcel", Zone: "Europe", Cost: 0}},
+		},
+		{
+			name:         "free shipping promotion",
+			country:      "JP",
+			parcel:       Parcel{Weight: 2, Subtotal: 20},
+			freeShipping: true,
+			want:         []Option{{MethodID: 6, Name: "International", Zone: "Rest of world", Cost: 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Quote(zones, methods, tt.country, tt.region, tt.parcel, tt.freeShipping)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBillableWeight(t *testing.T) {
+	// A 40x30x20 cm box weighs 4.8 kg volumetrically
+	assert.Equal(t, 4.8, BillableWeight(1.2, 40*30*20))
+	assert.Equal(t, 6.0, BillableWeight(6, 40*30*20))
+}
@@ -0,0 +1,104 @@
+// Package slug turns product names into readable URL path segments
+package slug
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength is the longest slug Make returns, leaving room for a
+// collision suffix
+const MaxLength = 80
+
+// fallback is used for names with nothing to transliterate
+const fallback = "product"
+
+// letters transliterates letters that do not decompose into a base letter
+// and combining marks
+var letters = map[rune]string{
+	'ß': "ss", 'ẞ': "ss",
+	'æ': "ae", 'Æ': "ae",
+	'œ': "oe", 'Œ': "oe",
+	'ø': "o", 'Ø': "o",
+	'ł': "l", 'Ł': "l",
+	'đ': "d", 'Đ': "d", 'ð': "d", 'Ð': "d",
+	'þ': "th", 'Þ': "th",
+	'ı': "i",
+	'&': " and ",
+	'+': " plus ",
+}
+
+// Make returns the slug of s: lower-case ASCII letters and digits, with
+// accents removed and every other run of characters turned into a hyphen,
+// e.g. "cafe-creme-200-ml" for "Café Crème 200 ml"
+func Make(s string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range norm.NFKD.String(s) {
+		if t, ok := letters[r]; ok {
+			for _, tr := range t {
+				hyphen = write(&b, tr, hyphen)
+			}
+			continue
+		}
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		hyphen = write(&b, unicode.ToLower(r), hyphen)
+	}
+
+	slug := strings.Trim(b.String(), "-")
+	if len(slug) > MaxLength {
+		slug = slug[:MaxLength]
+		if i := strings.LastIndex(slug, "-"); i > MaxLength/2 {
+			slug = slug[:i]
+		}
+		slug = strings.Trim(slug, "-")
+	}
+	if slug == "" {
+		return fallback
+	}
+	return slug
+}
+
+// write appends r to b, or a single hyphen in place of any run of
+// characters that are not ASCII letters or digits
+func write(b *strings.Builder, r rune, hyphen bool) bool {
+	if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+		b.WriteRune(r)
+		return false
+	}
+	if !hyphen {
+		b.WriteByte('-')
+	}
+	return true
+}
+
+// WithSuffix returns the n-th variant of a slug, used when the slug is
+// already taken: "hammer", "hammer-2", "hammer-3" and so on
+func WithSuffix(slug string, n int) string {
+	if n <= 1 {
+		return slug
+	}
+	return fmt.Sprintf("%s-%d", slug, n)
+}
+
+// IsVariant reports whether s is slug or one of its suffixed variants
+func IsVariant(s, slug string) bool {
+	if s == slug {
+		return true
+	}
+	rest, ok := strings.CutPrefix(s, slug+"-")
+	if !ok || rest == "" {
+		return false
+	}
+	for _, r := range rest {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
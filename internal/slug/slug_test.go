@@ -0,0 +1,43 @@
+package slug
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"Gaming Laptop", "gaming-laptop"},
+		{"  Café Crème 200 ml ", "cafe-creme-200-ml"},
+		{"Chave de Fenda Phillips Nº 2", "chave-de-fenda-phillips-no-2"},
+		{"Straße & Ærø", "strasse-and-aero"},
+		{"Łódź -- Edition!!", "lodz-edition"},
+		{"USB-C + HDMI", "usb-c-plus-hdmi"},
+		{"日本語", "product"},
+		{"", "product"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Make(tt.name), tt.name)
+	}
+}
+
+func TestMake_Length(t *testing.T) {
+	slug := Make(strings.Repeat("heavy duty ", 20))
+	assert.LessOrEqual(t, len(slug), MaxLength)
+	assert.False(t, strings.HasSuffix(slug, "-"))
+	assert.True(t, strings.HasSuffix(slug, "duty"))
+}
+
+func TestIsVariant(t *testing.T) {
+	assert.True(t, IsVariant("hammer", "hammer"))
+	assert.True(t, IsVariant("hammer-12", "hammer"))
+	assert.Equal(t, "hammer-2", WithSuffix("hammer", 2))
+	assert.False(t, IsVariant("hammer-drill", "hammer"))
+	assert.False(t, IsVariant("hammer-", "hammer"))
+	assert.False(t, IsVariant("hammers", "hammer"))
+}
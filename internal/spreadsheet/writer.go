@@ -0,0 +1,161 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	contentTypesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+
+	rootRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+	workbookXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+	workbookRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+
+	sheetHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+	sheetFooter = `</sheetData></worksheet>`
+)
+
+// Writer streams rows into a single-sheet .xlsx file. Rows are written as
+// they arrive, so arbitrarily large sheets can be produced without
+// buffering them in memory.
+type Writer struct {
+	zw    *zip.Writer
+	sheet *bufio.Writer
+	rows  int
+	err   error
+}
+
+// NewWriter starts an .xlsx file on w with one worksheet called sheetName
+func NewWriter(w io.Writer, sheetName string) (*Writer, error) {
+	zw := zip.NewWriter(w)
+
+	var name strings.Builder
+	xml.EscapeText(&name, []byte(sheetName))
+
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", contentTypesXML},
+		{"_rels/.rels", rootRelsXML},
+		{"xl/workbook.xml", fmt.Sprintf(workbookXML, name.String())},
+		{"xl/_rels/workbook.xml.rels", workbookRelsXML},
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(f)
+	if _, err := sheet.WriteString(sheetHeader); err != nil {
+		return nil, err
+	}
+
+	return &Writer{zw: zw, sheet: sheet}, nil
+}
+
+// WriteRow appends a row. Values of type int, int64 and float64 are written
+// as numbers, everything else as text.
+func (w *Writer) WriteRow(values ...interface{}) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.rows++
+
+	b := w.sheet
+	b.WriteString(`<row r="`)
+	b.WriteString(strconv.Itoa(w.rows))
+	b.WriteString(`">`)
+	for i, v := range values {
+		ref := ColumnName(i) + strconv.Itoa(w.rows)
+		switch n := v.(type) {
+		case int:
+			writeNumberCell(b, ref, strconv.Itoa(n))
+		case int64:
+			writeNumberCell(b, ref, strconv.FormatInt(n, 10))
+		case float64:
+			writeNumberCell(b, ref, strconv.FormatFloat(n, 'f', -1, 64))
+		case string:
+			writeStringCell(b, ref, n)
+		case nil:
+			writeStringCell(b, ref, "")
+		default:
+			writeStringCell(b, ref, fmt.Sprint(v))
+		}
+	}
+	_, w.err = b.WriteString(`</row>`)
+	return w.err
+}
+
+// Flush writes buffered rows to the underlying writer
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.err = w.sheet.Flush(); w.err != nil {
+		return w.err
+	}
+	w.err = w.zw.Flush()
+	return w.err
+}
+
+// Close finishes the worksheet and the zip archive. It does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if _, err := w.sheet.WriteString(sheetFooter); err != nil {
+		return err
+	}
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.zw.Close()
+}
+
+func writeNumberCell(b *bufio.Writer, ref, value string) {
+	b.WriteString(`<c r="`)
+	b.WriteString(ref)
+	b.WriteString(`"><v>`)
+	b.WriteString(value)
+	b.WriteString(`</v></c>`)
+}
+
+func writeStringCell(b *bufio.Writer, ref, value string) {
+	b.WriteString(`<c r="`)
+	b.WriteString(ref)
+	b.WriteString(`" t="inlineStr"><is><t xml:space="preserve">`)
+	xml.EscapeText(b, []byte(value))
+	b.WriteString(`</t></is></c>`)
+}
+
+// ColumnName converts a zero-based column index to its letter name ("A", "B", ..., "AA")
+func ColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
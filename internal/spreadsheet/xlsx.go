@@ -0,0 +1,233 @@
+// Package spreadsheet reads and writes the small subset of the Office Open
+// XML spreadsheet format (.xlsx) needed for catalog import and export.
+package spreadsheet
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Limits of the format: cell references beyond them come from broken or
+// hostile files and would make ReadXLSX pad rows without end
+const (
+	maxRows    = 1048576
+	maxColumns = 16384
+)
+
+// maxEntrySize caps how much of a single part of the archive is
+// decompressed, so a small upload cannot unpack into gigabytes
+const maxEntrySize = 100 << 20
+
+type xlsxSharedStrings struct {
+	Items []struct {
+		Text string `xml:"t"`
+		Runs []struct {
+			Text string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"si"`
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Index int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string `xml:"r,attr"`
+			Type   string `xml:"t,attr"`
+			Value  string `xml:"v"`
+			Inline struct {
+				Text string `xml:"t"`
+			} `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// ReadXLSX returns the cell values of the first worksheet of an .xlsx file as
+// rows of strings. Empty rows are kept so row indexes match spreadsheet line
+// numbers (row i of the result is line i+1).
+func ReadXLSX(r io.ReaderAt, size int64) ([][]string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid xlsx file: %v", err)
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var shared []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		var sst xlsxSharedStrings
+		if err := decodeZipXML(f, &sst); err != nil {
+			return nil, err
+		}
+		for _, si := range sst.Items {
+			text := si.Text
+			for _, run := range si.Runs {
+				text += run.Text
+			}
+			shared = append(shared, text)
+		}
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var sheet xlsxWorksheet
+	if err := decodeZipXML(files[sheetPath], &sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for i, row := range sheet.Rows {
+		index := row.Index
+		if index == 0 {
+			index = i + 1
+		}
+		if index < 1 || index > maxRows {
+			return nil, fmt.Errorf("invalid xlsx file: row %d is out of range", index)
+		}
+		for len(rows) < index-1 {
+			rows = append(rows, nil)
+		}
+
+		var values []string
+		for j, cell := range row.Cells {
+			col := j
+			if cell.Ref != "" {
+				col, err = columnIndex(cell.Ref)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if col >= maxColumns {
+				return nil, fmt.Errorf("invalid xlsx file: cell %s is out of range", cell.Ref)
+			}
+			for len(values) < col {
+				values = append(values, "")
+			}
+
+			value := cell.Value
+			switch cell.Type {
+			case "s":
+				n, err := strconv.Atoi(cell.Value)
+				if err != nil || n < 0 || n >= len(shared) {
+					return nil, fmt.Errorf("invalid shared string reference in cell %s", cell.Ref)
+				}
+				value = shared[n]
+			case "inlineStr":
+				value = cell.Inline.Text
+			case "b":
+				if value == "1" {
+					value = "TRUE"
+				} else {
+					value = "FALSE"
+				}
+			}
+			values = append(values, value)
+		}
+		rows = append(rows, values)
+	}
+
+	return rows, nil
+}
+
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	wb, ok := files["xl/workbook.xml"]
+	if !ok {
+		return "", errors.New("invalid xlsx file: missing workbook")
+	}
+
+	var workbook xlsxWorkbook
+	if err := decodeZipXML(wb, &workbook); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", errors.New("invalid xlsx file: workbook has no sheets")
+	}
+
+	if f, ok := files["xl/_rels/workbook.xml.rels"]; ok {
+		var rels xlsxRelationships
+		if err := decodeZipXML(f, &rels); err != nil {
+			return "", err
+		}
+		for _, rel := range rels.Relationships {
+			if rel.ID != workbook.Sheets[0].RID {
+				continue
+			}
+			target := rel.Target
+			if strings.HasPrefix(target, "/") {
+				target = strings.TrimPrefix(target, "/")
+			} else {
+				target = path.Join("xl", target)
+			}
+			if _, ok := files[target]; ok {
+				return target, nil
+			}
+		}
+	}
+
+	if _, ok := files["xl/worksheets/sheet1.xml"]; ok {
+		return "xl/worksheets/sheet1.xml", nil
+	}
+	return "", errors.New("invalid xlsx file: worksheet not found")
+}
+
+func decodeZipXML(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	lr := &io.LimitedReader{R: rc, N: maxEntrySize + 1}
+	if err := xml.NewDecoder(lr).Decode(v); err != nil {
+		if lr.N == 0 {
+			return fmt.Errorf("invalid xlsx file: %s is larger than %d MB uncompressed", f.Name, maxEntrySize>>20)
+		}
+		return fmt.Errorf("invalid xlsx file: %s: %v", f.Name, err)
+	}
+	return nil
+}
+
+// columnIndex converts a cell reference such as "C12" to a zero-based column index
+func columnIndex(ref string) (int, error) {
+	col := 0
+	n := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+		n++
+		if col > maxColumns {
+			return 0, fmt.Errorf("invalid xlsx file: cell %s is out of range", ref)
+		}
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("invalid cell reference %q", ref)
+	}
+	return col - 1, nil
+}
@@ -0,0 +1,83 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriterReadXLSX_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+
+	w, err := NewWriter(&buf, "Products")
+	assert.NoError(t, err)
+	assert.NoError(t, w.WriteRow("id", "name", "price"))
+	assert.NoError(t, w.WriteRow(1, "Hammer & <Nails>", 29.99))
+	assert.NoError(t, w.WriteRow(2, "", 19.5))
+	assert.NoError(t, w.Close())
+
+	rows, err := ReadXLSX(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"id", "name", "price"},
+		{"1", "Hammer & <Nails>", "29.99"},
+		{"2", "", "19.5"},
+	}, rows)
+}
+
+// sheetXLSX builds a minimal .xlsx file whose only worksheet holds sheetData
+func sheetXLSX(t *testing.T, sheetData string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	parts := map[string]string{
+		"xl/workbook.xml":          `<workbook><sheets><sheet name="Sheet1"/></sheets></workbook>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData>` + sheetData + `</sheetData></worksheet>`,
+	}
+	for name, body := range parts {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadXLSX_OutOfRange(t *testing.T) {
+	// Test case 1: Rows and columns at the limits of the format are read
+	data := sheetXLSX(t, `<row r="3"><c r="C3" t="inlineStr"><is><t>x</t></is></c></row>`)
+	rows, err := ReadXLSX(bytes.NewReader(data), int64(len(data)))
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{nil, nil, {"", "", "x"}}, rows)
+
+	// Test case 2: A row beyond the last one of the format is rejected
+	// rather than padded up to
+	data = sheetXLSX(t, `<row r="2000000000"><c r="A2000000000"><v>1</v></c></row>`)
+	_, err = ReadXLSX(bytes.NewReader(data), int64(len(data)))
+	assert.EqualError(t, err, "invalid xlsx file: row 2000000000 is out of range")
+
+	// Test case 3: So is a column beyond XFD
+	data = sheetXLSX(t, `<row r="1"><c r="XFE1"><v>1</v></c></row>`)
+	_, err = ReadXLSX(bytes.NewReader(data), int64(len(data)))
+	assert.EqualError(t, err, "invalid xlsx file: cell XFE1 is out of range")
+}
+
+func TestColumnName(t *testing.T) {
+	assert.Equal(t, "A", ColumnName(0))
+	assert.Equal(t, "Z", ColumnName(25))
+	assert.Equal(t, "AA", ColumnName(26))
+	assert.Equal(t, "AZ", ColumnName(51))
+
+	for i := 0; i < 100; i++ {
+		col, err := columnIndex(ColumnName(i) + "7")
+		assert.NoError(t, err)
+		assert.Equal(t, i, col)
+	}
+}
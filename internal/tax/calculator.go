@@ -0,0 +1,279 @@
+// Package tax computes the sales tax of order lines. Callers depend on the
+// TaxCalculator interface so an external tax service can replace the local
+// table-driven calculator without touching cart or checkout code.
+package tax
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultClass is the tax class of products that were not given one
+const DefaultClass = "standard"
+
+// Mode says whether prices already include tax
+type Mode string
+
+const (
+	// Exclusive prices have tax added on top
+	Exclusive Mode = "exclusive"
+	// Inclusive prices contain the tax, which is extracted from them
+	Inclusive Mode = "inclusive"
+)
+
+// Rounding says where tax amounts are rounded to cents
+type Rounding string
+
+const (
+	// PerLine rounds the tax of every line, then adds them up
+	PerLine Rounding = "line"
+	// PerOrder adds up the exact tax of all lines sharing a rate and rounds
+	// once; line amounts are then allocated so they add up to the total
+	PerOrder Rounding = "order"
+)
+
+// ParseMode validates a pricing mode name
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(strings.ToLower(s)); m {
+	case Exclusive, Inclusive:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown tax mode %q", s)
+}
+
+// ParseRounding validates a rounding rule name
+func ParseRounding(s string) (Rounding, error) {
+	switch r := Rounding(strings.ToLower(s)); r {
+	case PerLine, PerOrder:
+		return r, nil
+	}
+	return "", fmt.Errorf("unknown tax rounding %q", s)
+}
+
+// Address is the place that decides which rates apply
+type Address struct {
+	Country string `json:"country" example:"DE"`
+	Region  string `json:"region,omitempty" example:"BY"`
+}
+
+// Normalize uppercases the country and region codes
+func (a Address) Normalize() Address {
+	return Address{Country: strings.ToUpper(strings.TrimSpace(a.Country)), Region: strings.ToUpper(strings.TrimSpace(a.Region))}
+}
+
+// Line is an amount to tax: a line total after discounts, in the shop's
+// pricing mode
+type Line struct {
+	Class  string
+	Amount float64
+}
+
+// Request asks for the tax of a set of lines shipped to Address at a time
+type Request struct {
+	Address Address
+	Lines   []Line
+	At      time.Time
+}
+
+// LineTax is the tax of one line
+type LineTax struct {
+	Rate float64
+	Tax  float64
+}
+
+// RateTotal sums up the lines taxed at one rate, for display on carts and
+// invoices
+type RateTotal struct {
+	Name    string  `json:"name" example:"VAT"`
+	Rate    float64 `json:"rate" example:"19"`
+	Taxable float64 `json:"taxable" example:"100"`
+	Tax     float64 `json:"tax" example:"19"`
+}
+
+// Result is the tax of a request. Net and Gross are the totals without and
+// with tax, whatever the pricing mode.
+type Result struct {
+	Lines     []LineTax
+	Rates     []RateTotal
+	Tax       float64
+	Net       float64
+	Gross     float64
+	Inclusive bool
+}
+
+// TaxCalculator computes tax for order lines
+type TaxCalculator interface {
+	Calculate(ctx context.Context, req Request) (*Result, error)
+}
+
+// Rate is a tax rate in percent for a tax class in a country or, when Region
+// is set, a region of it. It applies from EffectiveFrom until EffectiveTo,
+// exclusive; a nil EffectiveTo means until further notice.
+type Rate struct {
+	ID            int        `json:"id" example:"1"`
+	Country       string     `json:"country" example:"DE"`
+	Region        string     `json:"region,omitempty" example:""`
+	Class         string     `json:"tax_class" example:"standard"`
+	Name          string     `json:"name" example:"VAT"`
+	Rate          float64    `json:"rate" example:"19"`
+	EffectiveFrom time.Time  `json:"effective_from"`
+	EffectiveTo   *time.Time `json:"effective_to,omitempty"`
+}
+
+// effective reports whether the rate applies at t
+func (r Rate) effective(t time.Time) bool {
+	return !t.Before(r.EffectiveFrom) && (r.EffectiveTo == nil || t.Before(*r.EffectiveTo))
+}
+
+// RateSource supplies the rates of a country in effect at a time. It may
+// return more; the calculator filters again.
+type RateSource interface {
+	RatesFor(ctx context.Context, country string, at time.Time) ([]Rate, error)
+}
+
+// Table is an in-memory RateSource
+type Table []Rate
+
+func (t Table) RatesFor(ctx context.Context, country string, at time.Time) ([]Rate, error) {
+	var rates []Rate
+	for _, r := range t {
+		if strings.EqualFold(r.Country, country) {
+			rates = append(rates, r)
+		}
+	}
+	return rates, nil
+}
+
+// TableCalculator is the local TaxCalculator. For every line it picks the
+// rate of the line's class for the region, falling back to the country-wide
+// rate; lines without a matching rate are not taxed.
+type TableCalculator struct {
+	Rates    RateSource
+	Mode     Mode
+	Rounding Rounding
+}
+
+func (c *TableCalculator) Calculate(ctx context.Context, req Request) (*Result, error) {
+	addr := req.Address.Normalize()
+	result := &Result{Lines: make([]LineTax, len(req.Lines)), Rates: []RateTotal{}, Inclusive: c.Mode == Inclusive}
+
+	var rates []Rate
+	if addr.Country != "" {
+		var err error
+		rates, err = c.Rates.RatesFor(ctx, addr.Country, req.At)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Lines are grouped by rate so per-order rounding and the summary work
+	// on the same buckets. Groups keep the order of their first line.
+	type group struct {
+		rate  Rate
+		lines []int
+		exact []float64
+	}
+	var groups []*group
+	byKey := map[string]*group{}
+	for i, line := range req.Lines {
+		rate, ok := pick(rates, addr, line.Class, req.At)
+		if !ok || line.Amount <= 0 {
+			continue
+		}
+		key := fmt.Sprintf("%s|%g", rate.Name, rate.Rate)
+		g := byKey[key]
+		if g == nil {
+			g = &group{rate: rate}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+		g.lines = append(g.lines, i)
+		g.exact = append(g.exact, c.exactTax(line.Amount, rate.Rate))
+		result.Lines[i].Rate = rate.Rate
+	}
+
+	for _, g := range groups {
+		total := RateTotal{Name: g.rate.Name, Rate: g.rate.Rate}
+		if c.Rounding == PerOrder {
+			// Allocate by rounding the running sum, so line amounts add up
+			// to the once-rounded group total
+			var running, allocated float64
+			for j, i := range g.lines {
+				running += g.exact[j]
+				result.Lines[i].Tax = round(round(running) - allocated)
+				allocated = round(allocated + result.Lines[i].Tax)
+			}
+		} else {
+			for j, i := range g.lines {
+				result.Lines[i].Tax = round(g.exact[j])
+			}
+		}
+		for _, i := range g.lines {
+			total.Taxable += req.Lines[i].Amount
+			total.Tax += result.Lines[i].Tax
+		}
+		if result.Inclusive {
+			total.Taxable -= total.Tax
+		}
+		total.Taxable = round(total.Taxable)
+		total.Tax = round(total.Tax)
+		result.Rates = append(result.Rates, total)
+		result.Tax += total.Tax
+	}
+	sort.SliceStable(result.Rates, func(i, j int) bool { return result.Rates[i].Rate > result.Rates[j].Rate })
+
+	var amount float64
+	for _, line := range req.Lines {
+		amount += line.Amount
+	}
+	result.Tax = round(result.Tax)
+	if result.Inclusive {
+		result.Gross = round(amount)
+		result.Net = round(amount - result.Tax)
+	} else {
+		result.Net = round(amount)
+		result.Gross = round(amount + result.Tax)
+	}
+	return result, nil
+}
+
+// exactTax is the unrounded tax of an amount at a percentage rate
+func (c *TableCalculator) exactTax(amount, rate float64) float64 {
+	if c.Mode == Inclusive {
+		return amount - amount/(1+rate/100)
+	}
+	return amount * rate / 100
+}
+
+// pick returns the rate for class at addr, preferring a regional rate
+func pick(rates []Rate, addr Address, class string, at time.Time) (Rate, bool) {
+	if class == "" {
+		class = DefaultClass
+	}
+	var countryWide *Rate
+	for i := range rates {
+		r := rates[i]
+		if r.Class != class || !r.effective(at) {
+			continue
+		}
+		region := strings.ToUpper(r.Region)
+		if region != "" && region == addr.Region {
+			return r, true
+		}
+		if region == "" && countryWide == nil {
+			countryWide = &rates[i]
+		}
+	}
+	if countryWide != nil {
+		return *countryWide, true
+	}
+	return Rate{}, false
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,177 @@
+package tax
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	jan2024 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	jul2024 = time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+)
+
+var rates = Table{
+	{ID: 1, Country: "DE", Class: "standard", Name: "VAT", Rate: 19, EffectiveFrom: jan2024},
+	{ID: 2, Country: "DE", Class: "reduced", Name: "VAT", Rate: 7, EffectiveFrom: jan2024},
+	{ID: 3, Country: "US", Region: "CA", Class: "standard", Name: "Sales tax", Rate: 7.25, EffectiveFrom: jan2024},
+	// A rate change half way through the year
+	{ID: 4, Country: "FR", Class: "standard", Name: "TVA", Rate: 20, EffectiveFrom: jan2024, EffectiveTo: &jul2024},
+	{ID: 5, Country: "FR", Class: "standard", Name: "TVA", Rate: 21, EffectiveFrom: jul2024},
+}
+
+func TestTableCalculator_Calculate(t *testing.T) {
+	at := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		mode      Mode
+		rounding  Rounding
+		address   Address
+		at        time.Time
+		lines     []Line
+		wantLines []float64
+		wantTax   float64
+		wantNet   float64
+		wantGross float64
+	}{
+		{
+			name:      "exclusive per line",
+			mode:      Exclusive,
+			rounding:  PerLine,
+			address:   Address{Country: "DE"},
+			lines:     []Line{{Class: "standard", Amount: 100}, {Class: "reduced", Amount: 10}},
+			wantLines: []float64{19, 0.7},
+			wantTax:   19.7,
+			wantNet:   110,
+			wantGross: 129.7,
+		},
+		{
+			name:      "inclusive extracts tax",
+			mode:      Inclusive,
+			rounding:  PerLine,
+			address:   Address{Country: "de"},
+			lines:     []Line{{Class: "standard", Amount: 119}},
+			wantLines: []float64{19},
+			wantTax:   19,
+			wantNet:   100,
+			wantGross: 119,
+		},
+		{
+			// 19% of 0.50 is 0.095: rounded per line that is 0.10 three
+			// times, while rounding the 0.285 total once gives 0.29
+			name:      "per line rounding",
+			mode:      Exclusive,
+			rounding:  PerLine,
+			address:   Address{Country: "DE"},
+			lines:     []Line{{Amount: 0.5}, {Amount: 0.5}, {Amount: 0.5}},
+			wantLines: []float64{0.1, 0.1, 0.1},
+			wantTax:   0.3,
+			wantNet:   1.5,
+			wantGross: 1.8,
+		},
+		{
+			name:      "per order rounding",
+			mode:      Exclusive,
+			rounding:  PerOrder,
+			address:   Address{Country: "DE"},
+			lines:     []Line{{Amount: 0.5}, {Amount: 0.5}, {Amount: 0.5}},
+			wantLines: []float64{0.1, 0.09, 0.1},
+			wantTax:   0.29,
+			wantNet:   1.5,
+			wantGross: 1.79,
+		},
+		{
+			name:      "regional rate",
+			mode:      Exclusive,
+			rounding:  PerLine,
+			address:   Address{Country: "US", Region: "CA"},
+			lines:     []Line{{Class: "standard", Amount: 100}},
+			wantLines: []float64{7.25},
+			wantTax:   7.25,
+			wantNet:   100,
+			wantGross: 107.25,
+		},
+		{
+			name:      "no rate for region",
+			mode:      Exclusive,
+			rounding:  PerLine,
+			address:   Address{Country: "US", Region: "OR"},
+			lines:     []Line{{Class: "standard", Amount: 100}},
+			wantLines: []float64{0},
+			wantTax:   0,
+			wantNet:   100,
+			wantGross: 100,
+		},
+		{
+			name:      "effective dates",
+			mode:      Exclusive,
+			rounding:  PerLine,
+			address:   Address{Country: "FR"},
+			at:        jul2024,
+			lines:     []Line{{Class: "standard", Amount: 100}},
+			wantLines: []float64{21},
+			wantTax:   21,
+			wantNet:   100,
+			wantGross: 121,
+		},
+		{
+			name:      "unknown class is not taxed",
+			mode:      Exclusive,
+			rounding:  PerLine,
+			address:   Address{Country: "DE"},
+			lines:     []Line{{Class: "zero", Amount: 50}},
+			wantLines: []float64{0},
+			wantTax:   0,
+			wantNet:   50,
+			wantGross: 50,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calc := &TableCalculator{Rates: rates, Mode: tt.mode, Rounding: tt.rounding}
+			when := at
+			if !tt.at.IsZero() {
+				when = tt.at
+			}
+
+			result, err := calc.Calculate(context.Background(), Request{Address: tt.address, Lines: tt.lines, At: when})
+			assert.NoError(t, err)
+
+			var lineTaxes []float64
+			for _, line := range result.Lines {
+				lineTaxes = append(lineTaxes, line.Tax)
+			}
+			assert.Equal(t, tt.wantLines, lineTaxes)
+			assert.Equal(t, tt.wantTax, result.Tax)
+			assert.Equal(t, tt.wantNet, result.Net)
+			assert.Equal(t, tt.wantGross, result.Gross)
+		})
+	}
+}
+
+func TestTableCalculator_RateSummary(t *testing.T) {
+	calc := &TableCalculator{Rates: rates, Mode: Inclusive, Rounding: PerOrder}
+	lines := []Line{{Class: "reduced", Amount: 10.7}, {Class: "standard", Amount: 119}, {Class: "standard", Amount: 11.9}}
+
+	result, err := calc.Calculate(context.Background(), Request{Address: Address{Country: "DE"}, Lines: lines, At: jul2024})
+	assert.NoError(t, err)
+	assert.Equal(t, []RateTotal{
+		{Name: "VAT", Rate: 19, Taxable: 110, Tax: 20.9},
+		{Name: "VAT", Rate: 7, Taxable: 10, Tax: 0.7},
+	}, result.Rates)
+	assert.Equal(t, 21.6, result.Tax)
+	assert.Equal(t, 141.6, result.Gross)
+}
+
+func TestParseRounding(t *testing.T) {
+	r, err := ParseRounding("ORDER")
+	assert.NoError(t, err)
+	assert.Equal(t, PerOrder, r)
+
+	_, err = ParseRounding("nearest")
+	assert.EqualError(t, err, `unknown tax rounding "nearest"`)
+}
@@ -0,0 +1,7 @@
+ALTER TABLE order_items DROP COLUMN IF EXISTS discount;
+ALTER TABLE orders DROP COLUMN IF EXISTS free_shipping;
+ALTER TABLE orders DROP COLUMN IF EXISTS coupon_code;
+ALTER TABLE orders DROP COLUMN IF EXISTS discount;
+ALTER TABLE carts DROP COLUMN IF EXISTS coupon_code;
+DROP TABLE IF EXISTS promotion_redemptions;
+DROP TABLE IF EXISTS promotions;
@@ -0,0 +1,39 @@
+-- Promotions with a code are coupons; promotions without one apply
+-- automatically to every cart they match
+CREATE TABLE IF NOT EXISTS promotions (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    code VARCHAR(64) UNIQUE,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('percentage', 'fixed_amount', 'free_shipping')),
+    value DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (value >= 0),
+    starts_at TIMESTAMP WITH TIME ZONE,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    min_subtotal DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    min_quantity INTEGER NOT NULL DEFAULT 0,
+    product_ids INTEGER[] NOT NULL DEFAULT '{}',
+    category_ids INTEGER[] NOT NULL DEFAULT '{}',
+    usage_limit INTEGER NOT NULL DEFAULT 0,
+    per_user_limit INTEGER NOT NULL DEFAULT 0,
+    priority INTEGER NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS promotion_redemptions (
+    id SERIAL PRIMARY KEY,
+    promotion_id INTEGER NOT NULL REFERENCES promotions(id) ON DELETE CASCADE,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount DECIMAL(10, 2) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (promotion_id, order_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_promotion_redemptions_user ON promotion_redemptions(promotion_id, user_id);
+
+ALTER TABLE carts ADD COLUMN coupon_code VARCHAR(64);
+
+ALTER TABLE orders ADD COLUMN discount DECIMAL(10, 2) NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN coupon_code VARCHAR(64);
+ALTER TABLE orders ADD COLUMN free_shipping BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE order_items ADD COLUMN discount DECIMAL(10, 2) NOT NULL DEFAULT 0;
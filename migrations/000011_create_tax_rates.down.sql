@@ -0,0 +1,9 @@
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax;
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax_rate;
+ALTER TABLE orders DROP COLUMN IF EXISTS tax_region;
+ALTER TABLE orders DROP COLUMN IF EXISTS tax_country;
+ALTER TABLE orders DROP COLUMN IF EXISTS prices_include_tax;
+ALTER TABLE orders DROP COLUMN IF EXISTS tax;
+DROP TABLE IF EXISTS tax_rates;
+ALTER TABLE products DROP COLUMN IF EXISTS tax_class;
+DROP TABLE IF EXISTS tax_classes;
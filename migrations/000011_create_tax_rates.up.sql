@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS tax_classes (
+    code VARCHAR(32) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL
+);
+
+INSERT INTO tax_classes (code, name) VALUES
+    ('standard', 'Standard rate'),
+    ('reduced', 'Reduced rate'),
+    ('zero', 'Zero rate')
+ON CONFLICT (code) DO NOTHING;
+
+ALTER TABLE products ADD COLUMN tax_class VARCHAR(32) NOT NULL DEFAULT 'standard' REFERENCES tax_classes(code);
+
+-- Rates in percent. A rate with an empty region applies to the whole
+-- country unless the region has a rate of its own.
+CREATE TABLE IF NOT EXISTS tax_rates (
+    id SERIAL PRIMARY KEY,
+    country CHAR(2) NOT NULL,
+    region VARCHAR(10) NOT NULL DEFAULT '',
+    tax_class VARCHAR(32) NOT NULL REFERENCES tax_classes(code) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    rate DECIMAL(7, 4) NOT NULL CHECK (rate >= 0),
+    effective_from DATE NOT NULL,
+    effective_to DATE,
+    CHECK (effective_to IS NULL OR effective_to > effective_from)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tax_rates_country ON tax_rates(country, tax_class);
+
+INSERT INTO tax_rates (country, region, tax_class, name, rate, effective_from) VALUES
+    ('DE', '', 'standard', 'VAT', 19, '2021-01-01'),
+    ('DE', '', 'reduced', 'VAT', 7, '2021-01-01'),
+    ('DE', '', 'zero', 'VAT', 0, '2023-01-01'),
+    ('US', 'CA', 'standard', 'Sales tax', 7.25, '2017-01-01'),
+    ('US', 'NY', 'standard', 'Sales tax', 4, '2017-01-01');
+
+ALTER TABLE orders ADD COLUMN tax DECIMAL(10, 2) NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN prices_include_tax BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE orders ADD COLUMN tax_country CHAR(2);
+ALTER TABLE orders ADD COLUMN tax_region VARCHAR(10);
+ALTER TABLE order_items ADD COLUMN tax_rate DECIMAL(7, 4) NOT NULL DEFAULT 0;
+ALTER TABLE order_items ADD COLUMN tax DECIMAL(10, 2) NOT NULL DEFAULT 0;
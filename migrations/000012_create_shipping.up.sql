@@ -0,0 +1,32 @@
+-- Weight in kilograms, dimensions in centimetres; 0 means unknown
+ALTER TABLE products ADD COLUMN weight DECIMAL(10, 3) NOT NULL DEFAULT 0 CHECK (weight >= 0);
+ALTER TABLE products ADD COLUMN length DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (length >= 0);
+ALTER TABLE products ADD COLUMN width DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (width >= 0);
+ALTER TABLE products ADD COLUMN height DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (height >= 0);
+
+-- Countries holds ISO country codes, country-region codes such as 'US-CA',
+-- or '*' for everywhere not covered by another zone
+CREATE TABLE IF NOT EXISTS shipping_zones (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    countries TEXT[] NOT NULL DEFAULT '{}'
+);
+
+-- Tiers is a JSON rate table of {"up_to": ..., "cost": ...} rows, by
+-- billable weight for 'weight' methods and by subtotal for 'price' methods
+CREATE TABLE IF NOT EXISTS shipping_methods (
+    id SERIAL PRIMARY KEY,
+    zone_id INTEGER NOT NULL REFERENCES shipping_zones(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('flat', 'weight', 'price')),
+    cost DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (cost >= 0),
+    tiers JSONB NOT NULL DEFAULT '[]',
+    free_over DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+CREATE INDEX IF NOT EXISTS idx_shipping_methods_zone_id ON shipping_methods(zone_id);
+
+ALTER TABLE orders ADD COLUMN shipping_method_id INTEGER REFERENCES shipping_methods(id) ON DELETE SET NULL;
+ALTER TABLE orders ADD COLUMN shipping_method VARCHAR(255);
+ALTER TABLE orders ADD COLUMN shipping_cost DECIMAL(10, 2) NOT NULL DEFAULT 0;
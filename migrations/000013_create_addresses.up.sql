@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS addresses (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    company VARCHAR(255),
+    line1 VARCHAR(255) NOT NULL,
+    line2 VARCHAR(255),
+    city VARCHAR(255) NOT NULL,
+    region VARCHAR(10),
+    postal_code VARCHAR(16),
+    country CHAR(2) NOT NULL,
+    phone VARCHAR(32),
+    is_default_shipping BOOLEAN NOT NULL DEFAULT FALSE,
+    is_default_billing BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_addresses_user_id ON addresses(user_id);
+
+-- A user has at most one default address of each kind
+CREATE UNIQUE INDEX IF NOT EXISTS idx_addresses_default_shipping ON addresses(user_id) WHERE is_default_shipping;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_addresses_default_billing ON addresses(user_id) WHERE is_default_billing;
+
+-- Orders keep a copy of their addresses, so editing or deleting an address
+-- does not rewrite past orders
+ALTER TABLE orders ADD COLUMN shipping_address JSONB;
+ALTER TABLE orders ADD COLUMN billing_address JSONB;
@@ -0,0 +1,24 @@
+-- Invoice numbers run without gaps per kind and year. Issuing a number
+-- increments last_number in the transaction that stores the invoice, so the
+-- row stays locked until it commits and a rollback gives the number back.
+CREATE TABLE IF NOT EXISTS invoice_sequences (
+    kind VARCHAR(20) NOT NULL,
+    year INTEGER NOT NULL,
+    last_number INTEGER NOT NULL,
+    PRIMARY KEY (kind, year)
+);
+
+-- Invoices are issued when an order is paid and credit notes when it is
+-- refunded. Total is the order total; a credit note reverses it.
+CREATE TABLE IF NOT EXISTS invoices (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id),
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('invoice', 'credit_note')),
+    number VARCHAR(32) NOT NULL UNIQUE,
+    year INTEGER NOT NULL,
+    sequence INTEGER NOT NULL,
+    credits_invoice_id INTEGER REFERENCES invoices(id),
+    total DECIMAL(10, 2) NOT NULL,
+    issued_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (order_id, kind)
+);
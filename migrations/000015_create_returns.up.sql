@@ -0,0 +1,32 @@
+-- Returns are requested by customers on lines of delivered orders and
+-- move through requested -> approved -> received -> refunded, or are
+-- rejected. Refund amount is fixed when the return is requested.
+CREATE TABLE IF NOT EXISTS returns (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id),
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'requested'
+        CHECK (status IN ('requested', 'approved', 'rejected', 'received', 'refunded')),
+    note TEXT NOT NULL DEFAULT '',
+    admin_note TEXT NOT NULL DEFAULT '',
+    refund_amount DECIMAL(10, 2) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_returns_order_id ON returns(order_id);
+CREATE INDEX IF NOT EXISTS idx_returns_user_id ON returns(user_id);
+CREATE INDEX IF NOT EXISTS idx_returns_status_created_at ON returns(status, created_at);
+
+CREATE TABLE IF NOT EXISTS return_items (
+    id SERIAL PRIMARY KEY,
+    return_id INTEGER NOT NULL REFERENCES returns(id) ON DELETE CASCADE,
+    order_item_id INTEGER NOT NULL REFERENCES order_items(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    reason VARCHAR(32) NOT NULL
+        CHECK (reason IN ('damaged', 'defective', 'wrong_item', 'not_as_described', 'no_longer_needed', 'other')),
+    comment TEXT NOT NULL DEFAULT '',
+    UNIQUE (return_id, order_item_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_return_items_order_item_id ON return_items(order_item_id);
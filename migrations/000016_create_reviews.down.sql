@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS review_votes;
+DROP TABLE IF EXISTS reviews;
+ALTER TABLE products DROP COLUMN IF EXISTS rating_count;
+ALTER TABLE products DROP COLUMN IF EXISTS rating_average;
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS products_back_in_stock ON products;
+DROP FUNCTION IF EXISTS enqueue_back_in_stock();
+DROP TABLE IF EXISTS stock_notifications;
+DROP TABLE IF EXISTS stock_subscriptions;
+DROP TABLE IF EXISTS wishlist_items;
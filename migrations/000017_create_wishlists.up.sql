@@ -0,0 +1,54 @@
+CREATE TABLE IF NOT EXISTS wishlist_items (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, product_id)
+);
+
+-- A subscription asks to be told once when an out-of-stock product is back
+CREATE TABLE IF NOT EXISTS stock_subscriptions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_subscriptions_product_id ON stock_subscriptions(product_id);
+
+-- Outbox of notifications waiting to be sent. A notification is claimed by
+-- pushing next_attempt_at forward, so a crashed sender's claims are retried.
+CREATE TABLE IF NOT EXISTS stock_notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    product_name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_notifications_pending ON stock_notifications(next_attempt_at) WHERE status = 'pending';
+
+-- Every stock movement that brings a product above zero, whichever code
+-- path makes it, queues a notification per subscription in the same
+-- transaction and ends the subscriptions
+CREATE OR REPLACE FUNCTION enqueue_back_in_stock() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO stock_notifications (user_id, product_id, product_name, email)
+    SELECT s.user_id, NEW.id, NEW.name, s.email FROM stock_subscriptions s WHERE s.product_id = NEW.id;
+    DELETE FROM stock_subscriptions WHERE product_id = NEW.id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER products_back_in_stock
+    AFTER UPDATE OF stock ON products
+    FOR EACH ROW
+    WHEN (OLD.stock <= 0 AND NEW.stock > 0)
+    EXECUTE FUNCTION enqueue_back_in_stock();
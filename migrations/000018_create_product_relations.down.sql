@@ -0,0 +1,10 @@
+DROP TRIGGER IF EXISTS products_propagate_bundle_changes ON products;
+DROP TRIGGER IF EXISTS products_keep_bundle_price ON products;
+DROP FUNCTION IF EXISTS propagate_bundle_changes();
+DROP FUNCTION IF EXISTS keep_bundle_price();
+DROP FUNCTION IF EXISTS refresh_bundle(INTEGER);
+DROP FUNCTION IF EXISTS bundle_stock(INTEGER);
+DROP FUNCTION IF EXISTS bundle_price(INTEGER);
+DROP TABLE IF EXISTS bundle_items;
+DROP TABLE IF EXISTS bundles;
+DROP TABLE IF EXISTS product_relations;
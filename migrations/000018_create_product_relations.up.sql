@@ -0,0 +1,94 @@
+CREATE TABLE IF NOT EXISTS product_relations (
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    related_product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('related', 'accessory', 'upsell', 'replacement')),
+    position INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (product_id, type, related_product_id),
+    CHECK (product_id <> related_product_id)
+);
+
+-- A bundle is a product sold as a set of component products. Its price is
+-- the components' total less the bundle discount, and its stock is the
+-- number of complete sets the components' stock makes up.
+CREATE TABLE IF NOT EXISTS bundles (
+    product_id INTEGER PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    discount_percent NUMERIC(5,2) NOT NULL DEFAULT 0 CHECK (discount_percent >= 0 AND discount_percent < 100)
+);
+
+CREATE TABLE IF NOT EXISTS bundle_items (
+    bundle_id INTEGER NOT NULL REFERENCES bundles(product_id) ON DELETE CASCADE,
+    component_id INTEGER NOT NULL REFERENCES products(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (bundle_id, component_id),
+    CHECK (bundle_id <> component_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bundle_items_component_id ON bundle_items(component_id);
+
+CREATE OR REPLACE FUNCTION bundle_price(bundle INTEGER) RETURNS NUMERIC AS $$
+    SELECT ROUND(COALESCE(SUM(c.price * bi.quantity), 0) * (100 - b.discount_percent) / 100, 2)
+    FROM bundles b
+    LEFT JOIN bundle_items bi ON bi.bundle_id = b.product_id
+    LEFT JOIN products c ON c.id = bi.component_id
+    WHERE b.product_id = bundle
+    GROUP BY b.discount_percent
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION bundle_stock(bundle INTEGER) RETURNS INTEGER AS $$
+    SELECT COALESCE(MIN(GREATEST(c.stock, 0) / bi.quantity), 0)::INTEGER
+    FROM bundle_items bi
+    JOIN products c ON c.id = bi.component_id
+    WHERE bi.bundle_id = bundle
+$$ LANGUAGE sql STABLE;
+
+-- refresh_bundle stores a bundle's derived price and stock
+CREATE OR REPLACE FUNCTION refresh_bundle(bundle INTEGER) RETURNS VOID AS $$
+    UPDATE products SET price = bundle_price(bundle), stock = bundle_stock(bundle)
+    WHERE id = bundle AND (price, stock) IS DISTINCT FROM (bundle_price(bundle), bundle_stock(bundle))
+$$ LANGUAGE sql;
+
+-- A bundle's price cannot be set by hand
+CREATE OR REPLACE FUNCTION keep_bundle_price() RETURNS trigger AS $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM bundles WHERE product_id = NEW.id) THEN
+        NEW.price := bundle_price(NEW.id);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER products_keep_bundle_price
+    BEFORE UPDATE OF price ON products
+    FOR EACH ROW
+    EXECUTE FUNCTION keep_bundle_price();
+
+-- Selling, cancelling or restocking a bundle moves its components' stock;
+-- changes to a component's price or stock are carried into its bundles.
+-- Only statements issued by the application (trigger depth 1) move
+-- components, so the bundle refreshes below do not move them again.
+CREATE OR REPLACE FUNCTION propagate_bundle_changes() RETURNS trigger AS $$
+BEGIN
+    IF pg_trigger_depth() = 1 AND NEW.stock <> OLD.stock
+        AND EXISTS (SELECT 1 FROM bundles WHERE product_id = NEW.id) THEN
+        UPDATE products c SET stock = c.stock + (NEW.stock - OLD.stock) * bi.quantity
+        FROM bundle_items bi
+        WHERE bi.bundle_id = NEW.id AND c.id = bi.component_id;
+        PERFORM refresh_bundle(NEW.id);
+    END IF;
+
+    PERFORM refresh_bundle(bi.bundle_id) FROM bundle_items bi WHERE bi.component_id = NEW.id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER products_propagate_bundle_changes
+    AFTER UPDATE OF price, stock ON products
+    FOR EACH ROW
+    WHEN (OLD.price IS DISTINCT FROM NEW.price OR OLD.stock IS DISTINCT FROM NEW.stock)
+    EXECUTE FUNCTION propagate_bundle_changes();
+
+INSERT INTO product_relations (product_id, related_product_id, type, position)
+SELECT l.id, r.id, 'accessory', CASE r.name WHEN 'Gaming Headset' THEN 0 ELSE 1 END
+FROM products l, products r
+WHERE l.name = 'Gaming Laptop' AND r.name IN ('Gaming Headset', 'RGB Mouse Pad')
+ON CONFLICT DO NOTHING;
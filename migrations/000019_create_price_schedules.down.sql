@@ -0,0 +1,14 @@
+DROP TRIGGER IF EXISTS products_record_price_revision ON products;
+DROP TRIGGER IF EXISTS products_touch_price_changed_at ON products;
+DROP FUNCTION IF EXISTS record_price_revision();
+DROP FUNCTION IF EXISTS touch_price_changed_at();
+DROP FUNCTION IF EXISTS effective_price(products);
+DROP FUNCTION IF EXISTS regular_price(products);
+DROP TABLE IF EXISTS product_price_revisions;
+DROP TABLE IF EXISTS scheduled_price_changes;
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_sale_window_check;
+ALTER TABLE products
+    DROP COLUMN IF EXISTS price_changed_at,
+    DROP COLUMN IF EXISTS sale_ends_at,
+    DROP COLUMN IF EXISTS sale_starts_at,
+    DROP COLUMN IF EXISTS sale_price;
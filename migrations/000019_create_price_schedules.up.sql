@@ -0,0 +1,101 @@
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS sale_price DECIMAL(10,2) CHECK (sale_price > 0),
+    ADD COLUMN IF NOT EXISTS sale_starts_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS sale_ends_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS price_changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+ALTER TABLE products ADD CONSTRAINT products_sale_window_check
+    CHECK (sale_starts_at IS NULL OR sale_ends_at IS NULL OR sale_ends_at > sale_starts_at);
+
+CREATE TABLE IF NOT EXISTS scheduled_price_changes (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    price DECIMAL(10,2) NOT NULL CHECK (price > 0),
+    effective_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'applied', 'superseded', 'cancelled')),
+    note TEXT NOT NULL DEFAULT '',
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    applied_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_price_changes_product_id ON scheduled_price_changes(product_id, effective_at);
+CREATE INDEX IF NOT EXISTS idx_scheduled_price_changes_due ON scheduled_price_changes(effective_at) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS product_price_revisions (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    old_price DECIMAL(10,2) NOT NULL,
+    new_price DECIMAL(10,2) NOT NULL,
+    source VARCHAR(20) NOT NULL CHECK (source IN ('manual', 'scheduled', 'bundle')),
+    price_change_id INTEGER REFERENCES scheduled_price_changes(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_price_revisions_product_id ON product_price_revisions(product_id, created_at);
+
+-- regular_price resolves scheduled changes at read time, so a change takes
+-- effect on the minute even before the scheduler stores it. A price set by
+-- hand after a change fell due wins over it.
+CREATE OR REPLACE FUNCTION regular_price(p products) RETURNS NUMERIC AS $$
+    SELECT COALESCE((
+        SELECT s.price FROM scheduled_price_changes s
+        WHERE s.product_id = p.id AND s.status = 'pending'
+            AND s.effective_at <= NOW() AND s.effective_at > p.price_changed_at
+        ORDER BY s.effective_at DESC, s.id DESC
+        LIMIT 1), p.price)
+$$ LANGUAGE sql STABLE;
+
+-- effective_price is what customers pay: the sale price while a sale runs,
+-- unless the regular price is lower
+CREATE OR REPLACE FUNCTION effective_price(p products) RETURNS NUMERIC AS $$
+    SELECT CASE
+        WHEN p.sale_price IS NOT NULL
+            AND (p.sale_starts_at IS NULL OR p.sale_starts_at <= NOW())
+            AND (p.sale_ends_at IS NULL OR p.sale_ends_at > NOW())
+        THEN LEAST(p.sale_price, regular_price(p))
+        ELSE regular_price(p)
+    END
+$$ LANGUAGE sql STABLE;
+
+-- The scheduler sets price_changed_at to the change's effective time; any
+-- other price change happens now
+CREATE OR REPLACE FUNCTION touch_price_changed_at() RETURNS trigger AS $$
+BEGIN
+    IF NEW.price IS DISTINCT FROM OLD.price AND NEW.price_changed_at IS NOT DISTINCT FROM OLD.price_changed_at THEN
+        NEW.price_changed_at := NOW();
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER products_touch_price_changed_at
+    BEFORE UPDATE OF price ON products
+    FOR EACH ROW
+    EXECUTE FUNCTION touch_price_changed_at();
+
+-- Every price change is recorded. The scheduler names the change it applies
+-- in the garage.price_change_id setting; bundles are repriced by triggers.
+CREATE OR REPLACE FUNCTION record_price_revision() RETURNS trigger AS $$
+DECLARE
+    change_id INTEGER := NULLIF(current_setting('garage.price_change_id', true), '')::INTEGER;
+BEGIN
+    IF pg_trigger_depth() > 1 THEN
+        INSERT INTO product_price_revisions (product_id, old_price, new_price, source)
+        VALUES (NEW.id, OLD.price, NEW.price, 'bundle');
+    ELSIF change_id IS NOT NULL THEN
+        INSERT INTO product_price_revisions (product_id, old_price, new_price, source, price_change_id)
+        VALUES (NEW.id, OLD.price, NEW.price, 'scheduled', change_id);
+    ELSE
+        INSERT INTO product_price_revisions (product_id, old_price, new_price, source)
+        VALUES (NEW.id, OLD.price, NEW.price, 'manual');
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER products_record_price_revision
+    AFTER UPDATE OF price ON products
+    FOR EACH ROW
+    WHEN (OLD.price IS DISTINCT FROM NEW.price)
+    EXECUTE FUNCTION record_price_revision();
@@ -0,0 +1,6 @@
+DROP FUNCTION IF EXISTS is_published(products);
+DROP TABLE IF EXISTS product_status_history;
+DROP INDEX IF EXISTS idx_products_status;
+ALTER TABLE products
+    DROP COLUMN IF EXISTS publish_at,
+    DROP COLUMN IF EXISTS status;
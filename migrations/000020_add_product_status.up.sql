@@ -0,0 +1,30 @@
+-- New products start as drafts; the catalog that existed before the
+-- publishing workflow stays on sale
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'draft'
+        CHECK (status IN ('draft', 'in_review', 'published', 'archived')),
+    ADD COLUMN IF NOT EXISTS publish_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE products SET status = 'published';
+
+CREATE INDEX IF NOT EXISTS idx_products_status ON products(status, publish_at);
+
+CREATE TABLE IF NOT EXISTS product_status_history (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    publish_at TIMESTAMP WITH TIME ZONE,
+    note TEXT NOT NULL DEFAULT '',
+    changed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_status_history_product_id ON product_status_history(product_id, created_at);
+
+-- A published product goes live at its publish_at, when one is set.
+-- Visibility is resolved when products are read, like prices, so a
+-- scheduled publication needs no background job.
+CREATE OR REPLACE FUNCTION is_published(p products) RETURNS BOOLEAN AS $$
+    SELECT p.status = 'published' AND (p.publish_at IS NULL OR p.publish_at <= NOW())
+$$ LANGUAGE sql STABLE;
@@ -0,0 +1,14 @@
+-- Products' own name and descriptions are in the store's default locale;
+-- translations hold the other locales. Empty descriptions fall back to the
+-- default locale.
+CREATE TABLE IF NOT EXISTS product_translations (
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    locale VARCHAR(35) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    html_content TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (product_id, locale)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_translations_locale ON product_translations(locale);
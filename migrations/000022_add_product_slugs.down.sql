@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS product_slug_redirects;
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_slug_key;
+ALTER TABLE products DROP COLUMN IF EXISTS slug;
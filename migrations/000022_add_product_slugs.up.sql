@@ -0,0 +1,31 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS slug VARCHAR(100);
+
+-- Backfill the existing catalog. The API derives slugs for new and renamed
+-- products itself; this approximation only knows Western European accents.
+WITH base AS (
+    SELECT id, COALESCE(NULLIF(TRIM(BOTH '-' FROM LEFT(regexp_replace(
+        lower(translate(name,
+            'ÀÁÂÃÄÅàáâãäåÇçÈÉÊËèéêëÌÍÎÏìíîïÑñÒÓÔÕÖØòóôõöøÙÚÛÜùúûüÝýÿ',
+            'AAAAAAaaaaaaCcEEEEeeeeIIIIiiiiNnOOOOOOooooooUUUUuuuuYyy')),
+        '[^a-z0-9]+', '-', 'g'), 80)), ''), 'product') AS slug
+    FROM products
+), numbered AS (
+    SELECT id, slug, ROW_NUMBER() OVER (PARTITION BY slug ORDER BY id) AS n FROM base
+)
+UPDATE products p
+SET slug = CASE WHEN numbered.n = 1 THEN numbered.slug ELSE numbered.slug || '-' || numbered.n END
+FROM numbered
+WHERE numbered.id = p.id;
+
+ALTER TABLE products ALTER COLUMN slug SET NOT NULL;
+ALTER TABLE products ADD CONSTRAINT products_slug_key UNIQUE (slug);
+
+-- Slugs a product was known by before it was renamed. They keep answering
+-- with a permanent redirect and are not given to other products.
+CREATE TABLE IF NOT EXISTS product_slug_redirects (
+    slug VARCHAR(100) PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_slug_redirects_product_id ON product_slug_redirects(product_id);
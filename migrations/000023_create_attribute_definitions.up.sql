@@ -0,0 +1,21 @@
+-- Attributes are defined per category: the products of a category carry
+-- values for its definitions in products.attributes, keyed by code
+CREATE TABLE IF NOT EXISTS attribute_definitions (
+    id SERIAL PRIMARY KEY,
+    category_id INTEGER NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    code VARCHAR(64) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(16) NOT NULL CHECK (type IN ('string', 'number', 'enum', 'boolean', 'unit')),
+    unit VARCHAR(16) NOT NULL DEFAULT '',
+    options TEXT[] NOT NULL DEFAULT '{}',
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    position INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (category_id, code),
+    CHECK ((type = 'unit') = (unit <> '')),
+    CHECK ((type = 'enum') = (cardinality(options) > 0))
+);
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}';
+
+-- jsonb_path_ops serves the containment (@>) filters of the product list
+CREATE INDEX IF NOT EXISTS idx_products_attributes ON products USING GIN (attributes jsonb_path_ops);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS product_view_daily;
+DROP TABLE IF EXISTS product_views;
@@ -0,0 +1,19 @@
+-- Raw product page views, kept for VIEW_RETENTION and then rolled up into
+-- product_view_daily
+CREATE TABLE IF NOT EXISTS product_views (
+    id BIGSERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    viewed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_product_views_viewed_at ON product_views(viewed_at);
+CREATE INDEX IF NOT EXISTS idx_product_views_user ON product_views(user_id, viewed_at DESC) WHERE user_id IS NOT NULL;
+
+-- Views per product and UTC day, for views older than the retention
+CREATE TABLE IF NOT EXISTS product_view_daily (
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    views INTEGER NOT NULL,
+    PRIMARY KEY (product_id, day)
+);
+CREATE INDEX IF NOT EXISTS idx_product_view_daily_day ON product_view_daily(day);
@@ -0,0 +1,11 @@
+-- Computed by the recommendations job from order lines and product views;
+-- the whole table is replaced on each run
+CREATE TABLE IF NOT EXISTS product_recommendations (
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    recommended_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    score DOUBLE PRECISION NOT NULL,
+    computed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (product_id, recommended_id),
+    CHECK (product_id <> recommended_id)
+);
+CREATE INDEX IF NOT EXISTS idx_product_recommendations_score ON product_recommendations(product_id, score DESC);